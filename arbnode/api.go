@@ -11,6 +11,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/offchainlabs/nitro/staker"
+	"github.com/offchainlabs/nitro/validator/server_api"
 	"github.com/pkg/errors"
 )
 
@@ -69,3 +70,39 @@ func (a *BlockValidatorDebugAPI) ValidateBlock(
 	result.Latency = fmt.Sprintf("%vms", time.Since(start_time).Milliseconds())
 	return result, err
 }
+
+// ValidationInput returns the input for validating a block, from the validation input store if it has it
+func (a *BlockValidatorDebugAPI) ValidationInput(ctx context.Context, blockNum rpc.BlockNumber) (*server_api.ValidationInputJson, error) {
+	if blockNum < 0 {
+		return nil, errors.New("this method only accepts absolute block numbers")
+	}
+	header := a.blockchain.GetHeaderByNumber(uint64(blockNum))
+	if header == nil {
+		return nil, errors.New("block not found")
+	}
+	if !a.blockchain.Config().IsArbitrumNitro(header.Number) {
+		return nil, types.ErrUseFallback
+	}
+	input, err := a.val.ValidationInput(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	return server_api.ValidationInputToJson(input), nil
+}
+
+type StakerAPI struct {
+	staker *staker.Staker
+}
+
+// Decisions returns up to count of the staker's most recent decisions, oldest first, or all of those kept if count is zero
+func (a *StakerAPI) Decisions(ctx context.Context, count hexutil.Uint64) ([]*staker.StakerDecision, error) {
+	return a.staker.Decisions(int(count)), nil
+}
+
+func (a *StakerAPI) LatestDecision(ctx context.Context) (*staker.StakerDecision, error) {
+	decisions := a.staker.Decisions(1)
+	if len(decisions) == 0 {
+		return nil, errors.New("staker hasn't made any decisions yet")
+	}
+	return decisions[0], nil
+}
@@ -434,6 +434,9 @@ func (c *Config) Validate() error {
 	if err := c.Staker.Validate(); err != nil {
 		return err
 	}
+	if err := c.BlockValidator.Validate(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -581,6 +584,7 @@ type Node struct {
 	DASLifecycleManager     *das.LifecycleManager
 	ClassicOutboxRetriever  *ClassicOutboxRetriever
 	SyncMonitor             *SyncMonitor
+	PrivacyWrapper          *privacy.PrivacyWrapper
 	configFetcher           ConfigFetcher
 	ctx                     context.Context
 }
@@ -831,7 +835,7 @@ func createNodeImpl(
 	txStreamer.SetInboxReaders(inboxReader, delayedBridge)
 
 	var statelessBlockValidator *staker.StatelessBlockValidator
-	if config.BlockValidator.URL != "" {
+	if config.BlockValidator.URL != "" || config.BlockValidator.ValidationServers != "" {
 		statelessBlockValidator, err = staker.NewStatelessBlockValidator(
 			inboxReader,
 			inboxTracker,
@@ -914,7 +918,7 @@ func createNodeImpl(
 		if err != nil {
 			return nil, err
 		}
-		log.Info("running as validator", "txSender", txSenderPtr, "actingAsWallet", wallet.Address(), "whitelisted", whitelisted, "strategy", config.Staker.Strategy)
+		log.Info("running as validator", "txSender", txSenderPtr, "actingAsWallet", wallet.Address(), "whitelisted", whitelisted, "strategy", config.Staker.Strategy, "dryRun", config.Staker.DryRun)
 	}
 
 	var batchPoster *BatchPoster
@@ -1002,6 +1006,14 @@ func CreateNode(
 			Public: false,
 		})
 	}
+	if currentNode.Staker != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "arbstaker",
+			Version:   "1.0",
+			Service:   &StakerAPI{staker: currentNode.Staker},
+			Public:    false,
+		})
+	}
 
 	apis = append(apis, rpc.API{
 		Namespace: "arb",
@@ -1013,10 +1025,18 @@ func CreateNode(
 
 	// add privacy api for asn node
 	if config.PrivacyConfig.Enable {
+		privacyConfig := config.PrivacyConfig
+		if privacyConfig.Auth.ChainID == 0 {
+			privacyConfig.Auth.ChainID = l2BlockChain.Config().ChainID.Uint64()
+		}
+		currentNode.PrivacyWrapper, err = privacy.NewWrapper(&privacyConfig, rawdb.NewTable(arbDb, PrivacyPrefix), dataSigner)
+		if err != nil {
+			return nil, err
+		}
 		apis = append(apis, rpc.API{
 			Namespace: "privacy",
 			Version:   "1.0",
-			Service:   privacy.NewPrivacyAPI(privacy.NewWrapper(&config.PrivacyConfig)),
+			Service:   privacy.NewPrivacyAPI(currentNode.PrivacyWrapper),
 			Public:    true,
 		})
 	}
@@ -1098,6 +1118,9 @@ func (n *Node) Start(ctx context.Context) error {
 	if n.MaintenanceRunner != nil {
 		n.MaintenanceRunner.Start(ctx)
 	}
+	if n.PrivacyWrapper != nil {
+		n.PrivacyWrapper.Start(ctx)
+	}
 	if n.DelayedSequencer != nil {
 		n.DelayedSequencer.Start(ctx)
 	}
@@ -1169,6 +1192,9 @@ func (n *Node) StopAndWait() {
 	if n.configFetcher != nil && n.configFetcher.Started() {
 		n.configFetcher.StopAndWait()
 	}
+	if n.PrivacyWrapper != nil && n.PrivacyWrapper.Started() {
+		n.PrivacyWrapper.StopAndWait()
+	}
 	if n.SeqCoordinator != nil && n.SeqCoordinator.Started() {
 		// Releases the chosen sequencer lockout,
 		// and stops the background thread but not the redis client.
@@ -5,6 +5,7 @@ package arbnode
 
 var (
 	BlockValidatorPrefix       string = "v"         // the prefix for all block validator keys
+	PrivacyPrefix              string = "p"         // the prefix for all privacy grant keys
 	messagePrefix              []byte = []byte("m") // maps a message sequence number to a message
 	legacyDelayedMessagePrefix []byte = []byte("d") // maps a delayed sequence number to an accumulator and a message as serialized on L1
 	rlpDelayedMessagePrefix    []byte = []byte("e") // maps a delayed sequence number to an accumulator and an RLP encoded message
@@ -22,6 +22,7 @@ import (
 	"github.com/pkg/errors"
 	flag "github.com/spf13/pflag"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 
@@ -70,6 +71,7 @@ type Config struct {
 	URLs                    []string                 `koanf:"url"`
 	Verifier                signature.VerifierConfig `koanf:"verify"`
 	EnableCompression       bool                     `koanf:"enable-compression" reload:"hot"`
+	Merge                   MergeConfig              `koanf:"merge" reload:"hot"`
 }
 
 func (c *Config) Enable() bool {
@@ -87,6 +89,23 @@ func ConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.StringSlice(prefix+".url", DefaultConfig.URLs, "URL of sequencer feed source")
 	signature.FeedVerifierConfigAddOptions(prefix+".verify", f)
 	f.Bool(prefix+".enable-compression", DefaultConfig.EnableCompression, "enable per message deflate compression support")
+	MergeConfigAddOptions(prefix+".merge", f)
+}
+
+// MergeConfig is how messages from more than one feed URL are merged
+type MergeConfig struct {
+	MaxLag         uint64        `koanf:"max-lag" reload:"hot"`
+	DemotionPeriod time.Duration `koanf:"demotion-period" reload:"hot"`
+}
+
+func MergeConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Uint64(prefix+".max-lag", DefaultMergeConfig.MaxLag, "number of messages a feed source can be behind the others before it's demoted")
+	f.Duration(prefix+".demotion-period", DefaultMergeConfig.DemotionPeriod, "how long a feed source that disagreed with the others on a message is demoted for")
+}
+
+var DefaultMergeConfig = MergeConfig{
+	MaxLag:         40,
+	DemotionPeriod: 10 * time.Minute,
 }
 
 var DefaultConfig = Config{
@@ -98,6 +117,7 @@ var DefaultConfig = Config{
 	URLs:                    []string{""},
 	Timeout:                 20 * time.Second,
 	EnableCompression:       true,
+	Merge:                   DefaultMergeConfig,
 }
 
 var DefaultTestConfig = Config{
@@ -109,6 +129,7 @@ var DefaultTestConfig = Config{
 	URLs:                    []string{""},
 	Timeout:                 200 * time.Millisecond,
 	EnableCompression:       true,
+	Merge:                   DefaultMergeConfig,
 }
 
 type TransactionStreamerInterface interface {
@@ -224,6 +245,7 @@ func (bc *BroadcastClient) connect(ctx context.Context, nextSeqNum arbutil.Messa
 	var foundFeedServerVersion bool
 	var chainId uint64
 	var feedServerVersion uint64
+	var feedSigner *common.Address
 
 	config := bc.config()
 	var extensions []httphead.Option
@@ -268,6 +290,12 @@ func (bc *BroadcastClient) connect(ctx context.Context, nextSeqNum arbutil.Messa
 					)
 					return ErrIncorrectChainId
 				}
+			} else if headerName == wsbroadcastserver.HTTPHeaderFeedSigner {
+				if !common.IsHexAddress(headerValue) {
+					return errors.Errorf("malformed feed signer %v", headerValue)
+				}
+				signer := common.HexToAddress(headerValue)
+				feedSigner = &signer
 			}
 			return nil
 		},
@@ -320,6 +348,15 @@ func (bc *BroadcastClient) connect(ctx context.Context, nextSeqNum arbutil.Messa
 	bc.conn = conn
 	bc.connMutex.Unlock()
 	log.Info("Feed connected", "feedServerVersion", feedServerVersion, "chainId", chainId, "requestedSeqNum", nextSeqNum)
+	if feedSigner != nil {
+		// A relay re-signing the feed says whose signatures to expect
+		verifier := config.Verifier
+		if bc.sigVerifier != nil && !verifier.Dangerous.AcceptMissing && !verifier.AcceptSequencer && !bc.sigVerifier.IsAllowedAddress(*feedSigner) {
+			log.Warn("feed signer isn't an allowed address, its messages will fail verification", "url", bc.websocketUrl, "signer", feedSigner)
+		} else {
+			log.Info("feed messages are signed by", "url", bc.websocketUrl, "signer", feedSigner)
+		}
+	}
 
 	return earlyFrameData, nil
 }
@@ -16,6 +16,8 @@ import (
 
 type BroadcastClients struct {
 	clients []*broadcastclient.BroadcastClient
+	// Dedups and compares the messages from each client when there's more than one
+	merger *feedMerger
 
 	// Use atomic access
 	connected int32
@@ -38,18 +40,37 @@ func NewBroadcastClients(
 
 	clients := BroadcastClients{}
 	clients.clients = make([]*broadcastclient.BroadcastClient, 0, urlCount)
+	if urlCount > 1 {
+		clients.merger = newFeedMerger(
+			func() *broadcastclient.MergeConfig { return &configFetcher().Merge },
+			l2ChainId,
+			currentMessageCount,
+			txStreamer,
+			config.URLs,
+		)
+	}
 	var lastClientErr error
-	for _, address := range config.URLs {
+	for i, address := range config.URLs {
+		i := i
+		clientStreamer := txStreamer
+		if clients.merger != nil {
+			clientStreamer = clients.merger.source(i)
+		}
 		client, err := broadcastclient.NewBroadcastClient(
 			configFetcher,
 			address,
 			l2ChainId,
 			currentMessageCount,
-			txStreamer,
+			clientStreamer,
 			confirmedSequenceNumberListener,
 			fatalErrChan,
 			bpVerifier,
-			func(delta int32) { clients.adjustCount(delta) },
+			func(delta int32) {
+				clients.adjustCount(delta)
+				if clients.merger != nil {
+					clients.merger.setConnected(i, delta > 0)
+				}
+			},
 		)
 		if err != nil {
 			lastClientErr = err
@@ -0,0 +1,308 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcastclients
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/broadcastclient"
+	"github.com/offchainlabs/nitro/broadcaster"
+)
+
+var (
+	mergeDuplicatesCounter     = metrics.NewRegisteredCounter("arb/feed/merge/duplicates", nil)
+	mergeGapsCounter           = metrics.NewRegisteredCounter("arb/feed/merge/gaps", nil)
+	mergeEquivocationsCounter  = metrics.NewRegisteredCounter("arb/feed/merge/equivocations", nil)
+	mergeDemotedDroppedCounter = metrics.NewRegisteredCounter("arb/feed/merge/demoted/dropped", nil)
+	mergeDemotedGauge          = metrics.NewRegisteredGauge("arb/feed/merge/demoted", nil)
+)
+
+// Forwarded messages are remembered for this many sequence numbers to compare against the other sources
+const mergeWindow = 4096
+
+type forwardedMessage struct {
+	message *broadcaster.BroadcastFeedMessage
+	source  int
+	hash    *common.Hash
+	// The sources that sent the forwarded message, and the hashes of those that sent a different one
+	agreed   map[int]struct{}
+	disputed map[int]common.Hash
+}
+
+type feedSource struct {
+	url          string
+	connected    bool
+	highest      arbutil.MessageIndex
+	hasHighest   bool
+	lagging      bool
+	demotedUntil time.Time
+}
+
+func (s *feedSource) demoted(now time.Time) bool {
+	return s.lagging || now.Before(s.demotedUntil)
+}
+
+// feedMerger forwards each sequence number once from whichever source has it first, and compares
+// what the other sources send for it. A source that disagrees with a forwarded message while another
+// healthy source still agrees with it, or that falls behind the others, is demoted: its messages are only
+// forwarded while no healthy source is connected. Otherwise a different message is a reorg, and replaces the forwarded one.
+type feedMerger struct {
+	config     func() *broadcastclient.MergeConfig
+	chainId    uint64
+	downstream broadcastclient.TransactionStreamerInterface
+
+	mutex     sync.Mutex
+	sources   []*feedSource
+	forwarded map[arbutil.MessageIndex]*forwardedMessage
+	// Messages before floor are no longer compared and are dropped
+	floor arbutil.MessageIndex
+	// next is one past the highest sequence number forwarded
+	next arbutil.MessageIndex
+}
+
+func newFeedMerger(
+	config func() *broadcastclient.MergeConfig,
+	chainId uint64,
+	currentMessageCount arbutil.MessageIndex,
+	downstream broadcastclient.TransactionStreamerInterface,
+	urls []string,
+) *feedMerger {
+	m := &feedMerger{
+		config:     config,
+		chainId:    chainId,
+		downstream: downstream,
+		forwarded:  make(map[arbutil.MessageIndex]*forwardedMessage),
+		floor:      currentMessageCount,
+		next:       currentMessageCount,
+	}
+	for _, url := range urls {
+		m.sources = append(m.sources, &feedSource{url: url})
+	}
+	return m
+}
+
+// feedMergerSource is what each BroadcastClient adds its messages to
+type feedMergerSource struct {
+	merger *feedMerger
+	index  int
+}
+
+func (s *feedMergerSource) AddBroadcastMessages(feedMessages []*broadcaster.BroadcastFeedMessage) error {
+	return s.merger.add(s.index, feedMessages, time.Now())
+}
+
+func (m *feedMerger) source(index int) *feedMergerSource {
+	return &feedMergerSource{m, index}
+}
+
+func (m *feedMerger) setConnected(index int, connected bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.sources[index].connected = connected
+}
+
+func (m *feedMerger) hash(forwarded *forwardedMessage) (common.Hash, error) {
+	if forwarded.hash == nil {
+		hash, err := forwarded.message.Hash(m.chainId)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		forwarded.hash = &hash
+	}
+	return *forwarded.hash, nil
+}
+
+// othersAgree is whether a healthy source other than the given one sent the forwarded message
+func (m *feedMerger) othersAgree(except int, forwarded *forwardedMessage, now time.Time) bool {
+	for i := range forwarded.agreed {
+		source := m.sources[i]
+		if i != except && source.connected && !source.demoted(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// healthySourceConnected is whether a source other than the given one can be relied on instead of it
+func (m *feedMerger) healthySourceConnected(except int, now time.Time) bool {
+	for i, source := range m.sources {
+		if i != except && source.connected && !source.demoted(now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *feedMerger) add(index int, feedMessages []*broadcaster.BroadcastFeedMessage, now time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	source := m.sources[index]
+	var toForward []*broadcaster.BroadcastFeedMessage
+	for _, message := range feedMessages {
+		if message == nil {
+			continue
+		}
+		seqNum := message.SequenceNumber
+		if !source.hasHighest || seqNum > source.highest {
+			source.highest = seqNum
+			source.hasHighest = true
+		}
+		if seqNum < m.floor {
+			mergeDuplicatesCounter.Inc(1)
+			continue
+		}
+		if forwarded, ok := m.forwarded[seqNum]; ok {
+			mergeDuplicatesCounter.Inc(1)
+			hash, forwardedHash, differs := m.differs(index, forwarded, message)
+			if !differs {
+				forwarded.agreed[index] = struct{}{}
+				continue
+			}
+			if forwarded.source != index && m.othersAgree(index, forwarded, now) {
+				m.equivocated(index, forwarded, message.SequenceNumber, hash, forwardedHash, now)
+				continue
+			}
+			if forwarded.source != index && source.demoted(now) && m.healthySourceConnected(index, now) {
+				mergeDemotedDroppedCounter.Inc(1)
+				continue
+			}
+			// The source of the forwarded message resent it, or no healthy source still agrees with it: a reorg
+			m.reorged(index, forwarded, message, hash, forwardedHash)
+			toForward = append(toForward, message)
+			continue
+		}
+		if source.demoted(now) && m.healthySourceConnected(index, now) {
+			mergeDemotedDroppedCounter.Inc(1)
+			continue
+		}
+		if seqNum > m.next {
+			log.Warn("gap in merged feed", "from", m.next, "to", seqNum-1, "url", source.url)
+			mergeGapsCounter.Inc(int64(seqNum - m.next))
+		}
+		if seqNum >= m.next {
+			m.next = seqNum + 1
+		}
+		m.forwarded[seqNum] = &forwardedMessage{
+			message:  message,
+			source:   index,
+			agreed:   map[int]struct{}{index: {}},
+			disputed: make(map[int]common.Hash),
+		}
+		toForward = append(toForward, message)
+	}
+	m.prune()
+	m.updateLagging(now)
+	if len(toForward) == 0 {
+		return nil
+	}
+	return m.downstream.AddBroadcastMessages(toForward)
+}
+
+// differs compares a message with the one already forwarded for its sequence number
+func (m *feedMerger) differs(index int, forwarded *forwardedMessage, message *broadcaster.BroadcastFeedMessage) (common.Hash, common.Hash, bool) {
+	if forwarded.message == message {
+		return common.Hash{}, common.Hash{}, false
+	}
+	forwardedHash, err := m.hash(forwarded)
+	if err != nil {
+		log.Warn("unable to hash forwarded feed message", "sequenceNumber", message.SequenceNumber, "err", err)
+		return common.Hash{}, common.Hash{}, false
+	}
+	hash, err := message.Hash(m.chainId)
+	if err != nil {
+		log.Warn("unable to hash feed message", "sequenceNumber", message.SequenceNumber, "url", m.sources[index].url, "err", err)
+		return common.Hash{}, common.Hash{}, false
+	}
+	return hash, forwardedHash, hash != forwardedHash
+}
+
+func (m *feedMerger) equivocated(index int, forwarded *forwardedMessage, seqNum arbutil.MessageIndex, hash common.Hash, forwardedHash common.Hash, now time.Time) {
+	source := m.sources[index]
+	mergeEquivocationsCounter.Inc(1)
+	log.Error(
+		"feed sources disagree on message",
+		"sequenceNumber", seqNum,
+		"url", source.url,
+		"hash", hash,
+		"forwardedUrl", m.sources[forwarded.source].url,
+		"forwardedHash", forwardedHash,
+	)
+	if !source.demoted(now) {
+		log.Warn("demoting feed source that disagreed with the forwarded message", "url", source.url)
+	}
+	source.demotedUntil = now.Add(m.config().DemotionPeriod)
+	forwarded.disputed[index] = hash
+}
+
+// reorged replaces the forwarded message, the sources that were demoted for sending the new one were right after all
+func (m *feedMerger) reorged(index int, forwarded *forwardedMessage, message *broadcaster.BroadcastFeedMessage, hash common.Hash, forwardedHash common.Hash) {
+	if forwarded.source != index {
+		log.Warn(
+			"forwarding reorg of feed message no healthy source still agrees with",
+			"sequenceNumber", message.SequenceNumber,
+			"url", m.sources[index].url,
+			"hash", hash,
+			"forwardedUrl", m.sources[forwarded.source].url,
+			"forwardedHash", forwardedHash,
+		)
+	}
+	agreed := map[int]struct{}{index: {}}
+	for i, disputedHash := range forwarded.disputed {
+		if disputedHash != hash {
+			continue
+		}
+		agreed[i] = struct{}{}
+		if m.sources[i].demotedUntil != (time.Time{}) {
+			log.Info("promoting feed source that sent the reorged message", "url", m.sources[i].url)
+			m.sources[i].demotedUntil = time.Time{}
+		}
+	}
+	forwarded.message, forwarded.source, forwarded.hash = message, index, &hash
+	forwarded.agreed = agreed
+	forwarded.disputed = make(map[int]common.Hash)
+}
+
+func (m *feedMerger) prune() {
+	if len(m.forwarded) <= 2*mergeWindow || m.next < mergeWindow {
+		return
+	}
+	floor := m.next - mergeWindow
+	for seqNum := range m.forwarded {
+		if seqNum < floor {
+			delete(m.forwarded, seqNum)
+		}
+	}
+	if floor > m.floor {
+		m.floor = floor
+	}
+}
+
+// updateLagging demotes connected sources too far behind the merged feed, and promotes those that caught up
+func (m *feedMerger) updateLagging(now time.Time) {
+	if m.next == 0 {
+		return
+	}
+	maxLag := arbutil.MessageIndex(m.config().MaxLag)
+	latest := m.next - 1
+	demoted := 0
+	for _, source := range m.sources {
+		lagging := source.connected && source.hasHighest && source.highest+maxLag < latest
+		if lagging && !source.lagging {
+			log.Warn("demoting lagging feed source", "url", source.url, "highest", source.highest, "latest", latest)
+		} else if !lagging && source.lagging {
+			log.Info("feed source caught up", "url", source.url, "highest", source.highest)
+		}
+		source.lagging = lagging
+		if source.demoted(now) {
+			demoted++
+		}
+	}
+	mergeDemotedGauge.Update(int64(demoted))
+}
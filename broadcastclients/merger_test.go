@@ -0,0 +1,163 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcastclients
+
+import (
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/broadcastclient"
+	"github.com/offchainlabs/nitro/broadcaster"
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+type recordingStreamer struct {
+	messages []*broadcaster.BroadcastFeedMessage
+}
+
+func (s *recordingStreamer) AddBroadcastMessages(feedMessages []*broadcaster.BroadcastFeedMessage) error {
+	s.messages = append(s.messages, feedMessages...)
+	return nil
+}
+
+func (s *recordingStreamer) take() []arbutil.MessageIndex {
+	var seqNums []arbutil.MessageIndex
+	for _, message := range s.messages {
+		seqNums = append(seqNums, message.SequenceNumber)
+	}
+	s.messages = nil
+	return seqNums
+}
+
+// feedMessage's variant changes its hash, as if a source sent a different message for the sequence number
+func feedMessage(seqNum arbutil.MessageIndex, variant uint64) *broadcaster.BroadcastFeedMessage {
+	message := arbostypes.EmptyTestMessageWithMetadata
+	message.DelayedMessagesRead = variant
+	return &broadcaster.BroadcastFeedMessage{SequenceNumber: seqNum, Message: message}
+}
+
+func feedMessages(from, to arbutil.MessageIndex) []*broadcaster.BroadcastFeedMessage {
+	var messages []*broadcaster.BroadcastFeedMessage
+	for seqNum := from; seqNum <= to; seqNum++ {
+		messages = append(messages, feedMessage(seqNum, 0))
+	}
+	return messages
+}
+
+func expectForwarded(t *testing.T, streamer *recordingStreamer, expected ...arbutil.MessageIndex) {
+	t.Helper()
+	forwarded := streamer.take()
+	if len(forwarded) != len(expected) {
+		testhelpers.FailImpl(t, "forwarded", forwarded, "expected", expected)
+	}
+	for i := range expected {
+		if forwarded[i] != expected[i] {
+			testhelpers.FailImpl(t, "forwarded", forwarded, "expected", expected)
+		}
+	}
+}
+
+func TestFeedMerger(t *testing.T) {
+	config := broadcastclient.MergeConfig{MaxLag: 3, DemotionPeriod: time.Minute}
+	streamer := &recordingStreamer{}
+	merger := newFeedMerger(func() *broadcastclient.MergeConfig { return &config }, 5555, 1, streamer, []string{"a", "b", "c"})
+	now := time.Now()
+	add := func(source int, messages ...*broadcaster.BroadcastFeedMessage) {
+		t.Helper()
+		testhelpers.RequireImpl(t, merger.add(source, messages, now))
+	}
+	for i := range merger.sources {
+		merger.setConnected(i, true)
+	}
+
+	// Each sequence number is forwarded once from whichever source has it first
+	add(0, feedMessages(0, 3)...)
+	expectForwarded(t, streamer, 1, 2, 3)
+	add(1, feedMessages(1, 4)...)
+	expectForwarded(t, streamer, 4)
+	add(2, feedMessages(2, 4)...)
+	expectForwarded(t, streamer)
+
+	// A source disagreeing with what was forwarded is demoted
+	add(0, feedMessage(5, 0))
+	add(1, feedMessage(5, 1))
+	expectForwarded(t, streamer, 5)
+	if !merger.sources[1].demoted(now) {
+		testhelpers.FailImpl(t, "equivocating source not demoted")
+	}
+	add(1, feedMessage(6, 1))
+	expectForwarded(t, streamer)
+	add(2, feedMessage(6, 0))
+	expectForwarded(t, streamer, 6)
+
+	// But it's relied on if it's all there is
+	merger.setConnected(0, false)
+	merger.setConnected(2, false)
+	add(1, feedMessage(7, 1))
+	expectForwarded(t, streamer, 7)
+
+	// Gaps are filled by whichever source has the missing messages
+	merger.setConnected(0, true)
+	add(0, feedMessage(10, 0))
+	expectForwarded(t, streamer, 10)
+	add(0, feedMessages(8, 9)...)
+	expectForwarded(t, streamer, 8, 9)
+
+	// The source a message came from can replace it, as in a reorg
+	add(0, feedMessage(10, 2))
+	expectForwarded(t, streamer, 10)
+	add(0, feedMessage(10, 2))
+	expectForwarded(t, streamer)
+
+	// A source too far behind is demoted until it catches up
+	merger.setConnected(2, true)
+	add(2, feedMessage(6, 0))
+	if !merger.sources[2].lagging {
+		testhelpers.FailImpl(t, "lagging source not demoted")
+	}
+	add(2, feedMessage(11, 0))
+	expectForwarded(t, streamer)
+	if merger.sources[2].lagging {
+		testhelpers.FailImpl(t, "source not promoted after catching up")
+	}
+	add(0, feedMessage(11, 0))
+	expectForwarded(t, streamer, 11)
+
+	// Equivocating sources are promoted once the demotion period is over, and they've caught up
+	now = now.Add(config.DemotionPeriod)
+	add(1, feedMessage(8, 0), feedMessage(9, 0), feedMessage(10, 2), feedMessage(11, 0))
+	if merger.sources[1].demoted(now) {
+		testhelpers.FailImpl(t, "source still demoted after the demotion period")
+	}
+	add(1, feedMessage(12, 0))
+	expectForwarded(t, streamer, 12)
+
+	// A source sending a reorg first is demoted while the others still agree on the old message,
+	// and promoted once the source the message came from sends the reorg as well
+	add(2, feedMessage(12, 0))
+	add(0, feedMessage(12, 3))
+	expectForwarded(t, streamer)
+	if !merger.sources[0].demoted(now) {
+		testhelpers.FailImpl(t, "source disagreeing with the others not demoted")
+	}
+	add(1, feedMessage(12, 3))
+	expectForwarded(t, streamer, 12)
+	if merger.sources[0].demoted(now) {
+		testhelpers.FailImpl(t, "source that sent the reorg first still demoted")
+	}
+
+	// A reorg is forwarded from any source once no healthy source agrees on the old message
+	add(0, feedMessage(13, 0))
+	expectForwarded(t, streamer, 13)
+	merger.setConnected(0, false)
+	add(1, feedMessage(13, 4))
+	expectForwarded(t, streamer, 13)
+	if merger.sources[1].demoted(now) {
+		testhelpers.FailImpl(t, "source forwarding a reorg demoted")
+	}
+	add(2, feedMessage(13, 4))
+	expectForwarded(t, streamer)
+}
@@ -6,6 +6,7 @@ package broadcaster
 import (
 	"context"
 	"net"
+	"net/http"
 
 	"github.com/gobwas/ws"
 
@@ -23,6 +24,7 @@ type Broadcaster struct {
 	catchupBuffer *SequenceNumberCatchupBuffer
 	chainId       uint64
 	dataSigner    signature.DataSignerFunc
+	config        wsbroadcastserver.BroadcasterConfigFetcher
 }
 
 // BroadcastMessage is the base message type for messages to send over the network.
@@ -44,6 +46,10 @@ type BroadcastMessage struct {
 	// TODO better name than messages since there are different types of messages
 	Messages                       []*BroadcastFeedMessage         `json:"messages,omitempty"`
 	ConfirmedSequenceNumberMessage *ConfirmedSequenceNumberMessage `json:"confirmedSequenceNumberMessage,omitempty"`
+
+	// Only sent to clients that subscribed with a filter, in place of the fields above
+	FilteredMessages     []*FilteredFeedMessage `json:"filteredMessages,omitempty"`
+	FeedHeartbeatMessage *FeedHeartbeatMessage  `json:"feedHeartbeatMessage,omitempty"`
 }
 
 type BroadcastFeedMessage struct {
@@ -62,11 +68,13 @@ type ConfirmedSequenceNumberMessage struct {
 
 func NewBroadcaster(config wsbroadcastserver.BroadcasterConfigFetcher, chainId uint64, feedErrChan chan error, dataSigner signature.DataSignerFunc) *Broadcaster {
 	catchupBuffer := NewSequenceNumberCatchupBuffer(func() bool { return config().LimitCatchup })
+	feedFilter := newFeedFilter(chainId, catchupBuffer)
 	return &Broadcaster{
-		server:        wsbroadcastserver.NewWSBroadcastServer(config, catchupBuffer, chainId, feedErrChan),
+		server:        wsbroadcastserver.NewWSBroadcastServer(config, catchupBuffer, feedFilter, chainId, feedErrChan),
 		catchupBuffer: catchupBuffer,
 		chainId:       chainId,
 		dataSigner:    dataSigner,
+		config:        config,
 	}
 }
 
@@ -138,19 +146,45 @@ func (b *Broadcaster) GetCachedMessageCount() int {
 }
 
 func (b *Broadcaster) Initialize() error {
+	if b.config().Backlog.Enable {
+		backlog, err := OpenPersistentBacklog(func() *wsbroadcastserver.BacklogConfig { return &b.config().Backlog })
+		if err != nil {
+			return err
+		}
+		b.catchupBuffer.backlog = backlog
+	}
 	return b.server.Initialize()
 }
 
+// BacklogNextSequenceNumber is the first sequence number missing from the persistent backlog,
+// which an upstream feed should be asked for to fill it, or false if there's no backlog
+func (b *Broadcaster) BacklogNextSequenceNumber() (arbutil.MessageIndex, bool, error) {
+	if b.catchupBuffer.backlog == nil {
+		return 0, false, nil
+	}
+	next, err := b.catchupBuffer.backlog.NextSequenceNumber()
+	return next, true, err
+}
+
 func (b *Broadcaster) Start(ctx context.Context) error {
 	return b.server.Start(ctx)
 }
 
+func (b *Broadcaster) StartWithExtraHeader(ctx context.Context, extra http.Header) error {
+	return b.server.StartWithExtraHeader(ctx, extra)
+}
+
 func (b *Broadcaster) StartWithHeader(ctx context.Context, header ws.HandshakeHeader) error {
 	return b.server.StartWithHeader(ctx, header)
 }
 
 func (b *Broadcaster) StopAndWait() {
 	b.server.StopAndWait()
+	if b.catchupBuffer.backlog != nil {
+		if err := b.catchupBuffer.backlog.Close(); err != nil {
+			log.Warn("error closing feed backlog", "err", err)
+		}
+	}
 }
 
 func (b *Broadcaster) Started() bool {
@@ -0,0 +1,237 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcaster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/arbos"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/wsbroadcastserver"
+)
+
+const maxFilterEntries = 1024
+
+// FeedFilterRequest is the JSON a client sends in the wsbroadcastserver.HTTPHeaderFeedFilter header to
+// subscribe to only some transactions. A transaction matches if its message kind is one of Kinds, when
+// given, and its sender is one of From or its recipient one of To, when either is given.
+type FeedFilterRequest struct {
+	From  []common.Address `json:"from,omitempty"`
+	To    []common.Address `json:"to,omitempty"`
+	Kinds []uint8          `json:"kinds,omitempty"`
+}
+
+// FilteredFeedMessage is sent to filtered clients in place of a BroadcastFeedMessage with matching transactions
+type FilteredFeedMessage struct {
+	SequenceNumber arbutil.MessageIndex   `json:"sequenceNumber"`
+	Kind           uint8                  `json:"kind"`
+	Transactions   []*FilteredTransaction `json:"transactions"`
+}
+
+type FilteredTransaction struct {
+	From        common.Address     `json:"from"`
+	Transaction *types.Transaction `json:"transaction"`
+}
+
+// FeedHeartbeatMessage tells filtered clients the feed has got to the sequence number,
+// so they know they haven't missed anything up to there
+type FeedHeartbeatMessage struct {
+	SequenceNumber arbutil.MessageIndex `json:"sequenceNumber"`
+}
+
+type clientFilter struct {
+	key   string
+	from  map[common.Address]bool
+	to    map[common.Address]bool
+	kinds map[uint8]bool
+}
+
+func (f *clientFilter) Key() string {
+	return f.key
+}
+
+func (f *clientFilter) needsSenders() bool {
+	return len(f.from) > 0
+}
+
+func (f *clientFilter) matches(kind uint8, from common.Address, tx *types.Transaction) bool {
+	if len(f.kinds) > 0 && !f.kinds[kind] {
+		return false
+	}
+	if len(f.from) == 0 && len(f.to) == 0 {
+		return true
+	}
+	if f.from[from] {
+		return true
+	}
+	return tx.To() != nil && f.to[*tx.To()]
+}
+
+// preparedFeedMessage is a feed message decoded into its transactions, with senders recovered on first use
+type preparedFeedMessage struct {
+	sequenceNumber arbutil.MessageIndex
+	kind           uint8
+	txs            types.Transactions
+	senders        []common.Address
+}
+
+type feedFilter struct {
+	chainId       *big.Int
+	signer        types.Signer
+	catchupBuffer *SequenceNumberCatchupBuffer
+}
+
+func newFeedFilter(chainId uint64, catchupBuffer *SequenceNumberCatchupBuffer) *feedFilter {
+	bigChainId := new(big.Int).SetUint64(chainId)
+	return &feedFilter{
+		chainId:       bigChainId,
+		signer:        types.NewArbitrumSigner(types.LatestSignerForChainID(bigChainId)),
+		catchupBuffer: catchupBuffer,
+	}
+}
+
+func (f *feedFilter) ParseFilter(value []byte) (wsbroadcastserver.ClientFilter, error) {
+	var request FeedFilterRequest
+	if err := json.Unmarshal(value, &request); err != nil {
+		return nil, err
+	}
+	if len(request.From) == 0 && len(request.To) == 0 && len(request.Kinds) == 0 {
+		return nil, errors.New("filter matches everything, connect without one instead")
+	}
+	if len(request.From)+len(request.To)+len(request.Kinds) > maxFilterEntries {
+		return nil, fmt.Errorf("filter has more than %d entries", maxFilterEntries)
+	}
+	filter := &clientFilter{
+		from:  make(map[common.Address]bool),
+		to:    make(map[common.Address]bool),
+		kinds: make(map[uint8]bool),
+	}
+	var keyParts []string
+	for _, from := range request.From {
+		filter.from[from] = true
+	}
+	for _, to := range request.To {
+		filter.to[to] = true
+	}
+	for _, kind := range request.Kinds {
+		filter.kinds[kind] = true
+	}
+	for from := range filter.from {
+		keyParts = append(keyParts, "f"+from.Hex())
+	}
+	for to := range filter.to {
+		keyParts = append(keyParts, "t"+to.Hex())
+	}
+	for kind := range filter.kinds {
+		keyParts = append(keyParts, fmt.Sprintf("k%d", kind))
+	}
+	// Equal filters share a key whatever order they were given in
+	sort.Strings(keyParts)
+	filter.key = strings.Join(keyParts, ",")
+	return filter, nil
+}
+
+func (f *feedFilter) Prepare(bmi interface{}) (interface{}, error) {
+	var bm *BroadcastMessage
+	switch msg := bmi.(type) {
+	case BroadcastMessage:
+		bm = &msg
+	case *BroadcastMessage:
+		bm = msg
+	default:
+		return nil, errors.New("requested to filter message of unknown type")
+	}
+	prepared := make([]*preparedFeedMessage, 0, len(bm.Messages))
+	for _, feedMessage := range bm.Messages {
+		message := feedMessage.Message.Message
+		if message == nil || message.Header == nil {
+			continue
+		}
+		// Batch posting reports are sent without their batch, whose only effect is on the cost in the internal tx
+		txs, err := arbos.ParseL2Transactions(message, f.chainId, func(uint64, common.Hash) []byte { return nil })
+		if err != nil {
+			log.Debug("filtered clients skipping unparseable message", "sequenceNumber", feedMessage.SequenceNumber, "err", err)
+			continue
+		}
+		prepared = append(prepared, &preparedFeedMessage{
+			sequenceNumber: feedMessage.SequenceNumber,
+			kind:           message.Header.Kind,
+			txs:            txs,
+		})
+	}
+	return prepared, nil
+}
+
+func (f *feedFilter) sender(message *preparedFeedMessage, i int) common.Address {
+	if message.senders == nil {
+		message.senders = make([]common.Address, len(message.txs))
+		for j, tx := range message.txs {
+			sender, err := types.Sender(f.signer, tx)
+			if err != nil {
+				log.Debug("couldn't recover sender of feed transaction", "sequenceNumber", message.sequenceNumber, "tx", tx.Hash(), "err", err)
+			}
+			message.senders[j] = sender
+		}
+	}
+	return message.senders[i]
+}
+
+func (f *feedFilter) Apply(prepared interface{}, filter wsbroadcastserver.ClientFilter) interface{} {
+	messages, ok := prepared.([]*preparedFeedMessage)
+	if !ok {
+		return nil
+	}
+	clientFilter, ok := filter.(*clientFilter)
+	if !ok {
+		return nil
+	}
+	var filtered []*FilteredFeedMessage
+	for _, message := range messages {
+		var txs []*FilteredTransaction
+		for i, tx := range message.txs {
+			// Senders are only recovered when a filter asks for them
+			var from common.Address
+			if clientFilter.needsSenders() {
+				from = f.sender(message, i)
+			}
+			if clientFilter.matches(message.kind, from, tx) {
+				txs = append(txs, &FilteredTransaction{From: f.sender(message, i), Transaction: tx})
+			}
+		}
+		if len(txs) > 0 {
+			filtered = append(filtered, &FilteredFeedMessage{
+				SequenceNumber: message.sequenceNumber,
+				Kind:           message.kind,
+				Transactions:   txs,
+			})
+		}
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+	return BroadcastMessage{
+		Version:          1,
+		FilteredMessages: filtered,
+	}
+}
+
+func (f *feedFilter) Heartbeat() interface{} {
+	sequenceNumber, ok := f.catchupBuffer.lastSequenceNumber()
+	if !ok {
+		return nil
+	}
+	return BroadcastMessage{
+		Version:              1,
+		FeedHeartbeatMessage: &FeedHeartbeatMessage{sequenceNumber},
+	}
+}
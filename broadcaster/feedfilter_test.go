@@ -0,0 +1,199 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcaster
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gobwas/ws"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/offchainlabs/nitro/arbos"
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/wsbroadcastserver"
+)
+
+func signedTxMessage(t *testing.T, chainId uint64, nonce uint64, to common.Address) (arbostypes.MessageWithMetadata, common.Address) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	Require(t, err)
+	bigChainId := new(big.Int).SetUint64(chainId)
+	tx, err := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+		ChainID:   bigChainId,
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(0),
+		GasFeeCap: big.NewInt(1e9),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(1),
+	}), types.LatestSignerForChainID(bigChainId), key)
+	Require(t, err)
+	txBytes, err := tx.MarshalBinary()
+	Require(t, err)
+	return arbostypes.MessageWithMetadata{
+		Message: &arbostypes.L1IncomingMessage{
+			Header: &arbostypes.L1IncomingMessageHeader{
+				Kind:      arbostypes.L1MessageType_L2Message,
+				L1BaseFee: big.NewInt(0),
+			},
+			L2msg: append([]byte{arbos.L2MessageKind_SignedTx}, txBytes...),
+		},
+	}, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+type feedConn struct {
+	net.Conn
+	earlyFrameData io.Reader
+}
+
+func dialFeed(t *testing.T, ctx context.Context, addr net.Addr, header http.Header) (*feedConn, error) {
+	t.Helper()
+	dialer := ws.Dialer{
+		Header:  ws.HandshakeHeaderHTTP(header),
+		Timeout: 2 * time.Second,
+	}
+	conn, br, _, err := dialer.Dial(ctx, "ws://"+addr.String())
+	if err != nil {
+		return nil, err
+	}
+	// The first frames may have been read along with the handshake
+	var earlyFrameData io.Reader
+	if br != nil {
+		earlyFrameData = io.LimitReader(br, int64(br.Buffered()))
+	}
+	return &feedConn{conn, earlyFrameData}, nil
+}
+
+func dialFiltered(t *testing.T, ctx context.Context, addr net.Addr, filter string) (*feedConn, error) {
+	t.Helper()
+	return dialFeed(t, ctx, addr, http.Header{wsbroadcastserver.HTTPHeaderFeedFilter: []string{filter}})
+}
+
+func readBroadcastMessage(t *testing.T, ctx context.Context, conn *feedConn) *BroadcastMessage {
+	t.Helper()
+	data, _, err := wsbroadcastserver.ReadData(ctx, conn.Conn, conn.earlyFrameData, 5*time.Second, ws.StateClientSide, false, nil)
+	Require(t, err)
+	var bm BroadcastMessage
+	Require(t, json.Unmarshal(data, &bm))
+	return &bm
+}
+
+func TestBroadcasterFilteredClients(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	config := wsbroadcastserver.DefaultTestBroadcasterConfig
+	config.EnableCompression = false
+	config.EnableFilters = true
+	config.FilterHeartbeat = 100 * time.Millisecond
+
+	chainId := uint64(5555)
+	feedErrChan := make(chan error, 10)
+	b := NewBroadcaster(func() *wsbroadcastserver.BroadcasterConfig { return &config }, chainId, feedErrChan, nil)
+	Require(t, b.Initialize())
+	Require(t, b.Start(ctx))
+	defer b.StopAndWait()
+
+	watched := common.HexToAddress("0x0000000000000000000000000000000000c0ffee")
+	other := common.HexToAddress("0x0000000000000000000000000000000000000bad")
+
+	// Already broadcast messages are filtered from the catchup buffer
+	first, firstSender := signedTxMessage(t, chainId, 0, watched)
+	Require(t, b.BroadcastSingle(first, 1))
+	unwatched, _ := signedTxMessage(t, chainId, 0, other)
+	Require(t, b.BroadcastSingle(unwatched, 2))
+	waitUntilUpdated(t, &messageCountPredicate{b, 2, "after catchup messages", 0})
+
+	if _, err := dialFiltered(t, ctx, b.ListenerAddr(), "not json"); err == nil {
+		Fail(t, "connected with a malformed filter")
+	}
+	byTo, err := dialFiltered(t, ctx, b.ListenerAddr(), `{"to":["`+watched.Hex()+`"]}`)
+	Require(t, err)
+	defer byTo.Close()
+
+	catchup := readBroadcastMessage(t, ctx, byTo)
+	if len(catchup.Messages) != 0 || len(catchup.FilteredMessages) != 1 || catchup.FilteredMessages[0].SequenceNumber != 1 {
+		Fail(t, "unexpected catchup for filtered client", catchup)
+	}
+	filteredTx := catchup.FilteredMessages[0].Transactions[0]
+	if filteredTx.From != firstSender || *filteredTx.Transaction.To() != watched {
+		Fail(t, "unexpected filtered transaction", filteredTx.From, filteredTx.Transaction.To())
+	}
+	heartbeat := readBroadcastMessage(t, ctx, byTo)
+	if heartbeat.FeedHeartbeatMessage == nil || heartbeat.FeedHeartbeatMessage.SequenceNumber != 2 {
+		Fail(t, "filtered client wasn't told where the feed is", heartbeat)
+	}
+
+	// A second client filtering by sender is served alongside
+	second, secondSender := signedTxMessage(t, chainId, 1, other)
+	bySender, err := dialFiltered(t, ctx, b.ListenerAddr(), `{"from":["`+secondSender.Hex()+`"],"kinds":[`+strconv.Itoa(int(arbostypes.L1MessageType_L2Message))+`]}`)
+	Require(t, err)
+	defer bySender.Close()
+	if heartbeat := readBroadcastMessage(t, ctx, bySender); heartbeat.FeedHeartbeatMessage == nil {
+		Fail(t, "expected only a heartbeat for a client nothing in the catchup buffer matched", heartbeat)
+	}
+
+	Require(t, b.BroadcastSingle(second, 3))
+	third, _ := signedTxMessage(t, chainId, 2, watched)
+	Require(t, b.BroadcastSingle(third, 4))
+
+	expectFiltered := func(conn *feedConn, seqNum arbutil.MessageIndex) {
+		t.Helper()
+		for {
+			bm := readBroadcastMessage(t, ctx, conn)
+			if len(bm.Messages) != 0 || bm.ConfirmedSequenceNumberMessage != nil {
+				Fail(t, "filtered client sent the full feed", bm)
+			}
+			if bm.FeedHeartbeatMessage != nil {
+				continue
+			}
+			if len(bm.FilteredMessages) != 1 || bm.FilteredMessages[0].SequenceNumber != seqNum {
+				Fail(t, "unexpected filtered message", bm, "expected", seqNum)
+			}
+			return
+		}
+	}
+	expectFiltered(byTo, 4)
+	expectFiltered(bySender, 3)
+
+	// Heartbeats carry on when nothing matches
+	for {
+		bm := readBroadcastMessage(t, ctx, bySender)
+		if bm.FeedHeartbeatMessage != nil && bm.FeedHeartbeatMessage.SequenceNumber == 4 {
+			break
+		}
+		if len(bm.FilteredMessages) != 0 {
+			Fail(t, "unexpected filtered message", bm)
+		}
+	}
+}
+
+func TestBroadcasterFiltersDisabled(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	config := wsbroadcastserver.DefaultTestBroadcasterConfig
+	config.EnableCompression = false
+	feedErrChan := make(chan error, 10)
+	b := NewBroadcaster(func() *wsbroadcastserver.BroadcasterConfig { return &config }, 5555, feedErrChan, nil)
+	Require(t, b.Initialize())
+	Require(t, b.Start(ctx))
+	defer b.StopAndWait()
+
+	if _, err := dialFiltered(t, ctx, b.ListenerAddr(), `{"kinds":[3]}`); err == nil {
+		Fail(t, "connected with a filter while filters are disabled")
+	}
+}
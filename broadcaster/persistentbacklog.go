@@ -0,0 +1,287 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcaster
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/wsbroadcastserver"
+)
+
+var (
+	backlogMessagePrefix = []byte("m") // maps a sequence number to a backlogEntry
+	backlogLastKey       = []byte("_last")
+
+	backlogMessagesGauge       = metrics.NewRegisteredGauge("arb/feed/backlog/messages", nil)
+	backlogCatchupSentCounter  = metrics.NewRegisteredCounter("arb/feed/backlog/sent", nil)
+	backlogWriteFailureCounter = metrics.NewRegisteredCounter("arb/feed/backlog/failed/write", nil)
+)
+
+const backlogPruneInterval = time.Minute
+
+type backlogEntry struct {
+	StoredAt uint64
+	Message  *BroadcastFeedMessage
+}
+
+// PersistentBacklog keeps broadcast messages on disk for the retention window,
+// whether or not they've been confirmed. It's not thread safe, and is used from
+// the broadcast thread once the broadcaster is started.
+type PersistentBacklog struct {
+	db        ethdb.Database
+	retention func() time.Duration
+
+	first       arbutil.MessageIndex
+	last        arbutil.MessageIndex
+	hasMessages bool
+	lastPrune   time.Time
+}
+
+func backlogKey(seqNum arbutil.MessageIndex) []byte {
+	key := make([]byte, len(backlogMessagePrefix)+8)
+	copy(key, backlogMessagePrefix)
+	binary.BigEndian.PutUint64(key[len(backlogMessagePrefix):], uint64(seqNum))
+	return key
+}
+
+func OpenPersistentBacklog(config func() *wsbroadcastserver.BacklogConfig) (*PersistentBacklog, error) {
+	directory := config().Directory
+	if directory == "" {
+		return nil, errors.New("no directory for the feed backlog")
+	}
+	db, err := rawdb.NewLevelDBDatabase(directory, 16, 16, "arb/feed/backlog/", false)
+	if err != nil {
+		return nil, err
+	}
+	backlog, err := NewPersistentBacklog(db, func() time.Duration { return config().Retention })
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return backlog, nil
+}
+
+func NewPersistentBacklog(db ethdb.Database, retention func() time.Duration) (*PersistentBacklog, error) {
+	b := &PersistentBacklog{db: db, retention: retention}
+	lastBytes, err := db.Get(backlogLastKey)
+	if err == nil && len(lastBytes) == 8 {
+		b.last = arbutil.MessageIndex(binary.BigEndian.Uint64(lastBytes))
+		b.hasMessages = true
+	}
+	if err := b.prune(time.Now()); err != nil {
+		return nil, err
+	}
+	if b.hasMessages {
+		log.Info("loaded feed backlog", "first", b.first, "last", b.last)
+	}
+	return b, nil
+}
+
+// Range returns the first and last sequence numbers in the backlog, which may have gaps
+func (b *PersistentBacklog) Range() (arbutil.MessageIndex, arbutil.MessageIndex, bool) {
+	return b.first, b.last, b.hasMessages
+}
+
+// NextSequenceNumber is the first sequence number missing after the start of the backlog,
+// from which to catch up on what was broadcast while the backlog wasn't being written
+func (b *PersistentBacklog) NextSequenceNumber() (arbutil.MessageIndex, error) {
+	if !b.hasMessages {
+		return 0, nil
+	}
+	iter := b.db.NewIterator(backlogMessagePrefix, nil)
+	defer iter.Release()
+	expected := b.first
+	for iter.Next() {
+		seqNum := arbutil.MessageIndex(binary.BigEndian.Uint64(iter.Key()[len(backlogMessagePrefix):]))
+		if seqNum != expected {
+			return expected, nil
+		}
+		expected++
+	}
+	return expected, iter.Error()
+}
+
+// Put stores messages. A message replacing a different one already stored is a reorg, so every message
+// after it is deleted, and is broadcast again if it's still part of the chain.
+func (b *PersistentBacklog) Put(messages []*BroadcastFeedMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	now := time.Now()
+	batch := b.db.NewBatch()
+	first, last, hasMessages := b.first, b.last, b.hasMessages
+	for _, message := range messages {
+		if hasMessages && message.SequenceNumber <= last {
+			reorg, err := b.replacesStored(message)
+			if err != nil {
+				return err
+			}
+			if reorg {
+				if err := b.deleteAfter(batch, message.SequenceNumber); err != nil {
+					return err
+				}
+				last = message.SequenceNumber
+			}
+		}
+		data, err := rlp.EncodeToBytes(backlogEntry{uint64(now.Unix()), message})
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(backlogKey(message.SequenceNumber), data); err != nil {
+			return err
+		}
+		if !hasMessages || message.SequenceNumber > last {
+			last = message.SequenceNumber
+		}
+		if !hasMessages || message.SequenceNumber < first {
+			first = message.SequenceNumber
+		}
+		hasMessages = true
+	}
+	var lastBytes [8]byte
+	binary.BigEndian.PutUint64(lastBytes[:], uint64(last))
+	if err := batch.Put(backlogLastKey, lastBytes[:]); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	b.first, b.last = first, last
+	b.hasMessages = true
+
+	if now.Sub(b.lastPrune) >= backlogPruneInterval {
+		return b.prune(now)
+	}
+	return nil
+}
+
+// replacesStored tells whether a different message is stored at the sequence number of the message,
+// rebroadcasts of the same message, with whatever signature, don't replace it
+func (b *PersistentBacklog) replacesStored(message *BroadcastFeedMessage) (bool, error) {
+	key := backlogKey(message.SequenceNumber)
+	has, err := b.db.Has(key)
+	if err != nil || !has {
+		return false, err
+	}
+	data, err := b.db.Get(key)
+	if err != nil {
+		return false, err
+	}
+	var entry backlogEntry
+	if err := rlp.DecodeBytes(data, &entry); err != nil {
+		return false, err
+	}
+	if entry.Message == nil {
+		return true, nil
+	}
+	stored, err := rlp.EncodeToBytes(entry.Message.Message)
+	if err != nil {
+		return false, err
+	}
+	received, err := rlp.EncodeToBytes(message.Message)
+	if err != nil {
+		return false, err
+	}
+	return crypto.Keccak256Hash(stored) != crypto.Keccak256Hash(received), nil
+}
+
+// deleteAfter adds the deletion of every stored message after the sequence number to the batch
+func (b *PersistentBacklog) deleteAfter(batch ethdb.Batch, seqNum arbutil.MessageIndex) error {
+	iter := b.db.NewIterator(backlogMessagePrefix, backlogKey(seqNum + 1)[len(backlogMessagePrefix):])
+	defer iter.Release()
+	deleted := 0
+	for iter.Next() {
+		if err := batch.Delete(append([]byte(nil), iter.Key()...)); err != nil {
+			return err
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		log.Info("feed reorg, deleted backlog messages", "after", seqNum, "count", deleted)
+	}
+	return iter.Error()
+}
+
+// Get returns the messages from the sequence number up to and including the limit, stopping early at any gap
+func (b *PersistentBacklog) Get(from arbutil.MessageIndex, limit arbutil.MessageIndex) ([]*BroadcastFeedMessage, error) {
+	if !b.hasMessages || from > b.last || from > limit {
+		return nil, nil
+	}
+	iter := b.db.NewIterator(backlogMessagePrefix, backlogKey(from)[len(backlogMessagePrefix):])
+	defer iter.Release()
+	var messages []*BroadcastFeedMessage
+	expected := from
+	for expected <= limit && iter.Next() {
+		var entry backlogEntry
+		if err := rlp.DecodeBytes(iter.Value(), &entry); err != nil {
+			return nil, err
+		}
+		if entry.Message == nil || entry.Message.SequenceNumber != expected {
+			break
+		}
+		messages = append(messages, entry.Message)
+		expected++
+	}
+	return messages, iter.Error()
+}
+
+// prune deletes messages stored before the retention window, from the start of the backlog
+func (b *PersistentBacklog) prune(now time.Time) error {
+	b.lastPrune = now
+	cutoff := uint64(now.Add(-b.retention()).Unix())
+	iter := b.db.NewIterator(backlogMessagePrefix, nil)
+	defer iter.Release()
+	batch := b.db.NewBatch()
+	pruned := 0
+	b.first = b.last
+	remaining := false
+	for iter.Next() {
+		var entry backlogEntry
+		if err := rlp.DecodeBytes(iter.Value(), &entry); err != nil {
+			return err
+		}
+		if entry.StoredAt >= cutoff {
+			b.first = arbutil.MessageIndex(binary.BigEndian.Uint64(iter.Key()[len(backlogMessagePrefix):]))
+			remaining = true
+			break
+		}
+		if err := batch.Delete(append([]byte(nil), iter.Key()...)); err != nil {
+			return err
+		}
+		pruned++
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if !remaining {
+		b.hasMessages = false
+		b.first, b.last = 0, 0
+		if err := batch.Delete(backlogLastKey); err != nil {
+			return err
+		}
+	}
+	if pruned > 0 {
+		log.Debug("pruned feed backlog", "count", pruned, "first", b.first)
+	}
+	if b.hasMessages {
+		backlogMessagesGauge.Update(int64(b.last - b.first + 1))
+	} else {
+		backlogMessagesGauge.Update(0)
+	}
+	return batch.Write()
+}
+
+func (b *PersistentBacklog) Close() error {
+	return b.db.Close()
+}
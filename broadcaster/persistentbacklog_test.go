@@ -0,0 +1,222 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcaster
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/wsbroadcastserver"
+)
+
+func backlogTestMessages(from, to arbutil.MessageIndex) []*BroadcastFeedMessage {
+	var messages []*BroadcastFeedMessage
+	for seqNum := from; seqNum <= to; seqNum++ {
+		messages = append(messages, &BroadcastFeedMessage{
+			SequenceNumber: seqNum,
+			Message:        arbostypes.TestMessageWithMetadataAndRequestId,
+			Signature:      []byte{byte(seqNum)},
+		})
+	}
+	return messages
+}
+
+// reorgTestMessages differ from the test messages at the same sequence numbers
+func reorgTestMessages(from, to arbutil.MessageIndex) []*BroadcastFeedMessage {
+	messages := backlogTestMessages(from, to)
+	for _, message := range messages {
+		message.Message.DelayedMessagesRead++
+	}
+	return messages
+}
+
+func expectBacklogRange(t *testing.T, messages []*BroadcastFeedMessage, from, to arbutil.MessageIndex) {
+	t.Helper()
+	if len(messages) != int(to-from+1) {
+		Fail(t, "expected", to-from+1, "messages from", from, "got", len(messages))
+	}
+	for i, message := range messages {
+		if message.SequenceNumber != from+arbutil.MessageIndex(i) || message.Signature[0] != byte(message.SequenceNumber) {
+			Fail(t, "unexpected message", message.SequenceNumber, "at", i)
+		}
+	}
+}
+
+func TestPersistentBacklog(t *testing.T) {
+	retention := time.Hour
+	db := rawdb.NewMemoryDatabase()
+	backlog, err := NewPersistentBacklog(db, func() time.Duration { return retention })
+	Require(t, err)
+
+	next, err := backlog.NextSequenceNumber()
+	Require(t, err)
+	if next != 0 {
+		Fail(t, "empty backlog should resume from the start, not", next)
+	}
+
+	Require(t, backlog.Put(backlogTestMessages(1, 5)))
+	messages, err := backlog.Get(2, 4)
+	Require(t, err)
+	expectBacklogRange(t, messages, 2, 4)
+
+	// A gap stops reads and is where to resume from
+	Require(t, backlog.Put(backlogTestMessages(8, 9)))
+	messages, err = backlog.Get(3, 100)
+	Require(t, err)
+	expectBacklogRange(t, messages, 3, 5)
+	next, err = backlog.NextSequenceNumber()
+	Require(t, err)
+	if next != 6 {
+		Fail(t, "expected to resume from the gap, not", next)
+	}
+
+	// Reopening finds the same range
+	backlog, err = NewPersistentBacklog(db, func() time.Duration { return retention })
+	Require(t, err)
+	if first, last, ok := backlog.Range(); !ok || first != 1 || last != 9 {
+		Fail(t, "unexpected range after reopening", first, last, ok)
+	}
+	// Rebroadcasting stored messages and filling gaps keeps everything
+	Require(t, backlog.Put(backlogTestMessages(2, 3)))
+	Require(t, backlog.Put(backlogTestMessages(6, 7)))
+	if first, last, ok := backlog.Range(); !ok || first != 1 || last != 9 {
+		Fail(t, "unexpected range after rebroadcasting", first, last, ok)
+	}
+	messages, err = backlog.Get(1, 100)
+	Require(t, err)
+	expectBacklogRange(t, messages, 1, 9)
+
+	// A different message at or below the last one is a reorg, and drops everything after it
+	Require(t, backlog.Put(reorgTestMessages(3, 3)))
+	if first, last, ok := backlog.Range(); !ok || first != 1 || last != 3 {
+		Fail(t, "unexpected range after a reorg", first, last, ok)
+	}
+	next, err = backlog.NextSequenceNumber()
+	Require(t, err)
+	if next != 4 {
+		Fail(t, "expected to resume after the reorg, not", next)
+	}
+	backlog, err = NewPersistentBacklog(db, func() time.Duration { return retention })
+	Require(t, err)
+	if first, last, ok := backlog.Range(); !ok || first != 1 || last != 3 {
+		Fail(t, "reorg not persisted", first, last, ok)
+	}
+	messages, err = backlog.Get(1, 100)
+	Require(t, err)
+	expectBacklogRange(t, messages, 1, 3)
+	if messages[2].Message.DelayedMessagesRead == messages[1].Message.DelayedMessagesRead {
+		Fail(t, "reorged message not stored")
+	}
+	Require(t, backlog.Put(backlogTestMessages(4, 9)))
+	next, err = backlog.NextSequenceNumber()
+	Require(t, err)
+	if next != 10 {
+		Fail(t, "expected to resume after the rebroadcast messages, not", next)
+	}
+
+	// Everything is pruned once it's older than the retention window
+	Require(t, backlog.prune(time.Now().Add(2*retention)))
+	if _, _, ok := backlog.Range(); ok {
+		Fail(t, "backlog not pruned")
+	}
+	messages, err = backlog.Get(1, 100)
+	Require(t, err)
+	if len(messages) != 0 {
+		Fail(t, "pruned messages still returned", len(messages))
+	}
+}
+
+func TestCatchupBufferBacklogSkipsMessages(t *testing.T) {
+	backlog, err := NewPersistentBacklog(rawdb.NewMemoryDatabase(), func() time.Duration { return time.Hour })
+	Require(t, err)
+	buffer := NewSequenceNumberCatchupBuffer(func() bool { return false })
+	buffer.backlog = backlog
+	Require(t, buffer.OnDoBroadcast(BroadcastMessage{Messages: backlogTestMessages(1, 5)}))
+
+	// Messages the buffer skips, even different ones, are left out of the backlog
+	Require(t, buffer.OnDoBroadcast(BroadcastMessage{Messages: reorgTestMessages(3, 6)}))
+	if first, last, ok := backlog.Range(); !ok || first != 1 || last != 6 {
+		Fail(t, "unexpected backlog range", first, last, ok)
+	}
+	messages, err := backlog.Get(1, 100)
+	Require(t, err)
+	expectBacklogRange(t, messages, 1, 6)
+	if messages[2].Message.DelayedMessagesRead != messages[0].Message.DelayedMessagesRead {
+		Fail(t, "skipped message stored in the backlog")
+	}
+}
+
+func readFeedMessages(t *testing.T, ctx context.Context, conn *feedConn, count int) []*BroadcastFeedMessage {
+	t.Helper()
+	var messages []*BroadcastFeedMessage
+	for len(messages) < count {
+		messages = append(messages, readBroadcastMessage(t, ctx, conn).Messages...)
+	}
+	return messages
+}
+
+func TestBroadcasterPersistentBacklog(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	config := wsbroadcastserver.DefaultTestBroadcasterConfig
+	config.EnableCompression = false
+	config.Backlog.Enable = true
+	config.Backlog.Directory = t.TempDir()
+
+	chainId := uint64(5555)
+	feedErrChan := make(chan error, 10)
+	b := NewBroadcaster(func() *wsbroadcastserver.BroadcasterConfig { return &config }, chainId, feedErrChan, nil)
+	Require(t, b.Initialize())
+	Require(t, b.Start(ctx))
+
+	for seqNum := arbutil.MessageIndex(1); seqNum <= 4; seqNum++ {
+		Require(t, b.BroadcastSingle(arbostypes.EmptyTestMessageWithMetadata, seqNum))
+	}
+	b.Confirm(3)
+	waitUntilUpdated(t, &messageCountPredicate{b, 1, "after confirming all but one message", 0})
+
+	requestFrom := func(b *Broadcaster, seqNum arbutil.MessageIndex) *feedConn {
+		conn, err := dialFeed(t, ctx, b.ListenerAddr(), http.Header{
+			wsbroadcastserver.HTTPHeaderRequestedSequenceNumber: []string{strconv.FormatUint(uint64(seqNum), 10)},
+		})
+		Require(t, err)
+		return conn
+	}
+
+	// Confirmed messages are still served from the backlog
+	conn := requestFrom(b, 2)
+	expectSeqNums := func(messages []*BroadcastFeedMessage, from arbutil.MessageIndex) {
+		t.Helper()
+		for i, message := range messages {
+			if message.SequenceNumber != from+arbutil.MessageIndex(i) {
+				Fail(t, "unexpected catchup message", message.SequenceNumber, "at", i)
+			}
+		}
+	}
+	expectSeqNums(readFeedMessages(t, ctx, conn, 3), 2)
+	conn.Close()
+	b.StopAndWait()
+
+	// And after a restart, along with where to ask upstream for the rest
+	b = NewBroadcaster(func() *wsbroadcastserver.BroadcasterConfig { return &config }, chainId, feedErrChan, nil)
+	Require(t, b.Initialize())
+	Require(t, b.Start(ctx))
+	defer b.StopAndWait()
+	next, hasBacklog, err := b.BacklogNextSequenceNumber()
+	Require(t, err)
+	if !hasBacklog || next != 5 {
+		Fail(t, "expected to resume from 5, not", next, hasBacklog)
+	}
+	conn = requestFrom(b, 1)
+	defer conn.Close()
+	expectSeqNums(readFeedMessages(t, ctx, conn, 4), 1)
+}
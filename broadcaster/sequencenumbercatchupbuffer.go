@@ -29,6 +29,13 @@ type SequenceNumberCatchupBuffer struct {
 	messages     []*BroadcastFeedMessage
 	messageCount int32
 	limitCatchup func() bool
+
+	// The last message broadcast, which may have since been confirmed and removed from messages
+	lastSeqNum    arbutil.MessageIndex
+	hasLastSeqNum bool
+
+	// Optionally, where messages are kept after they're confirmed and across restarts
+	backlog *PersistentBacklog
 }
 
 func NewSequenceNumberCatchupBuffer(limitCatchup func() bool) *SequenceNumberCatchupBuffer {
@@ -77,14 +84,64 @@ func (b *SequenceNumberCatchupBuffer) getCacheMessages(requestedSeqNum arbutil.M
 	return nil
 }
 
+// getBacklogMessages returns the messages in the persistent backlog from the requested sequence number up to
+// the start of the in memory buffer, or nil if the backlog can't fill that whole gap
+func (b *SequenceNumberCatchupBuffer) getBacklogMessages(requestedSeqNum arbutil.MessageIndex) *BroadcastMessage {
+	if b.backlog == nil {
+		return nil
+	}
+	first, last, ok := b.backlog.Range()
+	if !ok || requestedSeqNum > last {
+		return nil
+	}
+	if requestedSeqNum < first {
+		requestedSeqNum = first
+	}
+	limit := last
+	if len(b.messages) > 0 {
+		firstCachedSeqNum := b.messages[0].SequenceNumber
+		if requestedSeqNum >= firstCachedSeqNum {
+			return nil
+		}
+		limit = firstCachedSeqNum - 1
+	}
+	if b.limitCatchup() && last > maxRequestedSeqNumOffset && requestedSeqNum < (last-maxRequestedSeqNumOffset) {
+		// Requested seqnum is too old, don't send any backlog
+		return nil
+	}
+	messages, err := b.backlog.Get(requestedSeqNum, limit)
+	if err != nil {
+		log.Warn("error reading feed backlog", "requestedSeqNum", requestedSeqNum, "err", err)
+		return nil
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	if len(b.messages) > 0 && messages[len(messages)-1].SequenceNumber != limit {
+		// The backlog has a gap before the in memory buffer, so the buffer alone is sent as before
+		return nil
+	}
+	return &BroadcastMessage{
+		Version:  1,
+		Messages: messages,
+	}
+}
+
 func (b *SequenceNumberCatchupBuffer) OnRegisterClient(clientConnection *wsbroadcastserver.ClientConnection) (error, int, time.Duration) {
 	start := time.Now()
-	bm := b.getCacheMessages(clientConnection.RequestedSeqNum())
-	var bmCount int
-	if bm != nil {
-		bmCount = len(bm.Messages)
+	requestedSeqNum := clientConnection.RequestedSeqNum()
+	var bms []*BroadcastMessage
+	if bm := b.getBacklogMessages(requestedSeqNum); bm != nil {
+		bms = append(bms, bm)
+		requestedSeqNum = bm.Messages[len(bm.Messages)-1].SequenceNumber + 1
+		backlogCatchupSentCounter.Inc(int64(len(bm.Messages)))
+	}
+	if bm := b.getCacheMessages(requestedSeqNum); bm != nil {
+		bms = append(bms, bm)
 	}
-	if bm != nil {
+	var bmCount int
+	for _, bm := range bms {
+		bmCount += len(bm.Messages)
 		// send the newly connected client the requested messages
 		err := clientConnection.Write(bm)
 		if err != nil {
@@ -147,6 +204,7 @@ func (b *SequenceNumberCatchupBuffer) OnDoBroadcast(bmi interface{}) error {
 		confirmedSequenceNumberGauge.Update(int64(confirmMsg.SequenceNumber))
 	}
 
+	var accepted []*BroadcastFeedMessage
 	for _, newMsg := range broadcastMessage.Messages {
 		if len(b.messages) == 0 {
 			// Add to empty list
@@ -164,6 +222,20 @@ func (b *SequenceNumberCatchupBuffer) OnDoBroadcast(bmi interface{}) error {
 			b.messages = append(b.messages, newMsg)
 		} else {
 			log.Info("Skipping already seen message", "seqNum", newMsg.SequenceNumber)
+			continue
+		}
+		// Only appended messages move the feed on, heartbeats mustn't go back to a skipped one
+		b.lastSeqNum = newMsg.SequenceNumber
+		b.hasLastSeqNum = true
+		accepted = append(accepted, newMsg)
+	}
+
+	if b.backlog != nil {
+		// The backlog is a best effort addition to the in memory buffer, so failing to write it doesn't fail the broadcast.
+		// It only stores what the buffer accepted, so skipped messages can't truncate it.
+		if err := b.backlog.Put(accepted); err != nil {
+			backlogWriteFailureCounter.Inc(1)
+			log.Error("error writing feed backlog", "err", err)
 		}
 	}
 
@@ -171,6 +243,11 @@ func (b *SequenceNumberCatchupBuffer) OnDoBroadcast(bmi interface{}) error {
 
 }
 
+// lastSequenceNumber is only safe to call from the broadcast thread
+func (b *SequenceNumberCatchupBuffer) lastSequenceNumber() (arbutil.MessageIndex, bool) {
+	return b.lastSeqNum, b.hasLastSeqNum
+}
+
 func (b *SequenceNumberCatchupBuffer) GetMessageCount() int {
 	return int(atomic.LoadInt32(&b.messageCount))
 }
@@ -178,5 +255,9 @@ func (b *SequenceNumberCatchupBuffer) GetMessageCount() int {
 // Not thread safe
 func (b *SequenceNumberCatchupBuffer) Reset(messages []*BroadcastFeedMessage) {
 	b.messages = messages
+	if len(messages) > 0 {
+		b.lastSeqNum = messages[len(messages)-1].SequenceNumber
+		b.hasLastSeqNum = true
+	}
 	atomic.StoreInt32(&b.messageCount, int32(len(messages)))
 }
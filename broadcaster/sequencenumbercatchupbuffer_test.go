@@ -184,9 +184,11 @@ func TestBroadcastBadMessage(t *testing.T) {
 func TestBroadcastPastSeqNum(t *testing.T) {
 	indexes := []arbutil.MessageIndex{40}
 	buffer := SequenceNumberCatchupBuffer{
-		messages:     createDummyBroadcastMessagesImpl(indexes, len(indexes)*10+1),
-		messageCount: int32(len(indexes)),
-		limitCatchup: func() bool { return false },
+		messages:      createDummyBroadcastMessagesImpl(indexes, len(indexes)*10+1),
+		messageCount:  int32(len(indexes)),
+		limitCatchup:  func() bool { return false },
+		lastSeqNum:    40,
+		hasLastSeqNum: true,
 	}
 
 	bm := BroadcastMessage{
@@ -200,6 +202,9 @@ func TestBroadcastPastSeqNum(t *testing.T) {
 	if err != nil {
 		t.Error("expected error")
 	}
+	if last, _ := buffer.lastSequenceNumber(); last != 40 {
+		t.Error("skipped message moved the last sequence number to", last)
+	}
 
 }
 
@@ -221,5 +226,8 @@ func TestBroadcastFutureSeqNum(t *testing.T) {
 	if err != nil {
 		t.Error("expected error")
 	}
+	if last, ok := buffer.lastSequenceNumber(); !ok || last != 42 {
+		t.Error("unexpected last sequence number", last, ok)
+	}
 
 }
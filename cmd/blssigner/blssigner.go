@@ -0,0 +1,195 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// blssigner holds the BLS key of a DAS committee member in a separate process, serving the
+// blssigner JSON-RPC namespace that daserver's data-availability.key.signer-url connects to.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	koanfjson "github.com/knadh/koanf/parsers/json"
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/cmd/genericconf"
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+	"github.com/offchainlabs/nitro/das"
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+type BLSSignerConfig struct {
+	Key                das.KeyConfig                       `koanf:"key"`
+	IPCPath            string                              `koanf:"ipc-path"`
+	HTTPAddr           string                              `koanf:"http-addr"`
+	HTTPPort           uint64                              `koanf:"http-port"`
+	HTTPJWTSecret      string                              `koanf:"http-jwtsecret"`
+	HTTPServerTimeouts genericconf.HTTPServerTimeoutConfig `koanf:"http-server-timeouts"`
+	ConfConfig         genericconf.ConfConfig              `koanf:"conf"`
+	LogLevel           int                                 `koanf:"log-level"`
+}
+
+var DefaultBLSSignerConfig = BLSSignerConfig{
+	IPCPath:            "",
+	HTTPAddr:           "",
+	HTTPPort:           9878,
+	HTTPJWTSecret:      "",
+	HTTPServerTimeouts: genericconf.HTTPServerTimeoutConfigDefault,
+	ConfConfig:         genericconf.ConfConfigDefault,
+	LogLevel:           int(log.LvlInfo),
+}
+
+func main() {
+	if err := startup(); err != nil {
+		log.Error("Error running BLS signer", "err", err)
+		os.Exit(1)
+	}
+}
+
+func printSampleUsage(progname string) {
+	fmt.Printf("\n")
+	fmt.Printf("Sample usage:                  %s --key.key-dir=<dir> --key.watch --ipc-path=<socket> \n", progname)
+	fmt.Printf("                               %s --key.key-dir=<dir> --http-addr=<addr> --http-jwtsecret=<file> \n", progname)
+}
+
+func parseBLSSigner(args []string) (*BLSSignerConfig, error) {
+	f := flag.NewFlagSet("blssigner", flag.ContinueOnError)
+	das.KeyConfigAddOptions("key", f)
+	f.String("ipc-path", DefaultBLSSignerConfig.IPCPath, "path of the Unix socket to serve signing requests on")
+	f.String("http-addr", DefaultBLSSignerConfig.HTTPAddr, "HTTP server listening interface to serve signing requests on, empty to disable")
+	f.Uint64("http-port", DefaultBLSSignerConfig.HTTPPort, "HTTP server listening port")
+	f.String("http-jwtsecret", DefaultBLSSignerConfig.HTTPJWTSecret, "path to file with the jwt secret (32B hex) HTTP requests must be authenticated with, required with --http-addr")
+	genericconf.HTTPServerTimeoutConfigAddOptions("http-server-timeouts", f)
+	f.Int("log-level", DefaultBLSSignerConfig.LogLevel, "log level; 1: ERROR, 2: WARN, 3: INFO, 4: DEBUG, 5: TRACE")
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var config BLSSignerConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	if config.ConfConfig.Dump {
+		err = confighelpers.DumpConfig(k, map[string]interface{}{
+			"key.priv-key": "",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error removing extra parameters before dump: %w", err)
+		}
+		c, err := k.Marshal(koanfjson.Parser())
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal config file to JSON: %w", err)
+		}
+		fmt.Println(string(c))
+		os.Exit(0)
+	}
+	return &config, nil
+}
+
+func startup() error {
+	config, err := parseBLSSigner(os.Args[1:])
+	if err != nil {
+		confighelpers.PrintErrorAndExit(err, printSampleUsage)
+	}
+	if config.Key.SignerURL != "" {
+		confighelpers.PrintErrorAndExit(errors.New("the signer holds the key itself, key.signer-url can't be used"), printSampleUsage)
+	}
+	if config.IPCPath == "" && config.HTTPAddr == "" {
+		confighelpers.PrintErrorAndExit(errors.New("please specify at least one of --ipc-path or --http-addr"), printSampleUsage)
+	}
+	if config.HTTPAddr != "" && config.HTTPJWTSecret == "" {
+		// anyone reaching the signer could have it attest to the availability of any data
+		confighelpers.PrintErrorAndExit(errors.New("--http-addr requires --http-jwtsecret"), printSampleUsage)
+	}
+
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(config.LogLevel))
+	log.Root().SetHandler(glogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signer, err := config.Key.BLSSigner(ctx)
+	if err != nil {
+		return err
+	}
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName("blssigner", das.NewBLSSignerAPI(signer)); err != nil {
+		return err
+	}
+	defer rpcServer.Stop()
+
+	if config.IPCPath != "" {
+		// the socket is the only access control, so only the owner may connect
+		if err := os.Remove(config.IPCPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		listener, err := net.Listen("unix", config.IPCPath)
+		if err != nil {
+			return err
+		}
+		if err := os.Chmod(config.IPCPath, 0o600); err != nil {
+			return err
+		}
+		defer os.Remove(config.IPCPath)
+		log.Info("Serving BLS signer over IPC", "path", config.IPCPath)
+		go func() {
+			if err := rpcServer.ServeListener(listener); err != nil {
+				log.Warn("IPC server stopped", "err", err)
+			}
+		}()
+	}
+
+	var httpServer *http.Server
+	if config.HTTPAddr != "" {
+		jwtSecret, err := signature.LoadSigningKey(config.HTTPJWTSecret)
+		if err != nil {
+			return err
+		}
+		listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", config.HTTPAddr, config.HTTPPort))
+		if err != nil {
+			return err
+		}
+		httpServer = &http.Server{
+			Handler:           node.NewHTTPHandlerStack(rpcServer, nil, []string{"*"}, jwtSecret.Bytes()),
+			ReadTimeout:       config.HTTPServerTimeouts.ReadTimeout,
+			ReadHeaderTimeout: config.HTTPServerTimeouts.ReadHeaderTimeout,
+			WriteTimeout:      config.HTTPServerTimeouts.WriteTimeout,
+			IdleTimeout:       config.HTTPServerTimeouts.IdleTimeout,
+		}
+		log.Info("Serving BLS signer over HTTP", "addr", listener.Addr())
+		go func() {
+			if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Warn("HTTP server stopped", "err", err)
+			}
+		}()
+	}
+
+	pubKey, err := signer.PublicKey(ctx)
+	if err != nil {
+		return err
+	}
+	log.Info("BLS signer ready", "pubKey", das.EncodeBLSPublicKey(pubKey))
+
+	sigint := make(chan os.Signal, 1)
+	signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
+	<-sigint
+
+	if httpServer != nil {
+		return httpServer.Shutdown(ctx)
+	}
+	return nil
+}
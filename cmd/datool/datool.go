@@ -21,6 +21,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/offchainlabs/nitro/arbstate"
 	"github.com/offchainlabs/nitro/cmd/genericconf"
 	"github.com/offchainlabs/nitro/cmd/util"
@@ -34,7 +35,7 @@ import (
 func main() {
 	args := os.Args
 	if len(args) < 2 {
-		panic("Usage: datool [client|keygen|generatehash] ...")
+		panic("Usage: datool [client|keygen|generatehash|disk-usage|backfill|verify|keyset] ...")
 	}
 
 	var err error
@@ -45,8 +46,16 @@ func main() {
 		err = startKeyGen(args[2:])
 	case "generatehash":
 		err = generateHash(args[2])
+	case "disk-usage":
+		err = startDiskUsage(args[2:])
+	case "backfill":
+		err = startBackfill(args[2:])
+	case "verify":
+		err = startVerify(args[2:])
+	case "keyset":
+		err = startKeyset(args[2:])
 	default:
-		panic(fmt.Sprintf("Unknown tool '%s' specified, valid tools are 'client', 'keygen', 'generatehash'", args[1]))
+		panic(fmt.Sprintf("Unknown tool '%s' specified, valid tools are 'client', 'keygen', 'generatehash', 'disk-usage', 'backfill', 'verify', 'keyset'", args[1]))
 	}
 	if err != nil {
 		panic(err)
@@ -313,3 +322,485 @@ func generateHash(message string) error {
 	fmt.Printf("Hex Encoded Data Hash: %s\n", hexutil.Encode(dastree.HashBytes([]byte(message))))
 	return nil
 }
+
+// datool disk-usage
+
+type DiskUsageConfig struct {
+	DataDir    string                 `koanf:"data-dir"`
+	ConfConfig genericconf.ConfConfig `koanf:"conf"`
+}
+
+func parseDiskUsageConfig(args []string) (*DiskUsageConfig, error) {
+	f := flag.NewFlagSet("datool disk-usage", flag.ContinueOnError)
+	f.String("data-dir", "", "data directory of the local file storage")
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var config DiskUsageConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func startDiskUsage(args []string) error {
+	config, err := parseDiskUsageConfig(args)
+	if err != nil {
+		return err
+	}
+	if config.DataDir == "" {
+		return errors.New("--data-dir must be specified")
+	}
+
+	usage, err := das.LocalFileStorageDiskUsage(context.Background(), config.DataDir)
+	if err != nil {
+		return err
+	}
+	now := uint64(time.Now().Unix())
+	printUsage := func(label string, u das.LocalFileUsage) {
+		fmt.Printf("%-36s %12d files %16d bytes\n", label, u.Files, u.Bytes)
+	}
+	for _, u := range usage.Buckets {
+		label := "expires by " + time.Unix(int64(u.Expiry), 0).UTC().Format(time.RFC3339)
+		if u.Expiry <= now {
+			label += " (due)"
+		}
+		printUsage(label, u)
+	}
+	printUsage("kept forever", usage.KeepForever)
+	if usage.Flat.Files > 0 {
+		printUsage("not migrated from the flat layout", usage.Flat)
+	}
+	return nil
+}
+
+// datool backfill and datool verify
+
+type BatchRangeConfig struct {
+	L1NodeURL             string                     `koanf:"l1-node-url"`
+	L1ConnectionAttempts  int                        `koanf:"l1-connection-attempts"`
+	SequencerInboxAddress string                     `koanf:"sequencer-inbox-address"`
+	FromBlock             uint64                     `koanf:"from-block"`
+	ToBlock               uint64                     `koanf:"to-block"`
+	Scanner               das.BatchScannerConfig     `koanf:"scanner"`
+	DAConf                das.DataAvailabilityConfig `koanf:"data-availability"`
+	ConfConfig            genericconf.ConfConfig     `koanf:"conf"`
+}
+
+// parseBatchRangeConfig parses the l1 block range to scan for DAS batches and the storage to write or audit,
+// backfill also needs the rest mirrors to fetch from
+func parseBatchRangeConfig(name string, args []string, withMirrors bool) (*BatchRangeConfig, error) {
+	f := flag.NewFlagSet(name, flag.ContinueOnError)
+	f.String("l1-node-url", "", "URL of the L1 node to read the batches posted to the sequencer inbox from")
+	f.Int("l1-connection-attempts", das.DefaultDataAvailabilityConfig.L1ConnectionAttempts, "layer 1 RPC connection attempts (spaced out at least 1 second per attempt, 0 to retry infinitely)")
+	f.String("sequencer-inbox-address", "", "L1 address of SequencerInbox contract")
+	f.Uint64("from-block", 0, "first L1 block to look for batches in")
+	f.Uint64("to-block", 0, "last L1 block to look for batches in (0 for the latest block)")
+	das.BatchScannerConfigAddOptions("scanner", f)
+	das.LocalDBStorageConfigAddOptions("data-availability.local-db-storage", f)
+	das.LocalFileStorageConfigAddOptions("data-availability.local-file-storage", f)
+	das.S3ConfigAddOptions("data-availability.s3-storage", f)
+	if withMirrors {
+		das.RestfulClientAggregatorConfigAddOptions("data-availability.rest-aggregator", f)
+	}
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var config BatchRangeConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	if config.L1NodeURL == "" || config.SequencerInboxAddress == "" {
+		return nil, errors.New("--l1-node-url and --sequencer-inbox-address must be specified")
+	}
+	return &config, nil
+}
+
+// open returns the l1 client, the scanner of the block range, resolving its end, and the storage service configured
+func (config *BatchRangeConfig) open(ctx context.Context) (*ethclient.Client, *das.BatchScanner, das.StorageService, *das.LifecycleManager, error) {
+	l1client, err := das.GetL1Client(ctx, config.L1ConnectionAttempts, config.L1NodeURL)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if config.ToBlock == 0 {
+		config.ToBlock, err = l1client.BlockNumber(ctx)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+	scanner, err := das.NewBatchScanner(config.Scanner, l1client, common.HexToAddress(config.SequencerInboxAddress))
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	var syncFromStorageServices []*das.IterableStorageService
+	var syncToStorageServices []das.StorageService
+	storageService, lifecycleManager, err := das.CreatePersistentStorageService(ctx, &config.DAConf, &syncFromStorageServices, &syncToStorageServices)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if storageService == nil {
+		return nil, nil, nil, nil, errors.New("at least one of --data-availability.(local-db-storage|local-file-storage|s3-storage) must be enabled")
+	}
+	return l1client, scanner, storageService, lifecycleManager, nil
+}
+
+func startBackfill(args []string) error {
+	config, err := parseBatchRangeConfig("datool backfill", args, true)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	l1client, scanner, storageService, lifecycleManager, err := config.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer lifecycleManager.StopAndWaitUntil(time.Minute)
+
+	restAgg, err := das.NewRestfulClientAggregator(ctx, &config.DAConf.RestfulClientAggregatorConfig)
+	if err != nil {
+		return err
+	}
+	restAgg.Start(ctx)
+	lifecycleManager.Register(restAgg)
+	// keysets missing from the mirrors are on L1
+	source, err := das.NewChainFetchReader(restAgg, l1client, common.HexToAddress(config.SequencerInboxAddress))
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	report, err := das.NewBackfiller(source, storageService).Backfill(ctx, scanner, config.FromBlock, config.ToBlock)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Scanned L1 blocks %d to %d in %v\n", config.FromBlock, config.ToBlock, time.Since(start).Round(time.Second))
+	fmt.Printf("DAS batches:          %d (%d expired)\n", report.Batches, report.Expired)
+	fmt.Printf("Preimages present:    %d\n", report.Present)
+	fmt.Printf("Preimages backfilled: %d (%d bytes, %d replacing corrupt ones)\n", report.Backfilled, report.Bytes, report.Corrupt)
+	fmt.Printf("Preimages failed:     %d\n", len(report.Failed))
+	for _, hash := range report.Failed {
+		fmt.Printf("  %v\n", hash)
+	}
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("%d preimages couldn't be backfilled", len(report.Failed))
+	}
+	return nil
+}
+
+func startVerify(args []string) error {
+	config, err := parseBatchRangeConfig("datool verify", args, false)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	_, scanner, storageService, lifecycleManager, err := config.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer lifecycleManager.StopAndWaitUntil(time.Minute)
+
+	report, err := das.VerifyStore(ctx, scanner, storageService, config.FromBlock, config.ToBlock)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Scanned L1 blocks %d to %d\n", config.FromBlock, config.ToBlock)
+	fmt.Printf("DAS batches:       %d (%d expired)\n", report.Batches, report.Expired)
+	fmt.Printf("Preimages valid:   %d\n", report.Valid)
+	fmt.Printf("Preimages missing: %d\n", len(report.Missing))
+	for _, hash := range report.Missing {
+		fmt.Printf("  %v\n", hash)
+	}
+	fmt.Printf("Preimages corrupt: %d\n", len(report.Corrupt))
+	for _, hash := range report.Corrupt {
+		fmt.Printf("  %v\n", hash)
+	}
+	if len(report.Missing) > 0 || len(report.Corrupt) > 0 {
+		return fmt.Errorf("store is missing %d preimages and has %d corrupt ones", len(report.Missing), len(report.Corrupt))
+	}
+	return nil
+}
+
+// datool keyset ...
+
+func startKeyset(args []string) error {
+	if len(args) == 0 {
+		return errors.New("datool keyset needs one of 'build', 'hash', 'decode', 'diff' or 'verify-cert'")
+	}
+	switch strings.ToLower(args[0]) {
+	case "build":
+		return startKeysetBuild(args[1:])
+	case "hash":
+		return startKeysetHash(args[1:])
+	case "decode":
+		return startKeysetDecode(args[1:])
+	case "diff":
+		return startKeysetDiff(args[1:])
+	case "verify-cert":
+		return startKeysetVerifyCert(args[1:])
+	}
+	return fmt.Errorf("datool keyset '%s' not supported, valid arguments are 'build', 'hash', 'decode', 'diff' and 'verify-cert'", args[0])
+}
+
+// readHexOrFile decodes the value as hex if prefixed with 0x, otherwise reads the hex from the file it names
+func readHexOrFile(value string, name string) ([]byte, error) {
+	if value == "" {
+		return nil, fmt.Errorf("--%s must be specified", name)
+	}
+	if !strings.HasPrefix(value, "0x") {
+		contents, err := os.ReadFile(value)
+		if err != nil {
+			return nil, err
+		}
+		value = strings.TrimSpace(string(contents))
+	}
+	return hexutil.Decode(value)
+}
+
+func readKeyset(value string, name string) (*arbstate.DataAvailabilityKeyset, error) {
+	keysetBytes, err := readHexOrFile(value, name)
+	if err != nil {
+		return nil, err
+	}
+	keyset, err := arbstate.DeserializeKeyset(bytes.NewReader(keysetBytes), false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keyset in --%s: %w", name, err)
+	}
+	return keyset, nil
+}
+
+func printKeyset(keyset *arbstate.DataAvailabilityKeyset) error {
+	keysetBytes, keysetHash, err := das.SerializeKeyset(keyset)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Keyset: %s\n", hexutil.Encode(keysetBytes))
+	fmt.Printf("KeysetHash: %s\n", keysetHash.Hex())
+	return nil
+}
+
+// datool keyset build
+
+type KeysetBuildConfig struct {
+	Backends      string                 `koanf:"backends"`
+	AssumedHonest int                    `koanf:"assumed-honest"`
+	Next          bool                   `koanf:"next"`
+	ConfConfig    genericconf.ConfConfig `koanf:"conf"`
+}
+
+func startKeysetBuild(args []string) error {
+	f := flag.NewFlagSet("datool keyset build", flag.ContinueOnError)
+	f.String("backends", "", "JSON backend configuration, as given to the batch poster's rpc-aggregator.backends")
+	f.Int("assumed-honest", 0, "number of assumed honest backends (H)")
+	f.Bool("next", false, "build the keyset the backends are rotating to, using their nextpubkey where set")
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return err
+	}
+	var config KeysetBuildConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return err
+	}
+
+	keyset, err := das.KeysetFromBackends(config.Backends, config.AssumedHonest, config.Next)
+	if err != nil {
+		return err
+	}
+	return printKeyset(keyset)
+}
+
+// datool keyset hash and decode
+
+type KeysetConfig struct {
+	Keyset     string                 `koanf:"keyset"`
+	ConfConfig genericconf.ConfConfig `koanf:"conf"`
+}
+
+func parseKeysetConfig(name string, args []string) (*KeysetConfig, error) {
+	f := flag.NewFlagSet("datool keyset "+name, flag.ContinueOnError)
+	f.String("keyset", "", "the keyset, hex encoded if it starts with '0x', otherwise the file it's in")
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+	var config KeysetConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func startKeysetHash(args []string) error {
+	config, err := parseKeysetConfig("hash", args)
+	if err != nil {
+		return err
+	}
+	keyset, err := readKeyset(config.Keyset, "keyset")
+	if err != nil {
+		return err
+	}
+	_, keysetHash, err := das.SerializeKeyset(keyset)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("KeysetHash: %s\n", keysetHash.Hex())
+	return nil
+}
+
+func startKeysetDecode(args []string) error {
+	config, err := parseKeysetConfig("decode", args)
+	if err != nil {
+		return err
+	}
+	keyset, err := readKeyset(config.Keyset, "keyset")
+	if err != nil {
+		return err
+	}
+	_, keysetHash, err := das.SerializeKeyset(keyset)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("KeysetHash: %s\n", keysetHash.Hex())
+	fmt.Printf("AssumedHonest: %d\n", keyset.AssumedHonest)
+	fmt.Printf("Members: %d\n", len(keyset.PubKeys))
+	for i, pubKey := range keyset.PubKeys {
+		fmt.Printf("  %d signermask=%d pubkey=%s\n", i, uint64(1)<<i, das.EncodeBLSPublicKey(pubKey))
+	}
+	return nil
+}
+
+// datool keyset diff
+
+type KeysetDiffConfig struct {
+	From       string                 `koanf:"from"`
+	To         string                 `koanf:"to"`
+	ConfConfig genericconf.ConfConfig `koanf:"conf"`
+}
+
+func startKeysetDiff(args []string) error {
+	f := flag.NewFlagSet("datool keyset diff", flag.ContinueOnError)
+	f.String("from", "", "the current keyset, hex encoded if it starts with '0x', otherwise the file it's in")
+	f.String("to", "", "the new keyset, hex encoded if it starts with '0x', otherwise the file it's in")
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return err
+	}
+	var config KeysetDiffConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return err
+	}
+	from, err := readKeyset(config.From, "from")
+	if err != nil {
+		return err
+	}
+	to, err := readKeyset(config.To, "to")
+	if err != nil {
+		return err
+	}
+
+	_, fromHash, err := das.SerializeKeyset(from)
+	if err != nil {
+		return err
+	}
+	_, toHash, err := das.SerializeKeyset(to)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("KeysetHash: %s -> %s\n", fromHash.Hex(), toHash.Hex())
+	if from.AssumedHonest != to.AssumedHonest {
+		fmt.Printf("AssumedHonest: %d -> %d\n", from.AssumedHonest, to.AssumedHonest)
+	}
+	if len(from.PubKeys) != len(to.PubKeys) {
+		fmt.Printf("Members: %d -> %d\n", len(from.PubKeys), len(to.PubKeys))
+	}
+	changes := das.DiffKeysets(from, to)
+	for _, change := range changes {
+		switch {
+		case change.From == "":
+			fmt.Printf("+ %d pubkey=%s\n", change.Index, change.To)
+		case change.To == "":
+			fmt.Printf("- %d pubkey=%s\n", change.Index, change.From)
+		default:
+			fmt.Printf("~ %d pubkey=%s -> %s\n", change.Index, change.From, change.To)
+		}
+	}
+	if fromHash == toHash {
+		fmt.Println("Keysets are identical")
+	}
+	return nil
+}
+
+// datool keyset verify-cert
+
+type KeysetVerifyCertConfig struct {
+	Keyset     string                 `koanf:"keyset"`
+	Cert       string                 `koanf:"cert"`
+	DataFile   string                 `koanf:"data-file"`
+	ConfConfig genericconf.ConfConfig `koanf:"conf"`
+}
+
+func startKeysetVerifyCert(args []string) error {
+	f := flag.NewFlagSet("datool keyset verify-cert", flag.ContinueOnError)
+	f.String("keyset", "", "the keyset, hex encoded if it starts with '0x', otherwise the file it's in")
+	f.String("cert", "", "the serialized certificate as posted in the batch, hex encoded if it starts with '0x', otherwise the file it's in")
+	f.String("data-file", "", "optionally, a file with the batch data to check the certificate is for")
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return err
+	}
+	var config KeysetVerifyCertConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return err
+	}
+	keyset, err := readKeyset(config.Keyset, "keyset")
+	if err != nil {
+		return err
+	}
+	certBytes, err := readHexOrFile(config.Cert, "cert")
+	if err != nil {
+		return err
+	}
+	cert, err := arbstate.DeserializeDASCertFrom(bytes.NewReader(certBytes))
+	if err != nil {
+		return fmt.Errorf("invalid certificate: %w", err)
+	}
+
+	fmt.Printf("Version: %d\n", cert.Version)
+	fmt.Printf("KeysetHash: %s\n", common.Hash(cert.KeysetHash).Hex())
+	fmt.Printf("DataHash: %s\n", common.Hash(cert.DataHash).Hex())
+	fmt.Printf("Timeout: %s\n", time.Unix(int64(cert.Timeout), 0).UTC())
+	fmt.Printf("SignersMask: %b\n", cert.SignersMask)
+	if cert.Timeout < uint64(time.Now().Unix()) {
+		fmt.Println("Warning: the certificate has expired, committee members may have discarded the data")
+	}
+
+	if err := das.VerifyCertificate(cert, keyset); err != nil {
+		return fmt.Errorf("certificate doesn't verify: %w", err)
+	}
+	if config.DataFile != "" {
+		data, err := os.ReadFile(config.DataFile)
+		if err != nil {
+			return err
+		}
+		if !dastree.ValidHash(cert.DataHash, data) {
+			return errors.New("certificate isn't for the data")
+		}
+	}
+	fmt.Println("Certificate verified")
+	return nil
+}
@@ -78,21 +78,23 @@ func HTTPServerTimeoutConfigAddOptions(prefix string, f *flag.FlagSet) {
 }
 
 type WSConfig struct {
-	Addr      string   `koanf:"addr"`
-	Port      int      `koanf:"port"`
-	API       []string `koanf:"api"`
-	RPCPrefix string   `koanf:"rpcprefix"`
-	Origins   []string `koanf:"origins"`
-	ExposeAll bool     `koanf:"expose-all"`
+	Addr           string                  `koanf:"addr"`
+	Port           int                     `koanf:"port"`
+	API            []string                `koanf:"api"`
+	RPCPrefix      string                  `koanf:"rpcprefix"`
+	Origins        []string                `koanf:"origins"`
+	ExposeAll      bool                    `koanf:"expose-all"`
+	ServerTimeouts HTTPServerTimeoutConfig `koanf:"server-timeouts"`
 }
 
 var WSConfigDefault = WSConfig{
-	Addr:      node.DefaultConfig.WSHost,
-	Port:      8548,
-	API:       append(node.DefaultConfig.WSModules, "eth", "arb"),
-	RPCPrefix: node.DefaultConfig.WSPathPrefix,
-	Origins:   node.DefaultConfig.WSOrigins,
-	ExposeAll: node.DefaultConfig.WSExposeAll,
+	Addr:           node.DefaultConfig.WSHost,
+	Port:           8548,
+	API:            append(node.DefaultConfig.WSModules, "eth", "arb"),
+	RPCPrefix:      node.DefaultConfig.WSPathPrefix,
+	Origins:        node.DefaultConfig.WSOrigins,
+	ExposeAll:      node.DefaultConfig.WSExposeAll,
+	ServerTimeouts: HTTPServerTimeoutConfigDefault,
 }
 
 func (c WSConfig) Apply(stackConf *node.Config) {
@@ -111,6 +113,7 @@ func WSConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.String(prefix+".rpcprefix", WSConfigDefault.RPCPrefix, "WS path path prefix on which JSON-RPC is served. Use '/' to serve on all paths")
 	f.StringSlice(prefix+".origins", WSConfigDefault.Origins, "Origins from which to accept websockets requests")
 	f.Bool(prefix+".expose-all", WSConfigDefault.ExposeAll, "expose private api via websocket")
+	HTTPServerTimeoutConfigAddOptions(prefix+".server-timeouts", f)
 }
 
 type IPCConfig struct {
@@ -241,6 +241,7 @@ func mainImpl() int {
 	if nodeConfig.WS.ExposeAll {
 		stackConf.WSModules = append(stackConf.WSModules, "personal")
 	}
+	privacyWs := movePrivacyWs(&stackConf, nodeConfig.Privacy.Enable)
 	stackConf.P2P.ListenAddr = ""
 	stackConf.P2P.NoDial = true
 	stackConf.P2P.NoDiscovery = true
@@ -469,6 +470,12 @@ func mainImpl() int {
 		log.Error("failed to create node", "err", err)
 		return 1
 	}
+	if valNode != nil && currentNode.StatelessBlockValidator != nil {
+		currentNode.StatelessBlockValidator.SetLocalValidationNode(valNode.GetValidationSpawner(), valNode.GetExec())
+	}
+	if nodeConfig.Revalidate.Enable {
+		return revalidate(ctx, &nodeConfig.Revalidate, valNode, currentNode)
+	}
 	liveNodeConfig.setOnReloadHook(func(oldCfg *NodeConfig, newCfg *NodeConfig) error {
 		return currentNode.OnConfigReload(&oldCfg.Node, &newCfg.Node)
 	})
@@ -496,6 +503,11 @@ func mainImpl() int {
 		}
 	}
 
+	if err := privacyWs.mount(stack, &stackConf); err != nil {
+		log.Error("failed to mount the privacy websocket endpoint", "err", err)
+		return 1
+	}
+
 	if valNode != nil {
 		err = valNode.Start(ctx)
 		if err != nil {
@@ -508,6 +520,12 @@ func mainImpl() int {
 			fatalErrChan <- fmt.Errorf("error starting node: %w", err)
 		}
 	}
+	if err == nil {
+		err = privacyWs.start(ctx, stack, &nodeConfig.WS.ServerTimeouts)
+		if err != nil {
+			fatalErrChan <- fmt.Errorf("error starting privacy websocket endpoint: %w", err)
+		}
+	}
 
 	sigint := make(chan os.Signal, 1)
 	signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
@@ -550,6 +568,7 @@ type NodeConfig struct {
 	Init          InitConfig                      `koanf:"init"`
 	Rpc           genericconf.RpcConfig           `koanf:"rpc"`
 	Privacy       privacy.PrivacyConfig           `koanf:"privacy"`
+	Revalidate    RevalidateConfig                `koanf:"revalidate"`
 }
 
 var NodeConfigDefault = NodeConfig{
@@ -566,6 +585,7 @@ var NodeConfigDefault = NodeConfig{
 	IPC:           genericconf.IPCConfigDefault,
 	Metrics:       false,
 	MetricsServer: genericconf.MetricsServerConfigDefault,
+	Revalidate:    RevalidateConfigDefault,
 }
 
 func NodeConfigAddOptions(f *flag.FlagSet) {
@@ -589,6 +609,7 @@ func NodeConfigAddOptions(f *flag.FlagSet) {
 	genericconf.RpcConfigAddOptions("rpc", f)
 	// add asn privacy options
 	privacy.PrivacyRPCConfigAddOptions("privacy", f)
+	RevalidateConfigAddOptions("revalidate", f)
 }
 
 func (c *NodeConfig) ResolveDirectoryNames() error {
@@ -641,6 +662,9 @@ func (c *NodeConfig) CanReload(new *NodeConfig) error {
 }
 
 func (c *NodeConfig) Validate() error {
+	if err := c.Revalidate.Validate(); err != nil {
+		return err
+	}
 	return c.Node.Validate()
 }
 
@@ -0,0 +1,147 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/cmd/genericconf"
+	"github.com/offchainlabs/nitro/privacy"
+)
+
+// geth only installs the privacy middleware on its http handler, so when privacy is enabled its websocket
+// server is moved to loopback, and the configured websocket endpoint is served through the privacy
+// websocket middleware in front of it
+type privacyWs struct {
+	endpoint string // empty if websockets are disabled
+	shared   bool   // whether the endpoint is the http one
+	prefix   string
+}
+
+// movePrivacyWs moves the websocket server of the stack to loopback if privacy is enabled
+func movePrivacyWs(stackConf *node.Config, privacyEnabled bool) *privacyWs {
+	if !privacyEnabled || stackConf.WSHost == "" {
+		return &privacyWs{}
+	}
+	p := &privacyWs{
+		endpoint: fmt.Sprintf("%v:%v", stackConf.WSHost, stackConf.WSPort),
+		// geth shares the http server with websockets when they're on the same port
+		shared: stackConf.HTTPHost != "" && stackConf.HTTPPort == stackConf.WSPort,
+		prefix: stackConf.WSPathPrefix,
+	}
+	stackConf.WSHost = "127.0.0.1"
+	stackConf.WSPort = 0
+	stackConf.WSPathPrefix = ""
+	return p
+}
+
+// handler proxies websockets to the websocket server of the stack through the privacy websocket middleware
+func (p *privacyWs) handler(stack *node.Node) http.Handler {
+	proxy := &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			// the websocket server only listens once the stack is started
+			u, err := url.Parse(stack.WSEndpoint())
+			if err != nil {
+				log.Error("invalid websocket endpoint", "endpoint", stack.WSEndpoint(), "err", err)
+				return
+			}
+			r.URL.Scheme = "http"
+			r.URL.Host = u.Host
+			r.URL.Path = "/"
+			r.URL.RawPath = ""
+		},
+	}
+	return privacy.RpcWsMiddleware(proxy)
+}
+
+// mount serves the websockets of the shared endpoint on the http server of the stack, it must be called
+// once the apis are registered and before the stack is started. The mount takes over the http requests
+// on its path, so those are served by an rpc server with the http modules, the way geth serves them.
+func (p *privacyWs) mount(stack *node.Node, stackConf *node.Config) error {
+	if p.endpoint == "" || !p.shared {
+		return nil
+	}
+	srv := rpc.NewServer()
+	apis, _ := stack.GetAPIs()
+	if err := node.RegisterApis(apis, stackConf.HTTPModules, srv); err != nil {
+		return err
+	}
+	rpcHandler := node.NewHTTPHandlerStack(privacy.RpcResponseMiddleware(srv), stackConf.HTTPCors, stackConf.HTTPVirtualHosts, nil)
+	wsHandler := p.handler(stack)
+	path := p.prefix
+	if path == "" {
+		path = "/"
+	}
+	stack.RegisterHandler("privacy websocket", path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isWebsocket(r) {
+			if checkPath(r, p.prefix) {
+				wsHandler.ServeHTTP(w, r)
+			}
+			return
+		}
+		if checkPath(r, stackConf.HTTPPathPrefix) {
+			rpcHandler.ServeHTTP(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	return nil
+}
+
+// start serves the endpoint if it isn't shared with http, once the stack is started
+func (p *privacyWs) start(ctx context.Context, stack *node.Node, timeouts *genericconf.HTTPServerTimeoutConfig) error {
+	if p.endpoint == "" || p.shared {
+		return nil
+	}
+	listener, err := net.Listen("tcp", p.endpoint)
+	if err != nil {
+		return err
+	}
+	wsHandler := p.handler(stack)
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if checkPath(r, p.prefix) {
+				wsHandler.ServeHTTP(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}),
+		ReadTimeout:       timeouts.ReadTimeout,
+		ReadHeaderTimeout: timeouts.ReadHeaderTimeout,
+		WriteTimeout:      timeouts.WriteTimeout,
+		IdleTimeout:       timeouts.IdleTimeout,
+	}
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.Background())
+	}()
+	log.Info("WebSocket endpoint opened through the privacy middleware", "url", fmt.Sprintf("ws://%v%v", listener.Addr(), p.prefix))
+	return nil
+}
+
+func isWebsocket(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// checkPath matches the path prefix the way geth does, an empty prefix only matches the root
+func checkPath(r *http.Request, prefix string) bool {
+	if prefix == "" {
+		return r.URL.Path == "/"
+	}
+	return strings.HasPrefix(r.URL.Path, prefix)
+}
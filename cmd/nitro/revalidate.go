@@ -0,0 +1,212 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/staker"
+	"github.com/offchainlabs/nitro/validator"
+	"github.com/offchainlabs/nitro/validator/valnode"
+)
+
+type RevalidateConfig struct {
+	Enable         bool     `koanf:"enable"`
+	FromBlock      uint64   `koanf:"from-block"`
+	ToBlock        uint64   `koanf:"to-block"`
+	ModuleRoots    []string `koanf:"module-roots"`
+	Workers        int      `koanf:"workers"`
+	Report         string   `koanf:"report"`
+	ReportFormat   string   `koanf:"report-format"`
+	DumpMismatches bool     `koanf:"dump-mismatches"`
+}
+
+var RevalidateConfigDefault = RevalidateConfig{
+	Enable:         false,
+	FromBlock:      0,
+	ToBlock:        0,
+	ModuleRoots:    []string{},
+	Workers:        4,
+	Report:         "revalidation.json",
+	ReportFormat:   "json",
+	DumpMismatches: true,
+}
+
+func RevalidateConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", RevalidateConfigDefault.Enable, "re-validate a range of blocks from the database, write a report and quit instead of running the node (requires node.block-validator.url or validation-servers)")
+	f.Uint64(prefix+".from-block", RevalidateConfigDefault.FromBlock, "first block to re-validate")
+	f.Uint64(prefix+".to-block", RevalidateConfigDefault.ToBlock, "last block to re-validate")
+	f.StringSlice(prefix+".module-roots", RevalidateConfigDefault.ModuleRoots, "wasm module roots to re-validate against, 'latest' for the validation server's latest (defaults to the current and pending module roots)")
+	f.Int(prefix+".workers", RevalidateConfigDefault.Workers, "number of blocks to re-validate in parallel")
+	f.String(prefix+".report", RevalidateConfigDefault.Report, "file to write the report of expected and computed global states to")
+	f.String(prefix+".report-format", RevalidateConfigDefault.ReportFormat, "format of the report (json or csv)")
+	f.Bool(prefix+".dump-mismatches", RevalidateConfigDefault.DumpMismatches, "write the validation input of blocks that don't match to the validation server's output path")
+}
+
+func (c *RevalidateConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.ToBlock < c.FromBlock {
+		return fmt.Errorf("revalidate to-block %v is before from-block %v", c.ToBlock, c.FromBlock)
+	}
+	if c.ReportFormat != "json" && c.ReportFormat != "csv" {
+		return fmt.Errorf("invalid revalidate report-format %#v, expected json or csv", c.ReportFormat)
+	}
+	if c.Report == "" {
+		return errors.New("revalidate report file must be set")
+	}
+	for _, root := range c.ModuleRoots {
+		if root != "latest" && (common.HexToHash(root) == common.Hash{}) {
+			return fmt.Errorf("invalid revalidate module root %#v", root)
+		}
+	}
+	return nil
+}
+
+func (c *RevalidateConfig) moduleRoots(ctx context.Context, val *staker.StatelessBlockValidator) ([]common.Hash, error) {
+	if len(c.ModuleRoots) == 0 {
+		var roots []common.Hash
+		for _, root := range val.GetModuleRootsToValidate() {
+			if (root != common.Hash{}) {
+				roots = append(roots, root)
+			}
+		}
+		if len(roots) == 0 {
+			return nil, errors.New("no current wasm module root, must set revalidate.module-roots")
+		}
+		return roots, nil
+	}
+	var roots []common.Hash
+	for _, root := range c.ModuleRoots {
+		if root == "latest" {
+			latest, err := val.LatestWasmModuleRoot(ctx)
+			if err != nil {
+				return nil, err
+			}
+			roots = append(roots, latest)
+		} else {
+			roots = append(roots, common.HexToHash(root))
+		}
+	}
+	return roots, nil
+}
+
+// revalidate runs the revalidate mode, returning the exit code: 1 if a block didn't validate or on error
+func revalidate(ctx context.Context, config *RevalidateConfig, valNode *valnode.ValidationNode, node *arbnode.Node) int {
+	val := node.StatelessBlockValidator
+	if val == nil {
+		log.Error("revalidating blocks requires a block validator, see node.block-validator.url and validation-servers")
+		return 1
+	}
+	if valNode != nil {
+		if err := valNode.Start(ctx); err != nil {
+			log.Error("error starting validation node", "err", err)
+			return 1
+		}
+	}
+	if err := val.Start(ctx); err != nil {
+		log.Error("error starting block validator", "err", err)
+		return 1
+	}
+	defer val.Stop()
+
+	moduleRoots, err := config.moduleRoots(ctx, val)
+	if err != nil {
+		log.Error("error getting module roots to revalidate against", "err", err)
+		return 1
+	}
+	log.Info("revalidating blocks", "from", config.FromBlock, "to", config.ToBlock, "moduleRoots", moduleRoots)
+	results, err := val.RevalidateRange(ctx, config.FromBlock, config.ToBlock, moduleRoots, config.Workers, config.DumpMismatches)
+	if err != nil {
+		log.Error("error revalidating blocks", "err", err)
+		return 1
+	}
+
+	file, err := os.Create(config.Report)
+	if err != nil {
+		log.Error("error creating revalidation report", "err", err)
+		return 1
+	}
+	defer file.Close()
+	if config.ReportFormat == "csv" {
+		err = writeRevalidationCSV(file, results)
+	} else {
+		err = writeRevalidationJSON(file, results)
+	}
+	if err != nil {
+		log.Error("error writing revalidation report", "err", err)
+		return 1
+	}
+
+	invalid := 0
+	for _, result := range results {
+		if !result.Valid {
+			invalid++
+			log.Error("block didn't validate", "block", result.BlockNumber, "moduleRoot", result.ModuleRoot, "expected", result.Expected, "computed", result.Computed, "err", result.Error)
+		}
+	}
+	log.Info("wrote revalidation report", "file", config.Report, "results", len(results), "invalid", invalid)
+	if invalid > 0 {
+		return 1
+	}
+	return 0
+}
+
+func writeRevalidationJSON(w io.Writer, results []staker.RevalidationResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+func writeRevalidationCSV(w io.Writer, results []staker.RevalidationResult) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{
+		"block", "blockHash", "moduleRoot", "valid",
+		"expectedBlockHash", "expectedSendRoot", "expectedBatch", "expectedPosInBatch",
+		"computedBlockHash", "computedSendRoot", "computedBatch", "computedPosInBatch",
+		"error", "dumped", "dumpError", "latency",
+	})
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		record := []string{
+			strconv.FormatUint(result.BlockNumber, 10),
+			result.BlockHash.Hex(),
+			result.ModuleRoot.Hex(),
+			strconv.FormatBool(result.Valid),
+		}
+		for _, state := range []*validator.GoGlobalState{result.Expected, result.Computed} {
+			if state == nil {
+				record = append(record, "", "", "", "")
+				continue
+			}
+			record = append(record,
+				state.BlockHash.Hex(),
+				state.SendRoot.Hex(),
+				strconv.FormatUint(state.Batch, 10),
+				strconv.FormatUint(state.PosInBatch, 10),
+			)
+		}
+		record = append(record, result.Error, strconv.FormatBool(result.Dumped), result.DumpError, result.Latency)
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,63 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/staker"
+	"github.com/offchainlabs/nitro/validator"
+)
+
+func TestRevalidationReport(t *testing.T) {
+	expected := validator.GoGlobalState{BlockHash: common.HexToHash("0x1"), Batch: 2, PosInBatch: 3}
+	computed := validator.GoGlobalState{BlockHash: common.HexToHash("0x4"), Batch: 2, PosInBatch: 3}
+	results := []staker.RevalidationResult{
+		{BlockNumber: 10, ModuleRoot: common.HexToHash("0xaa"), Expected: &expected, Computed: &expected, Valid: true},
+		{BlockNumber: 11, ModuleRoot: common.HexToHash("0xaa"), Expected: &expected, Computed: &computed, Dumped: true},
+		{BlockNumber: 12, ModuleRoot: common.HexToHash("0xaa"), Error: "block 12 not found"},
+	}
+
+	var buf bytes.Buffer
+	Require(t, writeRevalidationCSV(&buf, results))
+	records, err := csv.NewReader(&buf).ReadAll()
+	Require(t, err)
+	if len(records) != len(results)+1 {
+		Fail(t, "unexpected number of csv records", len(records))
+	}
+	if records[2][3] != "false" || records[2][8] != computed.BlockHash.Hex() || records[2][13] != "true" {
+		Fail(t, "unexpected csv record for mismatch", records[2])
+	}
+	if records[3][4] != "" || records[3][12] != "block 12 not found" {
+		Fail(t, "unexpected csv record for error", records[3])
+	}
+
+	buf.Reset()
+	Require(t, writeRevalidationJSON(&buf, results))
+	var decoded []staker.RevalidationResult
+	Require(t, json.Unmarshal(buf.Bytes(), &decoded))
+	if len(decoded) != len(results) || *decoded[1].Computed != computed || decoded[2].Expected != nil {
+		Fail(t, "unexpected json report", buf.String())
+	}
+
+	config := RevalidateConfigDefault
+	config.Enable = true
+	config.FromBlock = 10
+	config.ToBlock = 5
+	if config.Validate() == nil {
+		Fail(t, "accepted to-block before from-block")
+	}
+	config.ToBlock = 12
+	config.ModuleRoots = []string{"latest", "0xaa"}
+	Require(t, config.Validate())
+	config.ReportFormat = "xml"
+	if config.Validate() == nil {
+		Fail(t, "accepted unknown report format")
+	}
+}
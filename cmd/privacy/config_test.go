@@ -0,0 +1,17 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+func TestGatewayConfig(t *testing.T) {
+	args := strings.Split("--gateway.upstreams http://nitro:8547,http://nitro-backup:8547 --privacy.redis.enable --privacy.redis.url redis://redis:6379", " ")
+	config, err := ParseGateway(args)
+	testhelpers.RequireImpl(t, err)
+	if len(config.Gateway.Upstreams) != 2 {
+		testhelpers.FailImpl(t, "unexpected upstreams", config.Gateway.Upstreams)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/offchainlabs/nitro/das"
+	"github.com/offchainlabs/nitro/privacy"
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+// grantsCipher is the aes-gcm cipher snapshots are sealed with, they hold the tokens and anyone can fetch das data
+func grantsCipher(config *privacy.DASConfig) (cipher.AEAD, error) {
+	if config.EncryptionKey == "" {
+		return nil, errors.New("--privacy.das.encryption-key is required to publish or fetch grant snapshots")
+	}
+	key, err := hexutil.Decode(config.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grant encryption key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("grant encryption key is %d bytes, not 32", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealGrants encodes the snapshot and encrypts it, prefixed with the random nonce
+func sealGrants(config *privacy.DASConfig, snapshot privacy.GrantSnapshot) ([]byte, error) {
+	aead, err := grantsCipher(config)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openGrantsMessage decrypts and decodes a snapshot sealed by sealGrants
+func openGrantsMessage(config *privacy.DASConfig, message []byte) (privacy.GrantSnapshot, error) {
+	aead, err := grantsCipher(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(message) < aead.NonceSize() {
+		return nil, errors.New("grant snapshot too short")
+	}
+	plaintext, err := aead.Open(nil, message[:aead.NonceSize()], message[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt grant snapshot: %w", err)
+	}
+	var snapshot privacy.GrantSnapshot
+	if err := json.Unmarshal(plaintext, &snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// publishGrants stores the encrypted snapshot on every das backend and returns the data hash it can be fetched by
+func publishGrants(ctx context.Context, config *privacy.DASConfig, snapshot privacy.GrantSnapshot) (common.Hash, error) {
+	if len(config.Backends) == 0 {
+		return common.Hash{}, errors.New("no das backends to publish grants to")
+	}
+	message, err := sealGrants(config, snapshot)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var signer signature.DataSignerFunc
+	if config.SigningKey != "" {
+		privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(config.SigningKey, "0x"))
+		if err != nil {
+			return common.Hash{}, err
+		}
+		signer = signature.DataSignerFromPrivateKey(privateKey)
+	}
+	timeout := uint64(time.Now().Add(config.RetentionPeriod).Unix())
+
+	var dataHash common.Hash
+	for _, url := range config.Backends {
+		client, err := das.NewDASRPCClient(url)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		var writer das.DataAvailabilityServiceWriter = client
+		if signer != nil {
+			writer, err = das.NewStoreSigningDAS(writer, signer)
+			if err != nil {
+				return common.Hash{}, err
+			}
+		}
+		cert, err := writer.Store(ctx, message, timeout, nil)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("failed to publish grants to %v: %w", url, err)
+		}
+		dataHash = cert.DataHash
+		log.Info("published privacy grants", "backend", url, "dataHash", dataHash)
+	}
+	return dataHash, nil
+}
+
+// fetchGrants reads the snapshot with the data hash from the first rest backend that serves it
+func fetchGrants(ctx context.Context, config *privacy.DASConfig, dataHash common.Hash) (privacy.GrantSnapshot, error) {
+	if len(config.RestBackends) == 0 {
+		return nil, errors.New("no das rest backends to fetch grants from")
+	}
+	if _, err := grantsCipher(config); err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, url := range config.RestBackends {
+		client, err := das.NewRestfulDasClientFromURL(url)
+		if err != nil {
+			return nil, err
+		}
+		// the client checks the data against the hash
+		message, err := client.GetByHash(ctx, dataHash)
+		if err != nil {
+			log.Warn("failed to fetch privacy grants", "backend", url, "err", err)
+			lastErr = err
+			continue
+		}
+		return openGrantsMessage(config, message)
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/blsSignatures"
+	"github.com/offchainlabs/nitro/das"
+	"github.com/offchainlabs/nitro/das/dastree"
+	"github.com/offchainlabs/nitro/privacy"
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+// recordingDAS keeps the messages it is asked to store
+type recordingDAS struct {
+	mutex    sync.Mutex
+	messages [][]byte
+	priv     blsSignatures.PrivateKey
+}
+
+func (d *recordingDAS) Store(ctx context.Context, message hexutil.Bytes, timeout hexutil.Uint64, sig hexutil.Bytes) (*das.StoreResult, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.messages = append(d.messages, append([]byte{}, message...))
+	dataHash := dastree.Hash(message)
+	blsSig, err := blsSignatures.SignMessage(d.priv, dataHash[:])
+	if err != nil {
+		return nil, err
+	}
+	return &das.StoreResult{
+		DataHash: dataHash[:],
+		Timeout:  timeout,
+		Sig:      blsSignatures.SignatureToBytes(blsSig),
+		Version:  1,
+	}, nil
+}
+
+func TestPublishedGrantsHideTokens(t *testing.T) {
+	_, priv, err := blsSignatures.GenerateKeys()
+	testhelpers.RequireImpl(t, err)
+	backend := &recordingDAS{priv: priv}
+	server := rpc.NewServer()
+	testhelpers.RequireImpl(t, server.RegisterName("das", backend))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	snapshot := privacy.GrantSnapshot{
+		"0x0000000000000000000000000000000000000001": {
+			{Token: "auditor-secret-token", Mask: privacy.CapabilityBalance},
+			{Token: "owner-secret-token", Mask: privacy.CapabilityAll},
+		},
+	}
+	config := privacy.DASConfigDefaults
+	config.Backends = []string{httpServer.URL}
+	config.EncryptionKey = hexutil.Encode(crypto.Keccak256([]byte("grant key")))
+
+	ctx := context.Background()
+	dataHash, err := publishGrants(ctx, &config, snapshot)
+	testhelpers.RequireImpl(t, err)
+	if len(backend.messages) != 1 {
+		testhelpers.FailImpl(t, "published", len(backend.messages), "messages")
+	}
+	published := backend.messages[0]
+	if dastree.Hash(published) != dataHash {
+		testhelpers.FailImpl(t, "data hash doesn't match the published snapshot")
+	}
+	for _, acl := range snapshot {
+		for _, grant := range acl {
+			if bytes.Contains(published, []byte(grant.Token)) {
+				testhelpers.FailImpl(t, "published snapshot contains the token", grant.Token)
+			}
+		}
+	}
+
+	opened, err := openGrantsMessage(&config, published)
+	testhelpers.RequireImpl(t, err)
+	if len(opened) != 1 || len(opened["0x0000000000000000000000000000000000000001"]) != 2 {
+		testhelpers.FailImpl(t, "unexpected snapshot", opened)
+	}
+
+	wrongKey := config
+	wrongKey.EncryptionKey = hexutil.Encode(crypto.Keccak256([]byte("other key")))
+	if _, err := openGrantsMessage(&wrongKey, published); err == nil {
+		testhelpers.FailImpl(t, "opened snapshot with the wrong key")
+	}
+	noKey := config
+	noKey.EncryptionKey = ""
+	if _, err := publishGrants(ctx, &noKey, snapshot); err == nil {
+		testhelpers.FailImpl(t, "published snapshot without an encryption key")
+	}
+}
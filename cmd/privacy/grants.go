@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/cmd/genericconf"
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+	"github.com/offchainlabs/nitro/privacy"
+)
+
+// privacy export|import ...
+
+type GrantsConfig struct {
+	Database   string                 `koanf:"database"`
+	File       string                 `koanf:"file"`
+	DataHash   string                 `koanf:"data-hash"`
+	Privacy    privacy.PrivacyConfig  `koanf:"privacy"`
+	ConfConfig genericconf.ConfConfig `koanf:"conf"`
+}
+
+func parseGrantsConfig(name string, args []string) (*GrantsConfig, error) {
+	f := flag.NewFlagSet("privacy "+name, flag.ContinueOnError)
+	f.String("database", "", "path to the arbitrumdata directory of a stopped node, if empty the grants are read from the configured redis")
+	f.String("file", "", "grant snapshot file, stdin or stdout if empty")
+	f.String("data-hash", "", "das data hash of the grant snapshot to import instead of the file")
+	privacy.PrivacyRPCConfigAddOptions("privacy", f)
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var config GrantsConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// openGrants sets up the privacy wrapper on the grant store of the node, the caller closes the database
+func openGrants(config *GrantsConfig) (*privacy.PrivacyWrapper, ethdb.Database, error) {
+	// nothing is filtered here
+	config.Privacy.RedactionReceipts = false
+	if config.Database == "" {
+		if !config.Privacy.RedisCache.Enable {
+			return nil, nil, errors.New("either --database or --privacy.redis.enable is required")
+		}
+		wrapper, err := privacy.NewWrapper(&config.Privacy, nil, nil)
+		return wrapper, nil, err
+	}
+	db, err := rawdb.NewLevelDBDatabase(config.Database, 0, 0, "", false)
+	if err != nil {
+		return nil, nil, err
+	}
+	config.Privacy.Database.Enable = true
+	config.Privacy.RedisCache.Enable = false
+	wrapper, err := privacy.NewWrapper(&config.Privacy, rawdb.NewTable(db, arbnode.PrivacyPrefix), nil)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return wrapper, db, nil
+}
+
+func startExport(args []string) error {
+	config, err := parseGrantsConfig("export", args)
+	if err != nil {
+		return err
+	}
+	wrapper, db, err := openGrants(config)
+	if err != nil {
+		return err
+	}
+	if db != nil {
+		defer db.Close()
+	}
+
+	ctx := context.Background()
+	snapshot, err := wrapper.ExportGrants(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	if config.File == "" {
+		fmt.Println(string(data))
+	} else if err := os.WriteFile(config.File, data, 0600); err != nil {
+		return err
+	}
+
+	if config.Privacy.Das.Enable {
+		dataHash, err := publishGrants(ctx, &config.Privacy.Das, snapshot)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Published grants of %d addresses, data hash: %v\n", len(snapshot), dataHash)
+	}
+	return nil
+}
+
+func startImport(args []string) error {
+	config, err := parseGrantsConfig("import", args)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var snapshot privacy.GrantSnapshot
+	if config.DataHash != "" {
+		snapshot, err = fetchGrants(ctx, &config.Privacy.Das, common.HexToHash(config.DataHash))
+		if err != nil {
+			return err
+		}
+	} else {
+		var data []byte
+		if config.File == "" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(config.File)
+		}
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return err
+		}
+	}
+
+	wrapper, db, err := openGrants(config)
+	if err != nil {
+		return err
+	}
+	if db != nil {
+		defer db.Close()
+	}
+	imported, err := wrapper.ImportGrants(ctx, snapshot)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d grants on %d addresses\n", imported, len(snapshot))
+	return nil
+}
@@ -1,70 +1,274 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"crypto/ecdsa"
+	"errors"
 	"fmt"
-	"github.com/ethereum/go-ethereum/node"
-	"github.com/ethereum/go-ethereum/rpc"
-	"github.com/offchainlabs/nitro/privacy"
-	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"syscall"
 	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/metrics/exp"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/cmd/genericconf"
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+	"github.com/offchainlabs/nitro/privacy"
+	"github.com/offchainlabs/nitro/util/signature"
 )
 
+func init() {
+	http.DefaultServeMux = http.NewServeMux()
+}
+
 func main() {
-	var api privacy.IPrivacyAPI
-	api = privacy.NewPrivacyAPI(privacy.NewWrapper(&privacy.PrivacyRPCConfigDefault))
-	rpcAPI := []rpc.API{{
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		var err error
+		switch strings.ToLower(os.Args[1]) {
+		case "export":
+			err = startExport(os.Args[2:])
+		case "import":
+			err = startImport(os.Args[2:])
+		default:
+			panic(fmt.Sprintf("Unknown tool '%s' specified, valid tools are 'export', 'import'", os.Args[1]))
+		}
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+	if err := startup(); err != nil {
+		log.Error("Error running privacy gateway", "err", err)
+		os.Exit(1)
+	}
+}
+
+type Config struct {
+	Conf          genericconf.ConfConfig          `koanf:"conf"`
+	LogLevel      int                             `koanf:"log-level"`
+	LogType       string                          `koanf:"log-type"`
+	HTTP          genericconf.HTTPConfig          `koanf:"http"`
+	Database      string                          `koanf:"database"`
+	SigningKey    string                          `koanf:"signing-key"`
+	Metrics       bool                            `koanf:"metrics"`
+	MetricsServer genericconf.MetricsServerConfig `koanf:"metrics-server"`
+	Gateway       privacy.GatewayConfig           `koanf:"gateway"`
+	Privacy       privacy.PrivacyConfig           `koanf:"privacy"`
+}
+
+var ConfigDefault = Config{
+	Conf:          genericconf.ConfConfigDefault,
+	LogLevel:      int(log.LvlInfo),
+	LogType:       "plaintext",
+	HTTP:          genericconf.HTTPConfigDefault,
+	Database:      "",
+	SigningKey:    "",
+	Metrics:       false,
+	MetricsServer: genericconf.MetricsServerConfigDefault,
+	Gateway:       privacy.GatewayConfigDefault,
+	Privacy:       privacy.PrivacyRPCConfigDefault,
+}
+
+func ConfigAddOptions(f *flag.FlagSet) {
+	genericconf.ConfConfigAddOptions("conf", f)
+	f.Int("log-level", ConfigDefault.LogLevel, "log level")
+	f.String("log-type", ConfigDefault.LogType, "log type")
+	genericconf.HTTPConfigAddOptions("http", f)
+	f.String("database", ConfigDefault.Database, "directory of the database the grants are kept in when privacy.database.enable is set")
+	f.String("signing-key", ConfigDefault.SigningKey, "ecdsa private key to sign redaction receipts with, treated as a hex string if prefixed with 0x otherwise treated as a file")
+	f.Bool("metrics", ConfigDefault.Metrics, "enable metrics")
+	genericconf.MetricsServerAddOptions("metrics-server", f)
+	privacy.GatewayConfigAddOptions("gateway", f)
+	privacy.PrivacyRPCConfigAddOptions("privacy", f)
+}
+
+func ParseGateway(args []string) (*Config, error) {
+	f := flag.NewFlagSet("", flag.ContinueOnError)
+
+	ConfigAddOptions(f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+
+	if config.Conf.Dump {
+		err = confighelpers.DumpConfig(k, map[string]interface{}{})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &config, nil
+}
+
+func printSampleUsage(progname string) {
+	fmt.Printf("\n")
+	fmt.Printf("Sample usage:                  %s --gateway.upstreams=<nitro RPC> --privacy.backends=<backend list> \n", progname)
+	fmt.Printf("Export grants:                 %s export --database=<arbitrumdata dir> --file=<snapshot> \n", progname)
+	fmt.Printf("Import grants:                 %s import --database=<arbitrumdata dir> --file=<snapshot> \n", progname)
+}
+
+func startup() error {
+	ctx := context.Background()
+
+	config, err := ParseGateway(os.Args[1:])
+	if err != nil || len(config.Gateway.Upstreams) == 0 {
+		confighelpers.PrintErrorAndExit(err, printSampleUsage)
+	}
+
+	logFormat, err := genericconf.ParseLogType(config.LogType)
+	if err != nil {
+		flag.Usage()
+		panic(fmt.Sprintf("Error parsing log type: %v", err))
+	}
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, logFormat))
+	glogger.Verbosity(log.Lvl(config.LogLevel))
+	log.Root().SetHandler(glogger)
+
+	vcsRevision, vcsTime := confighelpers.GetVersion()
+	log.Info("Running privacy gateway", "revision", vcsRevision, "vcs.time", vcsTime)
+
+	// filtering is the whole point of the gateway
+	config.Privacy.Enable = true
+
+	var db ethdb.Database
+	if config.Privacy.Database.Enable {
+		if config.Database == "" {
+			return errors.New("--database is required by --privacy.database.enable")
+		}
+		db, err = rawdb.NewLevelDBDatabase(config.Database, 0, 0, "", false)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+	}
+	var grants ethdb.KeyValueStore
+	if db != nil {
+		grants = rawdb.NewTable(db, arbnode.PrivacyPrefix)
+	}
+	var dataSigner signature.DataSignerFunc
+	if config.SigningKey != "" {
+		var privateKey *ecdsa.PrivateKey
+		if strings.HasPrefix(config.SigningKey, "0x") {
+			privateKey, err = crypto.HexToECDSA(config.SigningKey[2:])
+		} else {
+			privateKey, err = crypto.LoadECDSA(config.SigningKey)
+		}
+		if err != nil {
+			return err
+		}
+		dataSigner = signature.DataSignerFromPrivateKey(privateKey)
+	}
+	wrapper, err := privacy.NewWrapper(&config.Privacy, grants, dataSigner)
+	if err != nil {
+		return err
+	}
+	wrapper.Start(ctx)
+	defer wrapper.StopAndWait()
+
+	srv := rpc.NewServer()
+	err = node.RegisterApis([]rpc.API{{
 		Namespace: "privacy",
 		Version:   "1.0",
-		Service:   api,
+		Service:   privacy.NewPrivacyAPI(wrapper),
 		Public:    true,
-	}}
+	}}, []string{"privacy"}, srv)
+	if err != nil {
+		return err
+	}
+	defer srv.Stop()
 
-	srv := rpc.NewServer()
-	err := node.RegisterApis(rpcAPI, []string{"privacy"}, srv)
+	gateway, err := privacy.NewGateway(&config.Gateway, srv, func(h http.Handler) http.Handler {
+		return node.NewHTTPHandlerStack(h, config.HTTP.CORSDomain, config.HTTP.VHosts, nil)
+	})
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	gateway.Start(ctx)
+	defer gateway.StopAndWait()
+
+	endpoint := fmt.Sprintf("%v:%v", config.HTTP.Addr, config.HTTP.Port)
+	timeouts := rpc.HTTPTimeouts{
+		ReadTimeout:  config.HTTP.ServerTimeouts.ReadTimeout,
+		WriteTimeout: config.HTTP.ServerTimeouts.WriteTimeout,
+		IdleTimeout:  config.HTTP.ServerTimeouts.IdleTimeout,
 	}
-	handler := node.NewHTTPHandlerStack(srv, []string{}, []string{}, nil)
-	privacyHandler := handler
-	//
-	s, addr, err := node.StartHTTPEndpoint("127.0.0.1:8080", rpc.DefaultHTTPTimeouts, privacyHandler)
+	s, addr, err := node.StartHTTPEndpoint(endpoint, timeouts, LoggingMiddleware(gateway))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	extapiURL := fmt.Sprintf("http://%v/", addr)
-	fmt.Println("HTTP endpoint opened", "url", extapiURL)
+	log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%v/", addr))
 	defer func() {
-		s.Shutdown(context.Background())
+		_ = s.Shutdown(context.Background())
 	}()
 
-	abortChan := make(chan os.Signal, 1)
-	signal.Notify(abortChan, os.Interrupt)
+	if config.Metrics && config.MetricsServer.Addr != "" {
+		go metrics.CollectProcessMetrics(config.MetricsServer.UpdateInterval)
+
+		address := fmt.Sprintf("%v:%v", config.MetricsServer.Addr, config.MetricsServer.Port)
+		exp.Setup(address)
+	}
 
-	sig := <-abortChan
-	log.Println("Exiting...", "signal", sig)
+	sigint := make(chan os.Signal, 1)
+	signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
+	<-sigint
+	log.Info("shutting down because of sigint")
+	return nil
 }
 
-// LoggingMiddleware 是一个用于日志记录的 middleware
+// LoggingMiddleware logs the status and duration of every request
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		startTime := time.Now()
 
-		//lrw := &LoggingResponseWriter{
-		//	ResponseWriter: w,
-		//	status:         http.StatusOK, // default status
-		//}
-
-		lrw := &privacy.PrivacyResponseWriter{
+		lrw := &loggingResponseWriter{
 			ResponseWriter: w,
+			status:         http.StatusOK, // default status
 		}
 
 		next.ServeHTTP(lrw, r)
 
-		duration := time.Now().Sub(startTime)
-		log.Printf("%s %s %d %s", r.Method, r.RequestURI, lrw.Status, duration)
+		log.Debug("privacy gateway request", "method", r.Method, "uri", r.RequestURI, "status", lrw.status, "duration", time.Since(startTime))
 	})
 }
+
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (lrw *loggingResponseWriter) WriteHeader(status int) {
+	lrw.status = status
+	lrw.ResponseWriter.WriteHeader(status)
+}
+
+// Hijack lets websocket connections through
+func (lrw *loggingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := lrw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer can't be hijacked")
+	}
+	lrw.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
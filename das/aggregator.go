@@ -60,16 +60,33 @@ type Aggregator struct {
 	maxAllowedServiceStoreFailures int
 	keysetHash                     [32]byte
 	keysetBytes                    []byte
+	nextKeysetHash                 [32]byte // zero unless a backend is rotating its key
 	bpVerifier                     *contracts.BatchPosterVerifier
 }
 
 type ServiceDetails struct {
 	service     DataAvailabilityServiceWriter
 	pubKey      blsSignatures.PublicKey
+	nextPubKey  *blsSignatures.PublicKey
 	signersMask uint64
 	metricName  string
 }
 
+// SetNextPubKey sets the key the backend is rotating to. Until the rotation is complete the aggregator
+// accepts signatures with either key, certifying with whichever of the current keyset and the next keyset,
+// both of which must be valid on the SequencerInbox, has enough signers.
+func (s *ServiceDetails) SetNextPubKey(pubKey blsSignatures.PublicKey) {
+	s.nextPubKey = &pubKey
+}
+
+// nextKey is the key of the backend in the next keyset
+func (s *ServiceDetails) nextKey() blsSignatures.PublicKey {
+	if s.nextPubKey != nil {
+		return *s.nextPubKey
+	}
+	return s.pubKey
+}
+
 func (s *ServiceDetails) String() string {
 	return fmt.Sprintf("ServiceDetails{service: %v, signersMask %d}", s.service, s.signersMask)
 }
@@ -124,12 +141,16 @@ func NewAggregatorWithSeqInboxCaller(
 ) (*Aggregator, error) {
 	var aggSignersMask uint64
 	pubKeys := []blsSignatures.PublicKey{}
+	nextPubKeys := []blsSignatures.PublicKey{}
+	rotating := false
 	for _, d := range services {
 		if bits.OnesCount64(d.signersMask) != 1 {
 			return nil, fmt.Errorf("tried to configure backend DAS %v with invalid signersMask %X", d.service, d.signersMask)
 		}
 		aggSignersMask |= d.signersMask
 		pubKeys = append(pubKeys, d.pubKey)
+		nextPubKeys = append(nextPubKeys, d.nextKey())
+		rotating = rotating || d.nextPubKey != nil
 	}
 	if bits.OnesCount64(aggSignersMask) != len(services) {
 		return nil, errors.New("at least two signers share a mask")
@@ -147,9 +168,28 @@ func NewAggregatorWithSeqInboxCaller(
 	if err != nil {
 		return nil, err
 	}
+	var nextKeysetHash [32]byte
+	var nextKsBuf bytes.Buffer
+	if rotating {
+		nextKeyset := &arbstate.DataAvailabilityKeyset{
+			AssumedHonest: uint64(config.AggregatorConfig.AssumedHonest),
+			PubKeys:       nextPubKeys,
+		}
+		if err := nextKeyset.Serialize(&nextKsBuf); err != nil {
+			return nil, err
+		}
+		nextKeysetHash, err = nextKeyset.Hash()
+		if err != nil {
+			return nil, err
+		}
+	}
 	if config.AggregatorConfig.DumpKeyset {
 		fmt.Printf("Keyset: %s\n", hexutil.Encode(ksBuf.Bytes()))
 		fmt.Printf("KeysetHash: %s\n", hexutil.Encode(keysetHash[:]))
+		if rotating {
+			fmt.Printf("NextKeyset: %s\n", hexutil.Encode(nextKsBuf.Bytes()))
+			fmt.Printf("NextKeysetHash: %s\n", hexutil.Encode(nextKeysetHash[:]))
+		}
 		os.Exit(0)
 	}
 
@@ -166,6 +206,7 @@ func NewAggregatorWithSeqInboxCaller(
 		maxAllowedServiceStoreFailures: config.AggregatorConfig.AssumedHonest - 1,
 		keysetHash:                     keysetHash,
 		keysetBytes:                    ksBuf.Bytes(),
+		nextKeysetHash:                 nextKeysetHash,
 		bpVerifier:                     bpVerifier,
 	}, nil
 }
@@ -174,6 +215,10 @@ type storeResponse struct {
 	details ServiceDetails
 	sig     blsSignatures.Signature
 	err     error
+
+	// which of the backend's keys signed, during a rotation it may be either
+	current bool
+	next    bool
 }
 
 // Store calls Store on each backend DAS in parallel and collects responses.
@@ -210,9 +255,30 @@ func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64,
 		}
 	}
 
+	expected := arbstate.DataAvailabilityCertificate{
+		DataHash: dastree.Hash(message),
+		Timeout:  timeout,
+		Version:  1,
+	}
+	store := func(ctx context.Context, d ServiceDetails) (*arbstate.DataAvailabilityCertificate, error) {
+		return d.service.Store(ctx, message, timeout, sig)
+	}
+	return a.collect(ctx, &expected, a.requiredServicesForStore, a.maxAllowedServiceStoreFailures, store)
+}
+
+// collect stores to every backend in parallel, checks the certificates returned
+// against the expected one and aggregates the first required signatures
+func (a *Aggregator) collect(
+	ctx context.Context,
+	expected *arbstate.DataAvailabilityCertificate,
+	required int,
+	maxFailures int,
+	store func(ctx context.Context, d ServiceDetails) (*arbstate.DataAvailabilityCertificate, error),
+) (*arbstate.DataAvailabilityCertificate, error) {
 	responses := make(chan storeResponse, len(a.services))
 
-	expectedHash := dastree.Hash(message)
+	expectedHash := expected.DataHash
+	timeout := expected.Timeout
 	for _, d := range a.services {
 		go func(ctx context.Context, d ServiceDetails) {
 			storeCtx, cancel := context.WithTimeout(ctx, a.requestTimeout)
@@ -224,7 +290,7 @@ func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64,
 				metrics.GetOrRegisterCounter(metricBase+"/error/all/total", nil).Inc(1)
 			}
 
-			cert, err := d.service.Store(storeCtx, message, timeout, sig)
+			cert, err := store(storeCtx, d)
 			if err != nil {
 				incFailureMetric()
 				if errors.Is(err, context.DeadlineExceeded) {
@@ -232,23 +298,27 @@ func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64,
 				} else {
 					metrics.GetOrRegisterCounter(metricWithServiceName+"/error/client/total", nil).Inc(1)
 				}
-				responses <- storeResponse{d, nil, err}
+				responses <- storeResponse{details: d, err: err}
 				return
 			}
 
-			verified, err := blsSignatures.VerifySignature(
+			current, err := blsSignatures.VerifySignature(
 				cert.Sig, cert.SerializeSignableFields(), d.pubKey,
 			)
+			next := current && d.nextPubKey == nil
+			if err == nil && !current && d.nextPubKey != nil {
+				next, err = blsSignatures.VerifySignature(cert.Sig, cert.SerializeSignableFields(), *d.nextPubKey)
+			}
 			if err != nil {
 				incFailureMetric()
 				metrics.GetOrRegisterCounter(metricWithServiceName+"/error/bad_response/total", nil).Inc(1)
-				responses <- storeResponse{d, nil, err}
+				responses <- storeResponse{details: d, err: err}
 				return
 			}
-			if !verified {
+			if !current && !next {
 				incFailureMetric()
 				metrics.GetOrRegisterCounter(metricWithServiceName+"/error/bad_response/total", nil).Inc(1)
-				responses <- storeResponse{d, nil, errors.New("signature verification failed")}
+				responses <- storeResponse{details: d, err: errors.New("signature verification failed")}
 				return
 			}
 
@@ -257,19 +327,19 @@ func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64,
 			if cert.DataHash != expectedHash {
 				incFailureMetric()
 				metrics.GetOrRegisterCounter(metricWithServiceName+"/error/bad_response/total", nil).Inc(1)
-				responses <- storeResponse{d, nil, errors.New("hash verification failed")}
+				responses <- storeResponse{details: d, err: errors.New("hash verification failed")}
 				return
 			}
 			if cert.Timeout != timeout {
 				incFailureMetric()
 				metrics.GetOrRegisterCounter(metricWithServiceName+"/error/bad_response/total", nil).Inc(1)
-				responses <- storeResponse{d, nil, fmt.Errorf("timeout was %d, expected %d", cert.Timeout, timeout)}
+				responses <- storeResponse{details: d, err: fmt.Errorf("timeout was %d, expected %d", cert.Timeout, timeout)}
 				return
 			}
 
 			metrics.GetOrRegisterCounter(metricWithServiceName+"/success/total", nil).Inc(1)
 			metrics.GetOrRegisterCounter(metricBase+"/success/all/total", nil).Inc(1)
-			responses <- storeResponse{d, cert.Sig, nil}
+			responses <- storeResponse{details: d, sig: cert.Sig, current: current, next: next}
 		}(ctx, d)
 	}
 
@@ -279,32 +349,66 @@ func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64,
 		pubKeys        []blsSignatures.PublicKey
 		sigs           []blsSignatures.Signature
 		aggSignersMask uint64
+		keysetHash     [32]byte
 		err            error
 	}
 
+	// signers with the keys of one keyset, during a key rotation there are two
+	type signers struct {
+		certDetails
+		count int
+	}
+	add := func(s *signers, pubKey blsSignatures.PublicKey, r storeResponse) {
+		s.pubKeys = append(s.pubKeys, pubKey)
+		s.sigs = append(s.sigs, r.sig)
+		s.aggSignersMask |= r.details.signersMask
+		s.count++
+	}
+	// the collector keeps appending after returning, so it returns copies
+	snapshot := func(s *signers) certDetails {
+		cd := certDetails{aggSignersMask: s.aggSignersMask, keysetHash: s.keysetHash}
+		cd.pubKeys = append(cd.pubKeys, s.pubKeys...)
+		cd.sigs = append(cd.sigs, s.sigs...)
+		return cd
+	}
+
 	// Collect responses from backends.
-	certDetailsChan := make(chan certDetails)
+	failed := func() certDetails {
+		return certDetails{err: fmt.Errorf("aggregator failed to store message to at least %d out of %d DASes (assuming %d are honest). %w", required, len(a.services), a.config.AssumedHonest, BatchToDasFailed)}
+	}
+	rotating := a.nextKeysetHash != ([32]byte{})
+	// buffered so the collector never blocks once Store has given up on it
+	certDetailsChan := make(chan certDetails, 1)
 	go func() {
-		var pubKeys []blsSignatures.PublicKey
-		var sigs []blsSignatures.Signature
-		var aggSignersMask uint64
-		var storeFailures, successfullyStoredCount int
+		current := signers{certDetails: certDetails{keysetHash: a.keysetHash}}
+		next := signers{certDetails: certDetails{keysetHash: a.nextKeysetHash}}
+		var storeFailures int
 		var returned bool
 		for i := 0; i < len(a.services); i++ {
 
+			var r storeResponse
 			select {
 			case <-ctx.Done():
-				break
-			case r := <-responses:
-				if r.err != nil {
-					storeFailures++
-					log.Warn("das.Aggregator: Error from backend", "backend", r.details.service, "signerMask", r.details.signersMask, "err", r.err)
-				} else {
-					pubKeys = append(pubKeys, r.details.pubKey)
-					sigs = append(sigs, r.sig)
-					aggSignersMask |= r.details.signersMask
-
-					successfullyStoredCount++
+				if !returned {
+					certDetailsChan <- certDetails{err: ctx.Err()}
+				}
+				return
+			case r = <-responses:
+			}
+			switch {
+			case r.err != nil:
+				storeFailures++
+				log.Warn("das.Aggregator: Error from backend", "backend", r.details.service, "signerMask", r.details.signersMask, "err", r.err)
+			case !r.current && !r.next:
+				// a signature counting towards neither keyset can't help, so it's as good as a failure
+				storeFailures++
+				log.Warn("das.Aggregator: Backend signature fits neither keyset", "backend", r.details.service, "signerMask", r.details.signersMask)
+			default:
+				if r.current {
+					add(&current, r.details.pubKey, r)
+				}
+				if r.next {
+					add(&next, r.details.nextKey(), r)
 				}
 			}
 
@@ -313,25 +417,32 @@ func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64,
 			// running until all responses are received (or the context is canceled)
 			// in order to produce accurate logs/metrics.
 			if !returned {
-				if successfullyStoredCount >= a.requiredServicesForStore {
-					cd := certDetails{}
-					cd.pubKeys = append(cd.pubKeys, pubKeys...)
-					cd.sigs = append(cd.sigs, sigs...)
-					cd.aggSignersMask = aggSignersMask
-					certDetailsChan <- cd
+				// When rotating, prefer the next keyset, since its backends are the ones moving to it
+				if rotating && next.count >= required {
+					certDetailsChan <- snapshot(&next)
+					returned = true
+				} else if current.count >= required {
+					certDetailsChan <- snapshot(&current)
 					returned = true
-				} else if storeFailures > a.maxAllowedServiceStoreFailures {
-					cd := certDetails{}
-					cd.err = fmt.Errorf("aggregator failed to store message to at least %d out of %d DASes (assuming %d are honest). %w", a.requiredServicesForStore, len(a.services), a.config.AssumedHonest, BatchToDasFailed)
-					certDetailsChan <- cd
+				} else if storeFailures > maxFailures {
+					certDetailsChan <- failed()
 					returned = true
 				}
 			}
 
 		}
+		// the responses can be split between the keysets so that neither has enough signers
+		if !returned {
+			certDetailsChan <- failed()
+		}
 	}()
 
-	cd := <-certDetailsChan
+	var cd certDetails
+	select {
+	case cd = <-certDetailsChan:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 
 	if cd.err != nil {
 		return nil, cd.err
@@ -343,8 +454,8 @@ func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64,
 
 	aggCert.DataHash = expectedHash
 	aggCert.Timeout = timeout
-	aggCert.KeysetHash = a.keysetHash
-	aggCert.Version = 1
+	aggCert.KeysetHash = cd.keysetHash
+	aggCert.Version = expected.Version
 
 	verified, err := blsSignatures.VerifySignature(aggCert.Sig, aggCert.SerializeSignableFields(), aggPubKey)
 	if err != nil {
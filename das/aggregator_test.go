@@ -44,7 +44,7 @@ func TestDAS_BasicAggregationLocal(t *testing.T) {
 		das, err := NewSignAfterStoreDASWriter(ctx, config, storageServices[i])
 		Require(t, err)
 		signerMask := uint64(1 << i)
-		details, err := NewServiceDetails(das, *das.pubKey, signerMask, "service"+strconv.Itoa(i))
+		details, err := NewServiceDetails(das, das.PublicKey(), signerMask, "service"+strconv.Itoa(i))
 		Require(t, err)
 		backends = append(backends, *details)
 	}
@@ -197,7 +197,7 @@ func testConfigurableStorageFailures(t *testing.T, shouldFailAggregation bool) {
 		das, err := NewSignAfterStoreDASWriter(ctx, config, storageServices[i])
 		Require(t, err)
 		signerMask := uint64(1 << i)
-		details, err := NewServiceDetails(&WrapStore{t, injectedFailures, das}, *das.pubKey, signerMask, "service"+strconv.Itoa(i))
+		details, err := NewServiceDetails(&WrapStore{t, injectedFailures, das}, das.PublicKey(), signerMask, "service"+strconv.Itoa(i))
 		Require(t, err)
 		backends = append(backends, *details)
 	}
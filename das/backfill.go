@@ -0,0 +1,369 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/das/dastree"
+	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
+	"github.com/offchainlabs/nitro/util/pretty"
+)
+
+type BatchScannerConfig struct {
+	L1BlocksPerRead uint64 `koanf:"l1-blocks-per-read"`
+	Workers         int    `koanf:"workers"`
+}
+
+var DefaultBatchScannerConfig = BatchScannerConfig{
+	L1BlocksPerRead: 1000,
+	Workers:         4,
+}
+
+func BatchScannerConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Uint64(prefix+".l1-blocks-per-read", DefaultBatchScannerConfig.L1BlocksPerRead, "max l1 blocks to read the batch logs of per request")
+	f.Int(prefix+".workers", DefaultBatchScannerConfig.Workers, "number of l1 block ranges, and the batches in them, to process in parallel")
+}
+
+// DASBatch is a batch posted to the sequencer inbox with a DAS certificate
+type DASBatch struct {
+	SequenceNumber uint64
+	Cert           *arbstate.DataAvailabilityCertificate
+}
+
+// BatchScanner finds the DAS certificates of the batches delivered to the sequencer inbox over a range of L1 blocks
+type BatchScanner struct {
+	config        BatchScannerConfig
+	l1client      arbutil.L1Interface
+	inboxContract *bridgegen.SequencerInbox
+	inboxAddr     common.Address
+}
+
+func NewBatchScanner(config BatchScannerConfig, l1client arbutil.L1Interface, inboxAddr common.Address) (*BatchScanner, error) {
+	if config.L1BlocksPerRead == 0 || config.Workers < 1 {
+		return nil, errors.New("batch scanner needs at least one worker reading at least one block")
+	}
+	inboxContract, err := bridgegen.NewSequencerInbox(inboxAddr, l1client)
+	if err != nil {
+		return nil, err
+	}
+	return &BatchScanner{
+		config:        config,
+		l1client:      l1client,
+		inboxContract: inboxContract,
+		inboxAddr:     inboxAddr,
+	}, nil
+}
+
+// Scan calls process for every DAS batch delivered in the blocks from to to inclusive. Block ranges are
+// read by parallel workers, so process is called concurrently and in no particular order. Scan stops at
+// the first error, from L1 or from process.
+func (s *BatchScanner) Scan(ctx context.Context, from, to uint64, process func(context.Context, *DASBatch) error) error {
+	if from > to {
+		return fmt.Errorf("from block %d is after to block %d", from, to)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ranges := make(chan [2]uint64)
+	go func() {
+		defer close(ranges)
+		for low := from; low <= to; low += s.config.L1BlocksPerRead {
+			high := low + s.config.L1BlocksPerRead - 1
+			if high > to || high < low {
+				high = to
+			}
+			select {
+			case ranges <- [2]uint64{low, high}:
+			case <-ctx.Done():
+				return
+			}
+			if high == to {
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	for i := 0; i < s.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range ranges {
+				if err := s.scanRange(ctx, r[0], r[1], process); err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("blocks %d to %d: %w", r[0], r[1], err)
+						cancel()
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+func (s *BatchScanner) scanRange(ctx context.Context, low, high uint64, process func(context.Context, *DASBatch) error) error {
+	log.Debug("scanning for DAS batches", "from", low, "to", high)
+	query := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(low),
+		ToBlock:   new(big.Int).SetUint64(high),
+		Addresses: []common.Address{s.inboxAddr},
+		Topics:    [][]common.Hash{{BatchDeliveredID}},
+	}
+	logs, err := s.l1client.FilterLogs(ctx, query)
+	if err != nil {
+		return err
+	}
+	for _, deliveredLog := range logs {
+		deliveredEvent, err := s.inboxContract.ParseSequencerBatchDelivered(deliveredLog)
+		if err != nil {
+			return err
+		}
+		data, err := FindDASDataFromLog(ctx, s.inboxContract, deliveredEvent, s.inboxAddr, s.l1client, deliveredLog)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			continue
+		}
+		cert, err := arbstate.DeserializeDASCertFrom(bytes.NewReader(data))
+		if err != nil {
+			log.Warn("couldn't deserialize DAS certificate", "batch", deliveredEvent.BatchSequenceNumber, "txhash", deliveredLog.TxHash, "err", err)
+			continue
+		}
+		batch := &DASBatch{
+			SequenceNumber: deliveredEvent.BatchSequenceNumber.Uint64(),
+			Cert:           cert,
+		}
+		if err := process(ctx, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackfillReport counts what a backfill found and did
+type BackfillReport struct {
+	mutex      sync.Mutex
+	Batches    int
+	Expired    int
+	Present    int
+	Backfilled int
+	Corrupt    int
+	Bytes      uint64
+	Failed     []common.Hash
+}
+
+// Backfiller copies the data and keysets of DAS batches missing from a storage service from a source,
+// usually the REST mirrors, checking every preimage against its hash before writing it
+type Backfiller struct {
+	source arbstate.DataAvailabilityReader
+	syncTo StorageService
+}
+
+func NewBackfiller(source arbstate.DataAvailabilityReader, syncTo StorageService) *Backfiller {
+	return &Backfiller{
+		source: source,
+		syncTo: syncTo,
+	}
+}
+
+func (b *Backfiller) Backfill(ctx context.Context, scanner *BatchScanner, from, to uint64) (*BackfillReport, error) {
+	report := &BackfillReport{}
+	err := scanner.Scan(ctx, from, to, func(ctx context.Context, batch *DASBatch) error {
+		return b.backfillBatch(ctx, batch, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, b.syncTo.Sync(ctx)
+}
+
+// backfillBatch stores the preimages of the batch the storage service is missing until the certificate expires.
+// Preimages that can't be fetched are reported, only failing to write them is an error.
+func (b *Backfiller) backfillBatch(ctx context.Context, batch *DASBatch, report *BackfillReport) error {
+	cert := batch.Cert
+	report.mutex.Lock()
+	report.Batches++
+	report.mutex.Unlock()
+	if cert.Timeout < uint64(time.Now().Unix()) {
+		report.mutex.Lock()
+		report.Expired++
+		report.mutex.Unlock()
+		return nil
+	}
+
+	var missing []common.Hash
+	for _, hash := range []common.Hash{cert.KeysetHash, cert.DataHash} {
+		existing, err := b.syncTo.GetByHash(ctx, hash)
+		if err == nil && dastree.ValidHash(hash, existing) {
+			report.mutex.Lock()
+			report.Present++
+			report.mutex.Unlock()
+			continue
+		}
+		if err == nil {
+			log.Warn("storing corrupt DAS preimage again", "hash", hash, "batch", batch.SequenceNumber)
+			report.mutex.Lock()
+			report.Corrupt++
+			report.mutex.Unlock()
+		} else if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		missing = append(missing, hash)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	preimages, err := b.fetch(ctx, missing)
+	if err != nil {
+		return err
+	}
+	for _, hash := range missing {
+		preimage, ok := preimages[hash]
+		if !ok {
+			log.Warn("couldn't backfill DAS preimage", "hash", hash, "batch", batch.SequenceNumber)
+			report.mutex.Lock()
+			report.Failed = append(report.Failed, hash)
+			report.mutex.Unlock()
+			continue
+		}
+		if err := b.syncTo.Put(ctx, preimage, cert.Timeout); err != nil {
+			return err
+		}
+		log.Debug("backfilled DAS preimage", "hash", pretty.PrettyHash(hash), "batch", batch.SequenceNumber, "size", len(preimage))
+		report.mutex.Lock()
+		report.Backfilled++
+		report.Bytes += uint64(len(preimage))
+		report.mutex.Unlock()
+	}
+	return nil
+}
+
+// fetch gets the valid preimages of the hashes from the source, in one request if the source allows it.
+// Preimages the source doesn't have are missing from the result, only the context ending is an error.
+func (b *Backfiller) fetch(ctx context.Context, hashes []common.Hash) (map[common.Hash][]byte, error) {
+	var found map[common.Hash][]byte
+	if multi, ok := b.source.(multiHashReader); ok {
+		var err error
+		found, err = multi.GetByHashes(ctx, hashes)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Warn("couldn't fetch DAS preimages", "count", len(hashes), "err", err)
+		}
+	} else {
+		found = make(map[common.Hash][]byte, len(hashes))
+		for _, hash := range hashes {
+			data, err := b.source.GetByHash(ctx, hash)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				log.Warn("couldn't fetch DAS preimage", "hash", hash, "err", err)
+				continue
+			}
+			found[hash] = data
+		}
+	}
+	valid := make(map[common.Hash][]byte, len(found))
+	for hash, data := range found {
+		if dastree.ValidHash(hash, data) {
+			valid[hash] = data
+		} else {
+			log.Warn("DAS source returned a corrupt preimage", "hash", hash)
+		}
+	}
+	return valid, nil
+}
+
+// VerifyReport lists the preimages of unexpired DAS batches a store is missing or has corrupted
+type VerifyReport struct {
+	mutex   sync.Mutex
+	Batches int
+	Expired int
+	Valid   int
+	Missing []common.Hash
+	Corrupt []common.Hash
+}
+
+// VerifyStore audits that the store has valid preimages for the keyset and data of every unexpired DAS batch
+// in the blocks.
+func VerifyStore(ctx context.Context, scanner *BatchScanner, store arbstate.DataAvailabilityReader, from, to uint64) (*VerifyReport, error) {
+	report := &VerifyReport{}
+	err := scanner.Scan(ctx, from, to, func(ctx context.Context, batch *DASBatch) error {
+		return verifyBatch(ctx, store, batch, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func verifyBatch(ctx context.Context, store arbstate.DataAvailabilityReader, batch *DASBatch, report *VerifyReport) error {
+	cert := batch.Cert
+	report.mutex.Lock()
+	report.Batches++
+	report.mutex.Unlock()
+	if cert.Timeout < uint64(time.Now().Unix()) {
+		report.mutex.Lock()
+		report.Expired++
+		report.mutex.Unlock()
+		return nil
+	}
+
+	// check tells whether the store has the preimage of the hash, and whether it is valid
+	check := func(hash common.Hash) (bool, bool, error) {
+		data, err := store.GetByHash(ctx, hash)
+		if errors.Is(err, ErrNotFound) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		return true, dastree.ValidHash(hash, data), nil
+	}
+
+	for _, hash := range []common.Hash{cert.KeysetHash, cert.DataHash} {
+		found, valid, err := check(hash)
+		if err != nil {
+			return err
+		}
+		report.mutex.Lock()
+		switch {
+		case !found:
+			log.Warn("missing DAS preimage", "hash", hash, "batch", batch.SequenceNumber)
+			report.Missing = append(report.Missing, hash)
+		case !valid:
+			log.Warn("corrupt DAS preimage", "hash", hash, "batch", batch.SequenceNumber)
+			report.Corrupt = append(report.Corrupt, hash)
+		default:
+			report.Valid++
+		}
+		report.mutex.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/das/dastree"
+)
+
+// corruptingStorageService returns garbage for one hash
+type corruptingStorageService struct {
+	StorageService
+	corrupt common.Hash
+}
+
+func (s *corruptingStorageService) GetByHash(ctx context.Context, hash common.Hash) ([]byte, error) {
+	if hash == s.corrupt {
+		return []byte("garbage"), nil
+	}
+	return s.StorageService.GetByHash(ctx, hash)
+}
+
+func TestBackfillAndVerify(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keyset := []byte("keyset")
+	data := []byte("batch data")
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	batch := &DASBatch{
+		SequenceNumber: 1,
+		Cert: &arbstate.DataAvailabilityCertificate{
+			KeysetHash: dastree.Hash(keyset),
+			DataHash:   dastree.Hash(data),
+			Timeout:    timeout,
+			Version:    1,
+		},
+	}
+	expired := &DASBatch{
+		SequenceNumber: 0,
+		Cert: &arbstate.DataAvailabilityCertificate{
+			DataHash: dastree.Hash([]byte("old data")),
+			Timeout:  uint64(time.Now().Add(-time.Hour).Unix()),
+			Version:  1,
+		},
+	}
+
+	mirror := NewMemoryBackedStorageService(ctx)
+	Require(t, mirror.Put(ctx, data, timeout))
+	store := NewMemoryBackedStorageService(ctx)
+
+	verifyReport := &VerifyReport{}
+	Require(t, verifyBatch(ctx, store, batch, verifyReport))
+	Require(t, verifyBatch(ctx, store, expired, verifyReport))
+	if verifyReport.Batches != 2 || verifyReport.Expired != 1 || len(verifyReport.Missing) != 2 {
+		Fail(t, "empty store isn't missing the batch", verifyReport)
+	}
+
+	// the keyset isn't on the mirror
+	backfiller := NewBackfiller(mirror, store)
+	backfillReport := &BackfillReport{}
+	Require(t, backfiller.backfillBatch(ctx, batch, backfillReport))
+	if backfillReport.Backfilled != 1 || len(backfillReport.Failed) != 1 || backfillReport.Failed[0] != batch.Cert.KeysetHash {
+		Fail(t, "unexpected backfill", backfillReport)
+	}
+	stored, err := store.GetByHash(ctx, batch.Cert.DataHash)
+	Require(t, err)
+	if !bytes.Equal(stored, data) {
+		Fail(t, "backfilled data is wrong")
+	}
+
+	Require(t, mirror.Put(ctx, keyset, timeout))
+	backfillReport = &BackfillReport{}
+	Require(t, backfiller.backfillBatch(ctx, batch, backfillReport))
+	if backfillReport.Backfilled != 1 || backfillReport.Present != 1 || len(backfillReport.Failed) != 0 {
+		Fail(t, "unexpected second backfill", backfillReport)
+	}
+
+	verifyReport = &VerifyReport{}
+	Require(t, verifyBatch(ctx, store, batch, verifyReport))
+	if verifyReport.Valid != 2 || len(verifyReport.Missing) != 0 || len(verifyReport.Corrupt) != 0 {
+		Fail(t, "backfilled store doesn't verify", verifyReport)
+	}
+
+	// REST mirrors serve both preimages in one request
+	server, port, err := NewRestfulDasServerOnRandomPort(LocalServerAddressForTest, mirror)
+	Require(t, err)
+	defer func() { Require(t, server.Shutdown()) }()
+	fromRest := NewMemoryBackedStorageService(ctx)
+	backfillReport = &BackfillReport{}
+	Require(t, NewBackfiller(NewRestfulDasClient("http", LocalServerAddressForTest, port), fromRest).backfillBatch(ctx, batch, backfillReport))
+	if backfillReport.Backfilled != 2 || len(backfillReport.Failed) != 0 {
+		Fail(t, "unexpected backfill from a REST mirror", backfillReport)
+	}
+
+	// a mirror serving corrupt data is never copied from
+	corruptMirror := &corruptingStorageService{NewMemoryBackedStorageService(ctx), batch.Cert.DataHash}
+	fresh := NewMemoryBackedStorageService(ctx)
+	backfillReport = &BackfillReport{}
+	Require(t, NewBackfiller(corruptMirror, fresh).backfillBatch(ctx, batch, backfillReport))
+	if _, err := fresh.GetByHash(ctx, batch.Cert.DataHash); err == nil {
+		Fail(t, "corrupt data was backfilled")
+	}
+
+	verifyReport = &VerifyReport{}
+	Require(t, verifyBatch(ctx, &corruptingStorageService{store, batch.Cert.DataHash}, batch, verifyReport))
+	if len(verifyReport.Corrupt) != 1 || verifyReport.Corrupt[0] != batch.Cert.DataHash {
+		Fail(t, "corrupt data isn't reported", verifyReport)
+	}
+}
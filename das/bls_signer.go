@@ -0,0 +1,234 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/offchainlabs/nitro/blsSignatures"
+)
+
+// BLSSigner signs the fields of DAS certificates. Keys may be rotated while running, so Sign
+// returns the public key it signed with.
+type BLSSigner interface {
+	Sign(ctx context.Context, message []byte) (blsSignatures.Signature, blsSignatures.PublicKey, error)
+	PublicKey(ctx context.Context) (blsSignatures.PublicKey, error)
+}
+
+// localBLSSigner signs with a key held in memory
+type localBLSSigner struct {
+	privKey blsSignatures.PrivateKey
+	pubKey  blsSignatures.PublicKey
+}
+
+func NewLocalBLSSigner(privKey blsSignatures.PrivateKey) (BLSSigner, error) {
+	pubKey, err := blsSignatures.PublicKeyFromPrivateKey(privKey)
+	if err != nil {
+		return nil, err
+	}
+	return &localBLSSigner{privKey, pubKey}, nil
+}
+
+func (s *localBLSSigner) Sign(_ context.Context, message []byte) (blsSignatures.Signature, blsSignatures.PublicKey, error) {
+	sig, err := blsSignatures.SignMessage(s.privKey, message)
+	return sig, s.pubKey, err
+}
+
+func (s *localBLSSigner) PublicKey(_ context.Context) (blsSignatures.PublicKey, error) {
+	return s.pubKey, nil
+}
+
+// fileBLSSigner signs with the private key in a key directory, reloading it whenever the file changes
+// so that keys can be rotated without a restart. A key file that can't be read keeps the previous key.
+type fileBLSSigner struct {
+	path string
+
+	mutex   sync.Mutex
+	modTime time.Time
+	signer  BLSSigner
+}
+
+func NewFileBLSSigner(keyDir string) (BLSSigner, error) {
+	s := &fileBLSSigner{path: keyDir + "/" + DefaultPrivKeyFilename}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileBLSSigner) reload() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(s.modTime) && s.signer != nil {
+		return nil
+	}
+	privKey, err := ReadPrivKeyFromFile(s.path)
+	if err != nil {
+		return err
+	}
+	signer, err := NewLocalBLSSigner(privKey)
+	if err != nil {
+		return err
+	}
+	if s.signer != nil {
+		log.Info("reloaded rotated BLS key", "path", s.path)
+	}
+	s.modTime = info.ModTime()
+	s.signer = signer
+	return nil
+}
+
+func (s *fileBLSSigner) current() BLSSigner {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.reload(); err != nil {
+		log.Warn("couldn't reload BLS key, signing with the previous one", "path", s.path, "err", err)
+	}
+	return s.signer
+}
+
+func (s *fileBLSSigner) Sign(ctx context.Context, message []byte) (blsSignatures.Signature, blsSignatures.PublicKey, error) {
+	return s.current().Sign(ctx, message)
+}
+
+func (s *fileBLSSigner) PublicKey(ctx context.Context) (blsSignatures.PublicKey, error) {
+	return s.current().PublicKey(ctx)
+}
+
+// BLSSignResult is the response of a remote signer
+type BLSSignResult struct {
+	Signature hexutil.Bytes `json:"signature"`
+	PublicKey hexutil.Bytes `json:"publicKey"`
+}
+
+// RemoteBLSSigner asks a signer process to sign over JSON-RPC, so that the key is never held by the DAS.
+// The URL may be http(s), authenticated with the jwt secret of the signer, or the path of a Unix socket.
+type RemoteBLSSigner struct {
+	url    string
+	client *rpc.Client
+}
+
+func NewRemoteBLSSigner(ctx context.Context, url string, jwtSecret []byte) (*RemoteBLSSigner, error) {
+	var client *rpc.Client
+	var err error
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		if len(jwtSecret) == 0 {
+			return nil, errors.New("a jwt secret is required to reach a remote signer over http")
+		}
+		client, err = rpc.DialHTTPWithClient(url, &http.Client{Transport: &jwtTransport{jwtSecret, http.DefaultTransport}})
+	} else {
+		client, err = rpc.DialContext(ctx, url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &RemoteBLSSigner{url, client}, nil
+}
+
+// jwtTransport authenticates each request with a fresh token, as servers only accept recently issued ones
+type jwtTransport struct {
+	secret []byte
+	next   http.RoundTripper
+}
+
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iat": time.Now().Unix()}).SignedString(t.secret)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.next.RoundTrip(req)
+}
+
+func (s *RemoteBLSSigner) Sign(ctx context.Context, message []byte) (blsSignatures.Signature, blsSignatures.PublicKey, error) {
+	var result BLSSignResult
+	if err := s.client.CallContext(ctx, &result, "blssigner_sign", hexutil.Bytes(message)); err != nil {
+		return nil, blsSignatures.PublicKey{}, err
+	}
+	sig, err := blsSignatures.SignatureFromBytes(result.Signature)
+	if err != nil {
+		return nil, blsSignatures.PublicKey{}, err
+	}
+	pubKey, err := blsSignatures.PublicKeyFromBytes(result.PublicKey, false)
+	if err != nil {
+		return nil, blsSignatures.PublicKey{}, err
+	}
+	// a broken signer shouldn't get as far as having data stored under its certificate
+	verified, err := blsSignatures.VerifySignature(sig, message, pubKey)
+	if err != nil {
+		return nil, blsSignatures.PublicKey{}, err
+	}
+	if !verified {
+		return nil, blsSignatures.PublicKey{}, fmt.Errorf("remote signer %v returned an invalid signature", s.url)
+	}
+	return sig, pubKey, nil
+}
+
+func (s *RemoteBLSSigner) PublicKey(ctx context.Context) (blsSignatures.PublicKey, error) {
+	var result hexutil.Bytes
+	if err := s.client.CallContext(ctx, &result, "blssigner_publicKey"); err != nil {
+		return blsSignatures.PublicKey{}, err
+	}
+	return blsSignatures.PublicKeyFromBytes(result, false)
+}
+
+func (s *RemoteBLSSigner) Close() {
+	s.client.Close()
+}
+
+// BLSSignerAPI serves a BLSSigner over JSON-RPC in the blssigner namespace.
+// It only signs messages shaped like the signable fields of a DAS certificate.
+type BLSSignerAPI struct {
+	signer BLSSigner
+}
+
+func NewBLSSignerAPI(signer BLSSigner) *BLSSignerAPI {
+	return &BLSSignerAPI{signer}
+}
+
+func (a *BLSSignerAPI) Sign(ctx context.Context, message hexutil.Bytes) (*BLSSignResult, error) {
+	if err := checkSignableFields(message); err != nil {
+		return nil, err
+	}
+	sig, pubKey, err := a.signer.Sign(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	return &BLSSignResult{
+		Signature: blsSignatures.SignatureToBytes(sig),
+		PublicKey: blsSignatures.PublicKeyToBytes(pubKey),
+	}, nil
+}
+
+func (a *BLSSignerAPI) PublicKey(ctx context.Context) (hexutil.Bytes, error) {
+	pubKey, err := a.signer.PublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return blsSignatures.PublicKeyToBytes(pubKey), nil
+}
+
+// checkSignableFields accepts the data hash, timeout, and version of a version 1 certificate
+func checkSignableFields(message []byte) error {
+	const v1Len = 32 + 8 + 1
+	if len(message) != v1Len || message[v1Len-1] != 1 {
+		return errors.New("message isn't the signable fields of a DAS certificate")
+	}
+	return nil
+}
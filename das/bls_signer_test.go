@@ -0,0 +1,290 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/blsSignatures"
+)
+
+// rotateKey replaces the key in the directory, with a later modification time however quickly it's called
+func rotateKey(t *testing.T, keyDir string, generation int) blsSignatures.PublicKey {
+	pubKey, _, err := GenerateAndStoreKeys(keyDir)
+	Require(t, err)
+	modTime := time.Now().Add(time.Duration(generation) * time.Minute)
+	Require(t, os.Chtimes(filepath.Join(keyDir, DefaultPrivKeyFilename), modTime, modTime))
+	return *pubKey
+}
+
+func singleKeysetHash(t *testing.T, pubKey blsSignatures.PublicKey) [32]byte {
+	keyset := &arbstate.DataAvailabilityKeyset{AssumedHonest: 1, PubKeys: []blsSignatures.PublicKey{pubKey}}
+	hash, err := keyset.Hash()
+	Require(t, err)
+	return hash
+}
+
+func TestDAS_RemoteSignerRotation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keyDir := t.TempDir()
+	oldKey := rotateKey(t, keyDir, 0)
+	fileSigner, err := NewFileBLSSigner(keyDir)
+	Require(t, err)
+
+	rpcServer := rpc.NewServer()
+	Require(t, rpcServer.RegisterName("blssigner", NewBLSSignerAPI(fileSigner)))
+	defer rpcServer.Stop()
+	socket := filepath.Join(t.TempDir(), "blssigner.ipc")
+	listener, err := net.Listen("unix", socket)
+	Require(t, err)
+	go func() { _ = rpcServer.ServeListener(listener) }()
+
+	remote, err := NewRemoteBLSSigner(ctx, socket, nil)
+	Require(t, err)
+	defer remote.Close()
+
+	// the signer only signs certificates
+	var result BLSSignResult
+	err = remote.client.CallContext(ctx, &result, "blssigner_sign", []byte("anything at all"))
+	if err == nil {
+		Fail(t, "signer signed an arbitrary message")
+	}
+
+	das, err := NewSignAfterStoreDASWriterWithSigner(ctx, remote, nil, NewMemoryBackedStorageService(ctx), "")
+	Require(t, err)
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	expectedKey := oldKey
+	for generation := 0; generation < 2; generation++ {
+		if generation == 1 {
+			expectedKey = rotateKey(t, keyDir, 1)
+		}
+		cert, err := das.Store(ctx, []byte("signed remotely "+strconv.Itoa(generation)), timeout, nil)
+		Require(t, err)
+		verified, err := blsSignatures.VerifySignature(cert.Sig, cert.SerializeSignableFields(), expectedKey)
+		Require(t, err)
+		if !verified {
+			Fail(t, "certificate isn't signed with the current key", generation)
+		}
+		if cert.KeysetHash != singleKeysetHash(t, expectedKey) {
+			Fail(t, "certificate isn't for the keyset of the current key", generation)
+		}
+		if !bytes.Equal(blsSignatures.PublicKeyToBytes(das.PublicKey()), blsSignatures.PublicKeyToBytes(expectedKey)) {
+			Fail(t, "writer doesn't know the current key", generation)
+		}
+	}
+}
+
+func TestDAS_RemoteSignerJWT(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, privKey, err := blsSignatures.GenerateKeys()
+	Require(t, err)
+	signer, err := NewLocalBLSSigner(privKey)
+	Require(t, err)
+	rpcServer := rpc.NewServer()
+	Require(t, rpcServer.RegisterName("blssigner", NewBLSSignerAPI(signer)))
+	defer rpcServer.Stop()
+	secret := crypto.Keccak256([]byte("signer secret"))
+	server := httptest.NewServer(node.NewHTTPHandlerStack(rpcServer, nil, []string{"*"}, secret))
+	defer server.Close()
+
+	if _, err := NewRemoteBLSSigner(ctx, server.URL, nil); err == nil {
+		Fail(t, "connected to an http signer without a jwt secret")
+	}
+	wrong, err := NewRemoteBLSSigner(ctx, server.URL, crypto.Keccak256([]byte("other secret")))
+	Require(t, err)
+	defer wrong.Close()
+	if _, err := wrong.PublicKey(ctx); err == nil {
+		Fail(t, "signer accepted the wrong jwt secret")
+	}
+
+	remote, err := NewRemoteBLSSigner(ctx, server.URL, secret)
+	Require(t, err)
+	defer remote.Close()
+	das, err := NewSignAfterStoreDASWriterWithSigner(ctx, remote, nil, NewMemoryBackedStorageService(ctx), "")
+	Require(t, err)
+	cert, err := das.Store(ctx, []byte("signed over http"), uint64(time.Now().Add(time.Hour).Unix()), nil)
+	Require(t, err)
+	pubKey, err := signer.PublicKey(ctx)
+	Require(t, err)
+	verified, err := blsSignatures.VerifySignature(cert.Sig, cert.SerializeSignableFields(), pubKey)
+	Require(t, err)
+	if !verified {
+		Fail(t, "certificate isn't signed by the remote signer")
+	}
+}
+
+// alternatingBLSSigner signs with each of its signers in turn, as if the key was rotated back and forth
+type alternatingBLSSigner struct {
+	mutex   sync.Mutex
+	next    int
+	signers []BLSSigner
+}
+
+func (s *alternatingBLSSigner) current() BLSSigner {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	signer := s.signers[s.next%len(s.signers)]
+	s.next++
+	return signer
+}
+
+func (s *alternatingBLSSigner) Sign(ctx context.Context, message []byte) (blsSignatures.Signature, blsSignatures.PublicKey, error) {
+	return s.current().Sign(ctx, message)
+}
+
+func (s *alternatingBLSSigner) PublicKey(ctx context.Context) (blsSignatures.PublicKey, error) {
+	return s.current().PublicKey(ctx)
+}
+
+func TestDAS_ConcurrentSignKeysetHash(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signer := &alternatingBLSSigner{}
+	keysets := make(map[[32]byte]blsSignatures.PublicKey)
+	for i := 0; i < 2; i++ {
+		pubKey, privKey, err := blsSignatures.GenerateKeys()
+		Require(t, err)
+		local, err := NewLocalBLSSigner(privKey)
+		Require(t, err)
+		signer.signers = append(signer.signers, local)
+		keysets[singleKeysetHash(t, pubKey)] = pubKey
+	}
+	das, err := NewSignAfterStoreDASWriterWithSigner(ctx, signer, nil, NewMemoryBackedStorageService(ctx), "")
+	Require(t, err)
+
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cert, err := das.Store(ctx, []byte("signed concurrently "+strconv.Itoa(i)), timeout, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			pubKey, ok := keysets[cert.KeysetHash]
+			if !ok {
+				t.Error("certificate is for an unknown keyset")
+				return
+			}
+			verified, err := blsSignatures.VerifySignature(cert.Sig, cert.SerializeSignableFields(), pubKey)
+			if err != nil || !verified {
+				t.Error("certificate isn't signed with the key of its keyset", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestDAS_AggregatorKeyRotation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	numBackendDAS := 3
+	keyDir := t.TempDir()
+	rotateKey(t, keyDir, 0)
+	var backends []ServiceDetails
+	var pubKeys []blsSignatures.PublicKey
+	for i := 0; i < numBackendDAS; i++ {
+		var signer BLSSigner
+		var err error
+		if i == 0 {
+			signer, err = NewFileBLSSigner(keyDir)
+		} else {
+			var privKey blsSignatures.PrivateKey
+			_, privKey, err = blsSignatures.GenerateKeys()
+			Require(t, err)
+			signer, err = NewLocalBLSSigner(privKey)
+		}
+		Require(t, err)
+		das, err := NewSignAfterStoreDASWriterWithSigner(ctx, signer, nil, NewMemoryBackedStorageService(ctx), "")
+		Require(t, err)
+		details, err := NewServiceDetails(das, das.PublicKey(), uint64(1<<i), "service"+strconv.Itoa(i))
+		Require(t, err)
+		backends = append(backends, *details)
+		pubKeys = append(pubKeys, das.PublicKey())
+	}
+
+	// the first backend is about to rotate to a key both keysets are registered for
+	nextKeyDir := t.TempDir()
+	nextKey := rotateKey(t, nextKeyDir, 1)
+	backends[0].SetNextPubKey(nextKey)
+	keyset := &arbstate.DataAvailabilityKeyset{AssumedHonest: 1, PubKeys: pubKeys}
+	nextKeyset := &arbstate.DataAvailabilityKeyset{AssumedHonest: 1, PubKeys: append([]blsSignatures.PublicKey{nextKey}, pubKeys[1:]...)}
+	keysetHash, err := keyset.Hash()
+	Require(t, err)
+	nextKeysetHash, err := nextKeyset.Hash()
+	Require(t, err)
+
+	aggregator, err := NewAggregator(ctx, DataAvailabilityConfig{AggregatorConfig: AggregatorConfig{AssumedHonest: 1}, L1NodeURL: "none", RequestTimeout: 5 * time.Second}, backends)
+	Require(t, err)
+
+	check := func(message string, expectedKeyset *arbstate.DataAvailabilityKeyset, expectedHash [32]byte) {
+		cert, err := aggregator.Store(ctx, []byte(message), 0, nil)
+		Require(t, err)
+		if cert.KeysetHash != expectedHash {
+			Fail(t, "certificate is for the wrong keyset", message)
+		}
+		Require(t, expectedKeyset.VerifySignature(cert.SignersMask, cert.SerializeSignableFields(), cert.Sig), message)
+	}
+	check("before the rotation", keyset, keysetHash)
+
+	// the new key is swapped in without restarting anything
+	Require(t, os.Rename(filepath.Join(nextKeyDir, DefaultPrivKeyFilename), filepath.Join(keyDir, DefaultPrivKeyFilename)))
+	check("after the rotation", nextKeyset, nextKeysetHash)
+}
+
+func TestDAS_AggregatorKeyRotationSplit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// both backends are rotating, but only the second has switched keys, so neither keyset has both signers
+	var backends []ServiceDetails
+	for i := 0; i < 2; i++ {
+		currentKey, currentPrivKey, err := blsSignatures.GenerateKeys()
+		Require(t, err)
+		nextKey, nextPrivKey, err := blsSignatures.GenerateKeys()
+		Require(t, err)
+		privKey := currentPrivKey
+		if i == 1 {
+			privKey = nextPrivKey
+		}
+		signer, err := NewLocalBLSSigner(privKey)
+		Require(t, err)
+		das, err := NewSignAfterStoreDASWriterWithSigner(ctx, signer, nil, NewMemoryBackedStorageService(ctx), "")
+		Require(t, err)
+		details, err := NewServiceDetails(das, currentKey, uint64(1<<i), "service"+strconv.Itoa(i))
+		Require(t, err)
+		details.SetNextPubKey(nextKey)
+		backends = append(backends, *details)
+	}
+
+	aggregator, err := NewAggregator(ctx, DataAvailabilityConfig{AggregatorConfig: AggregatorConfig{AssumedHonest: 1}, L1NodeURL: "none", RequestTimeout: 5 * time.Second}, backends)
+	Require(t, err)
+	_, err = aggregator.Store(ctx, []byte("split between keysets"), 0, nil)
+	if !errors.Is(err, BatchToDasFailed) {
+		Fail(t, "expected the store to fail, got", err)
+	}
+}
@@ -66,6 +66,59 @@ func (c *ChainFetchReader) GetByHash(ctx context.Context, hash common.Hash) ([]b
 	log.Trace("das.ChainFetchReader.GetByHash", "hash", pretty.PrettyHash(hash))
 	return chainFetchGetByHash(ctx, c.DataAvailabilityReader, &c.keysetCache, c.seqInboxCaller, c.seqInboxFilterer, hash)
 }
+
+// GetByHashes fetches the preimages of many hashes, in as few requests to the inner DAS as it allows, and
+// the keysets it doesn't have from the L1 chain. Preimages that can't be found are missing from the result.
+func (c *ChainFetchReader) GetByHashes(ctx context.Context, hashes []common.Hash) (map[common.Hash][]byte, error) {
+	log.Trace("das.ChainFetchReader.GetByHashes", "count", len(hashes))
+	results := make(map[common.Hash][]byte, len(hashes))
+	multi, ok := c.DataAvailabilityReader.(multiHashReader)
+	if !ok {
+		for _, hash := range hashes {
+			data, err := c.GetByHash(ctx, hash)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				continue
+			}
+			results[hash] = data
+		}
+		return results, nil
+	}
+
+	var uncached []common.Hash
+	for _, hash := range hashes {
+		if res, ok := c.keysetCache.get(hash); ok {
+			results[hash] = res
+		} else {
+			uncached = append(uncached, hash)
+		}
+	}
+	if len(uncached) == 0 {
+		return results, nil
+	}
+	innerRes, err := multi.GetByHashes(ctx, uncached)
+	if err != nil {
+		return nil, err
+	}
+	for _, hash := range uncached {
+		if data, ok := innerRes[hash]; ok && dastree.ValidHash(hash, data) {
+			results[hash] = data
+			continue
+		}
+		data, err := chainFetchKeyset(ctx, &c.keysetCache, c.seqInboxCaller, c.seqInboxFilterer, hash)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		results[hash] = data
+	}
+	return results, nil
+}
+
 func (c *ChainFetchReader) String() string {
 	return "ChainFetchReader"
 }
@@ -90,7 +143,17 @@ func chainFetchGetByHash(
 		return innerRes, nil
 	}
 
-	// try to fetch from the L1 chain
+	return chainFetchKeyset(ctx, cache, seqInboxCaller, seqInboxFilterer, hash)
+}
+
+// chainFetchKeyset fetches the keyset from the L1 chain, caching it
+func chainFetchKeyset(
+	ctx context.Context,
+	cache *syncedKeysetCache,
+	seqInboxCaller *bridgegen.SequencerInboxCaller,
+	seqInboxFilterer *bridgegen.SequencerInboxFilterer,
+	hash common.Hash,
+) ([]byte, error) {
 	blockNumBig, err := seqInboxCaller.GetKeysetCreationBlock(&bind.CallOpts{Context: ctx}, hash)
 	if err != nil {
 		return nil, err
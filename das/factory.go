@@ -45,7 +45,7 @@ func CreatePersistentStorageService(
 	}
 
 	if config.LocalFileStorageConfig.Enable {
-		s, err := NewLocalFileStorageService(config.LocalFileStorageConfig.DataDir)
+		s, err := NewLocalFileStorageService(ctx, config.LocalFileStorageConfig)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -266,7 +266,7 @@ func CreateDAComponentsForDaserver(
 	var daReader DataAvailabilityServiceReader = storageService
 	var daHealthChecker DataAvailabilityServiceHealthChecker = storageService
 
-	if config.KeyConfig.KeyDir != "" || config.KeyConfig.PrivKey != "" {
+	if config.KeyConfig.Enabled() {
 		var seqInboxCaller *bridgegen.SequencerInboxCaller
 		if seqInboxAddress != nil {
 			seqInbox, err := bridgegen.NewSequencerInbox(*seqInboxAddress, (*l1Reader).Client())
@@ -280,13 +280,14 @@ func CreateDAComponentsForDaserver(
 			seqInboxCaller = nil
 		}
 
-		privKey, err := config.KeyConfig.BLSPrivKey()
+		signer, err := config.KeyConfig.BLSSigner(ctx)
 		if err != nil {
 			return nil, nil, nil, nil, err
 		}
 
-		daWriter, err = NewSignAfterStoreDASWriterWithSeqInboxCaller(
-			privKey,
+		daWriter, err = NewSignAfterStoreDASWriterWithSigner(
+			ctx,
+			signer,
 			seqInboxCaller,
 			storageService,
 			config.ExtraSignatureCheckingPublicKey,
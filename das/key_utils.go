@@ -44,6 +44,11 @@ func DecodeBase64BLSPrivateKey(privKeyEncodedBytes []byte) (blsSignatures.Privat
 	return privKey, nil
 }
 
+// EncodeBLSPublicKey encodes the key the way it's given in backend configs and key files
+func EncodeBLSPublicKey(pubKey blsSignatures.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(blsSignatures.PublicKeyToBytes(pubKey))
+}
+
 const DefaultPubKeyFilename = "das_bls.pub"
 const DefaultPrivKeyFilename = "das_bls"
 
@@ -0,0 +1,101 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbstate"
+)
+
+// KeysetFromBackends builds the keyset an aggregator configured with the backends JSON would use,
+// or the keyset being rotated to if next is set. Each backend's signer mask must match its position,
+// since that's how certificates' signer masks index into the keyset.
+func KeysetFromBackends(backends string, assumedHonest int, next bool) (*arbstate.DataAvailabilityKeyset, error) {
+	var cs []BackendConfig
+	if err := json.Unmarshal([]byte(backends), &cs); err != nil {
+		return nil, err
+	}
+	if len(cs) == 0 {
+		return nil, errors.New("no backends")
+	}
+	if assumedHonest < 1 || assumedHonest > len(cs) {
+		return nil, fmt.Errorf("assumed honest %d must be between 1 and the %d backends", assumedHonest, len(cs))
+	}
+	keyset := &arbstate.DataAvailabilityKeyset{AssumedHonest: uint64(assumedHonest)}
+	for i, b := range cs {
+		if b.SignerMask != 1<<i {
+			return nil, fmt.Errorf("backend %d (%s) has signer mask %d, but its position needs %d", i, b.URL, b.SignerMask, uint64(1)<<i)
+		}
+		encoded := b.PubKeyBase64Encoded
+		if next && b.NextPubKeyBase64Encoded != "" {
+			encoded = b.NextPubKeyBase64Encoded
+		}
+		pubKey, err := DecodeBase64BLSPublicKey([]byte(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("backend %d (%s): %w", i, b.URL, err)
+		}
+		keyset.PubKeys = append(keyset.PubKeys, *pubKey)
+	}
+	return keyset, nil
+}
+
+// SerializeKeyset returns the keyset as SequencerInbox.setValidKeyset takes it, and the hash the inbox keys it by
+func SerializeKeyset(keyset *arbstate.DataAvailabilityKeyset) ([]byte, common.Hash, error) {
+	var buf bytes.Buffer
+	if err := keyset.Serialize(&buf); err != nil {
+		return nil, common.Hash{}, err
+	}
+	hash, err := keyset.Hash()
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	return buf.Bytes(), hash, nil
+}
+
+// KeysetChange is a committee member whose key differs between two keysets, an empty key means no member
+type KeysetChange struct {
+	Index int
+	From  string
+	To    string
+}
+
+// DiffKeysets lists the committee members whose keys differ, by position
+func DiffKeysets(from, to *arbstate.DataAvailabilityKeyset) []KeysetChange {
+	key := func(keyset *arbstate.DataAvailabilityKeyset, i int) string {
+		if i >= len(keyset.PubKeys) {
+			return ""
+		}
+		return EncodeBLSPublicKey(keyset.PubKeys[i])
+	}
+	var changes []KeysetChange
+	for i := 0; i < len(from.PubKeys) || i < len(to.PubKeys); i++ {
+		change := KeysetChange{Index: i, From: key(from, i), To: key(to, i)}
+		if change.From != change.To {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// VerifyCertificate checks offline that the certificate is for the keyset and signed by enough of it
+func VerifyCertificate(cert *arbstate.DataAvailabilityCertificate, keyset *arbstate.DataAvailabilityKeyset) error {
+	_, hash, err := SerializeKeyset(keyset)
+	if err != nil {
+		return err
+	}
+	if cert.KeysetHash != hash {
+		return fmt.Errorf("certificate is for keyset %v, not %v", common.Hash(cert.KeysetHash), hash)
+	}
+	if bits.Len64(cert.SignersMask) > len(keyset.PubKeys) {
+		return fmt.Errorf("signers mask %b has signers beyond the %d in the keyset", cert.SignersMask, len(keyset.PubKeys))
+	}
+	return keyset.VerifySignature(cert.SignersMask, cert.SerializeSignableFields(), cert.Sig)
+}
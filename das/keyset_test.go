@@ -0,0 +1,97 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/blsSignatures"
+)
+
+func TestDAS_KeysetTooling(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	numBackendDAS := 3
+	var backends []ServiceDetails
+	var backendConfigs []BackendConfig
+	for i := 0; i < numBackendDAS; i++ {
+		_, privKey, err := blsSignatures.GenerateKeys()
+		Require(t, err)
+		signer, err := NewLocalBLSSigner(privKey)
+		Require(t, err)
+		das, err := NewSignAfterStoreDASWriterWithSigner(ctx, signer, nil, NewMemoryBackedStorageService(ctx), "")
+		Require(t, err)
+		details, err := NewServiceDetails(das, das.PublicKey(), uint64(1<<i), "service"+strconv.Itoa(i))
+		Require(t, err)
+		backends = append(backends, *details)
+		backendConfigs = append(backendConfigs, BackendConfig{
+			URL:                 "service" + strconv.Itoa(i),
+			PubKeyBase64Encoded: EncodeBLSPublicKey(das.PublicKey()),
+			SignerMask:          uint64(1 << i),
+		})
+	}
+	nextKey, _, err := blsSignatures.GenerateKeys()
+	Require(t, err)
+	backendConfigs[1].NextPubKeyBase64Encoded = EncodeBLSPublicKey(nextKey)
+
+	backendsJSON, err := json.Marshal(backendConfigs)
+	Require(t, err)
+	keyset, err := KeysetFromBackends(string(backendsJSON), 2, false)
+	Require(t, err)
+	nextKeyset, err := KeysetFromBackends(string(backendsJSON), 2, true)
+	Require(t, err)
+
+	// the keyset built offline is the one the aggregator signs under
+	aggregator, err := NewAggregator(ctx, DataAvailabilityConfig{AggregatorConfig: AggregatorConfig{AssumedHonest: 2}, L1NodeURL: "none"}, backends)
+	Require(t, err)
+	keysetBytes, keysetHash, err := SerializeKeyset(keyset)
+	Require(t, err)
+	if keysetHash != aggregator.keysetHash {
+		Fail(t, "built keyset hash", keysetHash, "doesn't match the aggregator's", aggregator.keysetHash)
+	}
+	decoded, err := arbstate.DeserializeKeyset(bytes.NewReader(keysetBytes), false)
+	Require(t, err)
+	if _, decodedHash, err := SerializeKeyset(decoded); err != nil || decodedHash != keysetHash {
+		Fail(t, "keyset didn't round trip", err)
+	}
+
+	changes := DiffKeysets(keyset, nextKeyset)
+	if len(changes) != 1 || changes[0].Index != 1 || changes[0].To != backendConfigs[1].NextPubKeyBase64Encoded {
+		Fail(t, "unexpected keyset changes", changes)
+	}
+	if changes := DiffKeysets(keyset, keyset); len(changes) != 0 {
+		Fail(t, "keyset differs from itself", changes)
+	}
+
+	backendConfigs[2].SignerMask = 1
+	badJSON, err := json.Marshal(backendConfigs)
+	Require(t, err)
+	if _, err := KeysetFromBackends(string(badJSON), 2, false); err == nil {
+		Fail(t, "built a keyset with a signer mask that doesn't match its position")
+	}
+
+	cert, err := aggregator.Store(ctx, []byte("certified by the committee"), uint64(time.Now().Add(time.Hour).Unix()), nil)
+	Require(t, err)
+	Require(t, VerifyCertificate(cert, keyset))
+	if err := VerifyCertificate(cert, nextKeyset); err == nil {
+		Fail(t, "certificate verified against a keyset it isn't for")
+	}
+	tampered := *cert
+	tampered.DataHash[0] ^= 1
+	if err := VerifyCertificate(&tampered, keyset); err == nil {
+		Fail(t, "tampered certificate verified")
+	}
+	tampered = *cert
+	tampered.SignersMask |= 1 << numBackendDAS
+	if err := VerifyCertificate(&tampered, keyset); err == nil {
+		Fail(t, "certificate with signers outside the keyset verified")
+	}
+}
@@ -9,7 +9,12 @@ import (
 	"encoding/base32"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -17,109 +22,301 @@ import (
 	"github.com/offchainlabs/nitro/arbstate"
 	"github.com/offchainlabs/nitro/das/dastree"
 	"github.com/offchainlabs/nitro/util/pretty"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
 	flag "github.com/spf13/pflag"
 	"golang.org/x/sys/unix"
 )
 
 type LocalFileStorageConfig struct {
-	Enable                  bool   `koanf:"enable"`
-	DataDir                 string `koanf:"data-dir"`
-	SyncFromStorageServices bool   `koanf:"sync-from-storage-service"`
-	SyncToStorageServices   bool   `koanf:"sync-to-storage-service"`
+	Enable                  bool          `koanf:"enable"`
+	DataDir                 string        `koanf:"data-dir"`
+	DiscardAfterTimeout     bool          `koanf:"discard-after-timeout"`
+	PruneInterval           time.Duration `koanf:"prune-interval"`
+	SyncFromStorageServices bool          `koanf:"sync-from-storage-service"`
+	SyncToStorageServices   bool          `koanf:"sync-to-storage-service"`
 }
 
 var DefaultLocalFileStorageConfig = LocalFileStorageConfig{
-	DataDir: "",
+	DataDir:       "",
+	PruneInterval: time.Hour,
 }
 
 func LocalFileStorageConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultLocalFileStorageConfig.Enable, "enable storage/retrieval of sequencer batch data from a directory of files, one per batch")
 	f.String(prefix+".data-dir", DefaultLocalFileStorageConfig.DataDir, "local data directory")
+	f.Bool(prefix+".discard-after-timeout", DefaultLocalFileStorageConfig.DiscardAfterTimeout, "discard data after its expiry timeout")
+	f.Duration(prefix+".prune-interval", DefaultLocalFileStorageConfig.PruneInterval, "how often to remove data whose expiry timeout passed, if discard-after-timeout is set")
 	f.Bool(prefix+".sync-from-storage-service", DefaultLocalFileStorageConfig.SyncFromStorageServices, "enable local storage to be used as a source for regular sync storage")
 	f.Bool(prefix+".sync-to-storage-service", DefaultLocalFileStorageConfig.SyncToStorageServices, "enable local storage to be used as a sink for regular sync storage")
 }
 
+// The data of a hash lives in data/<first byte>/<second byte>/<hash>, so no directory grows too large.
+// When discarding after the timeout, the modification time of the data file is its expiry and an empty
+// file named after the hash in by-expiry/<bucket> indexes it by expiry for the pruner, the bucket being
+// the expiry rounded up to the hour. Storing the same data again with a later timeout moves its expiry out
+// and indexes it in the later bucket too, the pruner only removes data whose expiry passed.
+const (
+	localFileDataDir             = "data"
+	localFileExpiryDir           = "by-expiry"
+	localFileExpiryBucketSeconds = 3600
+)
+
 type LocalFileStorageService struct {
-	dataDir string
+	dataDir             string
+	discardAfterTimeout bool
+	pruneInterval       time.Duration
+
+	// orders writes against the pruner, so that data stored again is not removed under its new expiry
+	mutex      sync.Mutex
+	stopWaiter stopwaiter.StopWaiterSafe
 }
 
-func NewLocalFileStorageService(dataDir string) (StorageService, error) {
-	if unix.Access(dataDir, unix.W_OK|unix.R_OK) != nil {
-		return nil, fmt.Errorf("couldn't start LocalFileStorageService, directory '%s' must be readable and writeable", dataDir)
+func NewLocalFileStorageService(ctx context.Context, config LocalFileStorageConfig) (StorageService, error) {
+	if unix.Access(config.DataDir, unix.W_OK|unix.R_OK) != nil {
+		return nil, fmt.Errorf("couldn't start LocalFileStorageService, directory '%s' must be readable and writeable", config.DataDir)
+	}
+	s := &LocalFileStorageService{
+		dataDir:             config.DataDir,
+		discardAfterTimeout: config.DiscardAfterTimeout,
+		pruneInterval:       config.PruneInterval,
+	}
+	for _, dir := range []string{localFileDataDir, localFileExpiryDir} {
+		if err := os.MkdirAll(filepath.Join(s.dataDir, dir), 0o700); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.migrateFlatLayout(ctx); err != nil {
+		return nil, err
 	}
-	return &LocalFileStorageService{dataDir: dataDir}, nil
+	if err := s.stopWaiter.Start(ctx, s); err != nil {
+		return nil, err
+	}
+	if s.discardAfterTimeout && s.pruneInterval > 0 {
+		err := s.stopWaiter.CallIterativelySafe(func(ctx context.Context) time.Duration {
+			if err := s.prune(ctx, time.Now()); err != nil {
+				log.Error("das.LocalFileStorageService failed to prune expired data", "err", err)
+			}
+			return s.pruneInterval
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *LocalFileStorageService) dataPath(key common.Hash) string {
+	fileName := EncodeStorageServiceKey(key)
+	return filepath.Join(s.dataDir, localFileDataDir, fileName[:2], fileName[2:4], fileName)
+}
+
+func expiryBucket(timeout uint64) uint64 {
+	return (timeout + localFileExpiryBucketSeconds - 1) / localFileExpiryBucketSeconds * localFileExpiryBucketSeconds
+}
+
+func (s *LocalFileStorageService) expiryBucketDir(bucket uint64) string {
+	return filepath.Join(s.dataDir, localFileExpiryDir, strconv.FormatUint(bucket, 10))
 }
 
 func (s *LocalFileStorageService) GetByHash(ctx context.Context, key common.Hash) ([]byte, error) {
 	log.Trace("das.LocalFileStorageService.GetByHash", "key", pretty.PrettyHash(key), "this", s)
-	pathname := s.dataDir + "/" + EncodeStorageServiceKey(key)
-	data, err := os.ReadFile(pathname)
+	data, err := os.ReadFile(s.dataPath(key))
 	if err != nil {
-		// Just for backward compatability.
-		pathname = s.dataDir + "/" + base32.StdEncoding.EncodeToString(key.Bytes())
-		data, err = os.ReadFile(pathname)
-		if err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				return nil, ErrNotFound
-			}
-			return nil, err
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
 		}
-		return data, nil
+		return nil, err
 	}
 	return data, nil
 }
 
 func (s *LocalFileStorageService) Put(ctx context.Context, data []byte, timeout uint64) error {
 	logPut("das.LocalFileStorageService.Store", data, timeout, s)
-	fileName := EncodeStorageServiceKey(dastree.Hash(data))
-	finalPath := s.dataDir + "/" + fileName
+	return s.write(dastree.Hash(data), data, timeout, s.discardAfterTimeout, true)
+}
 
-	// Use a temp file and rename to achieve atomic writes.
-	f, err := os.CreateTemp(s.dataDir, fileName)
-	if err != nil {
+// putKeyValue overwrites the value of a key, the iterable storage updates some keys in place
+func (s *LocalFileStorageService) putKeyValue(ctx context.Context, key common.Hash, value []byte) error {
+	return s.write(key, value, 0, false, false)
+}
+
+// write stores the value under the key, indexing it by its expiry timeout if it is to be discarded.
+// Content addressed values are only written once, as the same key always holds the same value.
+func (s *LocalFileStorageService) write(key common.Hash, value []byte, timeout uint64, discard bool, contentAddressed bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	finalPath := s.dataPath(key)
+	info, err := os.Stat(finalPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
-	err = f.Chmod(0o600)
-	if err != nil {
+	if err == nil && contentAddressed {
+		// The data is already there, only its expiry may move out.
+		if !discard || !info.ModTime().Before(time.Unix(int64(timeout), 0)) {
+			return nil
+		}
+	} else {
+		dir := filepath.Dir(finalPath)
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+		// Use a temp file and rename to achieve atomic writes.
+		f, err := os.CreateTemp(dir, filepath.Base(finalPath))
+		if err != nil {
+			return err
+		}
+		err = f.Chmod(0o600)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(value)
+		if err != nil {
+			return err
+		}
+		err = f.Close()
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(f.Name(), finalPath); err != nil {
+			return err
+		}
+	}
+	if !discard {
+		return nil
+	}
+
+	expiry := time.Unix(int64(timeout), 0)
+	if err := os.Chtimes(finalPath, time.Now(), expiry); err != nil {
 		return err
 	}
-	_, err = f.Write(data)
-	if err != nil {
+	bucketDir := s.expiryBucketDir(expiryBucket(timeout))
+	if err := os.MkdirAll(bucketDir, 0o700); err != nil {
 		return err
 	}
-	err = f.Close()
+	return os.WriteFile(filepath.Join(bucketDir, EncodeStorageServiceKey(key)), nil, 0o600)
+}
+
+// prune removes the data whose expiry passed before now, along with the expiry buckets that ended
+func (s *LocalFileStorageService) prune(ctx context.Context, now time.Time) error {
+	buckets, err := os.ReadDir(filepath.Join(s.dataDir, localFileExpiryDir))
 	if err != nil {
 		return err
 	}
+	pruned := 0
+	for _, b := range buckets {
+		bucket, err := strconv.ParseUint(b.Name(), 10, 64)
+		if err != nil || !b.IsDir() || bucket > uint64(now.Unix()) {
+			continue
+		}
+		bucketDir := s.expiryBucketDir(bucket)
+		entries, err := os.ReadDir(bucketDir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			key, err := DecodeStorageServiceKey(e.Name())
+			if err != nil {
+				continue
+			}
+			removed, err := s.pruneEntry(key, filepath.Join(bucketDir, e.Name()), now)
+			if err != nil {
+				return err
+			}
+			if removed {
+				pruned++
+			}
+		}
+		if err := os.Remove(bucketDir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Warn("das.LocalFileStorageService couldn't remove expiry bucket", "dir", bucketDir, "err", err)
+		}
+	}
+	if pruned > 0 {
+		log.Info("das.LocalFileStorageService pruned expired data", "count", pruned)
+	}
+	return nil
+}
 
-	return os.Rename(f.Name(), finalPath)
+func (s *LocalFileStorageService) pruneEntry(key common.Hash, indexPath string, now time.Time) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
+	removed := false
+	dataPath := s.dataPath(key)
+	info, err := os.Stat(dataPath)
+	if err == nil && !info.ModTime().After(now) {
+		if err := os.Remove(dataPath); err != nil {
+			return false, err
+		}
+		removed = true
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	if err := os.Remove(indexPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	return removed, nil
 }
 
-func (s *LocalFileStorageService) putKeyValue(ctx context.Context, key common.Hash, value []byte) error {
-	fileName := EncodeStorageServiceKey(key)
-	finalPath := s.dataDir + "/" + fileName
-
-	// Use a temp file and rename to achieve atomic writes.
-	f, err := os.CreateTemp(s.dataDir, fileName)
-	if err != nil {
-		return err
+// flatLayoutKey returns the key of a file of the flat layout, where the data of each hash was stored
+// directly in the data directory under its hex encoding, or its base32 encoding before that
+func flatLayoutKey(fileName string) (common.Hash, bool) {
+	if len(fileName) == 2*common.HashLength {
+		key, err := DecodeStorageServiceKey(fileName)
+		return key, err == nil
 	}
-	err = f.Chmod(0o600)
-	if err != nil {
-		return err
+	key, err := base32.StdEncoding.DecodeString(fileName)
+	if err == nil && len(key) == common.HashLength {
+		return common.BytesToHash(key), true
 	}
-	_, err = f.Write(value)
+	return common.Hash{}, false
+}
+
+// migrateFlatLayout moves the data stored in the flat layout into the sharded one. Its expiry isn't known
+// so it is kept forever. Files that aren't named after a hash, such as other services sharing the
+// directory keep there, are left alone.
+func (s *LocalFileStorageService) migrateFlatLayout(ctx context.Context) error {
+	entries, err := os.ReadDir(s.dataDir)
 	if err != nil {
 		return err
 	}
-	err = f.Close()
-	if err != nil {
-		return err
+	migrated := 0
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		key, ok := flatLayoutKey(e.Name())
+		if !ok {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		oldPath := filepath.Join(s.dataDir, e.Name())
+		newPath := s.dataPath(key)
+		if err := os.MkdirAll(filepath.Dir(newPath), 0o700); err != nil {
+			return err
+		}
+		if _, err := os.Stat(newPath); err == nil {
+			err = os.Remove(oldPath)
+			if err != nil {
+				return err
+			}
+		} else if err := os.Rename(oldPath, newPath); err != nil {
+			return err
+		}
+		migrated++
+		if migrated%100000 == 0 {
+			log.Info("das.LocalFileStorageService migrating flat data directory", "migrated", migrated)
+		}
 	}
-
-	return os.Rename(f.Name(), finalPath)
-
+	if migrated > 0 {
+		log.Info("das.LocalFileStorageService migrated flat data directory", "dir", s.dataDir, "files", migrated)
+	}
+	return nil
 }
 
 func (s *LocalFileStorageService) Sync(ctx context.Context) error {
@@ -127,11 +324,15 @@ func (s *LocalFileStorageService) Sync(ctx context.Context) error {
 }
 
 func (s *LocalFileStorageService) Close(ctx context.Context) error {
-	return nil
+	return s.stopWaiter.StopAndWait()
 }
 
 func (s *LocalFileStorageService) ExpirationPolicy(ctx context.Context) (arbstate.ExpirationPolicy, error) {
-	return arbstate.KeepForever, nil
+	if s.discardAfterTimeout {
+		return arbstate.DiscardAfterDataTimeout, nil
+	} else {
+		return arbstate.KeepForever, nil
+	}
 }
 
 func (s *LocalFileStorageService) String() string {
@@ -153,3 +354,90 @@ func (s *LocalFileStorageService) HealthCheck(ctx context.Context) error {
 	}
 	return nil
 }
+
+// LocalFileUsage is the disk space used by the data of a LocalFileStorageService expiring in the same bucket
+type LocalFileUsage struct {
+	Expiry uint64 // end of the expiry bucket, 0 for data kept forever
+	Files  int
+	Bytes  int64
+}
+
+// LocalFileStorageUsage breaks down the disk space used by a LocalFileStorageService by expiry bucket
+type LocalFileStorageUsage struct {
+	Buckets     []LocalFileUsage // ordered by expiry
+	KeepForever LocalFileUsage
+	Flat        LocalFileUsage // not migrated to the sharded layout yet
+}
+
+// LocalFileStorageDiskUsage reports the disk space used in the data directory of a LocalFileStorageService
+func LocalFileStorageDiskUsage(ctx context.Context, dataDir string) (*LocalFileStorageUsage, error) {
+	usage := &LocalFileStorageUsage{}
+	indexed := make(map[string]struct{})
+	err := filepath.WalkDir(filepath.Join(dataDir, localFileExpiryDir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			indexed[d.Name()] = struct{}{}
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make(map[uint64]*LocalFileUsage)
+	err = filepath.WalkDir(filepath.Join(dataDir, localFileDataDir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return ctx.Err()
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		u := &usage.KeepForever
+		if _, ok := indexed[d.Name()]; ok {
+			bucket := expiryBucket(uint64(info.ModTime().Unix()))
+			if buckets[bucket] == nil {
+				buckets[bucket] = &LocalFileUsage{Expiry: bucket}
+			}
+			u = buckets[bucket]
+		}
+		u.Files++
+		u.Bytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range buckets {
+		usage.Buckets = append(usage.Buckets, *u)
+	}
+	sort.Slice(usage.Buckets, func(i, j int) bool { return usage.Buckets[i].Expiry < usage.Buckets[j].Expiry })
+
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if _, ok := flatLayoutKey(e.Name()); !ok || !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		usage.Flat.Files++
+		usage.Flat.Bytes += info.Size()
+	}
+	return usage, nil
+}
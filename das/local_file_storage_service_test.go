@@ -0,0 +1,136 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"encoding/base32"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/das/dastree"
+)
+
+func TestLocalFileStorageServiceExpiry(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+
+	// data stored in the flat layout by earlier versions
+	flatVal := []byte("flat value")
+	legacyVal := []byte("legacy flat value")
+	Require(t, os.WriteFile(filepath.Join(dataDir, EncodeStorageServiceKey(dastree.Hash(flatVal))), flatVal, 0o600))
+	Require(t, os.WriteFile(filepath.Join(dataDir, base32.StdEncoding.EncodeToString(dastree.HashBytes(legacyVal))), legacyVal, 0o600))
+	Require(t, os.WriteFile(filepath.Join(dataDir, "MANIFEST"), []byte("not das data"), 0o600))
+
+	usage, err := LocalFileStorageDiskUsage(ctx, dataDir)
+	Require(t, err)
+	if usage.Flat.Files != 2 {
+		t.Fatal("unexpected flat usage", usage.Flat)
+	}
+
+	config := DefaultLocalFileStorageConfig
+	config.DataDir = dataDir
+	config.DiscardAfterTimeout = true
+	config.PruneInterval = 0
+	storage, err := NewLocalFileStorageService(ctx, config)
+	Require(t, err)
+	s := storage.(*LocalFileStorageService)
+	defer func() {
+		Require(t, s.Close(ctx))
+	}()
+	for _, val := range [][]byte{flatVal, legacyVal} {
+		res, err := s.GetByHash(ctx, dastree.Hash(val))
+		Require(t, err)
+		if !bytes.Equal(res, val) {
+			t.Fatal("flat data was not migrated", string(val))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "MANIFEST")); err != nil {
+		t.Fatal("migration moved a file that isn't das data", err)
+	}
+
+	now := time.Now()
+	soon := uint64(now.Add(time.Hour).Unix())
+	later := uint64(now.Add(3 * time.Hour).Unix())
+	expiring := []byte("expiring value")
+	extended := []byte("extended value")
+	Require(t, s.Put(ctx, expiring, soon))
+	Require(t, s.Put(ctx, extended, soon))
+	Require(t, s.Put(ctx, extended, later))
+
+	usage, err = LocalFileStorageDiskUsage(ctx, dataDir)
+	Require(t, err)
+	if usage.Flat.Files != 0 || usage.KeepForever.Files != 2 || len(usage.Buckets) != 2 {
+		t.Fatal("unexpected usage", usage)
+	}
+	if usage.Buckets[0].Expiry != expiryBucket(soon) || usage.Buckets[0].Files != 1 || usage.Buckets[0].Bytes != int64(len(expiring)) {
+		t.Fatal("unexpected first bucket", usage.Buckets[0])
+	}
+
+	// nothing expired yet
+	Require(t, s.prune(ctx, now))
+	for _, val := range [][]byte{expiring, extended, flatVal} {
+		_, err := s.GetByHash(ctx, dastree.Hash(val))
+		Require(t, err)
+	}
+
+	// the first bucket ended, the data stored again with a later timeout stays
+	Require(t, s.prune(ctx, time.Unix(int64(expiryBucket(soon)), 0)))
+	if _, err := s.GetByHash(ctx, dastree.Hash(expiring)); !errors.Is(err, ErrNotFound) {
+		t.Fatal("expired data was not pruned", err)
+	}
+	_, err = s.GetByHash(ctx, dastree.Hash(extended))
+	Require(t, err)
+	if _, err := os.Stat(s.expiryBucketDir(expiryBucket(soon))); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("ended expiry bucket was not removed", err)
+	}
+
+	Require(t, s.prune(ctx, time.Unix(int64(expiryBucket(later)), 0)))
+	if _, err := s.GetByHash(ctx, dastree.Hash(extended)); !errors.Is(err, ErrNotFound) {
+		t.Fatal("extended data was not pruned", err)
+	}
+	// data of unknown expiry is kept
+	_, err = s.GetByHash(ctx, dastree.Hash(flatVal))
+	Require(t, err)
+}
+
+func TestLocalFileStorageServiceIterable(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultLocalFileStorageConfig
+	config.DataDir = t.TempDir()
+	config.PruneInterval = 0
+	storage, err := NewLocalFileStorageService(ctx, config)
+	Require(t, err)
+	s := storage.(*LocalFileStorageService)
+	defer func() {
+		Require(t, s.Close(ctx))
+	}()
+
+	// the iterator keys are updated in place as data is added
+	iterable := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(s))
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	var hashes []common.Hash
+	for _, val := range []string{"first", "second", "third"} {
+		Require(t, iterable.Put(ctx, []byte(val), timeout))
+		hashes = append(hashes, dastree.Hash([]byte(val)))
+	}
+
+	restarted := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(s))
+	if restarted.End(ctx) != hashes[len(hashes)-1] {
+		t.Fatal("iterator end wasn't updated")
+	}
+	hash := restarted.DefaultBegin()
+	for i, expected := range hashes {
+		hash = restarted.Next(ctx, hash)
+		if hash != expected {
+			t.Fatal("unexpected data in iteration", i)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/offchainlabs/nitro/arbstate"
@@ -21,6 +22,9 @@ import (
 // RestfulDasClient implements DataAvailabilityReader
 type RestfulDasClient struct {
 	url string
+
+	capabilitiesMutex sync.Mutex
+	capabilities      map[string]bool // nil until the server has answered
 }
 
 func NewRestfulDasClient(protocol string, host string, port int) *RestfulDasClient {
@@ -39,14 +43,28 @@ func NewRestfulDasClientFromURL(url string) (*RestfulDasClient, error) {
 	}, nil
 }
 
-func (c *RestfulDasClient) GetByHash(ctx context.Context, hash common.Hash) ([]byte, error) {
-	res, err := http.Get(c.url + getByHashRequestPath + EncodeStorageServiceKey(hash))
+func (c *RestfulDasClient) get(ctx context.Context, requestPath string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+requestPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
 		return nil, fmt.Errorf("HTTP error with status %d returned by server: %s", res.StatusCode, http.StatusText(res.StatusCode))
 	}
+	return res, nil
+}
+
+func (c *RestfulDasClient) GetByHash(ctx context.Context, hash common.Hash) ([]byte, error) {
+	res, err := c.get(ctx, getByHashRequestPath+EncodeStorageServiceKey(hash))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
 
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
@@ -59,8 +77,7 @@ func (c *RestfulDasClient) GetByHash(ctx context.Context, hash common.Hash) ([]b
 		return nil, err
 	}
 
-	decoder := base64.NewDecoder(base64.StdEncoding, bytes.NewReader([]byte(response.Data)))
-	decodedBytes, err := io.ReadAll(decoder)
+	decodedBytes, err := decodeResponseData(response.Data)
 	if err != nil {
 		return nil, err
 	}
@@ -71,6 +88,113 @@ func (c *RestfulDasClient) GetByHash(ctx context.Context, hash common.Hash) ([]b
 	return decodedBytes, nil
 }
 
+func decodeResponseData(data string) ([]byte, error) {
+	decoder := base64.NewDecoder(base64.StdEncoding, bytes.NewReader([]byte(data)))
+	return io.ReadAll(decoder)
+}
+
+// hasCapability tells whether the server advertises the optional endpoint, asking it until it answers.
+// Servers that predate the capabilities endpoint reject the request and are taken to have none.
+func (c *RestfulDasClient) hasCapability(ctx context.Context, capability string) bool {
+	c.capabilitiesMutex.Lock()
+	defer c.capabilitiesMutex.Unlock()
+	if c.capabilities != nil {
+		return c.capabilities[capability]
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+capabilitiesRequestPath, nil)
+	if err != nil {
+		return false
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// the server may just be down, ask again next time
+		return false
+	}
+	defer res.Body.Close()
+	capabilities := make(map[string]bool)
+	switch res.StatusCode {
+	case http.StatusOK:
+		var response RestfulDasServerResponse
+		if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+			return false
+		}
+		for _, capability := range response.Capabilities {
+			capabilities[capability] = true
+		}
+	case http.StatusBadRequest, http.StatusNotFound:
+		// an older server that doesn't know the endpoint
+	default:
+		return false
+	}
+	c.capabilities = capabilities
+	return c.capabilities[capability]
+}
+
+// GetByHashes fetches the preimages of many hashes in as few requests as the server allows,
+// falling back to one request per hash for servers without the batch endpoint.
+// Preimages the server doesn't have are missing from the result rather than being an error.
+func (c *RestfulDasClient) GetByHashes(ctx context.Context, hashes []common.Hash) (map[common.Hash][]byte, error) {
+	results := make(map[common.Hash][]byte, len(hashes))
+	if !c.hasCapability(ctx, capabilityGetByHashes) {
+		for _, hash := range hashes {
+			data, err := c.GetByHash(ctx, hash)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				continue
+			}
+			results[hash] = data
+		}
+		return results, nil
+	}
+
+	for start := 0; start < len(hashes); start += maxHashesPerRequest {
+		end := start + maxHashesPerRequest
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+		keys := make([]string, 0, end-start)
+		for _, hash := range hashes[start:end] {
+			keys = append(keys, EncodeStorageServiceKey(hash))
+		}
+		if err := c.getByHashes(ctx, keys, results); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (c *RestfulDasClient) getByHashes(ctx context.Context, keys []string, results map[common.Hash][]byte) error {
+	res, err := c.get(ctx, getByHashesRequestPath+strings.Join(keys, ","))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	decoder := json.NewDecoder(res.Body)
+	for decoder.More() {
+		var response RestfulDasServerResponse
+		if err := decoder.Decode(&response); err != nil {
+			return err
+		}
+		hash, err := DecodeStorageServiceKey(response.Hash)
+		if err != nil {
+			return err
+		}
+		data, err := decodeResponseData(response.Data)
+		if err != nil {
+			return err
+		}
+		if !dastree.ValidHash(hash, data) {
+			return arbstate.ErrHashMismatch
+		}
+		results[hash] = data
+	}
+	return nil
+}
+
 func (c *RestfulDasClient) HealthCheck(ctx context.Context) error {
 	res, err := http.Get(c.url + healthRequestPath)
 	if err != nil {
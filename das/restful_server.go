@@ -19,6 +19,7 @@ import (
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/offchainlabs/nitro/arbstate"
 	"github.com/offchainlabs/nitro/cmd/genericconf"
+	"github.com/offchainlabs/nitro/das/dastree"
 	"github.com/offchainlabs/nitro/util/pretty"
 )
 
@@ -28,6 +29,18 @@ var (
 	restGetByHashFailureGauge       = metrics.NewRegisteredGauge("arb/das/rest/getbyhash/failure", nil)
 	restGetByHashReturnedBytesGauge = metrics.NewRegisteredGauge("arb/das/rest/getbyhash/bytes", nil)
 	restGetByHashDurationHistogram  = metrics.NewRegisteredHistogram("arb/das/rest/getbyhash/duration", nil, metrics.NewBoundedHistogramSample())
+
+	restGetByHashesRequestGauge       = metrics.NewRegisteredGauge("arb/das/rest/getbyhashes/requests", nil)
+	restGetByHashesSuccessGauge       = metrics.NewRegisteredGauge("arb/das/rest/getbyhashes/success", nil)
+	restGetByHashesFailureGauge       = metrics.NewRegisteredGauge("arb/das/rest/getbyhashes/failure", nil)
+	restGetByHashesReturnedBytesGauge = metrics.NewRegisteredGauge("arb/das/rest/getbyhashes/bytes", nil)
+	restGetByHashesDurationHistogram  = metrics.NewRegisteredHistogram("arb/das/rest/getbyhashes/duration", nil, metrics.NewBoundedHistogramSample())
+
+	restGetBatchByRootRequestGauge       = metrics.NewRegisteredGauge("arb/das/rest/getbatchbyroot/requests", nil)
+	restGetBatchByRootSuccessGauge       = metrics.NewRegisteredGauge("arb/das/rest/getbatchbyroot/success", nil)
+	restGetBatchByRootFailureGauge       = metrics.NewRegisteredGauge("arb/das/rest/getbatchbyroot/failure", nil)
+	restGetBatchByRootReturnedBytesGauge = metrics.NewRegisteredGauge("arb/das/rest/getbatchbyroot/bytes", nil)
+	restGetBatchByRootDurationHistogram  = metrics.NewRegisteredHistogram("arb/das/rest/getbatchbyroot/duration", nil, metrics.NewBoundedHistogramSample())
 )
 
 type RestfulDasServer struct {
@@ -74,17 +87,32 @@ func NewRestfulDasServerOnListener(listener net.Listener, restServerTimeouts gen
 }
 
 type RestfulDasServerResponse struct {
-	Data             string `json:"data,omitempty"`
-	ExpirationPolicy string `json:"expirationPolicy,omitempty"`
+	Data             string   `json:"data,omitempty"`
+	ExpirationPolicy string   `json:"expirationPolicy,omitempty"`
+	Hash             string   `json:"hash,omitempty"`
+	Proof            []string `json:"proof,omitempty"`
+	Capabilities     []string `json:"capabilities,omitempty"`
 }
 
 var cacheControlKey = http.CanonicalHeaderKey("cache-control")
+var etagKey = http.CanonicalHeaderKey("etag")
+var ifNoneMatchKey = http.CanonicalHeaderKey("if-none-match")
 
 const cacheControlValueDefault = "public, max-age=1"                                 // cache for up to 1 second (Used to reduce DOS possibility)
 const cacheControlValueForSuccessfulGetByHash = "public, max-age=2419200, immutable" // cache for up to 28 days
 const healthRequestPath = "/health"
 const expirationPolicyRequestPath = "/expiration-policy/"
 const getByHashRequestPath = "/get-by-hash/"
+const getByHashesRequestPath = "/get-by-hashes/"
+const getBatchByRootRequestPath = "/get-batch-by-root/"
+const capabilitiesRequestPath = "/capabilities"
+
+// The endpoints advertised under /capabilities beyond the ones every server has
+const capabilityGetByHashes = "get-by-hashes"
+const capabilityGetBatchByRoot = "get-batch-by-root"
+
+// maxHashesPerRequest bounds the comma separated hashes of a get-by-hashes request, keeping the URL short
+const maxHashesPerRequest = 64
 
 func (rds *RestfulDasServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header()[cacheControlKey] = []string{cacheControlValueDefault}
@@ -97,6 +125,12 @@ func (rds *RestfulDasServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		rds.ExpirationPolicyHandler(w, r, requestPath)
 	case strings.HasPrefix(requestPath, getByHashRequestPath):
 		rds.GetByHashHandler(w, r, requestPath)
+	case strings.HasPrefix(requestPath, getByHashesRequestPath):
+		rds.GetByHashesHandler(w, r, requestPath)
+	case strings.HasPrefix(requestPath, getBatchByRootRequestPath):
+		rds.GetBatchByRootHandler(w, r, requestPath)
+	case strings.HasPrefix(requestPath, capabilitiesRequestPath):
+		rds.CapabilitiesHandler(w, r, requestPath)
 	default:
 		log.Warn("Unknown requestPath", "requestPath", requestPath)
 		w.WriteHeader(http.StatusBadRequest)
@@ -151,7 +185,7 @@ func (rds *RestfulDasServer) GetByHashHandler(w http.ResponseWriter, r *http.Req
 		restGetByHashDurationHistogram.Update(time.Since(start).Nanoseconds())
 	}()
 
-	hashBytes, err := DecodeStorageServiceKey(strings.TrimPrefix(requestPath, "/get-by-hash/"))
+	hashBytes, err := DecodeStorageServiceKey(strings.TrimPrefix(requestPath, getByHashRequestPath))
 	if err != nil {
 		log.Warn("Failed to decode hex-encoded hash", "path", requestPath, "err", err)
 		w.WriteHeader(http.StatusBadRequest)
@@ -162,8 +196,13 @@ func (rds *RestfulDasServer) GetByHashHandler(w http.ResponseWriter, r *http.Req
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	hash := common.BytesToHash(hashBytes[:32])
+	if notModified(w, r, hash) {
+		success = true
+		return
+	}
 
-	responseData, err := rds.daReader.GetByHash(r.Context(), common.BytesToHash(hashBytes[:32]))
+	responseData, err := rds.daReader.GetByHash(r.Context(), hash)
 	if err != nil {
 		log.Warn("Unable to find data", "path", requestPath, "err", err, "remoteAddr", r.RemoteAddr)
 		w.WriteHeader(http.StatusNotFound)
@@ -177,16 +216,175 @@ func (rds *RestfulDasServer) GetByHashHandler(w http.ResponseWriter, r *http.Req
 	response.Data = string(encodedResponseData)
 	restGetByHashReturnedBytesGauge.Inc(int64(len(response.Data)))
 
+	// headers must be set before the body is written
+	setImmutable(w, hash)
 	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
 		log.Warn("Failed encoding and writing response", "path", requestPath, "err", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	w.Header()[cacheControlKey] = []string{cacheControlValueForSuccessfulGetByHash}
 	success = true
 }
 
+// GetByHashesHandler streams the preimages of a comma separated list of hashes as a sequence of JSON
+// responses, in the order requested, as each is read. Preimages the server doesn't have are left out.
+// Since whether a preimage is found is only known after the headers are sent, the list isn't immutable.
+func (rds *RestfulDasServer) GetByHashesHandler(w http.ResponseWriter, r *http.Request, requestPath string) {
+	restGetByHashesRequestGauge.Inc(1)
+	start := time.Now()
+	success := false
+	defer func() {
+		if success {
+			restGetByHashesSuccessGauge.Inc(1)
+		} else {
+			restGetByHashesFailureGauge.Inc(1)
+		}
+		restGetByHashesDurationHistogram.Update(time.Since(start).Nanoseconds())
+	}()
+
+	keys := strings.Split(strings.TrimPrefix(requestPath, getByHashesRequestPath), ",")
+	if len(keys) > maxHashesPerRequest {
+		log.Warn("Too many hashes requested", "path", requestPath, "count", len(keys))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	hashes := make([]common.Hash, 0, len(keys))
+	for _, key := range keys {
+		hash, err := DecodeStorageServiceKey(key)
+		if err != nil {
+			log.Warn("Failed to decode hex-encoded hash", "path", requestPath, "err", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		hashes = append(hashes, hash)
+	}
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	for _, hash := range hashes {
+		data, err := rds.daReader.GetByHash(r.Context(), hash)
+		if err != nil {
+			log.Debug("Unable to find data", "hash", hash, "err", err, "remoteAddr", r.RemoteAddr)
+			continue
+		}
+		response := RestfulDasServerResponse{
+			Hash: EncodeStorageServiceKey(hash),
+			Data: base64.StdEncoding.EncodeToString(data),
+		}
+		restGetByHashesReturnedBytesGauge.Inc(int64(len(response.Data)))
+		if err := encoder.Encode(response); err != nil {
+			log.Warn("Failed encoding and writing response", "path", requestPath, "err", err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	success = true
+}
+
+// GetBatchByRootHandler returns the whole batch under a dastree root along with the tree's node and leaf
+// preimages. Stores holding the batch whole serve it directly, otherwise the tree is walked node by node.
+func (rds *RestfulDasServer) GetBatchByRootHandler(w http.ResponseWriter, r *http.Request, requestPath string) {
+	restGetBatchByRootRequestGauge.Inc(1)
+	start := time.Now()
+	success := false
+	defer func() {
+		if success {
+			restGetBatchByRootSuccessGauge.Inc(1)
+		} else {
+			restGetBatchByRootFailureGauge.Inc(1)
+		}
+		restGetBatchByRootDurationHistogram.Update(time.Since(start).Nanoseconds())
+	}()
+
+	root, err := DecodeStorageServiceKey(strings.TrimPrefix(requestPath, getBatchByRootRequestPath))
+	if err != nil {
+		log.Warn("Failed to decode hex-encoded hash", "path", requestPath, "err", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if notModified(w, r, root) {
+		success = true
+		return
+	}
+
+	ctx := r.Context()
+	data, err := rds.daReader.GetByHash(ctx, root)
+	if err != nil || !dastree.ValidHash(root, data) {
+		data, err = dastree.Content(root, func(hash common.Hash) ([]byte, error) {
+			return rds.daReader.GetByHash(ctx, hash)
+		})
+	}
+	if err != nil {
+		log.Warn("Unable to find batch", "path", requestPath, "err", err, "remoteAddr", r.RemoteAddr)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	response := RestfulDasServerResponse{
+		Data: base64.StdEncoding.EncodeToString(data),
+	}
+	for _, preimage := range batchProof(data) {
+		response.Proof = append(response.Proof, base64.StdEncoding.EncodeToString(preimage))
+	}
+	restGetBatchByRootReturnedBytesGauge.Inc(int64(len(response.Data)))
+
+	setImmutable(w, root)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Warn("Failed encoding and writing response", "path", requestPath, "err", err)
+		return
+	}
+	success = true
+}
+
+// CapabilitiesHandler lists the optional endpoints the server has, older servers reject the request
+func (rds *RestfulDasServer) CapabilitiesHandler(w http.ResponseWriter, r *http.Request, requestPath string) {
+	response := RestfulDasServerResponse{
+		Capabilities: []string{capabilityGetByHashes, capabilityGetBatchByRoot},
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Warn("Failed encoding and writing response", "path", requestPath, "err", err)
+	}
+}
+
+// batchProof returns the node and leaf preimages of the dastree of the batch, which are keyed by their keccak
+// hashes, leaving out the bins of data themselves
+func batchProof(data []byte) [][]byte {
+	var proof [][]byte
+	dastree.RecordHash(func(_ common.Hash, preimage []byte) {
+		if len(preimage) > 0 && (preimage[0] == dastree.NodeByte || preimage[0] == dastree.LeafByte) {
+			proof = append(proof, preimage)
+		}
+	}, data)
+	return proof
+}
+
+func etagOf(hash common.Hash) string {
+	return "\"" + EncodeStorageServiceKey(hash) + "\""
+}
+
+// setImmutable marks a response as cacheable forever, since content addressed responses never change
+func setImmutable(w http.ResponseWriter, hash common.Hash) {
+	w.Header()[cacheControlKey] = []string{cacheControlValueForSuccessfulGetByHash}
+	w.Header()[etagKey] = []string{etagOf(hash)}
+}
+
+// notModified answers a conditional request for content the client already has without reading it
+func notModified(w http.ResponseWriter, r *http.Request, hash common.Hash) bool {
+	etag := etagOf(hash)
+	for _, match := range strings.Split(r.Header.Get(ifNoneMatchKey), ",") {
+		match = strings.TrimPrefix(strings.TrimSpace(match), "W/")
+		if match == etag {
+			setImmutable(w, hash)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
 func (rds *RestfulDasServer) GetServerExitedChan() <-chan interface{} { // channel will close when server terminates
 	return rds.httpServerExitedChan
 }
@@ -6,13 +6,18 @@ package das
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/offchainlabs/nitro/cmd/genericconf"
 	"github.com/offchainlabs/nitro/das/dastree"
 )
@@ -68,3 +73,132 @@ func TestRestfulClientServer(t *testing.T) {
 	err = server.Shutdown()
 	Require(t, err)
 }
+
+func TestRestfulClientServerBatches(t *testing.T) {
+	initTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage := NewMemoryBackedStorageService(ctx)
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	var hashes []common.Hash
+	for i := 0; i < maxHashesPerRequest+3; i++ {
+		data := []byte(fmt.Sprintf("preimage %d", i))
+		Require(t, storage.Put(ctx, data, timeout))
+		hashes = append(hashes, dastree.Hash(data))
+	}
+	absent := dastree.Hash([]byte("absent data"))
+	hashes = append(hashes, absent)
+
+	batch := bytes.Repeat([]byte("a batch spanning a few dastree bins "), 3*dastree.BinSize/32)
+	root := dastree.Hash(batch)
+	Require(t, storage.Put(ctx, batch, timeout))
+
+	server, port, err := NewRestfulDasServerOnRandomPort(LocalServerAddressForTest, storage)
+	Require(t, err)
+	defer func() { Require(t, server.Shutdown()) }()
+
+	// servers that predate the capabilities endpoint only serve get-by-hash
+	legacy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, getByHashRequestPath) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		server.ServeHTTP(w, r)
+	}))
+	defer legacy.Close()
+	legacyClient, err := NewRestfulDasClientFromURL(legacy.URL)
+	Require(t, err)
+
+	client := NewRestfulDasClient("http", LocalServerAddressForTest, port)
+	for _, client := range []*RestfulDasClient{client, legacyClient} {
+		results, err := client.GetByHashes(ctx, hashes)
+		Require(t, err)
+		if len(results) != len(hashes)-1 {
+			Fail(t, "expected every hash but one, got", len(results))
+		}
+		if _, ok := results[absent]; ok {
+			Fail(t, "got data for an absent hash")
+		}
+		for hash, data := range results {
+			if !dastree.ValidHash(hash, data) {
+				Fail(t, "got the wrong data for", hash)
+			}
+		}
+
+	}
+	if !client.hasCapability(ctx, capabilityGetByHashes) || legacyClient.hasCapability(ctx, capabilityGetByHashes) {
+		Fail(t, "wrong capabilities")
+	}
+
+	url := fmt.Sprintf("http://%s:%d%s%s", LocalServerAddressForTest, port, getBatchByRootRequestPath, EncodeStorageServiceKey(root))
+	res, err := http.Get(url)
+	Require(t, err)
+	var response RestfulDasServerResponse
+	err = json.NewDecoder(res.Body).Decode(&response)
+	res.Body.Close()
+	Require(t, err)
+	etag := res.Header.Get(etagKey)
+	if res.StatusCode != http.StatusOK || etag != etagOf(root) || !strings.Contains(res.Header.Get(cacheControlKey), "immutable") {
+		Fail(t, "missing caching headers", res.StatusCode, res.Header)
+	}
+	data, err := decodeResponseData(response.Data)
+	Require(t, err)
+	if !bytes.Equal(data, batch) {
+		Fail(t, "batch mismatch")
+	}
+	recorded := make(map[common.Hash][]byte)
+	dastree.RecordHash(func(hash common.Hash, preimage []byte) { recorded[hash] = preimage }, batch)
+	if len(response.Proof) == 0 {
+		Fail(t, "no proof for a multi-bin batch")
+	}
+	for _, encoded := range response.Proof {
+		preimage, err := decodeResponseData(encoded)
+		Require(t, err)
+		if !bytes.Equal(recorded[crypto.Keccak256Hash(preimage)], preimage) {
+			Fail(t, "proof preimage isn't in the tree")
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	Require(t, err)
+	req.Header.Set(ifNoneMatchKey, etag)
+	res, err = http.DefaultClient.Do(req)
+	Require(t, err)
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotModified {
+		Fail(t, "expected a revalidation to be not modified, got", res.StatusCode)
+	}
+}
+
+func TestRestfulClientCapabilities(t *testing.T) {
+	initTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, _, err := NewRestfulDasServerOnRandomPort(LocalServerAddressForTest, NewMemoryBackedStorageService(ctx))
+	Require(t, err)
+	defer func() { Require(t, server.Shutdown()) }()
+
+	// the first capabilities request hits a transient error
+	failures := 1
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		server.ServeHTTP(w, r)
+	}))
+	defer flaky.Close()
+	client, err := NewRestfulDasClientFromURL(flaky.URL)
+	Require(t, err)
+
+	if client.hasCapability(ctx, capabilityGetByHashes) {
+		Fail(t, "got a capability from a failed request")
+	}
+	if !client.hasCapability(ctx, capabilityGetByHashes) {
+		Fail(t, "capabilities weren't asked again after a transient error")
+	}
+}
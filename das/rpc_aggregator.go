@@ -19,6 +19,9 @@ type BackendConfig struct {
 	URL                 string `json:"url"`
 	PubKeyBase64Encoded string `json:"pubkey"`
 	SignerMask          uint64 `json:"signermask"`
+
+	// The key the backend is rotating to, in the keyset set valid on the SequencerInbox alongside the current one
+	NextPubKeyBase64Encoded string `json:"nextpubkey,omitempty"`
 }
 
 func NewRPCAggregator(ctx context.Context, config DataAvailabilityConfig) (*Aggregator, error) {
@@ -75,6 +78,13 @@ func setUpServices(config DataAvailabilityConfig) ([]ServiceDetails, error) {
 		if err != nil {
 			return nil, err
 		}
+		if b.NextPubKeyBase64Encoded != "" {
+			nextPubKey, err := DecodeBase64BLSPublicKey([]byte(b.NextPubKeyBase64Encoded))
+			if err != nil {
+				return nil, err
+			}
+			d.SetNextPubKey(*nextPubKey)
+		}
 
 		services = append(services, *d)
 	}
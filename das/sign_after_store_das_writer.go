@@ -9,6 +9,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -24,11 +25,49 @@ import (
 	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
 	"github.com/offchainlabs/nitro/util/contracts"
 	"github.com/offchainlabs/nitro/util/pretty"
+	"github.com/offchainlabs/nitro/util/signature"
 )
 
 type KeyConfig struct {
-	KeyDir  string `koanf:"key-dir"`
-	PrivKey string `koanf:"priv-key"`
+	KeyDir          string `koanf:"key-dir"`
+	PrivKey         string `koanf:"priv-key"`
+	Watch           bool   `koanf:"watch"`
+	SignerURL       string `koanf:"signer-url"`
+	SignerJWTSecret string `koanf:"signer-jwtsecret"`
+}
+
+// Enabled tells whether any source of a signing key is configured
+func (c *KeyConfig) Enabled() bool {
+	return c.KeyDir != "" || c.PrivKey != "" || c.SignerURL != ""
+}
+
+// BLSSigner returns the signer for the configured key, which is held by a remote signer if signer-url is set
+func (c *KeyConfig) BLSSigner(ctx context.Context) (BLSSigner, error) {
+	if c.SignerURL != "" {
+		if c.KeyDir != "" || c.PrivKey != "" {
+			return nil, errors.New("a remote signer holds the key, don't specify key-dir or priv-key as well")
+		}
+		jwtSecret, err := signature.LoadSigningKey(c.SignerJWTSecret)
+		if err != nil {
+			return nil, err
+		}
+		var secret []byte
+		if jwtSecret != nil {
+			secret = jwtSecret.Bytes()
+		}
+		return NewRemoteBLSSigner(ctx, c.SignerURL, secret)
+	}
+	if c.Watch {
+		if c.KeyDir == "" || c.PrivKey != "" {
+			return nil, errors.New("only keys in key-dir can be watched")
+		}
+		return NewFileBLSSigner(c.KeyDir)
+	}
+	privKey, err := c.BLSPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	return NewLocalBLSSigner(privKey)
 }
 
 func (c *KeyConfig) BLSPrivKey() (blsSignatures.PrivateKey, error) {
@@ -59,6 +98,9 @@ var DefaultKeyConfig = KeyConfig{}
 func KeyConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.String(prefix+".key-dir", DefaultKeyConfig.KeyDir, fmt.Sprintf("the directory to read the bls keypair ('%s' and '%s') from; if using any of the DAS storage types exactly one of key-dir or priv-key must be specified", DefaultPubKeyFilename, DefaultPrivKeyFilename))
 	f.String(prefix+".priv-key", DefaultKeyConfig.PrivKey, "the base64 BLS private key to use for signing DAS certificates; if using any of the DAS storage types exactly one of key-dir or priv-key must be specified")
+	f.Bool(prefix+".watch", DefaultKeyConfig.Watch, "reload the private key in key-dir whenever it changes, to rotate keys without a restart")
+	f.String(prefix+".signer-url", DefaultKeyConfig.SignerURL, "URL or Unix socket path of a remote BLS signer holding the key, instead of key-dir or priv-key")
+	f.String(prefix+".signer-jwtsecret", DefaultKeyConfig.SignerJWTSecret, "path to file with the jwt secret (32B hex) of the remote signer, required for http signer urls")
 }
 
 // SignAfterStoreDASWriter provides DAS signature functionality over a StorageService
@@ -66,32 +108,35 @@ func KeyConfigAddOptions(prefix string, f *flag.FlagSet) {
 // There are two different signature functionalities it provides:
 //
 // 1) SignAfterStoreDASWriter.Store(...) assembles the returned hash into a
-// DataAvailabilityCertificate and signs it with its BLS private key.
+// DataAvailabilityCertificate and signs it with its BLS signer, which may be
+// a remote signer process and may rotate keys while running.
 //
 // 2) If Sequencer Inbox contract details are provided when a SignAfterStoreDASWriter is
 // constructed, calls to Store(...) will try to verify the passed-in data's signature
 // is from the batch poster. If the contract details are not provided, then the
 // signature is not checked, which is useful for testing.
 type SignAfterStoreDASWriter struct {
-	privKey        blsSignatures.PrivateKey
-	pubKey         *blsSignatures.PublicKey
-	keysetHash     [32]byte
-	keysetBytes    []byte
+	signer         BLSSigner
 	storageService StorageService
 	bpVerifier     *contracts.BatchPosterVerifier
 
 	// Extra batch poster verifier, for local installations to have their
 	// own way of testing Stores.
 	extraBpVerifier func(message []byte, timeout uint64, sig []byte) bool
+
+	// the key last signed with, and the hash of the keyset of just that key
+	keyMutex   sync.Mutex
+	pubKey     blsSignatures.PublicKey
+	keysetHash [32]byte
 }
 
 func NewSignAfterStoreDASWriter(ctx context.Context, config DataAvailabilityConfig, storageService StorageService) (*SignAfterStoreDASWriter, error) {
-	privKey, err := config.KeyConfig.BLSPrivKey()
+	signer, err := config.KeyConfig.BLSSigner(ctx)
 	if err != nil {
 		return nil, err
 	}
 	if config.L1NodeURL == "none" {
-		return NewSignAfterStoreDASWriterWithSeqInboxCaller(privKey, nil, storageService, config.ExtraSignatureCheckingPublicKey)
+		return NewSignAfterStoreDASWriterWithSigner(ctx, signer, nil, storageService, config.ExtraSignatureCheckingPublicKey)
 	}
 	l1client, err := GetL1Client(ctx, config.L1ConnectionAttempts, config.L1NodeURL)
 	if err != nil {
@@ -102,14 +147,14 @@ func NewSignAfterStoreDASWriter(ctx context.Context, config DataAvailabilityConf
 		return nil, err
 	}
 	if seqInboxAddress == nil {
-		return NewSignAfterStoreDASWriterWithSeqInboxCaller(privKey, nil, storageService, config.ExtraSignatureCheckingPublicKey)
+		return NewSignAfterStoreDASWriterWithSigner(ctx, signer, nil, storageService, config.ExtraSignatureCheckingPublicKey)
 	}
 
 	seqInboxCaller, err := bridgegen.NewSequencerInboxCaller(*seqInboxAddress, l1client)
 	if err != nil {
 		return nil, err
 	}
-	return NewSignAfterStoreDASWriterWithSeqInboxCaller(privKey, seqInboxCaller, storageService, config.ExtraSignatureCheckingPublicKey)
+	return NewSignAfterStoreDASWriterWithSigner(ctx, signer, seqInboxCaller, storageService, config.ExtraSignatureCheckingPublicKey)
 }
 
 func NewSignAfterStoreDASWriterWithSeqInboxCaller(
@@ -118,20 +163,21 @@ func NewSignAfterStoreDASWriterWithSeqInboxCaller(
 	storageService StorageService,
 	extraSignatureCheckingPublicKey string,
 ) (*SignAfterStoreDASWriter, error) {
-	publicKey, err := blsSignatures.PublicKeyFromPrivateKey(privKey)
+	signer, err := NewLocalBLSSigner(privKey)
 	if err != nil {
 		return nil, err
 	}
+	return NewSignAfterStoreDASWriterWithSigner(context.Background(), signer, seqInboxCaller, storageService, extraSignatureCheckingPublicKey)
+}
 
-	keyset := &arbstate.DataAvailabilityKeyset{
-		AssumedHonest: 1,
-		PubKeys:       []blsSignatures.PublicKey{publicKey},
-	}
-	ksBuf := bytes.NewBuffer([]byte{})
-	if err := keyset.Serialize(ksBuf); err != nil {
-		return nil, err
-	}
-	ksHash, err := keyset.Hash()
+func NewSignAfterStoreDASWriterWithSigner(
+	ctx context.Context,
+	signer BLSSigner,
+	seqInboxCaller *bridgegen.SequencerInboxCaller,
+	storageService StorageService,
+	extraSignatureCheckingPublicKey string,
+) (*SignAfterStoreDASWriter, error) {
+	publicKey, err := signer.PublicKey(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -168,38 +214,70 @@ func NewSignAfterStoreDASWriterWithSeqInboxCaller(
 		}
 	}
 
-	return &SignAfterStoreDASWriter{
-		privKey:         privKey,
-		pubKey:          &publicKey,
-		keysetHash:      ksHash,
-		keysetBytes:     ksBuf.Bytes(),
+	d := &SignAfterStoreDASWriter{
+		signer:          signer,
 		storageService:  storageService,
 		bpVerifier:      bpVerifier,
 		extraBpVerifier: extraBpVerifier,
-	}, nil
+	}
+	if _, err := d.setPublicKey(publicKey); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// PublicKey is the key the writer last signed with
+func (d *SignAfterStoreDASWriter) PublicKey() blsSignatures.PublicKey {
+	d.keyMutex.Lock()
+	defer d.keyMutex.Unlock()
+	return d.pubKey
+}
+
+// setPublicKey makes the key the one last signed with, and returns the hash of its keyset
+func (d *SignAfterStoreDASWriter) setPublicKey(pubKey blsSignatures.PublicKey) ([32]byte, error) {
+	d.keyMutex.Lock()
+	defer d.keyMutex.Unlock()
+	if d.keysetHash != ([32]byte{}) && bytes.Equal(blsSignatures.PublicKeyToBytes(pubKey), blsSignatures.PublicKeyToBytes(d.pubKey)) {
+		return d.keysetHash, nil
+	}
+	keyset := &arbstate.DataAvailabilityKeyset{
+		AssumedHonest: 1,
+		PubKeys:       []blsSignatures.PublicKey{pubKey},
+	}
+	ksHash, err := keyset.Hash()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	if d.keysetHash != ([32]byte{}) {
+		log.Info("DAS signing key rotated", "pubKey", hexutil.Encode(blsSignatures.PublicKeyToBytes(pubKey)))
+	}
+	d.pubKey = pubKey
+	d.keysetHash = ksHash
+	return ksHash, nil
+}
+
+// sign signs the certificate, and sets its keyset to that of the key it was signed with
+func (d *SignAfterStoreDASWriter) sign(ctx context.Context, c *arbstate.DataAvailabilityCertificate) error {
+	sig, pubKey, err := d.signer.Sign(ctx, c.SerializeSignableFields())
+	if err != nil {
+		return err
+	}
+	// the keyset must be that of the key returned, not of whatever key a concurrent signing set last
+	keysetHash, err := d.setPublicKey(pubKey)
+	if err != nil {
+		return err
+	}
+	c.Sig = sig
+	c.KeysetHash = keysetHash
+	return nil
 }
 
 func (d *SignAfterStoreDASWriter) Store(
 	ctx context.Context, message []byte, timeout uint64, sig []byte,
 ) (c *arbstate.DataAvailabilityCertificate, err error) {
 	log.Trace("das.SignAfterStoreDASWriter.Store", "message", pretty.FirstFewBytes(message), "timeout", time.Unix(int64(timeout), 0), "sig", pretty.FirstFewBytes(sig), "this", d)
-	var verified bool
-	if d.extraBpVerifier != nil {
-		verified = d.extraBpVerifier(message, timeout, sig)
-	}
-
-	if !verified && d.bpVerifier != nil {
-		actualSigner, err := DasRecoverSigner(message, timeout, sig)
-		if err != nil {
-			return nil, err
-		}
-		isBatchPoster, err := d.bpVerifier.IsBatchPoster(ctx, actualSigner)
-		if err != nil {
-			return nil, err
-		}
-		if !isBatchPoster {
-			return nil, errors.New("store request not properly signed")
-		}
+	if err := d.verifyBatchPoster(ctx, message, timeout, sig); err != nil {
+		return nil, err
 	}
 
 	c = &arbstate.DataAvailabilityCertificate{
@@ -209,9 +287,7 @@ func (d *SignAfterStoreDASWriter) Store(
 		SignersMask: 1, // The aggregator will override this if we're part of a committee.
 	}
 
-	fields := c.SerializeSignableFields()
-	c.Sig, err = blsSignatures.SignMessage(d.privKey, fields)
-	if err != nil {
+	if err := d.sign(ctx, c); err != nil {
 		return nil, err
 	}
 
@@ -224,11 +300,31 @@ func (d *SignAfterStoreDASWriter) Store(
 		return nil, err
 	}
 
-	c.KeysetHash = d.keysetHash
-
 	return c, nil
 }
 
+func (d *SignAfterStoreDASWriter) verifyBatchPoster(ctx context.Context, message []byte, timeout uint64, sig []byte) error {
+	var verified bool
+	if d.extraBpVerifier != nil {
+		verified = d.extraBpVerifier(message, timeout, sig)
+	}
+
+	if !verified && d.bpVerifier != nil {
+		actualSigner, err := DasRecoverSigner(message, timeout, sig)
+		if err != nil {
+			return err
+		}
+		isBatchPoster, err := d.bpVerifier.IsBatchPoster(ctx, actualSigner)
+		if err != nil {
+			return err
+		}
+		if !isBatchPoster {
+			return errors.New("store request not properly signed")
+		}
+	}
+	return nil
+}
+
 func (d *SignAfterStoreDASWriter) String() string {
-	return fmt.Sprintf("SignAfterStoreDASWriter{%v}", hexutil.Encode(blsSignatures.PublicKeyToBytes(*d.pubKey)))
+	return fmt.Sprintf("SignAfterStoreDASWriter{%v}", hexutil.Encode(blsSignatures.PublicKeyToBytes(d.PublicKey())))
 }
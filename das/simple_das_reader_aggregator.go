@@ -242,6 +242,77 @@ func (a *SimpleDASReaderAggregator) GetByHash(ctx context.Context, hash common.H
 	return nil, fmt.Errorf("data wasn't able to be retrieved from any DAS Reader: %v", errorCollection)
 }
 
+// GetByHashes fetches the preimages of many hashes, asking the readers in the order of the strategy for
+// whichever are still missing, using the batch endpoint of the servers that have it.
+// Preimages no reader has are missing from the result.
+func (a *SimpleDASReaderAggregator) GetByHashes(ctx context.Context, hashes []common.Hash) (map[common.Hash][]byte, error) {
+	a.readersMutex.RLock()
+	defer a.readersMutex.RUnlock()
+	log.Trace("das.SimpleDASReaderAggregator.GetByHashes", "count", len(hashes), "this", a)
+
+	results := make(map[common.Hash][]byte, len(hashes))
+	missing := hashes
+	si := a.strategy.newInstance()
+	for readers := si.nextReaders(); len(readers) != 0 && len(missing) != 0; readers = si.nextReaders() {
+		for _, reader := range readers {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			var found map[common.Hash][]byte
+			if multi, ok := reader.(multiHashReader); ok {
+				found = a.tryGetByHashes(ctx, missing, reader, multi)
+			} else {
+				found = make(map[common.Hash][]byte)
+				for _, hash := range missing {
+					if data, err := a.tryGetByHash(ctx, hash, reader); err == nil {
+						found[hash] = data
+					}
+				}
+			}
+			var stillMissing []common.Hash
+			for _, hash := range missing {
+				if data, ok := found[hash]; ok {
+					results[hash] = data
+				} else {
+					stillMissing = append(stillMissing, hash)
+				}
+			}
+			missing = stillMissing
+			if len(missing) == 0 {
+				break
+			}
+		}
+	}
+	return results, nil
+}
+
+func (a *SimpleDASReaderAggregator) tryGetByHashes(
+	ctx context.Context, hashes []common.Hash, reader arbstate.DataAvailabilityReader, multi multiHashReader,
+) map[common.Hash][]byte {
+	stat := readerStatMessage{reader: reader}
+	start := time.Now()
+	found, err := multi.GetByHashes(ctx, hashes)
+	if err != nil {
+		log.Debug("SimpleDASReaderAggregator couldn't get hashes", "reader", reader, "err", err)
+		found = nil
+	}
+	valid := make(map[common.Hash][]byte, len(found))
+	for hash, data := range found {
+		if dastree.ValidHash(hash, data) {
+			valid[hash] = data
+		}
+	}
+	stat.success = err == nil && len(valid) == len(found)
+	stat.latency = time.Since(start)
+	a.recordStat(stat)
+	return valid
+}
+
+// multiHashReader is implemented by readers that can fetch many preimages per request
+type multiHashReader interface {
+	GetByHashes(ctx context.Context, hashes []common.Hash) (map[common.Hash][]byte, error)
+}
+
 func (a *SimpleDASReaderAggregator) tryGetByHash(
 	ctx context.Context, hash common.Hash, reader arbstate.DataAvailabilityReader,
 ) ([]byte, error) {
@@ -258,15 +329,18 @@ func (a *SimpleDASReaderAggregator) tryGetByHash(
 		}
 	}
 	stat.latency = time.Since(start)
+	a.recordStat(stat)
 
+	return result, err
+}
+
+func (a *SimpleDASReaderAggregator) recordStat(stat readerStatMessage) {
 	select {
 	case a.statMessages <- stat:
 		// Non-blocking write to stat channel
 	default:
 		log.Warn("SimpleDASReaderAggregator stats processing goroutine is backed up, dropping", "dropped stats", stat)
 	}
-
-	return result, err
 }
 
 func (a *SimpleDASReaderAggregator) Start(ctx context.Context) {
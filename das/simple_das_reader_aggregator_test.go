@@ -12,6 +12,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+
 	"github.com/offchainlabs/nitro/das/dastree"
 )
 
@@ -84,3 +86,49 @@ func TestSimpleDASReaderAggregator(t *testing.T) { //nolint
 	Require(t, err)
 
 }
+
+func TestSimpleDASReaderAggregatorBatches(t *testing.T) {
+	initTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	var messages [][]byte
+	var hashes []common.Hash
+	for i := 0; i < 2; i++ {
+		message := []byte{}
+		for j := 0; j < 100; j++ {
+			message = append(message, []byte(fmt.Sprintf("line %d held by mirror %d\n", j, i))...)
+		}
+		messages = append(messages, message)
+		hashes = append(hashes, dastree.Hash(message))
+	}
+
+	// each mirror holds one of the messages, so fetching both needs both mirrors
+	var urls []string
+	for i := 0; i < 2; i++ {
+		storage := NewMemoryBackedStorageService(ctx)
+		Require(t, storage.Put(ctx, messages[i], timeout))
+		server, port, err := NewRestfulDasServerOnRandomPort(LocalServerAddressForTest, storage)
+		Require(t, err)
+		defer func() { Require(t, server.Shutdown()) }()
+		urls = append(urls, "http://localhost:"+strconv.Itoa(port))
+	}
+
+	config := RestfulClientAggregatorConfig{
+		Urls:                   urls,
+		Strategy:               "testing-sequential",
+		StrategyUpdateInterval: time.Second,
+		WaitBeforeTryNext:      500 * time.Millisecond,
+		MaxPerEndpointStats:    10,
+	}
+	agg, err := NewRestfulClientAggregator(ctx, &config)
+	Require(t, err)
+
+	results, err := agg.GetByHashes(ctx, hashes)
+	Require(t, err)
+	if len(results) != 2 || !bytes.Equal(results[hashes[0]], messages[0]) || !bytes.Equal(results[hashes[1]], messages[1]) {
+		Fail(t, "expected the messages from both mirrors, got", len(results))
+	}
+}
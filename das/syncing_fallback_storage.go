@@ -4,6 +4,7 @@
 package das
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"errors"
@@ -22,6 +23,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/offchainlabs/nitro/arbstate"
 	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/das/dastree"
 	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
 	"github.com/offchainlabs/nitro/util/arbmath"
 	"github.com/offchainlabs/nitro/util/headerreader"
@@ -211,9 +213,10 @@ func (s *l1SyncService) processBatchDelivered(ctx context.Context, batchDelivere
 	binary.BigEndian.PutUint64(header[24:32], deliveredEvent.TimeBounds.MaxBlockNumber)
 	binary.BigEndian.PutUint64(header[32:40], deliveredEvent.AfterDelayedMessagesRead.Uint64())
 
+	dataSource := s.prefetch(ctx, data)
 	data = append(header, data...)
 	preimages := make(map[common.Hash][]byte)
-	if _, err = arbstate.RecoverPayloadFromDasBatch(ctx, deliveredEvent.BatchSequenceNumber.Uint64(), data, s.dataSource, preimages, arbstate.KeysetValidate); err != nil {
+	if _, err = arbstate.RecoverPayloadFromDasBatch(ctx, deliveredEvent.BatchSequenceNumber.Uint64(), data, dataSource, preimages, arbstate.KeysetValidate); err != nil {
 		log.Error("recover payload failed", "txhash", batchDeliveredLog.TxHash, "data", data)
 		return err
 	}
@@ -242,6 +245,38 @@ func (s *l1SyncService) processBatchDelivered(ctx context.Context, batchDelivere
 	return nil
 }
 
+// prefetch fetches the keyset and data of the certificate in one request when the data source allows it,
+// returning a reader serving them ahead of the data source
+func (s *l1SyncService) prefetch(ctx context.Context, certData []byte) arbstate.DataAvailabilityReader {
+	multi, ok := s.dataSource.(multiHashReader)
+	if !ok {
+		return s.dataSource
+	}
+	cert, err := arbstate.DeserializeDASCertFrom(bytes.NewReader(certData))
+	if err != nil || cert.Version != 1 {
+		return s.dataSource
+	}
+	preimages, err := multi.GetByHashes(ctx, []common.Hash{cert.KeysetHash, cert.DataHash})
+	if err != nil {
+		log.Warn("couldn't prefetch DAS preimages", "err", err)
+		return s.dataSource
+	}
+	return &prefetchedReader{s.dataSource, preimages}
+}
+
+// prefetchedReader serves the preimages fetched ahead of time, reading any others from the inner reader
+type prefetchedReader struct {
+	arbstate.DataAvailabilityReader
+	preimages map[common.Hash][]byte
+}
+
+func (r *prefetchedReader) GetByHash(ctx context.Context, hash common.Hash) ([]byte, error) {
+	if data, ok := r.preimages[hash]; ok && dastree.ValidHash(hash, data) {
+		return data, nil
+	}
+	return r.DataAvailabilityReader.GetByHash(ctx, hash)
+}
+
 func FindDASDataFromLog(
 	ctx context.Context,
 	inboxContract *bridgegen.SequencerInbox,
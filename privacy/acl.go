@@ -0,0 +1,319 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/allegro/bigcache"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/go-redis/redis/v8"
+)
+
+// Capabilities a grant may cover on a protected address
+const (
+	CapabilityBalance uint64 = 1 << iota
+	CapabilityNonce
+	CapabilityCalldata
+	CapabilityLogs
+	CapabilityState // storage, code and eth_call
+
+	CapabilityAll = CapabilityBalance | CapabilityNonce | CapabilityCalldata | CapabilityLogs | CapabilityState
+)
+
+// AccessList holds every grant on a protected address
+type AccessList []AuthToken
+
+const grantIndexKey = "privacy grant index"
+
+func (g *AuthToken) expired(now uint64) bool {
+	return g.ExpirationTime != 0 && g.ExpirationTime <= now
+}
+
+// Allows reports whether the token was granted the capability, and the grant has not expired yet
+func (l AccessList) Allows(token string, capability uint64) bool {
+	if token == "" {
+		return false
+	}
+	now := uint64(time.Now().Unix())
+	for _, grant := range l {
+		if grant.Token == token && grant.Mask&capability == capability && !grant.expired(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// add returns the list with the grant added, replacing an earlier grant of the same token
+func (l AccessList) add(grant AuthToken) AccessList {
+	for i := range l {
+		if l[i].Token == grant.Token {
+			l[i] = grant
+			return l
+		}
+	}
+	return append(l, grant)
+}
+
+// revoke returns the list without the token, and whether it was there at all
+func (l AccessList) revoke(token string) (AccessList, bool) {
+	for i := range l {
+		if l[i].Token == token {
+			return append(l[:i:i], l[i+1:]...), true
+		}
+	}
+	return l, false
+}
+
+// rotate returns the list with the grant of the old token moved to the new one, and whether the old token was there
+func (l AccessList) rotate(oldToken string, newToken string, now uint64) (AccessList, bool) {
+	for i := range l {
+		if l[i].Token == oldToken {
+			grant := l[i]
+			grant.Token = newToken
+			grant.CreationTime = now
+			l, _ = l.revoke(oldToken)
+			return l.add(grant), true
+		}
+	}
+	return l, false
+}
+
+// withoutExpired returns the list without the grants expired at now, and whether any was
+func (l AccessList) withoutExpired(now uint64) (AccessList, bool) {
+	res := make(AccessList, 0, len(l))
+	for _, grant := range l {
+		if !grant.expired(now) {
+			res = append(res, grant)
+		}
+	}
+	return res, len(res) != len(l)
+}
+
+// lifetime returns the seconds until every grant expired, or 0 if a grant never expires
+func (l AccessList) lifetime(now uint64) uint64 {
+	var last uint64
+	for _, grant := range l {
+		if grant.ExpirationTime == 0 {
+			return 0
+		}
+		if grant.ExpirationTime > last {
+			last = grant.ExpirationTime
+		}
+	}
+	if last <= now {
+		return 1
+	}
+	return last - now
+}
+
+func decodeAccessList(data []byte) AccessList {
+	var acl AccessList
+	if err := json.Unmarshal(data, &acl); err != nil {
+		// entries written before access lists existed hold a single raw token with full visibility
+		return AccessList{{Token: string(data), Mask: CapabilityAll}}
+	}
+	return acl
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, bigcache.ErrEntryNotFound) || errors.Is(err, redis.Nil) || errors.Is(err, ErrEntryNotFound)
+}
+
+// accessList returns the grants on the address, an address nobody was granted has an empty list
+func (w *PrivacyWrapper) accessList(ctx context.Context, addr string) (AccessList, error) {
+	data, err := w.cache.Get(ctx, addr)
+	if isNotFound(err) {
+		cacheMissCounter.Inc(1)
+		return AccessList{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cacheHitCounter.Inc(1)
+	return decodeAccessList(data), nil
+}
+
+// updateAccessList applies update to the access list of the address, atomically with the updates of other nodes
+// sharing the cache. update may be called more than once. The cache drops the list once every grant expired.
+func (w *PrivacyWrapper) updateAccessList(ctx context.Context, addr string, update func(AccessList) AccessList) error {
+	return w.cache.Update(ctx, addr, func(data []byte) ([]byte, uint64, error) {
+		acl := AccessList{}
+		if data != nil {
+			acl = decodeAccessList(data)
+		}
+		acl = update(acl)
+		if len(acl) == 0 {
+			return nil, 0, nil
+		}
+		data, err := json.Marshal(acl)
+		if err != nil {
+			return nil, 0, err
+		}
+		return data, acl.lifetime(uint64(time.Now().Unix())), nil
+	})
+}
+
+// grantIndex returns the addresses holding grants, for the purge job to visit
+func (w *PrivacyWrapper) grantIndex(ctx context.Context) ([]string, error) {
+	data, err := w.cache.Get(ctx, grantIndexKey)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var index []string
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// updateGrantIndex applies update to the grant index like updateAccessList does to an access list
+func (w *PrivacyWrapper) updateGrantIndex(ctx context.Context, update func([]string) []string) error {
+	return w.cache.Update(ctx, grantIndexKey, func(data []byte) ([]byte, uint64, error) {
+		var index []string
+		if data != nil {
+			if err := json.Unmarshal(data, &index); err != nil {
+				return nil, 0, err
+			}
+		}
+		data, err := json.Marshal(update(index))
+		return data, 0, err
+	})
+}
+
+func (w *PrivacyWrapper) addToGrantIndex(ctx context.Context, addr string) error {
+	return w.updateGrantIndex(ctx, func(index []string) []string {
+		for _, a := range index {
+			if a == addr {
+				return index
+			}
+		}
+		return append(index, addr)
+	})
+}
+
+// grant adds the grant to the access list of the address
+func (w *PrivacyWrapper) grant(ctx context.Context, addr string, grant AuthToken) error {
+	err := w.updateAccessList(ctx, addr, func(acl AccessList) AccessList {
+		return acl.add(grant)
+	})
+	if err != nil {
+		return err
+	}
+	return w.addToGrantIndex(ctx, addr)
+}
+
+// rotate moves the grant of the old token on the address to the new token, and reports whether the old token was granted
+func (w *PrivacyWrapper) rotate(ctx context.Context, addr string, oldToken string, newToken string) (bool, error) {
+	now := uint64(time.Now().Unix())
+	var found bool
+	err := w.updateAccessList(ctx, addr, func(acl AccessList) AccessList {
+		acl, found = acl.rotate(oldToken, newToken, now)
+		return acl
+	})
+	return found, err
+}
+
+// purgeExpired removes the expired grants of every address in the grant index
+func (w *PrivacyWrapper) purgeExpired(ctx context.Context) error {
+	index, err := w.grantIndex(ctx)
+	if err != nil {
+		return err
+	}
+	now := uint64(time.Now().Unix())
+	emptied := make(map[string]struct{})
+	purged := 0
+	for _, addr := range index {
+		var changed bool
+		var left int
+		err := w.updateAccessList(ctx, addr, func(acl AccessList) AccessList {
+			acl, changed = acl.withoutExpired(now)
+			left = len(acl)
+			return acl
+		})
+		if err != nil {
+			return err
+		}
+		if changed {
+			purged++
+		}
+		if left == 0 {
+			emptied[addr] = struct{}{}
+		}
+	}
+	if len(emptied) == 0 {
+		return nil
+	}
+	log.Debug("purged expired privacy grants", "addresses", purged, "emptied", len(emptied))
+	return w.updateGrantIndex(ctx, func(index []string) []string {
+		remaining := make([]string, 0, len(index))
+		for _, addr := range index {
+			if _, ok := emptied[addr]; ok {
+				// another grant may have landed since, it is only added to the index after its access list
+				acl, err := w.accessList(ctx, addr)
+				if err == nil && len(acl) == 0 {
+					continue
+				}
+			}
+			remaining = append(remaining, addr)
+		}
+		return remaining
+	})
+}
+
+// revoke removes the token from the access list of the address, and reports whether it was granted
+func (w *PrivacyWrapper) revoke(ctx context.Context, addr string, token string) (bool, error) {
+	var found bool
+	err := w.updateAccessList(ctx, addr, func(acl AccessList) AccessList {
+		acl, found = acl.revoke(token)
+		return acl
+	})
+	return found, err
+}
+
+// GrantSnapshot holds the access lists of every address with grants, keyed by address
+type GrantSnapshot map[string]AccessList
+
+// ExportGrants returns the grants that have not expired yet
+func (w *PrivacyWrapper) ExportGrants(ctx context.Context) (GrantSnapshot, error) {
+	index, err := w.grantIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := uint64(time.Now().Unix())
+	snapshot := make(GrantSnapshot, len(index))
+	for _, addr := range index {
+		acl, err := w.accessList(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		acl, _ = acl.withoutExpired(now)
+		if len(acl) > 0 {
+			snapshot[addr] = acl
+		}
+	}
+	return snapshot, nil
+}
+
+// ImportGrants adds the grants of the snapshot to the current ones, skipping those that expired meanwhile,
+// and returns how many grants were imported
+func (w *PrivacyWrapper) ImportGrants(ctx context.Context, snapshot GrantSnapshot) (int, error) {
+	now := uint64(time.Now().Unix())
+	imported := 0
+	for addr, acl := range snapshot {
+		for _, grant := range acl {
+			if grant.expired(now) {
+				continue
+			}
+			if err := w.grant(ctx, addr, grant); err != nil {
+				return imported, err
+			}
+			imported++
+		}
+	}
+	return imported, nil
+}
@@ -0,0 +1,288 @@
+package privacy
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+func TestAccessList(t *testing.T) {
+	var acl AccessList
+	acl = acl.add(AuthToken{Token: "auditor", Mask: CapabilityBalance})
+	acl = acl.add(AuthToken{Token: "owner", Mask: CapabilityAll})
+	acl = acl.add(AuthToken{Token: "indexer", Mask: CapabilityCalldata})
+
+	if !acl.Allows("auditor", CapabilityBalance) || acl.Allows("auditor", CapabilityNonce) {
+		t.Fatal("auditor should only see the balance")
+	}
+	if !acl.Allows("owner", CapabilityNonce) || !acl.Allows("indexer", CapabilityCalldata) {
+		t.Fatal("grant is missing")
+	}
+	if acl.Allows("", CapabilityBalance) || acl.Allows("stranger", CapabilityBalance) {
+		t.Fatal("unknown token is allowed")
+	}
+
+	// a second grant of the same token replaces the first one
+	acl = acl.add(AuthToken{Token: "auditor", Mask: CapabilityNonce})
+	if len(acl) != 3 || acl.Allows("auditor", CapabilityBalance) || !acl.Allows("auditor", CapabilityNonce) {
+		t.Fatal("grant is not replaced", acl)
+	}
+
+	acl, found := acl.revoke("owner")
+	if !found || acl.Allows("owner", CapabilityBalance) || !acl.Allows("indexer", CapabilityCalldata) {
+		t.Fatal("revoke failed", acl)
+	}
+	if _, found = acl.revoke("owner"); found {
+		t.Fatal("token revoked twice")
+	}
+
+	// entries from before access lists existed hold the raw token
+	legacy := decodeAccessList([]byte("legacy token"))
+	if !legacy.Allows("legacy token", CapabilityAll) {
+		t.Fatal("legacy token is not allowed")
+	}
+}
+
+func TestPrivacyAPIGrants(t *testing.T) {
+	ctx := context.Background()
+	ownerKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditorKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backends, err := json.Marshal([]backend{
+		{Name: "owner", PubKey: hexutil.Encode(crypto.FromECDSAPub(&ownerKey.PublicKey))},
+		{Name: "auditor", PubKey: hexutil.Encode(crypto.FromECDSAPub(&auditorKey.PublicKey)), Mask: CapabilityBalance},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := PrivacyRPCConfigDefault
+	config.Backends = string(backends)
+	wrapper, err := NewWrapper(&config, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := NewPrivacyAPI(wrapper)
+
+	deadline := uint64(time.Now().Add(time.Minute).Unix())
+	sign := func(key *ecdsa.PrivateKey, data ...[]byte) string {
+		data = append(data, arbmath.UintToBytes(deadline))
+		sig, err := crypto.Sign(crypto.Keccak256(data...), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hexutil.Encode(sig)
+	}
+	addr := common.HexToAddress("0x1234")
+	addresses := []string{addr.String()}
+	d := hexutil.Uint64(deadline)
+	noTtl := arbmath.UintToBytes(0)
+
+	setSig := sign(ownerKey, []byte(setTokenPrefix), []byte("owner token"), addressesBytes(addresses), noTtl)
+	_, err = api.SetToken(ctx, "owner token", addresses, 0, d, setSig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a captured signature can't be used twice
+	_, err = api.SetToken(ctx, "owner token", addresses, 0, d, setSig)
+	if err == nil {
+		t.Fatal("replayed signature accepted")
+	}
+	mask := hexutil.Uint64(CapabilityBalance)
+	_, err = api.GrantToken(ctx, "auditor token", addresses, mask, 0, d, sign(auditorKey, []byte(grantTokenPrefix), []byte("auditor token"), addressesBytes(addresses), arbmath.UintToBytes(uint64(mask)), noTtl))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the auditor backend may not grant more than the balance
+	mask = hexutil.Uint64(CapabilityAll)
+	_, err = api.GrantToken(ctx, "auditor token", addresses, mask, 0, d, sign(auditorKey, []byte(grantTokenPrefix), []byte("auditor token"), addressesBytes(addresses), arbmath.UintToBytes(uint64(mask)), noTtl))
+	if err == nil {
+		t.Fatal("grant beyond the backend mask succeeded")
+	}
+	// signature over other parameters
+	_, err = api.SetToken(ctx, "other token", addresses, 0, d, sign(ownerKey, []byte(setTokenPrefix), []byte("owner token"), addressesBytes(addresses), noTtl))
+	if err == nil {
+		t.Fatal("invalid signature accepted")
+	}
+	// signature of another method
+	_, err = api.SetToken(ctx, "auditor token", addresses, 0, d, sign(ownerKey, []byte(revokePrefix), []byte("auditor token"), addressesBytes(addresses)))
+	if err == nil {
+		t.Fatal("signature of another method accepted")
+	}
+
+	acl, err := api.ListGrants(ctx, addr.String(), d, sign(ownerKey, []byte(listGrantsPrefix), addr.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acl) != 2 || !acl.Allows("owner token", CapabilityAll) || !acl.Allows("auditor token", CapabilityBalance) || acl.Allows("auditor token", CapabilityNonce) {
+		t.Fatal("unexpected grants", acl)
+	}
+
+	rotated, err := api.RotateToken(ctx, "auditor token", "new auditor token", addresses, d, sign(ownerKey, []byte(rotatePrefix), []byte("auditor token"), []byte("new auditor token"), addressesBytes(addresses)))
+	if err != nil || rotated != 1 {
+		t.Fatal(rotated, err)
+	}
+	revoked, err := api.RevokeToken(ctx, "owner token", addresses, d, sign(ownerKey, []byte(revokePrefix), []byte("owner token"), addressesBytes(addresses)))
+	if err != nil || revoked != 1 {
+		t.Fatal(revoked, err)
+	}
+	acl, err = wrapper.accessList(ctx, addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acl.Allows("owner token", CapabilityBalance) || acl.Allows("auditor token", CapabilityBalance) || !acl.Allows("new auditor token", CapabilityBalance) {
+		t.Fatal("unexpected grants after rotate and revoke", acl)
+	}
+
+	// expired deadlines are rejected
+	deadline = uint64(time.Now().Add(-time.Second).Unix())
+	_, err = api.ListGrants(ctx, addr.String(), hexutil.Uint64(deadline), sign(ownerKey, []byte(listGrantsPrefix), addr.Bytes()))
+	if err == nil {
+		t.Fatal("expired signature accepted")
+	}
+}
+
+func TestGrantExpiration(t *testing.T) {
+	ctx := context.Background()
+	config := PrivacyRPCConfigDefault
+	wrapper, err := NewWrapper(&config, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := uint64(time.Now().Unix())
+	expiring := common.HexToAddress("0x01").String()
+	mixed := common.HexToAddress("0x02").String()
+	for _, grant := range []struct {
+		addr  string
+		grant AuthToken
+	}{
+		{expiring, AuthToken{Token: "old", Mask: CapabilityAll, ExpirationTime: now - 1}},
+		{mixed, AuthToken{Token: "old", Mask: CapabilityAll, ExpirationTime: now - 1}},
+		{mixed, AuthToken{Token: "forever", Mask: CapabilityAll}},
+	} {
+		if err := wrapper.grant(ctx, grant.addr, grant.grant); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	acl, err := wrapper.accessList(ctx, mixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acl.Allows("old", CapabilityBalance) || !acl.Allows("forever", CapabilityBalance) {
+		t.Fatal("expired grant is allowed", acl)
+	}
+
+	if err := wrapper.purgeExpired(ctx); err != nil {
+		t.Fatal(err)
+	}
+	acl, err = wrapper.accessList(ctx, expiring)
+	if err != nil || len(acl) != 0 {
+		t.Fatal("expired grants are not purged", acl, err)
+	}
+	acl, err = wrapper.accessList(ctx, mixed)
+	if err != nil || len(acl) != 1 {
+		t.Fatal("unexpected grants after purge", acl, err)
+	}
+	index, err := wrapper.grantIndex(ctx)
+	if err != nil || len(index) != 1 || index[0] != mixed {
+		t.Fatal("unexpected grant index", index, err)
+	}
+}
+
+func TestConcurrentGrantsSharedRedis(t *testing.T) {
+	ctx := context.Background()
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	config := PrivacyRPCConfigDefault
+	config.RedisCache.Enable = true
+	config.RedisCache.Url = "redis://" + server.Addr()
+	// two nodes sharing the same redis
+	var nodes []*PrivacyWrapper
+	for i := 0; i < 2; i++ {
+		wrapper, err := NewWrapper(&config, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes = append(nodes, wrapper)
+	}
+	addr := common.HexToAddress("0x03").String()
+	if err := nodes[0].grant(ctx, addr, AuthToken{Token: "revoked", Mask: CapabilityAll}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			node := nodes[i%len(nodes)]
+			if err := node.grant(ctx, addr, AuthToken{Token: fmt.Sprint("token ", i), Mask: CapabilityAll}); err != nil {
+				t.Error(err)
+			}
+			if i == 0 {
+				if _, err := node.revoke(ctx, addr, "revoked"); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	acl, err := nodes[1].accessList(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acl) != 16 || acl.Allows("revoked", CapabilityBalance) {
+		t.Fatal("concurrent updates are lost", acl)
+	}
+
+	// rotations and the grant index are shared the same way
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			node := nodes[i%len(nodes)]
+			if _, err := node.rotate(ctx, addr, fmt.Sprint("token ", i), fmt.Sprint("rotated ", i)); err != nil {
+				t.Error(err)
+			}
+			other := common.BigToAddress(big.NewInt(int64(0x100 + i))).String()
+			if err := node.grant(ctx, other, AuthToken{Token: "token", Mask: CapabilityAll}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	acl, err = nodes[0].accessList(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 16; i++ {
+		if !acl.Allows(fmt.Sprint("rotated ", i), CapabilityBalance) || acl.Allows(fmt.Sprint("token ", i), CapabilityBalance) {
+			t.Fatal("concurrent rotations are lost", acl)
+		}
+	}
+	index, err := nodes[1].grantIndex(ctx)
+	if err != nil || len(index) != 17 {
+		t.Fatal("grant index lost addresses", index, err)
+	}
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"encoding/json"
+	"time"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/offchainlabs/nitro/util/arbmath"
 )
 
 type PrivacyAPI struct {
@@ -21,6 +25,25 @@ type backend struct {
 	Mask   uint64 `json:"mask"`
 }
 
+// Every signed request commits to its method, so a signature can't be replayed on another one
+const (
+	setTokenPrefix     = "privacy_setToken"
+	grantTokenPrefix   = "privacy_grantToken"
+	revokePrefix       = "privacy_revokeToken"
+	rotatePrefix       = "privacy_rotateToken"
+	listGrantsPrefix   = "privacy_listGrants"
+	usedSignatureKey   = "privacy used signature "
+	usedSignatureSlack = uint64(1)
+)
+
+// allowedMask returns the capabilities the backend may grant, a zero mask places no restriction
+func (b *backend) allowedMask() uint64 {
+	if b.Mask == 0 {
+		return CapabilityAll
+	}
+	return b.Mask & CapabilityAll
+}
+
 func NewPrivacyAPI(wrapper *PrivacyWrapper) *PrivacyAPI {
 	var backends []backend
 	_ = json.Unmarshal([]byte(wrapper.config.Backends), &backends)
@@ -37,7 +60,9 @@ func NewPrivacyAPI(wrapper *PrivacyWrapper) *PrivacyAPI {
 	}
 }
 
-func (p *PrivacyAPI) SetToken(ctx context.Context, token string, addresses []string, sig string) (interface{}, error) {
+// SetToken grants the token every capability the signing backend may grant on the addresses,
+// for ttl seconds or forever if ttl is 0. The signature is only valid until deadline.
+func (p *PrivacyAPI) SetToken(ctx context.Context, token string, addresses []string, ttl hexutil.Uint64, deadline hexutil.Uint64, sig string) (interface{}, error) {
 	if !p.healthCheck(ctx) {
 		return nil, NewApiServiceError("PrivacyAPI service is not ok")
 	}
@@ -46,34 +71,126 @@ func (p *PrivacyAPI) SetToken(ctx context.Context, token string, addresses []str
 	}
 
 	// check signature
-	var addressesBytes = make([]byte, len(addresses)*common.AddressLength)
-	for i, addr := range addresses {
-		copy(addressesBytes[i*common.AddressLength:], common.HexToAddress(addr).Bytes())
+	signer, err := p.verify(ctx, uint64(deadline), sig,
+		[]byte(setTokenPrefix), []byte(token), addressesBytes(addresses), arbmath.UintToBytes(uint64(ttl)))
+	if err != nil {
+		return nil, err
 	}
-	hash := crypto.Keccak256([]byte(token), addressesBytes)
-	if !p.validSignature(ctx, hash, sig) {
-		return nil, NewSignatureVerificationFailedError("signature is not valid")
+	return p.set(ctx, token, addresses, signer.allowedMask(), uint64(ttl))
+}
+
+// GrantToken grants the token the capabilities in mask on the addresses, next to any other token granted there
+func (p *PrivacyAPI) GrantToken(ctx context.Context, token string, addresses []string, mask hexutil.Uint64, ttl hexutil.Uint64, deadline hexutil.Uint64, sig string) (interface{}, error) {
+	if !p.healthCheck(ctx) {
+		return nil, NewApiServiceError("PrivacyAPI service is not ok")
+	}
+	if token == "" || len(addresses) == 0 {
+		return nil, NewApiServiceError("token or address is empty")
+	}
+	if mask == 0 || uint64(mask)&^CapabilityAll != 0 {
+		return nil, NewApiServiceError("mask is not valid")
+	}
+
+	// check signature
+	signer, err := p.verify(ctx, uint64(deadline), sig,
+		[]byte(grantTokenPrefix), []byte(token), addressesBytes(addresses), arbmath.UintToBytes(uint64(mask)), arbmath.UintToBytes(uint64(ttl)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(mask)&^signer.allowedMask() != 0 {
+		return nil, NewSetTokenFailedError("backend is not allowed to grant the mask")
+	}
+	return p.set(ctx, token, addresses, uint64(mask), uint64(ttl))
+}
+
+// RevokeToken removes the token from the access lists of the addresses, leaving other grants in place
+func (p *PrivacyAPI) RevokeToken(ctx context.Context, token string, addresses []string, deadline hexutil.Uint64, sig string) (interface{}, error) {
+	if !p.healthCheck(ctx) {
+		return nil, NewApiServiceError("PrivacyAPI service is not ok")
+	}
+	if token == "" || len(addresses) == 0 {
+		return nil, NewApiServiceError("token or address is empty")
+	}
+
+	// check signature
+	if _, err := p.verify(ctx, uint64(deadline), sig, []byte(revokePrefix), []byte(token), addressesBytes(addresses)); err != nil {
+		return nil, err
+	}
+	revoked := 0
+	for _, addr := range addresses {
+		found, err := p.wrapper.revoke(ctx, common.HexToAddress(addr).String(), token)
+		if err != nil {
+			return nil, NewSetTokenFailedError("PrivacyAPI: revoke token failed")
+		}
+		if found {
+			revoked++
+		}
 	}
-	return p.set(ctx, token, addresses)
+	return revoked, nil
 }
 
-//func (p *PrivacyAPI) UpdateToken(ctx context.Context, token string, addresses []string, sig string) (interface{}, error) {
-//	if !p.healthCheck(ctx) {
-//		return nil, NewApiServiceError("PrivacyAPI service is not ok")
-//	}
-//	if token == "" || len(addresses) == 0 {
-//		return nil, NewApiServiceError("token or address is empty")
-//	}
-//	return p.set(ctx, token, addresses)
-//}
+// RotateToken replaces the old token with the new one on the addresses, keeping the capabilities and expiration of the grants
+func (p *PrivacyAPI) RotateToken(ctx context.Context, oldToken string, newToken string, addresses []string, deadline hexutil.Uint64, sig string) (interface{}, error) {
+	if !p.healthCheck(ctx) {
+		return nil, NewApiServiceError("PrivacyAPI service is not ok")
+	}
+	if oldToken == "" || newToken == "" || len(addresses) == 0 {
+		return nil, NewApiServiceError("token or address is empty")
+	}
 
-func (p *PrivacyAPI) set(ctx context.Context, token string, addresses []string) (interface{}, error) {
+	// check signature
+	if _, err := p.verify(ctx, uint64(deadline), sig,
+		[]byte(rotatePrefix), []byte(oldToken), []byte(newToken), addressesBytes(addresses)); err != nil {
+		return nil, err
+	}
+	rotated := 0
+	for _, addr := range addresses {
+		found, err := p.wrapper.rotate(ctx, common.HexToAddress(addr).String(), oldToken, newToken)
+		if err != nil {
+			return nil, NewSetTokenFailedError("PrivacyAPI: rotate token failed")
+		}
+		if found {
+			rotated++
+		}
+	}
+	return rotated, nil
+}
+
+// ListGrants returns every grant on the address that has not expired yet
+func (p *PrivacyAPI) ListGrants(ctx context.Context, address string, deadline hexutil.Uint64, sig string) (AccessList, error) {
+	if !p.healthCheck(ctx) {
+		return nil, NewApiServiceError("PrivacyAPI service is not ok")
+	}
+
+	// check signature
+	addr := common.HexToAddress(address)
+	if _, err := p.verify(ctx, uint64(deadline), sig, []byte(listGrantsPrefix), addr.Bytes()); err != nil {
+		return nil, err
+	}
+	acl, err := p.wrapper.accessList(ctx, addr.String())
+	if err != nil {
+		return nil, NewGetTokenFailedError("PrivacyAPI: list grants failed")
+	}
+	acl, _ = acl.withoutExpired(uint64(time.Now().Unix()))
+	return acl, nil
+}
+
+func (p *PrivacyAPI) set(ctx context.Context, token string, addresses []string, mask uint64, ttl uint64) (interface{}, error) {
+	now := uint64(time.Now().Unix())
+	grant := AuthToken{
+		Token:        token,
+		Mask:         mask,
+		CreationTime: now,
+	}
+	if ttl != 0 {
+		grant.ExpirationTime = now + ttl
+	}
 	for _, addr := range addresses {
 		if addr == "" {
 			return nil, NewSetTokenFailedError("address is empty")
 		}
 		addr = common.HexToAddress(addr).String()
-		if err := p.wrapper.cache.Set(ctx, addr, []byte(token), uint64(0)); err != nil {
+		if err := p.wrapper.grant(ctx, addr, grant); err != nil {
 			return nil, NewSetTokenFailedError("PrivacyAPI: set token failed")
 		}
 	}
@@ -92,27 +209,119 @@ func (p *PrivacyAPI) GetToken(ctx context.Context, token string, addresses []str
 	return nil, NewGetTokenFailedError("PrivacyAPI: method not allowed")
 }
 
+// ReceiptSigner returns the address redaction receipts are signed by, for clients to check them against
+func (p *PrivacyAPI) ReceiptSigner(ctx context.Context) (common.Address, error) {
+	if p.wrapper.receiptSigner == nil {
+		return common.Address{}, NewApiServiceError("PrivacyAPI: redaction receipts are disabled")
+	}
+	return p.wrapper.receiptSignerAddress, nil
+}
+
+// Challenge returns a sign in with ethereum (EIP-4361) message for the owner of the address to sign
+func (p *PrivacyAPI) Challenge(ctx context.Context, address common.Address) (string, error) {
+	if !p.wrapper.config.Auth.Siwe {
+		return "", NewApiServiceError("PrivacyAPI: sign in with ethereum is disabled")
+	}
+	if !p.healthCheck(ctx) {
+		return "", NewApiServiceError("PrivacyAPI service is not ok")
+	}
+	message, err := p.wrapper.challenge(address)
+	if err != nil {
+		return "", NewApiServiceError("PrivacyAPI: challenge failed")
+	}
+	return message, nil
+}
+
+// Login exchanges a challenge signed with personal_sign for a session token, sent as the privacy token
+// it lets the owner read the data of the address until the session expires
+func (p *PrivacyAPI) Login(ctx context.Context, message string, sig hexutil.Bytes) (string, error) {
+	if !p.wrapper.config.Auth.Siwe {
+		return "", NewApiServiceError("PrivacyAPI: sign in with ethereum is disabled")
+	}
+	token, owner, err := p.wrapper.login(ctx, message, sig)
+	if err != nil {
+		return "", NewSignatureVerificationFailedError(err.Error())
+	}
+	log.Debug("privacy sign in", "address", owner)
+	return token, nil
+}
+
+// Logout ends the session of the token
+func (p *PrivacyAPI) Logout(ctx context.Context, session string) (bool, error) {
+	if !p.wrapper.config.Auth.Siwe {
+		return false, NewApiServiceError("PrivacyAPI: sign in with ethereum is disabled")
+	}
+	if err := p.wrapper.logout(ctx, session); err != nil {
+		return false, NewApiServiceError("PrivacyAPI: logout failed")
+	}
+	return true, nil
+}
+
 func (p *PrivacyAPI) healthCheck(ctx context.Context) bool {
 	//return false
 	return p.wrapper.cache.HealthCheck(ctx)
 }
 
-func (p *PrivacyAPI) validSignature(ctx context.Context, hash []byte, sig string) bool {
+func addressesBytes(addresses []string) []byte {
+	var res = make([]byte, len(addresses)*common.AddressLength)
+	for i, addr := range addresses {
+		copy(res[i*common.AddressLength:], common.HexToAddress(addr).Bytes())
+	}
+	return res
+}
+
+// verify checks that a configured backend signed the request data followed by the deadline,
+// that the deadline has not passed, and that the signature was not used before
+func (p *PrivacyAPI) verify(ctx context.Context, deadline uint64, sig string, data ...[]byte) (*backend, error) {
+	now := uint64(time.Now().Unix())
+	if deadline < now {
+		return nil, NewSignatureVerificationFailedError("signature deadline passed")
+	}
+	if deadline > now+uint64(p.wrapper.config.SignatureWindow.Seconds()) {
+		return nil, NewSignatureVerificationFailedError("signature deadline is too far in the future")
+	}
+	hash := crypto.Keccak256(append(data, arbmath.UintToBytes(deadline))...)
+	signer := p.signer(ctx, hash, sig)
+	if signer == nil {
+		return nil, NewSignatureVerificationFailedError("signature is not valid")
+	}
+
+	// remember the signature until its deadline passed, it is rejected by the deadline check from then on
+	// in one step, two requests with the same signature must not both see it unused
+	key := usedSignatureKey + hexutil.Encode(hash)
+	unused, err := p.wrapper.cache.SetNX(ctx, key, []byte{1}, deadline-now+usedSignatureSlack)
+	if err != nil {
+		return nil, NewApiServiceError("PrivacyAPI: replay check failed")
+	}
+	if !unused {
+		return nil, NewSignatureVerificationFailedError("signature was already used")
+	}
+	return signer, nil
+}
+
+// signer returns the backend whose key signed the hash, or nil if none did
+func (p *PrivacyAPI) signer(ctx context.Context, hash []byte, sig string) *backend {
 	select {
 	case <-ctx.Done():
-		return false
+		return nil
 	default:
 		sigBytes, err := hexutil.Decode(sig)
+		if err != nil {
+			return nil
+		}
 		pub, err := crypto.Ecrecover(hash, sigBytes)
+		if err != nil {
+			return nil
+		}
 		pubkey, err := crypto.UnmarshalPubkey(pub)
 		if err != nil {
-			return false
+			return nil
 		}
-		for _, key := range p.pubKeys {
-			if key.Equal(pubkey) {
-				return true
+		for i, key := range p.pubKeys {
+			if key != nil && key.Equal(pubkey) {
+				return &p.backends[i]
 			}
 		}
-		return false
+		return nil
 	}
 }
@@ -0,0 +1,224 @@
+package privacy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	flag "github.com/spf13/pflag"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+type AuditConfig struct {
+	Enable         bool          `koanf:"enable"`
+	File           string        `koanf:"file"`
+	MaxSize        int           `koanf:"max-size"`
+	MaxAge         int           `koanf:"max-age"`
+	MaxBackups     int           `koanf:"max-backups"`
+	Compress       bool          `koanf:"compress"`
+	WebhookURL     string        `koanf:"webhook-url"`
+	WebhookTimeout time.Duration `koanf:"webhook-timeout"`
+	QueueSize      int           `koanf:"queue-size"`
+}
+
+var AuditConfigDefault = AuditConfig{
+	Enable:         false,
+	File:           "privacy-audit.log",
+	MaxSize:        100, // 100Mb
+	MaxAge:         0,   // don't remove old files based on age
+	MaxBackups:     20,
+	Compress:       true,
+	WebhookURL:     "",
+	WebhookTimeout: time.Second * 5,
+	QueueSize:      1024,
+}
+
+func AuditConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", AuditConfigDefault.Enable, "record every access to protected data")
+	f.String(prefix+".file", AuditConfigDefault.File, "path to the audit file (empty to only use the webhook)")
+	f.Int(prefix+".max-size", AuditConfigDefault.MaxSize, "audit file size in Mb that will trigger file rotation (0 = trigger disabled)")
+	f.Int(prefix+".max-age", AuditConfigDefault.MaxAge, "maximum number of days to retain old audit files based on the timestamp encoded in their filename (0 = no limit)")
+	f.Int(prefix+".max-backups", AuditConfigDefault.MaxBackups, "maximum number of old audit files to retain (0 = no limit)")
+	f.Bool(prefix+".compress", AuditConfigDefault.Compress, "enable compression of old audit files")
+	f.String(prefix+".webhook-url", AuditConfigDefault.WebhookURL, "url every audit record is posted to as json (empty to disable)")
+	f.Duration(prefix+".webhook-timeout", AuditConfigDefault.WebhookTimeout, "how long the webhook has to accept a record")
+	f.Int(prefix+".queue-size", AuditConfigDefault.QueueSize, "number of audit records waiting to be written before new ones are dropped")
+}
+
+var auditDroppedCounter = metrics.NewRegisteredCounter("arb/privacy/audit/dropped", nil)
+
+// Outcomes of an access to protected data
+const (
+	AuditGranted  = "granted"
+	AuditDenied   = "denied"
+	AuditRedacted = "redacted"
+)
+
+// AuditRecord is an access to the protected data of an address, Token is a fingerprint of the caller token
+type AuditRecord struct {
+	Time         time.Time `json:"time"`
+	Method       string    `json:"method"`
+	Address      string    `json:"address,omitempty"`
+	Capability   uint64    `json:"capability,omitempty"`
+	Token        string    `json:"token,omitempty"`
+	ClientIP     string    `json:"clientIp"`
+	ForwardedFor string    `json:"forwardedFor,omitempty"`
+	Outcome      string    `json:"outcome"`
+}
+
+// access is a visibility check done while filtering a message
+type access struct {
+	address    string
+	capability uint64
+	allowed    bool
+}
+
+// tokenFingerprint identifies a token in the audit trail without revealing it
+func tokenFingerprint(token string) string {
+	if token == "" {
+		return ""
+	}
+	return hexutil.Encode(crypto.Keccak256([]byte(token))[:8])
+}
+
+// clientIP returns the address the request came from, and the addresses it claims to be forwarded for
+func clientIP(r *http.Request) (string, string) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host, r.Header.Get("X-Forwarded-For")
+}
+
+// auditor writes the audit records to its sinks in the background
+type auditor struct {
+	config  *AuditConfig
+	records chan *AuditRecord
+	file    *lumberjack.Logger
+	client  *http.Client
+}
+
+func newAuditor(config *AuditConfig) *auditor {
+	a := &auditor{
+		config:  config,
+		records: make(chan *AuditRecord, config.QueueSize),
+		client:  &http.Client{Timeout: config.WebhookTimeout},
+	}
+	if config.File != "" {
+		a.file = &lumberjack.Logger{
+			Filename:   config.File,
+			MaxSize:    config.MaxSize,
+			MaxBackups: config.MaxBackups,
+			MaxAge:     config.MaxAge,
+			Compress:   config.Compress,
+		}
+	}
+	return a
+}
+
+// record queues the record, dropping it if the sinks fall behind rather than slowing the rpc down
+func (a *auditor) record(record *AuditRecord) {
+	select {
+	case a.records <- record:
+	default:
+		auditDroppedCounter.Inc(1)
+	}
+}
+
+// run writes the queued records until the context is done
+func (a *auditor) run(ctx context.Context) {
+	defer func() {
+		if a.file != nil {
+			_ = a.file.Close()
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case record := <-a.records:
+			a.write(ctx, record)
+		}
+	}
+}
+
+func (a *auditor) write(ctx context.Context, record *AuditRecord) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	if a.file != nil {
+		if _, err := a.file.Write(append(data, '\n')); err != nil {
+			log.Warn("failed to write privacy audit record", "err", err)
+		}
+	}
+	if a.config.WebhookURL != "" {
+		if err := a.post(ctx, data); err != nil {
+			log.Warn("failed to post privacy audit record", "url", a.config.WebhookURL, "err", err)
+		}
+	}
+}
+
+func (a *auditor) post(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.config.WebhookURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("status %v", res.Status)
+	}
+	return nil
+}
+
+// audit records the accesses made while filtering the response to method, the response itself tells
+// whether the hidden data was denied or redacted
+func (pw *PrivacyResponseWriter) audit(method string, denied bool) {
+	w := CurrentWrapper()
+	if w == nil || w.auditor == nil {
+		return
+	}
+	hidden := AuditRedacted
+	if denied {
+		hidden = AuditDenied
+	}
+	newRecord := func(address string, capability uint64, outcome string) *AuditRecord {
+		return &AuditRecord{
+			Time:         time.Now(),
+			Method:       method,
+			Address:      address,
+			Capability:   capability,
+			Token:        tokenFingerprint(pw.token),
+			ClientIP:     pw.clientIP,
+			ForwardedFor: pw.forwardedFor,
+			Outcome:      outcome,
+		}
+	}
+	if len(pw.accesses) == 0 && denied {
+		// methods denied outright, such as traces
+		w.auditor.record(newRecord("", 0, AuditDenied))
+		return
+	}
+	for _, a := range pw.accesses {
+		outcome := AuditGranted
+		if !a.allowed {
+			outcome = hidden
+		}
+		w.auditor.record(newRecord(a.address, a.capability, outcome))
+	}
+}
@@ -0,0 +1,84 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestAuditTrail(t *testing.T) {
+	handler := newTestMiddleware(t, map[string]interface{}{
+		"eth_getCode":            "0x60",
+		"debug_traceTransaction": map[string]interface{}{},
+	})
+
+	posted := make(chan AuditRecord, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var record AuditRecord
+		if err := json.Unmarshal(body, &record); err != nil {
+			t.Error(err)
+		}
+		posted <- record
+	}))
+	defer webhook.Close()
+
+	config := AuditConfigDefault
+	config.File = filepath.Join(t.TempDir(), "audit.log")
+	config.WebhookURL = webhook.URL
+	a := newAuditor(&config)
+	CurrentWrapper().auditor = a
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		a.run(ctx)
+		close(done)
+	}()
+
+	callTestMiddleware(t, handler, "logs token", "eth_getCode", protectedAddr, "latest")
+	callTestMiddleware(t, handler, "", "eth_getCode", publicAddr, "latest")
+	callTestMiddleware(t, handler, "", "debug_traceTransaction", common.Hash{})
+
+	var records []AuditRecord
+	for len(records) < 2 {
+		select {
+		case record := <-posted:
+			records = append(records, record)
+		case <-time.After(5 * time.Second):
+			t.Fatal("audit records are not posted", records)
+		}
+	}
+	cancel()
+	<-done
+
+	// the access to the unlisted address is not audited
+	if records[0].Method != "eth_getCode" || records[0].Address != protectedAddr.String() || records[0].Outcome != AuditDenied {
+		t.Fatal("unexpected audit record", records[0])
+	}
+	if records[0].Token != tokenFingerprint("logs token") || records[0].ClientIP == "" {
+		t.Fatal("audit record does not identify the caller", records[0])
+	}
+	if records[1].Method != "debug_traceTransaction" || records[1].Outcome != AuditDenied {
+		t.Fatal("unexpected audit record", records[1])
+	}
+
+	data, err := os.ReadFile(config.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 {
+		t.Fatal("unexpected audit file", string(data))
+	}
+	if strings.Contains(string(data), "logs token") {
+		t.Fatal("token is written to the audit file")
+	}
+}
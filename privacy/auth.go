@@ -0,0 +1,366 @@
+package privacy
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/golang-jwt/jwt/v4"
+	flag "github.com/spf13/pflag"
+)
+
+// AuthConfig configures the ways a caller can prove it may read the data of addresses without holding a grant:
+// a jwt naming the addresses, signed with the jwt secret (HS256) or the key of the public key file (ES256),
+// or a session obtained by signing in with the address (EIP-4361)
+type AuthConfig struct {
+	JwtPublicKey      string        `koanf:"jwt-public-key"`
+	Siwe              bool          `koanf:"siwe"`
+	Domain            string        `koanf:"domain"`
+	URI               string        `koanf:"uri"`
+	ChainID           uint64        `koanf:"chain-id"`
+	ChallengeLifetime time.Duration `koanf:"challenge-lifetime"`
+	MaxChallenges     int           `koanf:"max-challenges"`
+	SessionLifetime   time.Duration `koanf:"session-lifetime"`
+}
+
+var AuthConfigDefault = AuthConfig{
+	JwtPublicKey:      "",
+	Siwe:              false,
+	Domain:            "localhost",
+	URI:               "http://localhost",
+	ChainID:           0,
+	ChallengeLifetime: time.Minute * 5,
+	MaxChallenges:     10000,
+	SessionLifetime:   time.Hour,
+}
+
+func AuthConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.String(prefix+".jwt-public-key", AuthConfigDefault.JwtPublicKey, "path to the PEM file of the ecdsa public key ES256 privacy jwts are verified with (empty to disable)")
+	f.Bool(prefix+".siwe", AuthConfigDefault.Siwe, "let address owners sign in with ethereum (EIP-4361) to read their own data")
+	f.String(prefix+".domain", AuthConfigDefault.Domain, "domain asking for the sign in, as it appears in the message")
+	f.String(prefix+".uri", AuthConfigDefault.URI, "uri the sign in is for, as it appears in the message")
+	f.Uint64(prefix+".chain-id", AuthConfigDefault.ChainID, "chain id in the sign in message (0 to use the chain id of the node)")
+	f.Duration(prefix+".challenge-lifetime", AuthConfigDefault.ChallengeLifetime, "how long a sign in message may be signed for")
+	f.Int(prefix+".max-challenges", AuthConfigDefault.MaxChallenges, "how many sign in messages may wait to be signed at once, they are kept in memory by the node that issued them")
+	f.Duration(prefix+".session-lifetime", AuthConfigDefault.SessionLifetime, "how long a session token lasts after signing in")
+}
+
+const (
+	siweSessionKey           = "privacy siwe session "
+	siweSessionPrefix        = "siwe:"
+	siweStatement            = "Sign in to read the private data of this account."
+	siweNonceLinePrefix      = "Nonce: "
+	siweExpirationLinePrefix = "Expiration Time: "
+)
+
+// viewer is an authenticated caller, who may see the capabilities in mask of the addresses whatever their grants,
+// an admin may also run the methods that can read any state
+type viewer struct {
+	addresses map[string]struct{}
+	mask      uint64
+	admin     bool
+}
+
+func newViewer(addresses []string, mask uint64) *viewer {
+	if mask == 0 {
+		mask = CapabilityAll
+	}
+	v := &viewer{
+		addresses: make(map[string]struct{}, len(addresses)),
+		mask:      mask,
+	}
+	for _, addr := range addresses {
+		v.addresses[common.HexToAddress(addr).String()] = struct{}{}
+	}
+	return v
+}
+
+func (v *viewer) allows(addr string, capability uint64) bool {
+	if v == nil || v.mask&capability != capability {
+		return false
+	}
+	_, ok := v.addresses[common.HexToAddress(addr).String()]
+	return ok
+}
+
+func (v *viewer) isAdmin() bool {
+	return v != nil && v.admin
+}
+
+// ViewerClaims are the claims of a privacy jwt, a zero mask allows every capability
+type ViewerClaims struct {
+	Addresses []string `json:"addresses"`
+	Mask      uint64   `json:"mask,omitempty"`
+	Admin     bool     `json:"admin,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// siweSession is kept for every session token until it expires, the store may keep it for longer
+type siweSession struct {
+	Address common.Address `json:"address"`
+	Expiry  int64          `json:"expiry"` // unix time
+}
+
+// siweChallenge is a sign in message waiting to be signed
+type siweChallenge struct {
+	message string
+	expiry  time.Time
+}
+
+// loadJwtKeys reads the HS256 secret, treated as a hex string if prefixed with 0x otherwise treated as a file,
+// and the ES256 public key
+func loadJwtKeys(config *PrivacyConfig) ([]byte, *ecdsa.PublicKey, error) {
+	var secret []byte
+	if config.JwtSecret != "" {
+		hexSecret := config.JwtSecret
+		if !strings.HasPrefix(hexSecret, "0x") {
+			data, err := os.ReadFile(hexSecret)
+			if err != nil {
+				return nil, nil, err
+			}
+			hexSecret = strings.TrimSpace(string(data))
+		}
+		var err error
+		secret, err = hexutil.Decode(hexSecret)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid privacy jwt secret: %w", err)
+		}
+		if len(secret) < 32 {
+			return nil, nil, errors.New("privacy jwt secret is shorter than 32 bytes")
+		}
+	}
+	var publicKey *ecdsa.PublicKey
+	if config.Auth.JwtPublicKey != "" {
+		data, err := os.ReadFile(config.Auth.JwtPublicKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		publicKey, err = jwt.ParseECPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid privacy jwt public key: %w", err)
+		}
+	}
+	return secret, publicKey, nil
+}
+
+// looksLikeJwt tells a jwt from a plain token by its three dot separated parts
+func looksLikeJwt(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// authenticate returns the viewer the token proves the caller is, or nil if it is a plain token
+func (w *PrivacyWrapper) authenticate(ctx context.Context, token string) (*viewer, error) {
+	if strings.HasPrefix(token, siweSessionPrefix) {
+		if !w.config.Auth.Siwe {
+			return nil, nil
+		}
+		data, err := w.cache.Get(ctx, siweSessionKey+token)
+		if err != nil {
+			if isNotFound(err) {
+				return nil, errors.New("unknown or expired session")
+			}
+			return nil, err
+		}
+		var session siweSession
+		if err := json.Unmarshal(data, &session); err != nil {
+			return nil, err
+		}
+		if time.Now().Unix() >= session.Expiry {
+			return nil, errors.New("unknown or expired session")
+		}
+		return newViewer([]string{session.Address.String()}, CapabilityAll), nil
+	}
+	if !looksLikeJwt(token) || (w.jwtSecret == nil && w.jwtPublicKey == nil) {
+		return nil, nil
+	}
+	claims := &ViewerClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			if w.jwtSecret != nil {
+				return w.jwtSecret, nil
+			}
+		case *jwt.SigningMethodECDSA:
+			if w.jwtPublicKey != nil {
+				return w.jwtPublicKey, nil
+			}
+		}
+		return nil, fmt.Errorf("no key for signing method %v", t.Method.Alg())
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodES256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	// only short lived tokens are accepted, a leaked one can't be revoked
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("privacy jwt has no expiry")
+	}
+	if claims.Mask&^CapabilityAll != 0 {
+		return nil, errors.New("privacy jwt mask is not valid")
+	}
+	v := newViewer(claims.Addresses, claims.Mask)
+	v.admin = claims.Admin
+	return v, nil
+}
+
+// siweMessage is the EIP-4361 message the owner of the address signs to sign in
+func siweMessage(config *AuthConfig, address common.Address, nonce string, issuedAt time.Time) string {
+	return fmt.Sprintf("%s wants you to sign in with your Ethereum account:\n%s\n\n%s\n\nURI: %s\nVersion: 1\nChain ID: %d\n%s%s\nIssued At: %s\n%s%s",
+		config.Domain, address.String(), siweStatement, config.URI, config.ChainID, siweNonceLinePrefix, nonce,
+		issuedAt.UTC().Format(time.RFC3339), siweExpirationLinePrefix, issuedAt.Add(config.ChallengeLifetime).UTC().Format(time.RFC3339))
+}
+
+// siweField returns the value of the line of the message starting with prefix
+func siweField(message string, prefix string) string {
+	for _, line := range strings.Split(message, "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix)
+		}
+	}
+	return ""
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hexutil.Encode(b)[2:], nil
+}
+
+// challenge returns a new sign in message for the address, it can only be used once.
+// Anybody may ask for one, so they are only kept in memory and their number is bounded.
+func (w *PrivacyWrapper) challenge(address common.Address) (string, error) {
+	nonce, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	message := siweMessage(&w.config.Auth, address, nonce, now)
+
+	w.challengeMutex.Lock()
+	defer w.challengeMutex.Unlock()
+	if len(w.challenges) >= w.config.Auth.MaxChallenges {
+		for n, c := range w.challenges {
+			if !now.Before(c.expiry) {
+				delete(w.challenges, n)
+			}
+		}
+		if len(w.challenges) >= w.config.Auth.MaxChallenges {
+			return "", errors.New("too many pending sign in messages")
+		}
+	}
+	w.challenges[nonce] = siweChallenge{
+		message: message,
+		expiry:  now.Add(w.config.Auth.ChallengeLifetime),
+	}
+	return message, nil
+}
+
+// pendingChallenge returns the sign in message of the nonce unless it expired
+func (w *PrivacyWrapper) pendingChallenge(nonce string) (string, bool) {
+	w.challengeMutex.Lock()
+	defer w.challengeMutex.Unlock()
+	c, ok := w.challenges[nonce]
+	if !ok {
+		return "", false
+	}
+	if !time.Now().Before(c.expiry) {
+		delete(w.challenges, nonce)
+		return "", false
+	}
+	return c.message, true
+}
+
+// login checks that the message was issued by challenge and signed (EIP-191) by the address it names,
+// and returns a session token for that address
+func (w *PrivacyWrapper) login(ctx context.Context, message string, sig []byte) (string, common.Address, error) {
+	nonce := siweField(message, siweNonceLinePrefix)
+	if nonce == "" {
+		return "", common.Address{}, errors.New("message has no nonce")
+	}
+	issued, ok := w.pendingChallenge(nonce)
+	if !ok {
+		return "", common.Address{}, errors.New("unknown or expired sign in message")
+	}
+	if issued != message {
+		return "", common.Address{}, errors.New("sign in message was altered")
+	}
+	expiration, err := time.Parse(time.RFC3339, siweField(message, siweExpirationLinePrefix))
+	if err != nil || !time.Now().Before(expiration) {
+		return "", common.Address{}, errors.New("sign in message expired")
+	}
+	if len(sig) != crypto.SignatureLength {
+		return "", common.Address{}, errors.New("signature is not valid")
+	}
+	sig = common.CopyBytes(sig)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pubKey, err := crypto.SigToPub(accounts.TextHash([]byte(message)), sig)
+	if err != nil {
+		return "", common.Address{}, errors.New("signature is not valid")
+	}
+	// the second line of the message names the address
+	lines := strings.SplitN(message, "\n", 3)
+	if len(lines) < 2 || crypto.PubkeyToAddress(*pubKey) != common.HexToAddress(lines[1]) {
+		return "", common.Address{}, errors.New("message is not signed by its address")
+	}
+	// the message can be signed in with once, whoever got here first
+	w.challengeMutex.Lock()
+	_, unused := w.challenges[nonce]
+	delete(w.challenges, nonce)
+	w.challengeMutex.Unlock()
+	if !unused {
+		return "", common.Address{}, errors.New("unknown or expired sign in message")
+	}
+
+	random, err := randomHex(32)
+	if err != nil {
+		return "", common.Address{}, err
+	}
+	token := siweSessionPrefix + random
+	owner := crypto.PubkeyToAddress(*pubKey)
+	lifetime := w.config.Auth.SessionLifetime
+	data, err := json.Marshal(&siweSession{Address: owner, Expiry: time.Now().Add(lifetime).Unix()})
+	if err != nil {
+		return "", common.Address{}, err
+	}
+	if err := w.cache.Set(ctx, siweSessionKey+token, data, uint64(lifetime.Seconds())); err != nil {
+		return "", common.Address{}, err
+	}
+	return token, owner, nil
+}
+
+// logout ends the session before it expires
+func (w *PrivacyWrapper) logout(ctx context.Context, token string) error {
+	if !strings.HasPrefix(token, siweSessionPrefix) {
+		return errors.New("not a session token")
+	}
+	return w.cache.Delete(ctx, siweSessionKey+token)
+}
+
+// authenticate resolves the viewer of the request token once, a token that fails to authenticate
+// is still checked against the grants as a plain token
+func (pw *PrivacyResponseWriter) authenticate(ctx context.Context) {
+	w := CurrentWrapper()
+	if !pw.hasToken || w == nil {
+		return
+	}
+	v, err := w.authenticate(ctx, pw.token)
+	if err != nil {
+		log.Debug("privacy token did not authenticate", "token", tokenFingerprint(pw.token), "err", err)
+		return
+	}
+	pw.viewer = v
+}
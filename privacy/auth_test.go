@@ -0,0 +1,190 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestJwtAndSiwe(t *testing.T) {
+	ctx := context.Background()
+	secret := common.HexToHash("0x1234").Bytes()
+	config := PrivacyRPCConfigDefault
+	config.Enable = true
+	config.ProtectUnlisted = true
+	config.JwtSecret = hexutil.Encode(secret)
+	config.Auth.Siwe = true
+	config.Auth.ChainID = 412346
+	config.Auth.MaxChallenges = 4
+	handler := newConfiguredTestMiddleware(t, map[string]interface{}{
+		"eth_getBalance":          "0x64",
+		"eth_getTransactionCount": "0x1",
+	}, &config, nil)
+	api := NewPrivacyAPI(CurrentWrapper())
+
+	newJwt := func(method jwt.SigningMethod, key interface{}, claims *ViewerClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	claims := func(mask uint64, expiry time.Duration) *ViewerClaims {
+		c := &ViewerClaims{Addresses: []string{protectedAddr.String()}, Mask: mask}
+		if expiry != 0 {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(expiry))
+		}
+		return c
+	}
+
+	res := callTestMiddleware(t, handler, "", "eth_getBalance", protectedAddr, "latest")
+	if res.Error == nil {
+		t.Fatal("balance of protected address is visible without a token")
+	}
+	token := newJwt(jwt.SigningMethodHS256, secret, claims(0, time.Minute))
+	res = callTestMiddleware(t, handler, token, "eth_getBalance", protectedAddr, "latest")
+	if res.Error != nil {
+		t.Fatal("jwt naming the address is denied", res.Error)
+	}
+	res = callTestMiddleware(t, handler, token, "eth_getBalance", publicAddr, "latest")
+	if res.Error == nil {
+		t.Fatal("jwt shows an address it does not name")
+	}
+	token = newJwt(jwt.SigningMethodHS256, secret, claims(CapabilityNonce, time.Minute))
+	if res = callTestMiddleware(t, handler, token, "eth_getBalance", protectedAddr, "latest"); res.Error == nil {
+		t.Fatal("jwt shows a capability outside its mask")
+	}
+	if res = callTestMiddleware(t, handler, token, "eth_getTransactionCount", protectedAddr, "latest"); res.Error != nil {
+		t.Fatal("jwt mask is not applied", res.Error)
+	}
+	for name, token := range map[string]string{
+		"expired":   newJwt(jwt.SigningMethodHS256, secret, claims(0, -time.Minute)),
+		"no expiry": newJwt(jwt.SigningMethodHS256, secret, claims(0, 0)),
+		"wrong key": newJwt(jwt.SigningMethodHS256, common.HexToHash("0x5678").Bytes(), claims(0, time.Minute)),
+	} {
+		if res = callTestMiddleware(t, handler, token, "eth_getBalance", protectedAddr, "latest"); res.Error == nil {
+			t.Fatal("jwt is accepted:", name)
+		}
+	}
+	// ES256 is only accepted with a public key configured
+	ecKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token = newJwt(jwt.SigningMethodES256, ecKey, claims(0, time.Minute))
+	if res = callTestMiddleware(t, handler, token, "eth_getBalance", protectedAddr, "latest"); res.Error == nil {
+		t.Fatal("ES256 jwt is accepted without a public key")
+	}
+	CurrentWrapper().jwtPublicKey = &ecKey.PublicKey
+	if res = callTestMiddleware(t, handler, token, "eth_getBalance", protectedAddr, "latest"); res.Error != nil {
+		t.Fatal("ES256 jwt is denied", res.Error)
+	}
+
+	// the owner signs in to read its own balance
+	owner, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ownerAddr := crypto.PubkeyToAddress(owner.PublicKey)
+	message, err := api.Challenge(ctx, ownerAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(message string) hexutil.Bytes {
+		sig, err := crypto.Sign(accounts.TextHash([]byte(message)), owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig[crypto.RecoveryIDOffset] += 27
+		return sig
+	}
+	if _, err := api.Login(ctx, message+"\nResources:", sign(message+"\nResources:")); err == nil {
+		t.Fatal("altered message is accepted")
+	}
+	other, _ := crypto.GenerateKey()
+	otherSig, _ := crypto.Sign(accounts.TextHash([]byte(message)), other)
+	if _, err := api.Login(ctx, message, otherSig); err == nil {
+		t.Fatal("message signed by another address is accepted")
+	}
+	session, err := api.Login(ctx, message, sign(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.Login(ctx, message, sign(message)); err == nil {
+		t.Fatal("challenge is accepted twice")
+	}
+	if res = callTestMiddleware(t, handler, session, "eth_getBalance", ownerAddr, "latest"); res.Error != nil {
+		t.Fatal("owner can't read its balance", res.Error)
+	}
+	if res = callTestMiddleware(t, handler, session, "eth_getTransactionCount", ownerAddr, "latest"); res.Error != nil {
+		t.Fatal("owner can't read its nonce", res.Error)
+	}
+	if res = callTestMiddleware(t, handler, session, "eth_getBalance", protectedAddr, "latest"); res.Error == nil {
+		t.Fatal("session shows another address")
+	}
+	if _, err := api.Logout(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	if res = callTestMiddleware(t, handler, session, "eth_getBalance", ownerAddr, "latest"); res.Error == nil {
+		t.Fatal("session is still valid after logout")
+	}
+
+	// the store may outlive the expiry of challenges and sessions
+	wrapper := CurrentWrapper()
+	message, err = api.Challenge(ctx, ownerAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := siweField(message, siweNonceLinePrefix)
+	wrapper.challenges[nonce] = siweChallenge{message: message, expiry: time.Now().Add(-time.Second)}
+	if _, err := api.Login(ctx, message, sign(message)); err == nil {
+		t.Fatal("expired challenge is accepted")
+	}
+	expired := strings.Replace(message, siweExpirationLinePrefix+siweField(message, siweExpirationLinePrefix),
+		siweExpirationLinePrefix+time.Now().Add(-time.Second).UTC().Format(time.RFC3339), 1)
+	wrapper.challenges[nonce] = siweChallenge{message: expired, expiry: time.Now().Add(time.Minute)}
+	if _, err := api.Login(ctx, expired, sign(expired)); err == nil {
+		t.Fatal("message past its expiration time is accepted")
+	}
+	message, err = api.Challenge(ctx, ownerAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err = api.Login(ctx, message, sign(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(&siweSession{Address: ownerAddr, Expiry: time.Now().Add(-time.Second).Unix()})
+	if err := wrapper.cache.Set(ctx, siweSessionKey+session, data, 0); err != nil {
+		t.Fatal(err)
+	}
+	if res = callTestMiddleware(t, handler, session, "eth_getBalance", ownerAddr, "latest"); res.Error == nil {
+		t.Fatal("expired session is accepted")
+	}
+
+	// challenges are bounded
+	wrapper.challenges = make(map[string]siweChallenge)
+	for i := 0; i < config.Auth.MaxChallenges; i++ {
+		if _, err := api.Challenge(ctx, ownerAddr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := api.Challenge(ctx, ownerAddr); err == nil {
+		t.Fatal("challenges are not bounded")
+	}
+	for n, c := range wrapper.challenges {
+		wrapper.challenges[n] = siweChallenge{message: c.message, expiry: time.Now()}
+		break
+	}
+	if _, err := api.Challenge(ctx, ownerAddr); err != nil {
+		t.Fatal("expired challenge is not dropped", err)
+	}
+}
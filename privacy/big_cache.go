@@ -2,6 +2,8 @@ package privacy
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/allegro/bigcache"
@@ -30,6 +32,7 @@ const CheckStatusKey = "check status"
 type BigCacheStorageService struct {
 	config   BigCacheConfig
 	bigCache *bigcache.BigCache
+	setMutex sync.Mutex // of SetNX and Update
 }
 
 // NewBigCache generates a new BigCache storage
@@ -50,7 +53,8 @@ func NewBigCache(config BigCacheConfig) (ICacheService, error) {
 	return bcService, nil
 }
 
-// Set sets the key-value pair for the auth service
+// Set sets the key-value pair for the auth service, the entry expires after expiration seconds,
+// or when BigCache evicts it once the life window passes if expiration is 0
 func (bc *BigCacheStorageService) Set(ctx context.Context, key string, value []byte, expiration uint64) (err error) {
 	select {
 	case <-ctx.Done():
@@ -58,27 +62,80 @@ func (bc *BigCacheStorageService) Set(ctx context.Context, key string, value []b
 		err = ctx.Err()
 	default:
 		// write the key-value pair
-		err = bc.bigCache.Set(key, value)
+		err = bc.bigCache.Set(key, withExpiration(value, expiration))
 	}
 	return
 }
 
-// Get returns the value for the given key
-func (bc *BigCacheStorageService) Get(ctx context.Context, key string) (res []byte, err error) {
+// Get returns the value for the given key, or bigcache.ErrEntryNotFound if it is missing or expired
+func (bc *BigCacheStorageService) Get(ctx context.Context, key string) ([]byte, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
-		// get the key-value pair
-		res, err = bc.bigCache.Get(key)
-		if err != nil {
-			return nil, err
-		}
 	}
-	return
+	data, err := bc.bigCache.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	value, expired, err := splitExpiration(data)
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		_ = bc.bigCache.Delete(key)
+		return nil, bigcache.ErrEntryNotFound
+	}
+	return value, nil
+}
+
+// SetNX sets the key-value pair only if the key is missing or expired
+func (bc *BigCacheStorageService) SetNX(ctx context.Context, key string, value []byte, expiration uint64) (bool, error) {
+	bc.setMutex.Lock()
+	defer bc.setMutex.Unlock()
+	_, err := bc.Get(ctx, key)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, bigcache.ErrEntryNotFound) {
+		return false, err
+	}
+	return true, bc.Set(ctx, key, value, expiration)
+}
+
+// Update applies update to the value of the key, the cache is only shared within the node
+func (bc *BigCacheStorageService) Update(ctx context.Context, key string, update UpdateFunc) error {
+	bc.setMutex.Lock()
+	defer bc.setMutex.Unlock()
+	value, err := bc.Get(ctx, key)
+	if errors.Is(err, bigcache.ErrEntryNotFound) {
+		value = nil
+	} else if err != nil {
+		return err
+	}
+	newValue, expiration, err := update(value)
+	if err != nil {
+		return err
+	}
+	if newValue == nil {
+		return bc.Delete(ctx, key)
+	}
+	return bc.Set(ctx, key, newValue, expiration)
+}
+
+// Delete removes the key, a missing key is not an error
+func (bc *BigCacheStorageService) Delete(ctx context.Context, key string) error {
+	err := bc.bigCache.Delete(key)
+	if errors.Is(err, bigcache.ErrEntryNotFound) {
+		return nil
+	}
+	return err
 }
 
 func (bc *BigCacheStorageService) HealthCheck(ctx context.Context) bool {
-	_, err := bc.bigCache.Get(CheckStatusKey)
-	return err == nil
+	if _, err := bc.bigCache.Get(CheckStatusKey); err == nil {
+		return true
+	}
+	// the status entry is evicted with everything else once the life window passes
+	return bc.Set(ctx, CheckStatusKey, []byte{1}, 0) == nil
 }
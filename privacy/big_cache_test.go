@@ -7,6 +7,7 @@ import (
 	"github.com/allegro/bigcache"
 	"github.com/offchainlabs/nitro/das/dastree"
 	"testing"
+	"time"
 )
 
 func TestBigCache(t *testing.T) {
@@ -46,3 +47,34 @@ func TestBigCache(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestBigCacheExpiration(t *testing.T) {
+	ctx := context.Background()
+	cache, err := NewBigCache(BigCacheConfigDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Set(ctx, "session", []byte("short"), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Set(ctx, "kept", []byte("long"), 3600); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := cache.SetNX(ctx, "used signature", []byte{1}, 1); err != nil || !ok {
+		t.Fatal("couldn't set the signature", ok, err)
+	}
+	if ok, err := cache.SetNX(ctx, "used signature", []byte{1}, 1); err != nil || ok {
+		t.Fatal("signature is set twice", ok, err)
+	}
+	time.Sleep(time.Second * 2)
+	if _, err := cache.Get(ctx, "session"); !errors.Is(err, bigcache.ErrEntryNotFound) {
+		t.Fatal("expired entry is still there", err)
+	}
+	if v, err := cache.Get(ctx, "kept"); err != nil || !bytes.Equal(v, []byte("long")) {
+		t.Fatal("entry expired early", v, err)
+	}
+	// an expired entry may be set again
+	if ok, err := cache.SetNX(ctx, "used signature", []byte{1}, 1); err != nil || !ok {
+		t.Fatal("expired signature is not set again", ok, err)
+	}
+}
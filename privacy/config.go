@@ -1,36 +1,59 @@
 package privacy
 
 import (
+	"time"
+
 	flag "github.com/spf13/pflag"
 )
 
 type PrivacyConfig struct {
-	Enable     bool             `koanf:"enable"`
-	API        []string         `koanf:"api"`
-	LocalCache BigCacheConfig   `koanf:"cache"`
-	RedisCache RedisCacheConfig `koanf:"redis"`
-	Das        DASConfig        `koanf:"das"`
-	JwtSecret  string           `koanf:"jwtsecret"`
-	Backends   string           `koanf:"backends"`
+	Enable            bool                `koanf:"enable"`
+	API               []string            `koanf:"api"`
+	LocalCache        BigCacheConfig      `koanf:"cache"`
+	RedisCache        RedisCacheConfig    `koanf:"redis"`
+	Database          DatabaseCacheConfig `koanf:"database"`
+	Das               DASConfig           `koanf:"das"`
+	JwtSecret         string              `koanf:"jwtsecret"`
+	Auth              AuthConfig          `koanf:"auth"`
+	Backends          string              `koanf:"backends"`
+	ProtectUnlisted   bool                `koanf:"protect-unlisted"`
+	PurgeInterval     time.Duration       `koanf:"purge-interval"`
+	SignatureWindow   time.Duration       `koanf:"signature-window"`
+	RedactionReceipts bool                `koanf:"redaction-receipts"`
+	Audit             AuditConfig         `koanf:"audit"`
 }
 
 var PrivacyRPCConfigDefault = PrivacyConfig{
-	Backends:   "[]",
-	Enable:     false,
-	API:        []string{"eth", "web3"},
-	JwtSecret:  "",
-	Das:        DASConfigDefaults,
-	LocalCache: BigCacheConfigDefault,
-	RedisCache: RedisCacheConfigDefault,
+	Backends:          "[]",
+	Enable:            false,
+	API:               []string{"eth", "web3"},
+	JwtSecret:         "",
+	Auth:              AuthConfigDefault,
+	Das:               DASConfigDefaults,
+	LocalCache:        BigCacheConfigDefault,
+	RedisCache:        RedisCacheConfigDefault,
+	Database:          DatabaseCacheConfigDefault,
+	ProtectUnlisted:   true,
+	PurgeInterval:     time.Minute * 10,
+	SignatureWindow:   time.Hour,
+	RedactionReceipts: false,
+	Audit:             AuditConfigDefault,
 }
 
 // PrivacyRPCConfigAddOptions adds flags for configuring the privacy module.
 func PrivacyRPCConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", PrivacyRPCConfigDefault.Enable, "enable the privacy router")
 	f.StringSlice(prefix+".api", PrivacyRPCConfigDefault.API, "api list to support")
-	f.String(prefix+".jwtsecret", PrivacyRPCConfigDefault.JwtSecret, "jwt secret")
+	f.String(prefix+".jwtsecret", PrivacyRPCConfigDefault.JwtSecret, "secret HS256 privacy jwts are verified with, treated as a hex string if prefixed with 0x otherwise treated as a file holding it (empty to disable)")
 	f.String(prefix+".backends", PrivacyRPCConfigDefault.Backends, "backend `list` to support")
+	f.Duration(prefix+".purge-interval", PrivacyRPCConfigDefault.PurgeInterval, "how often expired grants and database entries are removed (0 to disable)")
+	f.Duration(prefix+".signature-window", PrivacyRPCConfigDefault.SignatureWindow, "how far in the future the deadline of a signed privacy request may lie")
+	f.Bool(prefix+".redaction-receipts", PrivacyRPCConfigDefault.RedactionReceipts, "add a signed arbPrivacy receipt listing the redacted fields and commitments to their plaintext to redacted responses")
+	f.Bool(prefix+".protect-unlisted", PrivacyRPCConfigDefault.ProtectUnlisted, "hide the data of addresses that have no grants, otherwise only addresses with grants are protected")
 	DASConfigAddOptions(prefix+".das", f)
 	BigCacheConfigAddOptions(prefix+".cache", f)
 	RedisCacheConfigAddOptions(prefix+".redis", f)
+	DatabaseCacheConfigAddOptions(prefix+".database", f)
+	AuditConfigAddOptions(prefix+".audit", f)
+	AuthConfigAddOptions(prefix+".auth", f)
 }
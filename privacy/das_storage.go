@@ -1,20 +1,34 @@
 package privacy
 
 import (
+	"time"
+
 	flag "github.com/spf13/pflag"
 )
 
 type DASConfig struct {
-	Enable   bool     `koanf:"enable"`
-	Backends []string `koanf:"backends"`
+	Enable          bool          `koanf:"enable"`
+	Backends        []string      `koanf:"backends"`
+	RestBackends    []string      `koanf:"rest-backends"`
+	RetentionPeriod time.Duration `koanf:"retention-period"`
+	SigningKey      string        `koanf:"signing-key"`
+	EncryptionKey   string        `koanf:"encryption-key"`
 }
 
 var DASConfigDefaults = DASConfig{
-	Enable:   false,
-	Backends: []string{},
+	Enable:          false,
+	Backends:        []string{},
+	RestBackends:    []string{},
+	RetentionPeriod: time.Hour * 24 * 15,
+	SigningKey:      "",
+	EncryptionKey:   "",
 }
 
 func DASConfigAddOptions(prefix string, f *flag.FlagSet) {
-	f.Bool(prefix+".enable", DASConfigDefaults.Enable, "enable the das reader, if enabled, will use the das config")
+	f.Bool(prefix+".enable", DASConfigDefaults.Enable, "publish exported grant snapshots to das")
 	f.StringSlice(prefix+".backends", DASConfigDefaults.Backends, "das storage backends")
+	f.StringSlice(prefix+".rest-backends", DASConfigDefaults.RestBackends, "das rest endpoints grant snapshots are fetched from")
+	f.Duration(prefix+".retention-period", DASConfigDefaults.RetentionPeriod, "how long the das backends are requested to keep grant snapshots")
+	f.String(prefix+".signing-key", DASConfigDefaults.SigningKey, "hex ecdsa private key to sign the store requests with, requests are not signed if empty")
+	f.String(prefix+".encryption-key", DASConfigDefaults.EncryptionKey, "hex 32 byte aes key grant snapshots are encrypted with, shared with the nodes importing them, as das data can be read by anyone")
 }
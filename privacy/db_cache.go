@@ -0,0 +1,174 @@
+package privacy
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	flag "github.com/spf13/pflag"
+)
+
+type DatabaseCacheConfig struct {
+	Enable bool `koanf:"enable"`
+}
+
+var DatabaseCacheConfigDefault = DatabaseCacheConfig{
+	Enable: false,
+}
+
+func DatabaseCacheConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DatabaseCacheConfigDefault.Enable, "keep privacy grants in the node database so they survive restarts and cache evictions")
+}
+
+// ErrEntryNotFound is returned by the database storage for missing and expired keys
+var ErrEntryNotFound = errors.New("privacy: entry not found")
+
+const expirationLength = 8
+
+// DBStorageService keeps the entries in a key-value store, each prefixed by its expiration time
+type DBStorageService struct {
+	db       ethdb.KeyValueStore
+	setMutex sync.Mutex // of SetNX and Update
+}
+
+// NewDBCache generates a storage on top of the database, which is usually a table of the node database
+func NewDBCache(db ethdb.KeyValueStore) ICacheService {
+	return &DBStorageService{
+		db: db,
+	}
+}
+
+// withExpiration prefixes the value with the time it expires at, expiration seconds from now or never if it is 0
+func withExpiration(value []byte, expiration uint64) []byte {
+	var expirationTime uint64
+	if expiration != 0 {
+		expirationTime = uint64(time.Now().Unix()) + expiration
+	}
+	data := make([]byte, expirationLength+len(value))
+	binary.BigEndian.PutUint64(data, expirationTime)
+	copy(data[expirationLength:], value)
+	return data
+}
+
+// splitExpiration returns the value prefixed by withExpiration, and whether it has expired
+func splitExpiration(data []byte) ([]byte, bool, error) {
+	if len(data) < expirationLength {
+		return nil, false, errors.New("privacy: cache entry is too short")
+	}
+	expirationTime := binary.BigEndian.Uint64(data)
+	expired := expirationTime != 0 && expirationTime <= uint64(time.Now().Unix())
+	return data[expirationLength:], expired, nil
+}
+
+// Set sets the key-value pair, the entry expires after expiration seconds, or never if expiration is 0
+func (d *DBStorageService) Set(ctx context.Context, key string, value []byte, expiration uint64) error {
+	return d.db.Put([]byte(key), withExpiration(value, expiration))
+}
+
+// Get returns the value for the given key, or ErrEntryNotFound if it is missing or expired
+func (d *DBStorageService) Get(ctx context.Context, key string) ([]byte, error) {
+	has, err := d.db.Has([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrEntryNotFound
+	}
+	data, err := d.db.Get([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	value, expired, err := splitExpiration(data)
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		// the entries nobody reads are left to Sweep
+		_ = d.db.Delete([]byte(key))
+		return nil, ErrEntryNotFound
+	}
+	return value, nil
+}
+
+// SetNX sets the key-value pair only if the key is missing or expired
+func (d *DBStorageService) SetNX(ctx context.Context, key string, value []byte, expiration uint64) (bool, error) {
+	d.setMutex.Lock()
+	defer d.setMutex.Unlock()
+	_, err := d.Get(ctx, key)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, ErrEntryNotFound) {
+		return false, err
+	}
+	return true, d.Set(ctx, key, value, expiration)
+}
+
+// Update applies update to the value of the key, the database is only shared within the node
+func (d *DBStorageService) Update(ctx context.Context, key string, update UpdateFunc) error {
+	d.setMutex.Lock()
+	defer d.setMutex.Unlock()
+	value, err := d.Get(ctx, key)
+	if errors.Is(err, ErrEntryNotFound) {
+		value = nil
+	} else if err != nil {
+		return err
+	}
+	newValue, expiration, err := update(value)
+	if err != nil {
+		return err
+	}
+	if newValue == nil {
+		return d.Delete(ctx, key)
+	}
+	return d.Set(ctx, key, newValue, expiration)
+}
+
+// Sweep removes every expired entry, Get only removes the expired entries it comes across
+func (d *DBStorageService) Sweep(ctx context.Context) (int, error) {
+	now := uint64(time.Now().Unix())
+	it := d.db.NewIterator(nil, nil)
+	defer it.Release()
+	batch := d.db.NewBatch()
+	removed := 0
+	for it.Next() {
+		if ctx.Err() != nil {
+			return removed, ctx.Err()
+		}
+		data := it.Value()
+		if len(data) < expirationLength {
+			continue
+		}
+		expirationTime := binary.BigEndian.Uint64(data)
+		if expirationTime == 0 || expirationTime > now {
+			continue
+		}
+		if err := batch.Delete(common.CopyBytes(it.Key())); err != nil {
+			return removed, err
+		}
+		removed++
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return removed, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return removed, err
+	}
+	return removed, batch.Write()
+}
+
+func (d *DBStorageService) Delete(ctx context.Context, key string) error {
+	return d.db.Delete([]byte(key))
+}
+
+func (d *DBStorageService) HealthCheck(ctx context.Context) bool {
+	_, err := d.db.Has([]byte(CheckStatusKey))
+	return err == nil
+}
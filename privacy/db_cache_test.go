@@ -0,0 +1,137 @@
+package privacy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+func TestDBCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDBCache(rawdb.NewMemoryDatabase())
+	if !cache.HealthCheck(ctx) {
+		t.Fatal("unhealthy database cache")
+	}
+
+	key := "0x0000000000000000000000000000000000000001"
+	val := []byte("token")
+	if _, err := cache.Get(ctx, key); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatal("expected a miss, got", err)
+	}
+	if err := cache.Set(ctx, key, val, 0); err != nil {
+		t.Fatal(err)
+	}
+	v, err := cache.Get(ctx, key)
+	if err != nil || !bytes.Equal(v, val) {
+		t.Fatal(v, err)
+	}
+	if err := cache.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Get(ctx, key); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatal("deleted entry is still there", err)
+	}
+
+	if err := cache.Set(ctx, key, val, 1); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second * 2)
+	if _, err := cache.Get(ctx, key); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatal("expired entry is still there", err)
+	}
+}
+
+func TestDBCacheSweep(t *testing.T) {
+	ctx := context.Background()
+	db := rawdb.NewMemoryDatabase()
+	cache := NewDBCache(db).(*DBStorageService)
+	if err := cache.Set(ctx, "kept", []byte("value"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Set(ctx, "live", []byte("value"), 3600); err != nil {
+		t.Fatal(err)
+	}
+	// entries that expired without ever being read again
+	for _, key := range []string{"expired 1", "expired 2"} {
+		data := make([]byte, expirationLength)
+		binary.BigEndian.PutUint64(data, uint64(time.Now().Unix())-1)
+		if err := db.Put([]byte(key), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	removed, err := NewTieredCache(NewDBCache(rawdb.NewMemoryDatabase()), cache, 0).(sweeper).Sweep(ctx)
+	if err != nil || removed != 2 {
+		t.Fatal("unexpected sweep", removed, err)
+	}
+	for key, want := range map[string]bool{"kept": true, "live": true, "expired 1": false, "expired 2": false} {
+		if has, _ := db.Has([]byte(key)); has != want {
+			t.Fatal("unexpected entry after sweep", key, has)
+		}
+	}
+}
+
+func TestGrantsSurviveRestart(t *testing.T) {
+	ctx := context.Background()
+	db := rawdb.NewMemoryDatabase()
+	config := PrivacyRPCConfigDefault
+	config.Database.Enable = true
+	wrapper, err := NewWrapper(&config, db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := "0x0000000000000000000000000000000000000003"
+	if err := wrapper.grant(ctx, addr, AuthToken{Token: "token", Mask: CapabilityBalance}); err != nil {
+		t.Fatal(err)
+	}
+	expired := AuthToken{Token: "old token", Mask: CapabilityAll, ExpirationTime: uint64(time.Now().Unix()) - 1}
+	if err := wrapper.grant(ctx, addr, expired); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new wrapper on the same database starts with an empty local cache
+	restarted, err := NewWrapper(&config, db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acl, err := restarted.accessList(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acl.Allows("token", CapabilityBalance) {
+		t.Fatal("grant is lost on restart", acl)
+	}
+
+	// migrate the grants to another node
+	snapshot, err := restarted.ExportGrants(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot[addr]) != 1 {
+		t.Fatal("unexpected snapshot", snapshot)
+	}
+	other, err := NewWrapper(&config, rawdb.NewMemoryDatabase(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := other.ImportGrants(ctx, snapshot)
+	if err != nil || imported != 1 {
+		t.Fatal(imported, err)
+	}
+	acl, err = other.accessList(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acl.Allows("token", CapabilityBalance) {
+		t.Fatal("imported grant is missing", acl)
+	}
+
+	config.RedisCache.Enable = true
+	if _, err := NewWrapper(&config, db, nil); err == nil {
+		t.Fatal("database and redis enabled together")
+	}
+}
@@ -0,0 +1,285 @@
+package privacy
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// MethodFilter rewrites the response data of a json-rpc method for the caller behind pw
+type MethodFilter func(new *[]byte, ori *[]byte, pw *PrivacyResponseWriter, reqMessage *JsonrpcMessage)
+
+// addressParser returns the address a request reads the data of
+type addressParser func(reqMessage *JsonrpcMessage) (string, error)
+
+type methodPrefixFilter struct {
+	prefix string
+	filter MethodFilter
+}
+
+var methodFilters = map[string]MethodFilter{
+	methodGetBalance():                          modifyBalanceMessage,
+	methodGetTransactionCount():                 modifyTxCountMessage,
+	methodGetTransaction():                      modifyTxMessage,
+	methodGetTransactionByBlockHashAndIndex():   modifyTxMessage,
+	methodGetTransactionByBlockNumberAndIndex(): modifyTxMessage,
+	methodGetTransactionReceipt():               modifyTxReceiptMessage,
+	methodGetBlockByHash():                      modifyBlockByHashMessage,
+	methodGetBlockByNumber():                    modifyBlockByNumberMessage,
+	methodGetLogs():                             modifyLogsMessage,
+	methodGetStorageAt():                        denyUnlessVisible(CapabilityState, "unauthorized to get storage", parseAddressFromReq),
+	methodGetCode():                             denyUnlessVisible(CapabilityState, "unauthorized to get code", parseAddressFromReq),
+	methodGetProof():                            denyUnlessVisible(CapabilityState, "unauthorized to get proof", parseAddressFromReq),
+	// the called contract may read the state of any other, the result can't be told apart
+	methodCall():             denyUnlessAdmin("unauthorized to call"),
+	methodEstimateGas():      denyUnlessAdmin("unauthorized to estimate gas"),
+	methodCreateAccessList(): denyUnlessAdmin("unauthorized to create access list"),
+	// the signed raw transaction can't be redacted
+	"eth_getRawTransactionByHash":                denyAll("raw transactions are not available in privacy mode"),
+	"eth_getRawTransactionByBlockHashAndIndex":   denyAll("raw transactions are not available in privacy mode"),
+	"eth_getRawTransactionByBlockNumberAndIndex": denyAll("raw transactions are not available in privacy mode"),
+	// filters are polled without the request that installed them, eth_getLogs serves the same logs
+	"eth_newFilter":        denyAll("filters are not available in privacy mode"),
+	"eth_getFilterLogs":    denyAll("filters are not available in privacy mode"),
+	"eth_getFilterChanges": denyAll("filters are not available in privacy mode"),
+	// the pool shows the calldata of pending transactions
+	"eth_pendingTransactions": denyAll("pending transactions are not available in privacy mode"),
+	"txpool_content":          denyAll("pending transactions are not available in privacy mode"),
+	"txpool_inspect":          denyAll("pending transactions are not available in privacy mode"),
+}
+
+// traces and raw chain data expose the calldata, storage and logs of every contract they touch
+var methodPrefixFilters = []methodPrefixFilter{
+	{prefix: "debug_trace", filter: denyAll("tracing is not available in privacy mode")},
+	{prefix: "debug_getRaw", filter: denyAll("raw chain data is not available in privacy mode")},
+	{prefix: "arbtrace_", filter: denyAll("tracing is not available in privacy mode")},
+}
+
+// passthroughMethods are answered without anything of a protected address in the response,
+// every other method without a filter is denied
+var passthroughMethods = map[string]struct{}{
+	"eth_chainId":                          {},
+	"eth_blockNumber":                      {},
+	"eth_syncing":                          {},
+	"eth_gasPrice":                         {},
+	"eth_maxPriorityFeePerGas":             {},
+	"eth_feeHistory":                       {},
+	"eth_sendRawTransaction":               {},
+	"eth_getBlockTransactionCountByHash":   {},
+	"eth_getBlockTransactionCountByNumber": {},
+	"eth_getUncleCountByBlockHash":         {},
+	"eth_getUncleCountByBlockNumber":       {},
+	"eth_uninstallFilter":                  {},
+	methodSubscribe():                      {},
+	methodUnsubscribe():                    {},
+	"net_version":                          {},
+	"net_listening":                        {},
+	"web3_clientVersion":                   {},
+	"web3_sha3":                            {},
+}
+
+// the privacy api filters its own responses
+var passthroughPrefixes = []string{"privacy_"}
+
+// RegisterMethodFilter installs the filter for the method, replacing the earlier one
+func RegisterMethodFilter(method string, filter MethodFilter) {
+	methodFilters[method] = filter
+}
+
+// RegisterMethodPrefixFilter installs the filter for every method starting with prefix that has no filter of its own
+func RegisterMethodPrefixFilter(prefix string, filter MethodFilter) {
+	methodPrefixFilters = append(methodPrefixFilters, methodPrefixFilter{prefix: prefix, filter: filter})
+}
+
+// RegisterPassthroughMethod lets the responses of the method through unfiltered, unless a filter is registered for it
+func RegisterPassthroughMethod(method string) {
+	passthroughMethods[method] = struct{}{}
+}
+
+// methodFilter returns the filter of the method, or nil if its response is passed through
+func methodFilter(method string) MethodFilter {
+	if filter, ok := methodFilters[method]; ok {
+		return filter
+	}
+	if _, ok := passthroughMethods[method]; ok {
+		return nil
+	}
+	for _, f := range methodPrefixFilters {
+		if strings.HasPrefix(method, f.prefix) {
+			return f.filter
+		}
+	}
+	for _, prefix := range passthroughPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return nil
+		}
+	}
+	return denyUnknown
+}
+
+func methodGetTransactionByBlockHashAndIndex() string {
+	return "eth_getTransactionByBlockHashAndIndex"
+}
+
+func methodGetTransactionByBlockNumberAndIndex() string {
+	return "eth_getTransactionByBlockNumberAndIndex"
+}
+
+func methodGetLogs() string {
+	return "eth_getLogs"
+}
+
+func methodCall() string {
+	return "eth_call"
+}
+
+func methodGetStorageAt() string {
+	return "eth_getStorageAt"
+}
+
+func methodGetCode() string {
+	return "eth_getCode"
+}
+
+func methodGetProof() string {
+	return "eth_getProof"
+}
+
+func methodEstimateGas() string {
+	return "eth_estimateGas"
+}
+
+func methodCreateAccessList() string {
+	return "eth_createAccessList"
+}
+
+func deniedMessage(new *[]byte, reqMessage *JsonrpcMessage, code int, message string) {
+	*new, _ = json.Marshal(JsonrpcMessage{
+		ID:      reqMessage.ID,
+		Version: reqMessage.Version,
+		Error:   errorMessage(code, message),
+	})
+}
+
+// denyUnlessVisible replaces the response with an error unless the caller may see the capability of the requested address
+func denyUnlessVisible(capability uint64, message string, address addressParser) MethodFilter {
+	return func(new *[]byte, ori *[]byte, pw *PrivacyResponseWriter, reqMessage *JsonrpcMessage) {
+		addr, err := address(reqMessage)
+		if err == nil && pw.visible(addr, capability) {
+			*new = *ori
+			return
+		}
+		deniedMessage(new, reqMessage, defaultPrivacyErrorCode, message)
+	}
+}
+
+// denyAll replaces every response with an error
+func denyAll(message string) MethodFilter {
+	return func(new *[]byte, ori *[]byte, pw *PrivacyResponseWriter, reqMessage *JsonrpcMessage) {
+		deniedMessage(new, reqMessage, defaultPrivacyErrorCode, message)
+	}
+}
+
+// denyUnlessAdmin replaces the response with an error unless the caller is an admin
+func denyUnlessAdmin(message string) MethodFilter {
+	return func(new *[]byte, ori *[]byte, pw *PrivacyResponseWriter, reqMessage *JsonrpcMessage) {
+		if pw.viewer.isAdmin() {
+			*new = *ori
+			return
+		}
+		deniedMessage(new, reqMessage, defaultPrivacyErrorCode, message)
+	}
+}
+
+// denyUnknown denies the methods nobody checked the responses of
+var denyUnknown = denyAll("method is not available in privacy mode")
+
+// redactLog keeps the emitter of the log but replaces its topics and data with a commitment to the data,
+// and returns the commitment to the topics
+func redactLog(hash hashFunc, l *types.Log) common.Hash {
+	topics := make([][]byte, 0, len(l.Topics))
+	for _, topic := range l.Topics {
+		topics = append(topics, topic.Bytes())
+	}
+	l.Topics = []common.Hash{}
+	l.Data = hash(l.Data).Bytes()
+	return hash(topics...)
+}
+
+// modifyTxReceiptMessage redacts the logs of the receipt emitted by contracts the caller may not see
+func modifyTxReceiptMessage(new *[]byte, ori *[]byte, pw *PrivacyResponseWriter, reqMessage *JsonrpcMessage) {
+	var resMessage JsonrpcMessage
+	err := json.Unmarshal(*ori, &resMessage)
+	if err != nil || resMessage.Error != nil {
+		*new = *ori
+		return
+	}
+	// keep every field, only the logs and the bloom filter built from them are rewritten
+	var receipt map[string]json.RawMessage
+	if err := json.Unmarshal(resMessage.Result, &receipt); err != nil || receipt == nil {
+		*new = *ori
+		return
+	}
+	var logs []*types.Log
+	if err := json.Unmarshal(receipt["logs"], &logs); err != nil {
+		*new = *ori
+		return
+	}
+
+	redacted := false
+	for i, l := range logs {
+		if !pw.visible(l.Address.String(), CapabilityLogs) {
+			topics := redactLog(pw.hash, l)
+			pw.redact(redactedLogPath(i, "topics"), topics)
+			pw.redact(redactedLogPath(i, "data"), common.BytesToHash(l.Data))
+			redacted = true
+		}
+	}
+	if !redacted {
+		*new = *ori
+		return
+	}
+	receipt["logs"], _ = json.Marshal(logs)
+	receipt["logsBloom"], _ = json.Marshal(types.Bloom{})
+	d, _ := json.Marshal(receipt)
+	*new, _ = json.Marshal(&JsonrpcMessage{
+		ID:      resMessage.ID,
+		Version: resMessage.Version,
+		Result:  d,
+	})
+}
+
+// modifyLogsMessage drops the logs emitted by contracts the caller may not see,
+// redacting them instead would still confirm matches of the topic filter
+func modifyLogsMessage(new *[]byte, ori *[]byte, pw *PrivacyResponseWriter, reqMessage *JsonrpcMessage) {
+	var resMessage JsonrpcMessage
+	err := json.Unmarshal(*ori, &resMessage)
+	if err != nil || resMessage.Error != nil {
+		*new = *ori
+		return
+	}
+	var logs []*types.Log
+	if err := json.Unmarshal(resMessage.Result, &logs); err != nil {
+		*new = *ori
+		return
+	}
+
+	visible := make([]*types.Log, 0, len(logs))
+	for _, l := range logs {
+		if pw.visible(l.Address.String(), CapabilityLogs) {
+			visible = append(visible, l)
+		}
+	}
+	if len(visible) == len(logs) {
+		*new = *ori
+		return
+	}
+	d, _ := json.Marshal(visible)
+	*new, _ = json.Marshal(&JsonrpcMessage{
+		ID:      resMessage.ID,
+		Version: resMessage.Version,
+		Result:  d,
+	})
+}
@@ -0,0 +1,320 @@
+package privacy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+var (
+	protectedAddr = common.HexToAddress("0x1111")
+	publicAddr    = common.HexToAddress("0x2222")
+)
+
+// newTestMiddleware serves the canned results through the privacy middleware,
+// protectedAddr only shows its logs to the "logs token" holder
+func newTestMiddleware(t *testing.T, results map[string]interface{}) http.Handler {
+	t.Helper()
+	return newSignedTestMiddleware(t, results, nil)
+}
+
+// newSignedTestMiddleware is newTestMiddleware issuing redaction receipts signed by dataSigner, if it is set
+func newSignedTestMiddleware(t *testing.T, results map[string]interface{}, dataSigner signature.DataSignerFunc) http.Handler {
+	t.Helper()
+	config := PrivacyRPCConfigDefault
+	config.Enable = true
+	config.ProtectUnlisted = false
+	config.RedactionReceipts = dataSigner != nil
+	return newConfiguredTestMiddleware(t, results, &config, dataSigner)
+}
+
+// newConfiguredTestMiddleware is newSignedTestMiddleware with the privacy config of the caller
+func newConfiguredTestMiddleware(t *testing.T, results map[string]interface{}, config *PrivacyConfig, dataSigner signature.DataSignerFunc) http.Handler {
+	t.Helper()
+	wrapper, err := NewWrapper(config, nil, dataSigner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wrapper.grant(context.Background(), protectedAddr.String(), AuthToken{Token: "logs token", Mask: CapabilityLogs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	respond := func(req *JsonrpcMessage) json.RawMessage {
+		result, err := json.Marshal(results[req.Method])
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, _ := json.Marshal(JsonrpcMessage{ID: req.ID, Version: req.Version, Result: result})
+		return res
+	}
+	return RpcResponseMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isBatch(body) {
+			var req JsonrpcMessage
+			if err := json.Unmarshal(body, &req); err != nil {
+				t.Fatal(err)
+			}
+			_, _ = w.Write(respond(&req))
+			return
+		}
+		// answer batches in reverse order
+		var reqs []*JsonrpcMessage
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			t.Fatal(err)
+		}
+		var res []json.RawMessage
+		for i := len(reqs) - 1; i >= 0; i-- {
+			res = append(res, respond(reqs[i]))
+		}
+		d, _ := json.Marshal(res)
+		_, _ = w.Write(d)
+	}))
+}
+
+func callTestMiddleware(t *testing.T, handler http.Handler, token string, method string, params ...interface{}) JsonrpcMessage {
+	t.Helper()
+	rawParams, _ := json.Marshal(params)
+	body, _ := json.Marshal(JsonrpcMessage{Version: "2.0", ID: json.RawMessage("1"), Method: method, Params: rawParams})
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	if token != "" {
+		req.Header.Set("X-ASN-Privacy-Token", token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var res JsonrpcMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err, rec.Body.String())
+	}
+	return res
+}
+
+func testLog(addr common.Address) *types.Log {
+	return &types.Log{
+		Address: addr,
+		Topics:  []common.Hash{common.HexToHash("0x01")},
+		Data:    []byte("secret"),
+	}
+}
+
+func TestLogsFilter(t *testing.T) {
+	handler := newTestMiddleware(t, map[string]interface{}{
+		"eth_getLogs": []*types.Log{testLog(protectedAddr), testLog(publicAddr)},
+	})
+
+	var logs []*types.Log
+	res := callTestMiddleware(t, handler, "", "eth_getLogs", map[string]interface{}{})
+	if err := json.Unmarshal(res.Result, &logs); err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 || logs[0].Address != publicAddr {
+		t.Fatal("protected log is not dropped", logs)
+	}
+
+	res = callTestMiddleware(t, handler, "logs token", "eth_getLogs", map[string]interface{}{})
+	if err := json.Unmarshal(res.Result, &logs); err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Fatal("granted log is dropped", logs)
+	}
+}
+
+func TestReceiptFilter(t *testing.T) {
+	handler := newTestMiddleware(t, map[string]interface{}{
+		"eth_getTransactionReceipt": map[string]interface{}{
+			"status":       "0x1",
+			"gasUsedForL1": "0x2",
+			"logs":         []*types.Log{testLog(protectedAddr), testLog(publicAddr)},
+			"logsBloom":    types.Bloom{1},
+			"blockHash":    common.Hash{},
+		},
+	})
+
+	res := callTestMiddleware(t, handler, "", "eth_getTransactionReceipt", common.Hash{})
+	var receipt struct {
+		GasUsedForL1 string       `json:"gasUsedForL1"`
+		Logs         []*types.Log `json:"logs"`
+		LogsBloom    types.Bloom  `json:"logsBloom"`
+	}
+	if err := json.Unmarshal(res.Result, &receipt); err != nil {
+		t.Fatal(err)
+	}
+	if receipt.GasUsedForL1 != "0x2" {
+		t.Fatal("receipt fields are lost", string(res.Result))
+	}
+	if len(receipt.Logs) != 2 || len(receipt.Logs[0].Topics) != 0 || bytes.Equal(receipt.Logs[0].Data, []byte("secret")) {
+		t.Fatal("protected log is not redacted", string(res.Result))
+	}
+	if !bytes.Equal(receipt.Logs[1].Data, []byte("secret")) {
+		t.Fatal("public log is redacted", string(res.Result))
+	}
+	if receipt.LogsBloom != (types.Bloom{}) {
+		t.Fatal("bloom filter is not cleared")
+	}
+}
+
+func TestStateAndTraceFilters(t *testing.T) {
+	handler := newTestMiddleware(t, map[string]interface{}{
+		"eth_call":                 "0x01",
+		"eth_getStorageAt":         "0x02",
+		"debug_traceTransaction":   map[string]interface{}{},
+		"arbtrace_replayBlockTxs":  []interface{}{},
+		"eth_getTransactionByHash": nil,
+	})
+
+	// the logs token does not cover the state of the contract
+	res := callTestMiddleware(t, handler, "logs token", "eth_call", map[string]interface{}{"to": protectedAddr}, "latest")
+	if res.Error == nil || res.Error.Code != defaultPrivacyErrorCode {
+		t.Fatal("call to protected contract is not denied", res)
+	}
+	// a public contract may read the protected one, such as a multicall or a token balance
+	res = callTestMiddleware(t, handler, "", "eth_call", map[string]interface{}{"to": publicAddr}, "latest")
+	if res.Error == nil {
+		t.Fatal("call to public contract is not denied")
+	}
+	res = callTestMiddleware(t, handler, "", "eth_getStorageAt", protectedAddr, "0x0", map[string]interface{}{"blockHash": common.Hash{}})
+	if res.Error == nil {
+		t.Fatal("storage of protected contract is not denied")
+	}
+	for _, method := range []string{"debug_traceTransaction", "arbtrace_replayBlockTxs"} {
+		res = callTestMiddleware(t, handler, "logs token", method, common.Hash{})
+		if res.Error == nil {
+			t.Fatal("trace is not denied", method)
+		}
+	}
+
+	// a registered filter takes over the method
+	RegisterMethodFilter("eth_getTransactionByHash", denyAll("denied by test"))
+	defer RegisterMethodFilter("eth_getTransactionByHash", modifyTxMessage)
+	res = callTestMiddleware(t, handler, "", "eth_getTransactionByHash", common.Hash{})
+	if res.Error == nil || res.Error.Message != "denied by test" {
+		t.Fatal("registered filter is not applied", res)
+	}
+}
+
+func TestUnknownMethodsDenied(t *testing.T) {
+	secret := common.HexToHash("0x1234").Bytes()
+	config := PrivacyRPCConfigDefault
+	config.Enable = true
+	config.ProtectUnlisted = false
+	config.JwtSecret = hexutil.Encode(secret)
+	handler := newConfiguredTestMiddleware(t, map[string]interface{}{
+		"eth_chainId":     "0x1",
+		"eth_call":        "0x01",
+		"eth_estimateGas": "0x5208",
+		"eth_getProof":    map[string]interface{}{},
+		"privacy_grant":   true,
+		"arb_unknown":     "0x01",
+	}, &config, nil)
+	newJwt := func(admin bool) string {
+		claims := &ViewerClaims{Admin: admin}
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Minute))
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	for _, method := range []string{"eth_chainId", "privacy_grant"} {
+		if res := callTestMiddleware(t, handler, "", method); res.Error != nil {
+			t.Fatal("passthrough method is denied", method, res.Error)
+		}
+	}
+	for _, method := range []string{
+		"arb_unknown",
+		"eth_getRawTransactionByHash",
+		"eth_getRawTransactionByBlockNumberAndIndex",
+		"eth_newFilter",
+		"eth_getFilterChanges",
+		"eth_pendingTransactions",
+		"txpool_content",
+		"debug_getRawBlock",
+	} {
+		if res := callTestMiddleware(t, handler, "", method); res.Error == nil {
+			t.Fatal("method is not denied", method)
+		}
+	}
+	if res := callTestMiddleware(t, handler, "", "eth_getProof", protectedAddr, []string{}, "latest"); res.Error == nil {
+		t.Fatal("proof of protected contract is not denied")
+	}
+	if res := callTestMiddleware(t, handler, "", "eth_getProof", publicAddr, []string{}, "latest"); res.Error != nil {
+		t.Fatal("proof of public contract is denied", res.Error)
+	}
+
+	// only admins may run the code of a contract
+	for _, method := range []string{"eth_call", "eth_estimateGas"} {
+		args := map[string]interface{}{"to": publicAddr}
+		if res := callTestMiddleware(t, handler, newJwt(false), method, args, "latest"); res.Error == nil {
+			t.Fatal("method is not denied to a viewer", method)
+		}
+		if res := callTestMiddleware(t, handler, newJwt(true), method, args, "latest"); res.Error != nil {
+			t.Fatal("method is denied to an admin", method, res.Error)
+		}
+	}
+}
+
+func TestBatchFilter(t *testing.T) {
+	handler := newTestMiddleware(t, map[string]interface{}{
+		"eth_getBalance":  "0x100",
+		"eth_chainId":     "0x1",
+		"eth_getCode":     "0x60",
+		"eth_blockNumber": "0x10",
+	})
+	body := `[
+		{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["` + publicAddr.String() + `","latest"]},
+		{"jsonrpc":"2.0","id":2,"method":"eth_chainId","params":[]},
+		{"jsonrpc":"2.0","id":3,"method":"eth_getCode","params":["` + protectedAddr.String() + `","latest"]}
+	]`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(body))))
+	var res []JsonrpcMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err, rec.Body.String())
+	}
+	if len(res) != 3 {
+		t.Fatal("unexpected batch response", rec.Body.String())
+	}
+	for _, r := range res {
+		switch string(r.ID) {
+		case "2":
+			if r.Error != nil {
+				t.Fatal("unfiltered method is denied", r.Error)
+			}
+		case "3":
+			if r.Error == nil {
+				t.Fatal("code of protected contract is not denied in batch")
+			}
+		}
+	}
+
+	// requests sharing an id can't be told apart
+	body = `[
+		{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]},
+		{"jsonrpc":"2.0","id":1,"method":"eth_getCode","params":["` + protectedAddr.String() + `","latest"]}
+	]`
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(body))))
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err, rec.Body.String())
+	}
+	for _, r := range res {
+		if r.Error == nil {
+			t.Fatal("response to duplicate id is not denied", r)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package privacy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+	flag "github.com/spf13/pflag"
+)
+
+type GatewayConfig struct {
+	Upstreams           []string      `koanf:"upstreams"`
+	WsUpstream          string        `koanf:"ws-upstream"`
+	HealthCheckInterval time.Duration `koanf:"health-check-interval"`
+	HealthCheckTimeout  time.Duration `koanf:"health-check-timeout"`
+	RequestTimeout      time.Duration `koanf:"request-timeout"`
+}
+
+var GatewayConfigDefault = GatewayConfig{
+	Upstreams:           []string{},
+	WsUpstream:          "",
+	HealthCheckInterval: time.Second * 10,
+	HealthCheckTimeout:  time.Second * 5,
+	RequestTimeout:      time.Second * 30,
+}
+
+func GatewayConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.StringSlice(prefix+".upstreams", GatewayConfigDefault.Upstreams, "rpc urls of the nodes to proxy to, in order of preference")
+	f.String(prefix+".ws-upstream", GatewayConfigDefault.WsUpstream, "websocket url of the node to proxy websockets to, when it is not served on the rpc url (empty to use the first available upstream)")
+	f.Duration(prefix+".health-check-interval", GatewayConfigDefault.HealthCheckInterval, "how often the upstreams are checked")
+	f.Duration(prefix+".health-check-timeout", GatewayConfigDefault.HealthCheckTimeout, "how long an upstream has to answer the health check")
+	f.Duration(prefix+".request-timeout", GatewayConfigDefault.RequestTimeout, "how long an upstream has to answer a proxied request")
+}
+
+var (
+	upstreamFailoverCounter = metrics.NewRegisteredCounter("arb/privacy/gateway/failover", nil)
+	healthyUpstreamsGauge   = metrics.NewRegisteredGauge("arb/privacy/gateway/upstreams/healthy", nil)
+)
+
+const privacyMethodPrefix = "privacy_"
+
+// headers that are not passed on to the upstream
+var strippedRequestHeaders = []string{
+	"X-ASN-Privacy-Token", // the upstream has no business with the token
+	"Accept-Encoding",     // the middleware needs plain responses to rewrite
+	"Connection",
+	"Content-Length",
+}
+
+type upstream struct {
+	url     *url.URL
+	healthy int32
+	proxy   *httputil.ReverseProxy
+}
+
+func (u *upstream) isHealthy() bool {
+	return atomic.LoadInt32(&u.healthy) == 1
+}
+
+func (u *upstream) setHealthy(healthy bool) {
+	var v int32
+	if healthy {
+		v = 1
+	}
+	if atomic.SwapInt32(&u.healthy, v) != v {
+		log.Info("privacy gateway upstream changed health", "url", u.url.Redacted(), "healthy", healthy)
+	}
+}
+
+// Gateway filters the json-rpc of unmodified nodes it proxies to, and serves the privacy namespace itself
+type Gateway struct {
+	stopwaiter.StopWaiter
+	config    *GatewayConfig
+	upstreams []*upstream
+	client    *http.Client
+	local     http.Handler
+	http      http.Handler
+	ws        http.Handler
+	wsProxy   *httputil.ReverseProxy // nil to proxy websockets to the upstreams
+}
+
+// NewGateway proxies to the configured upstreams, local serves the privacy namespace,
+// and wrapHTTP adds the usual http handlers, such as cors and vhosts, in front of the filtered json-rpc
+func NewGateway(config *GatewayConfig, local http.Handler, wrapHTTP func(http.Handler) http.Handler) (*Gateway, error) {
+	if len(config.Upstreams) == 0 {
+		return nil, errors.New("privacy gateway needs at least one upstream")
+	}
+	g := &Gateway{
+		config: config,
+		client: &http.Client{Timeout: config.RequestTimeout},
+		local:  local,
+	}
+	for _, rawUrl := range config.Upstreams {
+		u, err := url.Parse(rawUrl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid privacy gateway upstream %v: %w", rawUrl, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("privacy gateway upstream %v must be http or https", u.Redacted())
+		}
+		// unhealthy until proven otherwise
+		g.upstreams = append(g.upstreams, &upstream{url: u, proxy: newUpstreamProxy(u)})
+	}
+	if config.WsUpstream != "" {
+		u, err := url.Parse(config.WsUpstream)
+		if err != nil {
+			return nil, fmt.Errorf("invalid privacy gateway websocket upstream %v: %w", config.WsUpstream, err)
+		}
+		// the proxy dials the upgrade request over http
+		switch u.Scheme {
+		case "ws":
+			u.Scheme = "http"
+		case "wss":
+			u.Scheme = "https"
+		default:
+			return nil, fmt.Errorf("privacy gateway websocket upstream %v must be ws or wss", u.Redacted())
+		}
+		g.wsProxy = newUpstreamProxy(u)
+	}
+	g.http = wrapHTTP(RpcResponseMiddleware(http.HandlerFunc(g.route)))
+	g.ws = RpcWsMiddleware(http.HandlerFunc(g.proxyWebsocket))
+	return g, nil
+}
+
+func newUpstreamProxy(u *url.URL) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(u)
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		r.Host = u.Host
+		r.Header.Del("X-ASN-Privacy-Token")
+	}
+	return proxy
+}
+
+// Start launches the health checks of the upstreams
+func (g *Gateway) Start(ctx context.Context) {
+	g.StopWaiter.Start(ctx, g)
+	g.CallIteratively(func(ctx context.Context) time.Duration {
+		g.checkUpstreams(ctx)
+		return g.config.HealthCheckInterval
+	})
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if isWebsocket(r) {
+		g.ws.ServeHTTP(w, r)
+		return
+	}
+	g.http.ServeHTTP(w, r)
+}
+
+func (g *Gateway) checkUpstreams(ctx context.Context) {
+	healthy := int64(0)
+	for _, u := range g.upstreams {
+		err := g.checkUpstream(ctx, u)
+		if err != nil {
+			log.Debug("privacy gateway upstream health check failed", "url", u.url.Redacted(), "err", err)
+		} else {
+			healthy++
+		}
+		u.setHealthy(err == nil)
+	}
+	healthyUpstreamsGauge.Update(healthy)
+}
+
+func (g *Gateway) checkUpstream(ctx context.Context, u *upstream) error {
+	ctx, cancel := context.WithTimeout(ctx, g.config.HealthCheckTimeout)
+	defer cancel()
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.url.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %v", res.Status)
+	}
+	var msg JsonrpcMessage
+	if err := json.NewDecoder(res.Body).Decode(&msg); err != nil {
+		return err
+	}
+	if msg.Error != nil {
+		return errors.New(msg.Error.Message)
+	}
+	return nil
+}
+
+// candidates returns the healthy upstreams in order of preference, or all of them if none is healthy
+func (g *Gateway) candidates() []*upstream {
+	var healthy []*upstream
+	for _, u := range g.upstreams {
+		if u.isHealthy() {
+			healthy = append(healthy, u)
+		}
+	}
+	if len(healthy) == 0 {
+		return g.upstreams
+	}
+	return healthy
+}
+
+// route serves the privacy namespace locally and proxies everything else.
+// Batches mixing privacy and other methods go upstream, which does not know the privacy methods.
+func (g *Gateway) route(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if isPrivacyRequest(body) {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		g.local.ServeHTTP(w, r)
+		return
+	}
+	g.forward(w, r, body)
+}
+
+func isPrivacyRequest(body []byte) bool {
+	var reqMessages []*JsonrpcMessage
+	if isBatch(body) {
+		if err := json.Unmarshal(body, &reqMessages); err != nil || len(reqMessages) == 0 {
+			return false
+		}
+	} else {
+		var reqMessage JsonrpcMessage
+		if err := json.Unmarshal(body, &reqMessage); err != nil {
+			return false
+		}
+		reqMessages = append(reqMessages, &reqMessage)
+	}
+	for _, reqMessage := range reqMessages {
+		if reqMessage == nil || !strings.HasPrefix(reqMessage.Method, privacyMethodPrefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// forward sends the request to the upstreams in turn until one answers
+func (g *Gateway) forward(w http.ResponseWriter, r *http.Request, body []byte) {
+	for i, u := range g.candidates() {
+		if i > 0 {
+			upstreamFailoverCounter.Inc(1)
+		}
+		req, err := http.NewRequestWithContext(r.Context(), r.Method, u.url.String(), bytes.NewReader(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		req.Header = r.Header.Clone()
+		for _, key := range strippedRequestHeaders {
+			req.Header.Del(key)
+		}
+		res, err := g.client.Do(req)
+		if err != nil {
+			if r.Context().Err() != nil {
+				return
+			}
+			log.Warn("privacy gateway upstream failed", "url", u.url.Redacted(), "err", err)
+			u.setHealthy(false)
+			continue
+		}
+		if res.StatusCode >= http.StatusInternalServerError {
+			_ = res.Body.Close()
+			log.Warn("privacy gateway upstream failed", "url", u.url.Redacted(), "status", res.Status)
+			u.setHealthy(false)
+			continue
+		}
+		for key, values := range res.Header {
+			if key == "Content-Length" {
+				// the middleware changes the length
+				continue
+			}
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+		w.WriteHeader(res.StatusCode)
+		_, _ = io.Copy(w, res.Body)
+		_ = res.Body.Close()
+		return
+	}
+	http.Error(w, "no upstream available", http.StatusBadGateway)
+}
+
+// proxyWebsocket hands the websocket connection to the websocket upstream, or the first available upstream
+// if there's none, the privacy websocket middleware in front of it filters the messages
+func (g *Gateway) proxyWebsocket(w http.ResponseWriter, r *http.Request) {
+	if g.wsProxy != nil {
+		g.wsProxy.ServeHTTP(w, r)
+		return
+	}
+	g.candidates()[0].proxy.ServeHTTP(w, r)
+}
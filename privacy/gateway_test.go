@@ -0,0 +1,146 @@
+package privacy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gobwas/ws/wsutil"
+)
+
+// serveTestUpstream answers every request with the code of a contract, and counts the requests it gets
+func serveTestUpstream(t *testing.T, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if r.Header.Get("X-ASN-Privacy-Token") != "" {
+			t.Error("privacy token is passed upstream")
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req JsonrpcMessage
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Error(err)
+			return
+		}
+		res, _ := json.Marshal(JsonrpcMessage{Version: "2.0", ID: req.ID, Result: json.RawMessage(`"0x60"`)})
+		_, _ = w.Write(res)
+	}))
+}
+
+func TestGatewayFailover(t *testing.T) {
+	// set up the privacy wrapper and grants
+	newTestMiddleware(t, nil)
+
+	var downRequests, upRequests int32
+	down := serveTestUpstream(t, &downRequests)
+	down.Close()
+	up := serveTestUpstream(t, &upRequests)
+	defer up.Close()
+
+	localCalls := 0
+	local := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		localCalls++
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":null}`))
+	})
+	config := GatewayConfigDefault
+	config.Upstreams = []string{down.URL, up.URL}
+	gateway, err := NewGateway(&config, local, func(h http.Handler) http.Handler { return h })
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	gateway.checkUpstreams(ctx)
+	if gateway.upstreams[0].isHealthy() || !gateway.upstreams[1].isHealthy() {
+		t.Fatal("unexpected upstream health")
+	}
+
+	res := callTestMiddleware(t, gateway, "logs token", "eth_getCode", publicAddr, "latest")
+	if res.Error != nil || string(res.Result) != `"0x60"` {
+		t.Fatal("public code is not proxied", res)
+	}
+	res = callTestMiddleware(t, gateway, "logs token", "eth_getCode", protectedAddr, "latest")
+	if res.Error == nil {
+		t.Fatal("proxied code of protected contract is not denied")
+	}
+	// one health check and two calls
+	if atomic.LoadInt32(&upRequests) != 3 {
+		t.Fatal("unexpected upstream requests", upRequests)
+	}
+
+	// the privacy namespace is served by the gateway itself
+	callTestMiddleware(t, gateway, "", "privacy_getToken", common.Hash{})
+	if localCalls != 1 || atomic.LoadInt32(&upRequests) != 3 {
+		t.Fatal("privacy namespace is proxied")
+	}
+
+	// an upstream failing between health checks is skipped right away
+	up.Close()
+	gateway.upstreams[0].setHealthy(true)
+	rec := httptest.NewRecorder()
+	gateway.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`))))
+	if rec.Code != http.StatusBadGateway {
+		t.Fatal("expected bad gateway, got", rec.Code)
+	}
+	if gateway.upstreams[0].isHealthy() {
+		t.Fatal("failed upstream is still healthy")
+	}
+}
+
+func TestGatewayWsUpstream(t *testing.T) {
+	// set up the privacy wrapper and grants
+	newTestMiddleware(t, nil)
+
+	var requests int32
+	rpc := serveTestUpstream(t, &requests)
+	defer rpc.Close()
+	wsUpstream := httptest.NewServer(serveTestSubscriptions(t, "0xsub"))
+	defer wsUpstream.Close()
+
+	config := GatewayConfigDefault
+	config.Upstreams = []string{rpc.URL}
+	config.WsUpstream = rpc.URL
+	if _, err := NewGateway(&config, http.NotFoundHandler(), func(h http.Handler) http.Handler { return h }); err == nil {
+		t.Fatal("http websocket upstream is accepted")
+	}
+	config.WsUpstream = "ws" + strings.TrimPrefix(wsUpstream.URL, "http")
+	gateway, err := NewGateway(&config, http.NotFoundHandler(), func(h http.Handler) http.Handler { return h })
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+
+	conn := dialTestWs(t, server.URL)
+	defer conn.Close()
+	err = wsutil.WriteClientText(conn, []byte(`{"jsonrpc":"2.0","id":7,"method":"eth_subscribe","params":["logs",{}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := wsutil.ReadServerText(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res JsonrpcMessage
+	if err := json.Unmarshal(data, &res); err != nil || string(res.Result) != `"0xsub"` {
+		t.Fatal("websocket is not proxied to the websocket upstream", string(data), err)
+	}
+	// the log of the protected contract is dropped
+	data, err = wsutil.ReadServerText(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.ToLower(string(data)), strings.ToLower(publicAddr.Hex()[2:])) {
+		t.Fatal("protected log is notified", string(data))
+	}
+	if atomic.LoadInt32(&requests) != 0 {
+		t.Fatal("websocket is proxied to the rpc upstream")
+	}
+}
@@ -1,16 +1,43 @@
 package privacy
 
-import "context"
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// UpdateFunc returns the new value of a key and its expiration in seconds, or 0 to keep it forever
+type UpdateFunc func(value []byte) (newValue []byte, expiration uint64, err error)
 
 type ICacheService interface {
 	Set(ctx context.Context, key string, value []byte, expiration uint64) (err error)
 	Get(ctx context.Context, key string) (res []byte, err error)
+	Delete(ctx context.Context, key string) (err error)
+	// SetNX sets the key-value pair only if the key is missing or expired, and reports whether it did so atomically
+	SetNX(ctx context.Context, key string, value []byte, expiration uint64) (set bool, err error)
+	// Update replaces the value of the key with what update returns for it, atomically with other updates of the key.
+	// update is passed nil for a missing key, may be called again if another update got in between,
+	// and returns a nil value to remove the key.
+	Update(ctx context.Context, key string, update UpdateFunc) error
 	HealthCheck(ctx context.Context) bool
 }
 
+// sweeper is a cache service that only drops expired entries when asked to
+type sweeper interface {
+	Sweep(ctx context.Context) (int, error)
+}
+
 type IPrivacyAPI interface {
 	//CacheForTest() string
-	SetToken(ctx context.Context, token string, addresses []string, sig string) (interface{}, error)
-	//UpdateToken(ctx context.Context, token string, addresses []string, sig string) (interface{}, error)
+	SetToken(ctx context.Context, token string, addresses []string, ttl hexutil.Uint64, deadline hexutil.Uint64, sig string) (interface{}, error)
+	GrantToken(ctx context.Context, token string, addresses []string, mask hexutil.Uint64, ttl hexutil.Uint64, deadline hexutil.Uint64, sig string) (interface{}, error)
+	RevokeToken(ctx context.Context, token string, addresses []string, deadline hexutil.Uint64, sig string) (interface{}, error)
+	RotateToken(ctx context.Context, oldToken string, newToken string, addresses []string, deadline hexutil.Uint64, sig string) (interface{}, error)
+	ListGrants(ctx context.Context, address string, deadline hexutil.Uint64, sig string) (AccessList, error)
 	GetToken(ctx context.Context, token string, addresses []string) (interface{}, error)
+	ReceiptSigner(ctx context.Context) (common.Address, error)
+	Challenge(ctx context.Context, address common.Address) (string, error)
+	Login(ctx context.Context, message string, sig hexutil.Bytes) (string, error)
+	Logout(ctx context.Context, session string) (bool, error)
 }
@@ -0,0 +1,62 @@
+package privacy
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// the cache counters tell lookups of access lists that found an entry from those that did not,
+// the local ones the lookups served by the local cache in front of redis or the database
+var (
+	deniedCounter         = metrics.NewRegisteredCounter("arb/privacy/denied", nil)
+	redactedCounter       = metrics.NewRegisteredCounter("arb/privacy/redacted", nil)
+	cacheHitCounter       = metrics.NewRegisteredCounter("arb/privacy/cache/hit", nil)
+	cacheMissCounter      = metrics.NewRegisteredCounter("arb/privacy/cache/miss", nil)
+	localCacheHitCounter  = metrics.NewRegisteredCounter("arb/privacy/cache/local/hit", nil)
+	localCacheMissCounter = metrics.NewRegisteredCounter("arb/privacy/cache/local/miss", nil)
+)
+
+// methodMetricName keeps the number of metrics bounded, methods caught by a prefix filter share the prefix
+func methodMetricName(method string) string {
+	if _, ok := methodFilters[method]; ok || method == methodSubscription() {
+		return method
+	}
+	for _, f := range methodPrefixFilters {
+		if strings.HasPrefix(method, f.prefix) {
+			return f.prefix
+		}
+	}
+	return "other"
+}
+
+func methodFilterTimer(method string) metrics.Timer {
+	return metrics.GetOrRegisterTimer("arb/privacy/method/"+methodMetricName(method)+"/latency", nil)
+}
+
+func countDenied(method string) {
+	deniedCounter.Inc(1)
+	metrics.GetOrRegisterCounter("arb/privacy/method/"+methodMetricName(method)+"/denied", nil).Inc(1)
+}
+
+func countRedacted(method string) {
+	redactedCounter.Inc(1)
+	metrics.GetOrRegisterCounter("arb/privacy/method/"+methodMetricName(method)+"/redacted", nil).Inc(1)
+}
+
+// countFiltered tells a denied response from a redacted one by the error the filter put in its place,
+// filters only turn responses into errors to deny them, and reports whether it was denied
+func countFiltered(method string, filtered []byte, ori []byte) bool {
+	if bytes.Equal(filtered, ori) {
+		return false
+	}
+	var res JsonrpcMessage
+	if err := json.Unmarshal(filtered, &res); err == nil && res.Error != nil {
+		countDenied(method)
+		return true
+	}
+	countRedacted(method)
+	return false
+}
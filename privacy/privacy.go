@@ -3,14 +3,21 @@ package privacy
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/offchainlabs/nitro/util/signature"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
 )
 
 // var db ICacheService
@@ -20,20 +27,113 @@ const EmptyInput string = "0x"
 const EmptyHashOrAddress string = ""
 
 type PrivacyWrapper struct {
+	stopwaiter.StopWaiter
 	config *PrivacyConfig
 	cache  ICacheService
+
+	receiptSigner        signature.DataSignerFunc
+	receiptSignerAddress common.Address
+	auditor              *auditor
+	jwtSecret            []byte
+	jwtPublicKey         *ecdsa.PublicKey
+
+	challengeMutex sync.Mutex
+	challenges     map[string]siweChallenge // by nonce
 }
 
-func NewWrapper(config *PrivacyConfig) *PrivacyWrapper {
-	// todo change cache service
-	db, _ := NewBigCache(BigCacheConfigDefault)
+// NewWrapper sets up the privacy wrapper, db is where grants are persisted when the database cache is enabled
+// and dataSigner signs the redaction receipts when they are enabled
+func NewWrapper(config *PrivacyConfig, db ethdb.KeyValueStore, dataSigner signature.DataSignerFunc) (*PrivacyWrapper, error) {
+	cache, err := newCacheService(config, db)
+	if err != nil {
+		return nil, err
+	}
 	wrapper := &PrivacyWrapper{
-		config: config,
-		cache:  db,
+		config:     config,
+		cache:      cache,
+		challenges: make(map[string]siweChallenge),
+	}
+	wrapper.jwtSecret, wrapper.jwtPublicKey, err = loadJwtKeys(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.Audit.Enable {
+		wrapper.auditor = newAuditor(&config.Audit)
+	}
+	if config.RedactionReceipts {
+		if dataSigner == nil {
+			return nil, errors.New("privacy redaction receipts need a data signer")
+		}
+		wrapper.receiptSigner = dataSigner
+		wrapper.receiptSignerAddress, err = signerAddress(dataSigner)
+		if err != nil {
+			return nil, err
+		}
 	}
 	// rewrite current wrapper
 	currentWrapper = wrapper
-	return wrapper
+	return wrapper, nil
+}
+
+// newCacheService picks the token store from the config: the local cache in front of the database,
+// redis alone, the local cache in front of redis, or the local cache alone.
+func newCacheService(config *PrivacyConfig, db ethdb.KeyValueStore) (ICacheService, error) {
+	if config.Database.Enable {
+		if config.RedisCache.Enable {
+			return nil, errors.New("privacy grants can't be kept in both the database and redis")
+		}
+		if db == nil {
+			return nil, errors.New("privacy database cache is enabled but there is no database")
+		}
+		local, err := NewBigCache(config.LocalCache)
+		if err != nil {
+			return nil, err
+		}
+		// the database is only used by this node
+		return NewTieredCache(local, NewDBCache(db), 0), nil
+	}
+	if !config.RedisCache.Enable {
+		return NewBigCache(config.LocalCache)
+	}
+	remote, err := NewRedisCache(config.RedisCache)
+	if err != nil {
+		return nil, err
+	}
+	if !config.LocalCache.Enable {
+		return remote, nil
+	}
+	localConfig := config.LocalCache
+	localConfig.Expiration = config.RedisCache.Refresh
+	local, err := NewBigCache(localConfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewTieredCache(local, remote, config.RedisCache.Refresh), nil
+}
+
+// Start launches the background purge of expired grants and entries and the audit sinks
+func (w *PrivacyWrapper) Start(ctx context.Context) {
+	w.StopWaiter.Start(ctx, w)
+	if w.auditor != nil {
+		w.LaunchThread(w.auditor.run)
+	}
+	if w.config.PurgeInterval == 0 {
+		return
+	}
+	w.CallIteratively(func(ctx context.Context) time.Duration {
+		if err := w.purgeExpired(ctx); err != nil {
+			log.Warn("failed to purge expired privacy grants", "err", err)
+		}
+		if cache, ok := w.cache.(sweeper); ok {
+			removed, err := cache.Sweep(ctx)
+			if err != nil {
+				log.Warn("failed to sweep expired privacy entries", "err", err)
+			} else if removed > 0 {
+				log.Debug("swept expired privacy entries", "removed", removed)
+			}
+		}
+		return w.config.PurgeInterval
+	})
 }
 
 func CurrentWrapper() *PrivacyWrapper {
@@ -49,6 +149,12 @@ type PrivacyResponseWriter struct {
 	hash     hashFunc
 	token    string
 	hasToken bool
+	viewer   *viewer // who the token proves the caller is, if it is a jwt or session token
+
+	clientIP     string
+	forwardedFor string
+	redactions   []Redaction // of the message being filtered
+	accesses     []access    // of the message being filtered
 }
 
 // WriteHeader implements http.ResponseWriter.WriteHeader
@@ -83,11 +189,13 @@ func RpcResponseMiddleware(next http.Handler) http.Handler {
 		}
 		// check bearer token first
 		pw.token, pw.hasToken = containsTokenHeader(r)
-		var responseData []byte
+		pw.clientIP, pw.forwardedFor = clientIP(r)
+		pw.authenticate(r.Context())
 
 		d, err := io.ReadAll(r.Body)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 		_ = r.Body.Close()
 
@@ -97,40 +205,95 @@ func RpcResponseMiddleware(next http.Handler) http.Handler {
 		// serve the request
 		next.ServeHTTP(pw, r)
 
-		// get the response data
-		responseDataOri := pw.buf.Bytes()
-		// unmarshal request message
-		var reqMessage JsonrpcMessage
-		_ = json.Unmarshal(d, &reqMessage)
-
-		switch reqMessage.Method {
-		case methodGetBalance():
-			modifyBalanceMessage(&responseData, &responseDataOri, pw, &reqMessage)
-
-		case methodGetTransaction():
-			modifyTxMessage(&responseData, &responseDataOri, pw)
-
-		case methodGetTransactionCount():
-			modifyTxCountMessage(&responseData, &responseDataOri, pw, &reqMessage)
+		// use gzip writer
+		_, _ = w.Write(filterResponse(pw, d, pw.buf.Bytes()))
 
-		case methodGetTransactionReceipt():
-			modifyTxReceiptMessage(&responseData, &responseDataOri)
+		log.Trace("Privacy API Serve", "batch", isBatch(d), "time", time.Since(startTime))
+	})
+}
 
-		case methodGetBlockByHash():
-			modifyBlockByHashMessage(&responseData, &responseDataOri, pw, &reqMessage)
+// isBatch reports whether the json-rpc message is a batch, as go-ethereum decides it
+func isBatch(msg []byte) bool {
+	for _, c := range msg {
+		// skip insignificant whitespace (http://www.ietf.org/rfc/rfc4627.txt)
+		if c == 0x20 || c == 0x09 || c == 0x0a || c == 0x0d {
+			continue
+		}
+		return c == '['
+	}
+	return false
+}
 
-		case methodGetBlockByNumber():
-			modifyBlockByNumberMessage(&responseData, &responseDataOri, pw, &reqMessage)
+// filterResponse rewrites the response data to the request data, a single message or a batch, for the caller
+func filterResponse(pw *PrivacyResponseWriter, reqData []byte, resData []byte) []byte {
+	if !isBatch(reqData) {
+		var reqMessage JsonrpcMessage
+		_ = json.Unmarshal(reqData, &reqMessage)
+		return filterMessage(pw, []*JsonrpcMessage{&reqMessage}, resData)
+	}
 
-		default:
-			responseData = responseDataOri
+	var reqMessages []*JsonrpcMessage
+	if err := json.Unmarshal(reqData, &reqMessages); err != nil {
+		return resData
+	}
+	var resMessages []json.RawMessage
+	if err := json.Unmarshal(resData, &resMessages); err != nil {
+		// not a batch response, such as the error to an empty batch
+		return resData
+	}
+	// the responses of a batch may come in any order, pair them by id
+	requests := make(map[string][]*JsonrpcMessage, len(reqMessages))
+	for _, reqMessage := range reqMessages {
+		if reqMessage != nil {
+			requests[string(reqMessage.ID)] = append(requests[string(reqMessage.ID)], reqMessage)
 		}
+	}
+	for i, resMessage := range resMessages {
+		var res JsonrpcMessage
+		_ = json.Unmarshal(resMessage, &res)
+		resMessages[i] = filterMessage(pw, requests[string(res.ID)], resMessage)
+	}
+	d, _ := json.Marshal(resMessages)
+	return d
+}
 
-		// use gzip writer
-		_, _ = w.Write(responseData)
-
-		log.Trace("Privacy API Serve", "method", reqMessage.Method, "time", time.Since(startTime))
-	})
+// filterMessage rewrites a single response message to the requests that share its id
+func filterMessage(pw *PrivacyResponseWriter, reqMessages []*JsonrpcMessage, resData []byte) []byte {
+	var responseData []byte
+	switch len(reqMessages) {
+	case 0:
+		// nothing asked for this result, better not to hand it out
+		var res JsonrpcMessage
+		if err := json.Unmarshal(resData, &res); err != nil || res.Result == nil {
+			return resData
+		}
+		deniedMessage(&responseData, &res, defaultPrivacyErrorCode, "unmatched response")
+		countDenied("")
+	case 1:
+		method := reqMessages[0].Method
+		if filter := methodFilter(method); filter != nil {
+			pw.redactions = nil
+			pw.accesses = nil
+			start := time.Now()
+			filter(&responseData, &resData, pw, reqMessages[0])
+			methodFilterTimer(method).UpdateSince(start)
+			pw.audit(method, countFiltered(method, responseData, resData))
+			responseData = withRedactionReceipt(pw, responseData)
+		} else {
+			responseData = resData
+		}
+	default:
+		// requests reusing an id can't be told apart by their response
+		for _, reqMessage := range reqMessages {
+			if methodFilter(reqMessage.Method) != nil {
+				deniedMessage(&responseData, reqMessage, defaultPrivacyErrorCode, "duplicate request id")
+				countDenied(reqMessage.Method)
+				return responseData
+			}
+		}
+		responseData = resData
+	}
+	return responseData
 }
 
 func containsTokenHeader(r *http.Request) (strToken string, has bool) {
@@ -173,35 +336,62 @@ func errorMessage(code int, message string) *jsonError {
 }
 
 func parseAddressFromReq(reqMessage *JsonrpcMessage) (string, error) {
-	var params []string
+	// the block parameter following the address may be an object
+	var params []json.RawMessage
 	err := json.Unmarshal(reqMessage.Params, &params)
 	if err != nil {
 		return "", err
 	}
+	if len(params) == 0 {
+		return "", errors.New("missing address")
+	}
+	var addr string
+	if err := json.Unmarshal(params[0], &addr); err != nil {
+		return "", err
+	}
 	// make sure the address is well formatted
-	return common.HexToAddress(params[0]).String(), nil
+	return common.HexToAddress(addr).String(), nil
 }
 
 func parseHashAndFlagFromReq(reqMessage *JsonrpcMessage) (string, bool) {
 	var params []interface{}
 	_ = json.Unmarshal(reqMessage.Params, &params)
-	return params[0].(string), params[1].(bool)
+	if len(params) < 2 {
+		return "", false
+	}
+	hash, _ := params[0].(string)
+	fullTx, _ := params[1].(bool)
+	return hash, fullTx
 }
 
-func (pw *PrivacyResponseWriter) authorized(token []byte) bool {
-	return pw.hasToken && string(token) == pw.token
+// visible reports whether the request token may see the capability of the address.
+// Addresses nobody was granted are only hidden when the node protects unlisted addresses.
+func (pw *PrivacyResponseWriter) visible(addr string, capability uint64) bool {
+	acl, err := CurrentWrapper().accessList(context.Background(), addr)
+	if err != nil {
+		log.Warn("failed to read privacy access list", "address", addr, "err", err)
+		return false
+	}
+	if len(acl) == 0 && !CurrentWrapper().config.ProtectUnlisted {
+		return true
+	}
+	allowed := pw.viewer.allows(addr, capability) || (pw.hasToken && acl.Allows(pw.token, capability))
+	pw.accesses = append(pw.accesses, access{address: addr, capability: capability, allowed: allowed})
+	return allowed
 }
 
-func getAddressToken(addr string) ([]byte, error) {
-	return CurrentWrapper().cache.Get(context.Background(), addr)
+// calldataVisible reports whether the request token may see the calldata of the transaction
+func (pw *PrivacyResponseWriter) calldataVisible(tx *RPCTransaction) bool {
+	if pw.visible(tx.From.String(), CapabilityCalldata) {
+		return true
+	}
+	return tx.To != nil && pw.visible(tx.To.String(), CapabilityCalldata)
 }
 
 func modifyBalanceMessage(new *[]byte, ori *[]byte, pw *PrivacyResponseWriter, reqMessage *JsonrpcMessage) {
 	addr, _ := parseAddressFromReq(reqMessage)
-	token, _ := getAddressToken(addr)
 	// truly authorized
-	log.Info("RpcResponseMiddleware", "token", pw.token, "hasToken", pw.hasToken, "cache token", string(token))
-	if pw.authorized(token) {
+	if pw.visible(addr, CapabilityBalance) {
 		*new = *ori
 	} else {
 		*new, _ = json.Marshal(JsonrpcMessage{
@@ -217,7 +407,7 @@ func txWithInputHash(hash hashFunc, tx *RPCTransaction) {
 }
 
 // modifyTxMessage modifies the `data` params of response data of the eth_getTransaction method
-func modifyTxMessage(new *[]byte, ori *[]byte, pw *PrivacyResponseWriter) {
+func modifyTxMessage(new *[]byte, ori *[]byte, pw *PrivacyResponseWriter, reqMessage *JsonrpcMessage) {
 
 	var resMessage JsonrpcMessage
 	err := json.Unmarshal(*ori, &resMessage)
@@ -234,21 +424,13 @@ func modifyTxMessage(new *[]byte, ori *[]byte, pw *PrivacyResponseWriter) {
 		return
 	}
 
-	// get token, `From` and `To`
-	tokenFrom, _ := getAddressToken(tx.From.String())
-	var tokenTo []byte
-	if tx.To == nil {
-		tokenTo = []byte(EmptyHashOrAddress)
-	} else {
-		tokenTo, _ = getAddressToken(tx.To.String())
-	}
-
-	// check authorization
-	if pw.authorized(tokenFrom) || pw.authorized(tokenTo) {
+	// check authorization of `From` and `To`
+	if pw.calldataVisible(&tx) {
 		*new = *ori
 	} else {
 		// if not authorized, regenerate the response data
 		txWithInputHash(pw.hash, &tx)
+		pw.redact(redactedInputPath(""), common.BytesToHash(tx.Input))
 		d, _ := json.Marshal(tx)
 		*new, _ = json.Marshal(&JsonrpcMessage{
 			ID:      resMessage.ID,
@@ -260,9 +442,8 @@ func modifyTxMessage(new *[]byte, ori *[]byte, pw *PrivacyResponseWriter) {
 
 func modifyTxCountMessage(new *[]byte, ori *[]byte, pw *PrivacyResponseWriter, reqMessage *JsonrpcMessage) {
 	addr, _ := parseAddressFromReq(reqMessage)
-	token, _ := getAddressToken(addr)
 	// truly authorized
-	if pw.authorized(token) {
+	if pw.visible(addr, CapabilityNonce) {
 		*new = *ori
 	} else {
 		*new, _ = json.Marshal(JsonrpcMessage{
@@ -273,10 +454,6 @@ func modifyTxCountMessage(new *[]byte, ori *[]byte, pw *PrivacyResponseWriter, r
 	}
 }
 
-func modifyTxReceiptMessage(new *[]byte, ori *[]byte) {
-	*new = *ori
-}
-
 func modifyBlockByHashMessage(new *[]byte, ori *[]byte, pw *PrivacyResponseWriter, reqMessage *JsonrpcMessage) {
 	var resMessage JsonrpcMessage
 	err := json.Unmarshal(*ori, &resMessage)
@@ -293,25 +470,17 @@ func modifyBlockByHashMessage(new *[]byte, ori *[]byte, pw *PrivacyResponseWrite
 	if !fullTx {
 		*new = *ori
 	} else {
-		for _, tx := range block.Transactions {
+		for i, tx := range block.Transactions {
 			// if input is empty, return
 			if tx.Input.String() == EmptyInput {
 				txs = append(txs, tx)
 				continue
 			}
-			// get token, `From` and `To`
-			tokenFrom, _ := getAddressToken(tx.From.String())
-			var tokenTo []byte
-			if tx.To == nil {
-				tokenTo = []byte(EmptyHashOrAddress)
-			} else {
-				tokenTo, _ = getAddressToken(tx.To.String())
-			}
-
-			// check authorization
-			if !pw.authorized(tokenFrom) && !pw.authorized(tokenTo) {
+			// check authorization of `From` and `To`
+			if !pw.calldataVisible(&tx) {
 				// if not authorized, regenerate the response data
 				txWithInputHash(pw.hash, &tx)
+				pw.redact(redactedInputPath(fmt.Sprintf("/transactions/%d", i)), common.BytesToHash(tx.Input))
 			}
 			txs = append(txs, tx)
 		}
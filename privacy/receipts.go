@@ -0,0 +1,130 @@
+package privacy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+const redactionReceiptPrefix = "privacy_redactionReceipt"
+
+// Redaction marks a field of the result that was replaced, Path is a json pointer (RFC 6901) into the result,
+// or into the params of a subscription notification, and Commitment the keccak256 hash of the plaintext the field held.
+// The plaintext of redacted log topics is the topics concatenated.
+type Redaction struct {
+	Path       string      `json:"path"`
+	Commitment common.Hash `json:"commitment"`
+}
+
+// Commits reports whether the redacted field held the plaintext
+func (r *Redaction) Commits(plaintext []byte) bool {
+	return crypto.Keccak256Hash(plaintext) == r.Commitment
+}
+
+// RedactionReceipt lists the redactions of a result, signed by the node together with the result as sent
+type RedactionReceipt struct {
+	Redactions []Redaction    `json:"redactions"`
+	Signer     common.Address `json:"signer"`
+	Signature  hexutil.Bytes  `json:"signature"`
+}
+
+// redactionReceiptHash is the hash the node signs, binding the redactions to the compact json of the result
+func redactionReceiptHash(result json.RawMessage, redactions []Redaction) []byte {
+	data := [][]byte{[]byte(redactionReceiptPrefix), crypto.Keccak256(result)}
+	for _, r := range redactions {
+		data = append(data, crypto.Keccak256([]byte(r.Path)), r.Commitment.Bytes())
+	}
+	return crypto.Keccak256(data...)
+}
+
+// VerifyRedactionReceipt checks that the receipt was signed by its signer for the result, as sent by the node
+func VerifyRedactionReceipt(result json.RawMessage, receipt *RedactionReceipt) error {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, result); err != nil {
+		return err
+	}
+	pubKey, err := crypto.SigToPub(redactionReceiptHash(compact.Bytes(), receipt.Redactions), receipt.Signature)
+	if err != nil {
+		return err
+	}
+	if crypto.PubkeyToAddress(*pubKey) != receipt.Signer {
+		return errors.New("redaction receipt is not signed by its signer")
+	}
+	return nil
+}
+
+// signerAddress finds the address of the data signer by signing a throwaway hash
+func signerAddress(signer signature.DataSignerFunc) (common.Address, error) {
+	hash := crypto.Keccak256([]byte(redactionReceiptPrefix))
+	sig, err := signer(hash)
+	if err != nil {
+		return common.Address{}, err
+	}
+	pubKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
+
+// redact records that the field at path now holds the commitment instead of its plaintext
+func (pw *PrivacyResponseWriter) redact(path string, commitment common.Hash) {
+	pw.redactions = append(pw.redactions, Redaction{Path: path, Commitment: commitment})
+}
+
+func redactedInputPath(prefix string) string {
+	return prefix + "/input"
+}
+
+func redactedLogPath(i int, field string) string {
+	return fmt.Sprintf("/logs/%d/%s", i, field)
+}
+
+// withRedactionReceipt adds the signed receipt of the redactions recorded by pw to the json-rpc message data,
+// or returns the data as it is if the node does not issue receipts or nothing was redacted
+func withRedactionReceipt(pw *PrivacyResponseWriter, data []byte) []byte {
+	w := CurrentWrapper()
+	if len(pw.redactions) == 0 || w == nil || w.receiptSigner == nil {
+		return data
+	}
+	var msg JsonrpcMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return data
+	}
+	// a notification carries the result in its params
+	result := msg.Result
+	if result == nil {
+		result = msg.Params
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, result); err != nil {
+		return data
+	}
+	sig, err := w.receiptSigner(redactionReceiptHash(compact.Bytes(), pw.redactions))
+	if err != nil {
+		log.Warn("failed to sign privacy redaction receipt", "err", err)
+		return data
+	}
+	if msg.Result != nil {
+		msg.Result = compact.Bytes()
+	} else {
+		msg.Params = compact.Bytes()
+	}
+	msg.ArbPrivacy = &RedactionReceipt{
+		Redactions: pw.redactions,
+		Signer:     w.receiptSignerAddress,
+		Signature:  sig,
+	}
+	d, err := json.Marshal(msg)
+	if err != nil {
+		return data
+	}
+	return d
+}
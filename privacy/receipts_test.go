@@ -0,0 +1,81 @@
+package privacy
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+func TestRedactionReceipts(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := []byte("calldata")
+	handler := newSignedTestMiddleware(t, map[string]interface{}{
+		"eth_getTransactionByHash": map[string]interface{}{
+			"from":  protectedAddr,
+			"to":    protectedAddr,
+			"input": "0x" + common.Bytes2Hex(input),
+		},
+		"eth_getTransactionReceipt": map[string]interface{}{
+			"logs": []*types.Log{testLog(publicAddr), testLog(protectedAddr)},
+		},
+	}, signature.DataSignerFromPrivateKey(key))
+
+	// calldata of the protected contract
+	res := callTestMiddleware(t, handler, "", "eth_getTransactionByHash", common.Hash{})
+	receipt := res.ArbPrivacy
+	if receipt == nil || len(receipt.Redactions) != 1 || receipt.Redactions[0].Path != "/input" {
+		t.Fatal("unexpected redaction receipt", receipt)
+	}
+	if receipt.Signer != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatal("unexpected receipt signer", receipt.Signer)
+	}
+	if err := VerifyRedactionReceipt(res.Result, receipt); err != nil {
+		t.Fatal(err)
+	}
+	if !receipt.Redactions[0].Commits(input) {
+		t.Fatal("commitment does not match the calldata")
+	}
+	var tx RPCTransaction
+	if err := json.Unmarshal(res.Result, &tx); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tx.Input, receipt.Redactions[0].Commitment.Bytes()) {
+		t.Fatal("redacted field does not hold the commitment")
+	}
+
+	// a receipt does not verify against another result
+	tampered := bytes.Replace(res.Result, []byte(`"to"`), []byte(`"tO"`), 1)
+	if err := VerifyRedactionReceipt(tampered, receipt); err == nil {
+		t.Fatal("receipt verified against a tampered result")
+	}
+
+	// logs of the protected contract in a receipt
+	res = callTestMiddleware(t, handler, "", "eth_getTransactionReceipt", common.Hash{})
+	receipt = res.ArbPrivacy
+	if receipt == nil || len(receipt.Redactions) != 2 {
+		t.Fatal("unexpected redaction receipt", receipt)
+	}
+	if receipt.Redactions[0].Path != "/logs/1/topics" || receipt.Redactions[1].Path != "/logs/1/data" {
+		t.Fatal("unexpected redacted paths", receipt.Redactions)
+	}
+	if !receipt.Redactions[1].Commits([]byte("secret")) {
+		t.Fatal("commitment does not match the log data")
+	}
+	if err := VerifyRedactionReceipt(res.Result, receipt); err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing is redacted for the holder of a grant
+	res = callTestMiddleware(t, handler, "logs token", "eth_getLogs", map[string]interface{}{})
+	if res.ArbPrivacy != nil {
+		t.Fatal("receipt without redactions")
+	}
+}
@@ -2,8 +2,14 @@ package privacy
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/rand"
 	"time"
 
+	"github.com/go-redis/redis/v8"
+	"github.com/offchainlabs/nitro/util/arbmath"
+	"github.com/offchainlabs/nitro/util/redisutil"
 	flag "github.com/spf13/pflag"
 )
 
@@ -18,24 +24,202 @@ var RedisCacheConfigDefault = RedisCacheConfig{
 	Enable:  false,
 	Url:     "redis://127.0.0.1:6379",
 	Key:     "",
-	Refresh: time.Hour * 24,
+	Refresh: time.Minute,
 }
 
 func RedisCacheConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", RedisCacheConfigDefault.Enable, "enable redis cache")
 	f.String(prefix+".url", RedisCacheConfigDefault.Url, "redis url")
-	f.String(prefix+".key", RedisCacheConfigDefault.Key, "redis cache key")
-	f.Duration(prefix+".refresh-duration", RedisCacheConfigDefault.Refresh, "redis cache refresh duration")
+	f.String(prefix+".key", RedisCacheConfigDefault.Key, "prefix prepended to every redis cache key")
+	f.Duration(prefix+".refresh-duration", RedisCacheConfigDefault.Refresh, "how long entries read from redis are kept by the local cache before being read again (only used when the local cache is enabled too)")
 }
 
-func (c *RedisCacheConfig) Set(ctx context.Context, key string, value []byte, expiration uint64) (err error) {
-	return nil
+type RedisStorageService struct {
+	config RedisCacheConfig
+	client redis.UniversalClient
 }
 
-func (c *RedisCacheConfig) Get(ctx context.Context, key string) (res []byte, err error) {
-	return nil, nil
+// NewRedisCache generates a new redis backed storage, shared by every node pointing at the same redis
+func NewRedisCache(config RedisCacheConfig) (ICacheService, error) {
+	client, err := redisutil.RedisClientFromURL(config.Url)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, errors.New("redis url is empty")
+	}
+	return &RedisStorageService{
+		config: config,
+		client: client,
+	}, nil
 }
 
-func (c *RedisCacheConfig) HealthCheck(ctx context.Context) bool {
-	return false
+func (rs *RedisStorageService) key(key string) string {
+	return rs.config.Key + key
+}
+
+// Set sets the key-value pair, the entry is removed by redis after expiration seconds, or kept forever if expiration is 0
+func (rs *RedisStorageService) Set(ctx context.Context, key string, value []byte, expiration uint64) error {
+	return rs.client.Set(ctx, rs.key(key), value, time.Duration(expiration)*time.Second).Err()
+}
+
+// Get returns the value for the given key, or redis.Nil if there is no such entry
+func (rs *RedisStorageService) Get(ctx context.Context, key string) ([]byte, error) {
+	return rs.client.Get(ctx, rs.key(key)).Bytes()
+}
+
+// SetNX sets the key-value pair only if redis has no entry for the key
+func (rs *RedisStorageService) SetNX(ctx context.Context, key string, value []byte, expiration uint64) (bool, error) {
+	return rs.client.SetNX(ctx, rs.key(key), value, time.Duration(expiration)*time.Second).Result()
+}
+
+// Update retries when the key changed under it, backing off a random time that grows with every attempt
+const (
+	redisUpdateAttempts = 32
+	redisUpdateBackoff  = time.Millisecond * 5
+)
+
+// Update watches the key, so the update is discarded and retried if another node changed it meanwhile
+func (rs *RedisStorageService) Update(ctx context.Context, key string, update UpdateFunc) error {
+	key = rs.key(key)
+	for i := 0; i < redisUpdateAttempts; i++ {
+		err := rs.client.Watch(ctx, func(tx *redis.Tx) error {
+			value, err := tx.Get(ctx, key).Bytes()
+			if errors.Is(err, redis.Nil) {
+				value = nil
+			} else if err != nil {
+				return err
+			}
+			newValue, expiration, err := update(value)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				if newValue == nil {
+					return pipe.Del(ctx, key).Err()
+				}
+				return pipe.Set(ctx, key, newValue, time.Duration(expiration)*time.Second).Err()
+			})
+			return err
+		}, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(rand.Int63n(int64(redisUpdateBackoff) * int64(i+1)))):
+		}
+	}
+	return fmt.Errorf("privacy: %v kept changing during the update", key)
+}
+
+func (rs *RedisStorageService) Delete(ctx context.Context, key string) error {
+	return rs.client.Del(ctx, rs.key(key)).Err()
+}
+
+func (rs *RedisStorageService) HealthCheck(ctx context.Context) bool {
+	return rs.client.Ping(ctx).Err() == nil
+}
+
+func (rs *RedisStorageService) String() string {
+	return fmt.Sprintf("RedisStorageService(%v)", rs.config.Key)
+}
+
+// TieredStorageService keeps a local cache in front of a remote one.
+// Writes go to both, reads are served locally and fall back to the remote cache on a miss.
+// Local entries are kept for at most the refresh duration, so the changes other nodes make
+// to the remote cache are seen after it.
+type TieredStorageService struct {
+	local   ICacheService
+	remote  ICacheService
+	refresh uint64 // seconds, 0 if the remote cache isn't shared
+}
+
+func NewTieredCache(local ICacheService, remote ICacheService, refresh time.Duration) ICacheService {
+	ts := &TieredStorageService{
+		local:  local,
+		remote: remote,
+	}
+	if refresh > 0 {
+		ts.refresh = arbmath.MaxInt(uint64(refresh/time.Second), 1)
+	}
+	return ts
+}
+
+// localExpiration is the expiration of the local copy of an entry expiring after expiration seconds
+func (ts *TieredStorageService) localExpiration(expiration uint64) uint64 {
+	if ts.refresh != 0 && (expiration == 0 || expiration > ts.refresh) {
+		return ts.refresh
+	}
+	return expiration
+}
+
+func (ts *TieredStorageService) Set(ctx context.Context, key string, value []byte, expiration uint64) error {
+	if err := ts.remote.Set(ctx, key, value, expiration); err != nil {
+		return err
+	}
+	return ts.local.Set(ctx, key, value, ts.localExpiration(expiration))
+}
+
+func (ts *TieredStorageService) Get(ctx context.Context, key string) ([]byte, error) {
+	res, err := ts.local.Get(ctx, key)
+	if err == nil {
+		localCacheHitCounter.Inc(1)
+		return res, nil
+	}
+	localCacheMissCounter.Inc(1)
+	res, err = ts.remote.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	// a failure to fill the local cache only costs another remote read
+	_ = ts.local.Set(ctx, key, res, ts.localExpiration(0))
+	return res, nil
+}
+
+// SetNX decides on the remote cache, which is the one shared with other nodes
+func (ts *TieredStorageService) SetNX(ctx context.Context, key string, value []byte, expiration uint64) (bool, error) {
+	set, err := ts.remote.SetNX(ctx, key, value, expiration)
+	if err != nil || !set {
+		return false, err
+	}
+	return true, ts.local.Set(ctx, key, value, ts.localExpiration(expiration))
+}
+
+// Update decides on the remote cache and copies the result to the local one
+func (ts *TieredStorageService) Update(ctx context.Context, key string, update UpdateFunc) error {
+	var value []byte
+	var expiration uint64
+	err := ts.remote.Update(ctx, key, func(old []byte) ([]byte, uint64, error) {
+		var err error
+		value, expiration, err = update(old)
+		return value, expiration, err
+	})
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return ts.local.Delete(ctx, key)
+	}
+	return ts.local.Set(ctx, key, value, ts.localExpiration(expiration))
+}
+
+func (ts *TieredStorageService) Delete(ctx context.Context, key string) error {
+	if err := ts.remote.Delete(ctx, key); err != nil {
+		return err
+	}
+	return ts.local.Delete(ctx, key)
+}
+
+// Sweep removes the expired entries of the remote cache, the local one drops them by itself
+func (ts *TieredStorageService) Sweep(ctx context.Context) (int, error) {
+	if remote, ok := ts.remote.(sweeper); ok {
+		return remote.Sweep(ctx)
+	}
+	return 0, nil
+}
+
+func (ts *TieredStorageService) HealthCheck(ctx context.Context) bool {
+	return ts.remote.HealthCheck(ctx) && ts.local.HealthCheck(ctx)
 }
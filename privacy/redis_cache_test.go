@@ -0,0 +1,205 @@
+package privacy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	rs, err := NewRedisCache(RedisCacheConfig{
+		Enable: true,
+		Url:    "redis://" + server.Addr(),
+		Key:    "privacy:",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rs.HealthCheck(ctx) {
+		t.Fatal("redis cache is not healthy")
+	}
+
+	key := "0x0000000000000000000000000000000000000001"
+	val := []byte("this is a redis cache test value")
+
+	_, err = rs.Get(ctx, key)
+	if !errors.Is(err, redis.Nil) {
+		t.Fatal(err)
+	}
+
+	err = rs.Set(ctx, key, val, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := rs.Get(ctx, key)
+	if err != nil || !bytes.Equal(v, val) {
+		t.Fatal(v, err)
+	}
+	if !server.Exists("privacy:" + key) {
+		t.Fatal("key prefix is not applied")
+	}
+
+	// the entry is removed once its expiration passes
+	server.FastForward(11 * time.Second)
+	_, err = rs.Get(ctx, key)
+	if !errors.Is(err, redis.Nil) {
+		t.Fatal(err)
+	}
+
+	// an expiration of 0 keeps the entry forever
+	err = rs.Set(ctx, key, val, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.TTL("privacy:"+key) != 0 {
+		t.Fatal("entry should not expire")
+	}
+
+	server.Close()
+	if rs.HealthCheck(ctx) {
+		t.Fatal("redis cache should not be healthy once the server is gone")
+	}
+}
+
+func TestTieredCache(t *testing.T) {
+	ctx := context.Background()
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	config := PrivacyRPCConfigDefault
+	config.LocalCache.Enable = true
+	config.RedisCache.Enable = true
+	config.RedisCache.Url = "redis://" + server.Addr()
+	cache, err := newCacheService(&config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.(*TieredStorageService); !ok {
+		t.Fatal("expected a tiered cache, got", cache)
+	}
+
+	// a second node sharing the same redis
+	otherNode, err := newCacheService(&config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "0x0000000000000000000000000000000000000002"
+	val := []byte("token")
+	err = cache.Set(ctx, key, val, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := otherNode.Get(ctx, key)
+	if err != nil || !bytes.Equal(v, val) {
+		t.Fatal(v, err)
+	}
+
+	// served from the local tier once redis forgets the entry
+	server.Del(key)
+	v, err = otherNode.Get(ctx, key)
+	if err != nil || !bytes.Equal(v, val) {
+		t.Fatal(v, err)
+	}
+	if !cache.HealthCheck(ctx) {
+		t.Fatal("tiered cache is not healthy")
+	}
+
+	// a revocation on another node is seen once the local entry is refreshed
+	config.RedisCache.Refresh = time.Second
+	cache, err = newCacheService(&config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherNode, err = newCacheService(&config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Set(ctx, key, val, 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := otherNode.Get(ctx, key); err != nil || !bytes.Equal(v, val) {
+		t.Fatal(v, err)
+	}
+	if err := cache.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second * 2)
+	if v, err := otherNode.Get(ctx, key); err == nil {
+		t.Fatal("revoked entry is still served locally", v)
+	}
+}
+
+func TestSetNX(t *testing.T) {
+	ctx := context.Background()
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	redisCache, err := NewRedisCache(RedisCacheConfig{Enable: true, Url: "redis://" + server.Addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := NewBigCache(BigCacheConfigDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherLocal, err := NewBigCache(BigCacheConfigDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caches := map[string]ICacheService{
+		"redis":    redisCache,
+		"local":    local,
+		"database": NewDBCache(rawdb.NewMemoryDatabase()),
+		"tiered":   NewTieredCache(otherLocal, redisCache, time.Minute),
+	}
+	for name, cache := range caches {
+		key := "used signature " + name
+		var wg sync.WaitGroup
+		var set int32
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ok, err := cache.SetNX(ctx, key, []byte{1}, 10)
+				if err != nil {
+					t.Error(err)
+				}
+				if ok {
+					atomic.AddInt32(&set, 1)
+				}
+			}()
+		}
+		wg.Wait()
+		if set != 1 {
+			t.Fatal("key was set", set, "times by", name)
+		}
+	}
+
+	// an expired entry may be set again
+	server.FastForward(11 * time.Second)
+	if ok, err := redisCache.SetNX(ctx, "used signature redis", []byte{1}, 10); err != nil || !ok {
+		t.Fatal("expired entry is not replaced", ok, err)
+	}
+}
@@ -83,6 +83,8 @@ type JsonrpcMessage struct {
 	Params  json.RawMessage `json:"params,omitempty"`
 	Error   *jsonError      `json:"error,omitempty"`
 	Result  json.RawMessage `json:"result,omitempty"`
+
+	ArbPrivacy *RedactionReceipt `json:"arbPrivacy,omitempty"`
 }
 
 type jsonError struct {
@@ -91,8 +93,11 @@ type jsonError struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// AuthToken is a grant of the capabilities in Mask on a protected address to the holder of Token,
+// valid until ExpirationTime, or forever if it is 0
 type AuthToken struct {
-	Token        string
-	Address      []string
-	CreationTime uint64
+	Token          string `json:"token"`
+	Mask           uint64 `json:"mask"`
+	CreationTime   uint64 `json:"creationTime"`
+	ExpirationTime uint64 `json:"expirationTime,omitempty"`
 }
@@ -0,0 +1,454 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+// SubscriptionFilter rewrites the result of a subscription notification for the caller behind pw,
+// returning false to drop the notification
+type SubscriptionFilter func(pw *PrivacyResponseWriter, result json.RawMessage) (json.RawMessage, bool)
+
+var subscriptionFilters = map[string]SubscriptionFilter{
+	"logs":                   filterLogNotification,
+	"newPendingTransactions": filterTxNotification,
+}
+
+// RegisterSubscriptionFilter installs the filter for the notifications of a subscription kind, such as "logs"
+func RegisterSubscriptionFilter(kind string, filter SubscriptionFilter) {
+	subscriptionFilters[kind] = filter
+}
+
+func methodSubscribe() string {
+	return "eth_subscribe"
+}
+
+func methodUnsubscribe() string {
+	return "eth_unsubscribe"
+}
+
+func methodSubscription() string {
+	return "eth_subscription"
+}
+
+func filterLogNotification(pw *PrivacyResponseWriter, result json.RawMessage) (json.RawMessage, bool) {
+	var l struct {
+		Address string `json:"address"`
+	}
+	if err := json.Unmarshal(result, &l); err != nil {
+		return nil, false
+	}
+	return result, pw.visible(l.Address, CapabilityLogs)
+}
+
+// filterTxNotification hashes the calldata of pending transactions sent in full, plain hashes pass through
+func filterTxNotification(pw *PrivacyResponseWriter, result json.RawMessage) (json.RawMessage, bool) {
+	var tx RPCTransaction
+	if err := json.Unmarshal(result, &tx); err != nil {
+		return result, true
+	}
+	if tx.Input.String() == EmptyInput || pw.calldataVisible(&tx) {
+		return result, true
+	}
+	txWithInputHash(pw.hash, &tx)
+	pw.redact(redactedInputPath("/result"), common.BytesToHash(tx.Input))
+	d, _ := json.Marshal(tx)
+	return d, true
+}
+
+// RpcWsMiddleware filters json-rpc over websocket the way RpcResponseMiddleware does over http.
+// Each connection is proxied to next, responses are filtered by the request they answer,
+// and subscription notifications by the kind of subscription they belong to.
+func RpcWsMiddleware(next http.Handler) http.Handler {
+	upstream := newPipeListener()
+	go func() {
+		// the listener is never closed, the server lives as long as the handler
+		_ = (&http.Server{Handler: next}).Serve(upstream)
+	}()
+	dialer := ws.Dialer{
+		NetDial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return upstream.dial()
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if currentWrapper == nil || !currentWrapper.config.Enable || !isWebsocket(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		pw := &PrivacyResponseWriter{
+			hash: crypto.Keccak256Hash,
+		}
+		pw.token, pw.hasToken = containsTokenHeader(r)
+		pw.clientIP, pw.forwardedFor = clientIP(r)
+		pw.authenticate(r.Context())
+
+		// the upstream checks the origin and jwt of the client
+		header := http.Header{}
+		for _, key := range []string{"Origin", "Authorization"} {
+			if value := r.Header.Get(key); value != "" {
+				header.Set(key, value)
+			}
+		}
+		d := dialer
+		d.Header = ws.HandshakeHeaderHTTP(header)
+		upstreamConn, br, _, err := d.Dial(r.Context(), "ws://upstream"+r.URL.RequestURI())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		if br != nil {
+			upstreamConn = &bufferedConn{Conn: upstreamConn, r: io.MultiReader(br, upstreamConn)}
+		}
+		clientConn, rw, _, err := ws.UpgradeHTTP(r, w)
+		if err != nil {
+			_ = upstreamConn.Close()
+			return
+		}
+		// the server may have left its timeouts on the hijacked connection
+		_ = clientConn.SetDeadline(time.Time{})
+		if rw != nil && rw.Reader.Buffered() > 0 {
+			clientConn = &bufferedConn{Conn: clientConn, r: io.MultiReader(rw.Reader, clientConn)}
+		}
+
+		session := &wsSession{
+			pw:            pw,
+			pending:       make(map[string][]*JsonrpcMessage),
+			subscriptions: make(map[string]string),
+		}
+		go func() {
+			defer upstreamConn.Close()
+			session.forwardRequests(clientConn, upstreamConn)
+		}()
+		defer clientConn.Close()
+		session.forwardResponses(upstreamConn, clientConn)
+	})
+}
+
+func isWebsocket(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// wsSession pairs the messages of one websocket connection
+type wsSession struct {
+	pw *PrivacyResponseWriter
+
+	mutex         sync.Mutex
+	pending       map[string][]*JsonrpcMessage // requests waiting for a response, by id
+	subscriptions map[string]string            // subscription kinds, by subscription id
+
+	writeMutex sync.Mutex // both directions answer the client
+}
+
+func (s *wsSession) writeClient(client net.Conn, op ws.OpCode, payload []byte) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	return wsutil.WriteServerMessage(client, op, payload)
+}
+
+// forwardRequests records the requests of the client before passing them upstream
+func (s *wsSession) forwardRequests(client net.Conn, upstream net.Conn) {
+	for {
+		msgs, err := wsutil.ReadClientMessage(client, nil)
+		if err != nil {
+			return
+		}
+		for _, msg := range msgs {
+			if msg.OpCode == ws.OpText || msg.OpCode == ws.OpBinary {
+				if rejection := s.track(msg.Payload); rejection != nil {
+					if err := s.writeClient(client, msg.OpCode, rejection); err != nil {
+						return
+					}
+					continue
+				}
+			}
+			if err := wsutil.WriteClientMessage(upstream, msg.OpCode, msg.Payload); err != nil {
+				return
+			}
+			if msg.OpCode == ws.OpClose {
+				return
+			}
+		}
+	}
+}
+
+// forwardResponses filters the responses and notifications of the upstream before passing them to the client
+func (s *wsSession) forwardResponses(upstream net.Conn, client net.Conn) {
+	for {
+		msgs, err := wsutil.ReadServerMessage(upstream, nil)
+		if err != nil {
+			return
+		}
+		for _, msg := range msgs {
+			payload := msg.Payload
+			if msg.OpCode == ws.OpText || msg.OpCode == ws.OpBinary {
+				payload = s.filter(payload)
+				if payload == nil {
+					continue
+				}
+			}
+			if err := s.writeClient(client, msg.OpCode, payload); err != nil {
+				return
+			}
+			if msg.OpCode == ws.OpClose {
+				return
+			}
+		}
+	}
+}
+
+// track records the requests of the client. A subscription must be the only request waiting on its id,
+// or its response can't be told apart and the kind of its notifications stays unknown, so such messages
+// are not passed upstream and track returns the error response for the client instead.
+func (s *wsSession) track(data []byte) []byte {
+	batch := isBatch(data)
+	var reqMessages []*JsonrpcMessage
+	if batch {
+		if err := json.Unmarshal(data, &reqMessages); err != nil {
+			return nil
+		}
+	} else {
+		var reqMessage JsonrpcMessage
+		if err := json.Unmarshal(data, &reqMessage); err != nil {
+			return nil
+		}
+		reqMessages = append(reqMessages, &reqMessage)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.reusesSubscriptionID(reqMessages) {
+		log.Trace("privacy rejected request reusing the id of a subscription")
+		countRedacted(methodSubscribe())
+		return rejectRequests(reqMessages, batch)
+	}
+	for _, reqMessage := range reqMessages {
+		// notifications get no response
+		if reqMessage != nil && reqMessage.ID != nil {
+			s.pending[string(reqMessage.ID)] = append(s.pending[string(reqMessage.ID)], reqMessage)
+		}
+	}
+	return nil
+}
+
+// reusesSubscriptionID reports whether a subscription would share its id with another request waiting for a response,
+// the caller holds the mutex
+func (s *wsSession) reusesSubscriptionID(reqMessages []*JsonrpcMessage) bool {
+	methods := make(map[string][]string)
+	for id, pending := range s.pending {
+		for _, reqMessage := range pending {
+			methods[id] = append(methods[id], reqMessage.Method)
+		}
+	}
+	for _, reqMessage := range reqMessages {
+		if reqMessage != nil && reqMessage.ID != nil {
+			methods[string(reqMessage.ID)] = append(methods[string(reqMessage.ID)], reqMessage.Method)
+		}
+	}
+	for _, pending := range methods {
+		if len(pending) < 2 {
+			continue
+		}
+		for _, method := range pending {
+			if method == methodSubscribe() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rejectRequests answers every request of the message with an error, one of them has an id for it to be rejected
+func rejectRequests(reqMessages []*JsonrpcMessage, batch bool) []byte {
+	responses := make([]*JsonrpcMessage, 0, len(reqMessages))
+	for _, reqMessage := range reqMessages {
+		if reqMessage == nil || reqMessage.ID == nil {
+			continue
+		}
+		responses = append(responses, &JsonrpcMessage{
+			Version: "2.0",
+			ID:      reqMessage.ID,
+			Error:   &jsonError{Code: -32600, Message: "request id is in use by another request of a subscription"},
+		})
+	}
+	if !batch {
+		d, _ := json.Marshal(responses[0])
+		return d
+	}
+	d, _ := json.Marshal(responses)
+	return d
+}
+
+// filter rewrites a message of the upstream, returning nil if nothing is left to send
+func (s *wsSession) filter(data []byte) []byte {
+	if !isBatch(data) {
+		return s.filterElement(data)
+	}
+	var resMessages []json.RawMessage
+	if err := json.Unmarshal(data, &resMessages); err != nil {
+		return data
+	}
+	filtered := make([]json.RawMessage, 0, len(resMessages))
+	for _, resMessage := range resMessages {
+		if d := s.filterElement(resMessage); d != nil {
+			filtered = append(filtered, d)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+	d, _ := json.Marshal(filtered)
+	return d
+}
+
+func (s *wsSession) filterElement(data []byte) []byte {
+	var res JsonrpcMessage
+	if err := json.Unmarshal(data, &res); err != nil {
+		return data
+	}
+	if res.Method == methodSubscription() {
+		return s.filterNotification(&res, data)
+	}
+
+	s.mutex.Lock()
+	reqMessages := s.pending[string(res.ID)]
+	if len(reqMessages) > 1 {
+		s.pending[string(res.ID)] = reqMessages[1:]
+	} else {
+		delete(s.pending, string(res.ID))
+	}
+	if len(reqMessages) == 1 && res.Error == nil {
+		s.trackSubscription(reqMessages[0], &res)
+	}
+	s.mutex.Unlock()
+
+	return filterMessage(s.pw, reqMessages, data)
+}
+
+// trackSubscription keeps the kind of subscriptions the client opens, the caller holds the mutex
+func (s *wsSession) trackSubscription(reqMessage *JsonrpcMessage, res *JsonrpcMessage) {
+	var params []json.RawMessage
+	_ = json.Unmarshal(reqMessage.Params, &params)
+	switch reqMessage.Method {
+	case methodSubscribe():
+		var kind, id string
+		if len(params) == 0 || json.Unmarshal(params[0], &kind) != nil || json.Unmarshal(res.Result, &id) != nil {
+			return
+		}
+		s.subscriptions[id] = kind
+	case methodUnsubscribe():
+		var id string
+		if len(params) == 0 || json.Unmarshal(params[0], &id) != nil {
+			return
+		}
+		delete(s.subscriptions, id)
+	}
+}
+
+func (s *wsSession) filterNotification(res *JsonrpcMessage, data []byte) []byte {
+	var params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(res.Params, &params); err != nil {
+		return nil
+	}
+	s.mutex.Lock()
+	kind, known := s.subscriptions[params.Subscription]
+	s.mutex.Unlock()
+	if !known {
+		// the notification can't be filtered without knowing what it is
+		log.Trace("privacy dropped notification of unknown subscription", "subscription", params.Subscription)
+		countRedacted(methodSubscription())
+		return nil
+	}
+
+	filter, ok := subscriptionFilters[kind]
+	if !ok {
+		return data
+	}
+	s.pw.redactions = nil
+	s.pw.accesses = nil
+	result, keep := filter(s.pw, params.Result)
+	if !keep {
+		log.Trace("privacy dropped subscription notification", "kind", kind)
+		countRedacted(methodSubscription())
+		s.pw.audit(methodSubscription(), false)
+		return nil
+	}
+	s.pw.audit(methodSubscription(), countFiltered(methodSubscription(), result, params.Result))
+	params.Result = result
+	res.Params, _ = json.Marshal(params)
+	d, _ := json.Marshal(res)
+	return withRedactionReceipt(s.pw, d)
+}
+
+// bufferedConn reads the data buffered during the handshake before the connection itself
+type bufferedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// pipeListener hands in-memory connections to an http server
+type pipeListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+}
+
+func (l *pipeListener) dial() (net.Conn, error) {
+	server, client := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
@@ -0,0 +1,166 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+// serveTestSubscriptions answers eth_subscribe with the subscription 0xsub,
+// and then notifies a log of protectedAddr and one of publicAddr on the subscription notified
+func serveTestSubscriptions(t *testing.T, notified string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _, err := ws.UpgradeHTTP(r, w)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		data, _, err := wsutil.ReadClientData(conn)
+		if err != nil {
+			return
+		}
+		var req JsonrpcMessage
+		if err := json.Unmarshal(data, &req); err != nil {
+			t.Error(err)
+			return
+		}
+		res, _ := json.Marshal(JsonrpcMessage{Version: "2.0", ID: req.ID, Result: json.RawMessage(`"0xsub"`)})
+		if err := wsutil.WriteServerText(conn, res); err != nil {
+			return
+		}
+		for _, l := range []*types.Log{testLog(protectedAddr), testLog(publicAddr)} {
+			result, _ := json.Marshal(l)
+			params, _ := json.Marshal(map[string]interface{}{"subscription": notified, "result": json.RawMessage(result)})
+			notification, _ := json.Marshal(JsonrpcMessage{Version: "2.0", Method: "eth_subscription", Params: params})
+			if err := wsutil.WriteServerText(conn, notification); err != nil {
+				return
+			}
+		}
+		// wait for the client to go away
+		_, _, _ = wsutil.ReadClientData(conn)
+	})
+}
+
+func TestWsSubscriptionFilter(t *testing.T) {
+	// set up the privacy wrapper and grants
+	newTestMiddleware(t, nil)
+	server := httptest.NewServer(RpcWsMiddleware(serveTestSubscriptions(t, "0xsub")))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, _, err := ws.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	err = wsutil.WriteClientText(conn, []byte(`{"jsonrpc":"2.0","id":7,"method":"eth_subscribe","params":["logs",{}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := wsutil.ReadServerText(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res JsonrpcMessage
+	if err := json.Unmarshal(data, &res); err != nil || string(res.ID) != "7" {
+		t.Fatal("unexpected subscription response", string(data), err)
+	}
+
+	// the log of the protected contract is dropped
+	data, err = wsutil.ReadServerText(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notification struct {
+		Params struct {
+			Result types.Log `json:"result"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(data, &notification); err != nil {
+		t.Fatal(err)
+	}
+	if notification.Params.Result.Address != publicAddr {
+		t.Fatal("protected log is notified", string(data))
+	}
+}
+
+func dialTestWs(t *testing.T, url string) net.Conn {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, _, err := ws.Dial(ctx, "ws"+strings.TrimPrefix(url, "http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func TestWsUnknownSubscriptionDenied(t *testing.T) {
+	newTestMiddleware(t, nil)
+	server := httptest.NewServer(RpcWsMiddleware(serveTestSubscriptions(t, "0xother")))
+	defer server.Close()
+	conn := dialTestWs(t, server.URL)
+	defer conn.Close()
+
+	err := wsutil.WriteClientText(conn, []byte(`{"jsonrpc":"2.0","id":7,"method":"eth_subscribe","params":["logs",{}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wsutil.ReadServerText(conn); err != nil {
+		t.Fatal(err)
+	}
+	// neither log belongs to a subscription the client opened
+	_ = conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if data, err := wsutil.ReadServerText(conn); err == nil {
+		t.Fatal("notification of unknown subscription passed", string(data))
+	}
+}
+
+func TestWsSubscriptionIdReuseRejected(t *testing.T) {
+	newTestMiddleware(t, nil)
+	server := httptest.NewServer(RpcWsMiddleware(serveTestSubscriptions(t, "0xsub")))
+	defer server.Close()
+	conn := dialTestWs(t, server.URL)
+	defer conn.Close()
+
+	// the upstream would fail the test on the batch
+	batch := `[{"jsonrpc":"2.0","id":1,"method":"eth_subscribe","params":["logs",{}]},{"jsonrpc":"2.0","id":1,"method":"eth_subscribe","params":["logs",{}]}]`
+	if err := wsutil.WriteClientText(conn, []byte(batch)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := wsutil.ReadServerText(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var responses []JsonrpcMessage
+	if err := json.Unmarshal(data, &responses); err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 2 || responses[0].Error == nil || responses[1].Error == nil {
+		t.Fatal("subscriptions reusing an id weren't rejected", string(data))
+	}
+
+	session := &wsSession{pending: make(map[string][]*JsonrpcMessage), subscriptions: make(map[string]string)}
+	if session.track([]byte(`{"jsonrpc":"2.0","id":2,"method":"eth_subscribe","params":["logs",{}]}`)) != nil {
+		t.Fatal("first subscription rejected")
+	}
+	if session.track([]byte(`{"jsonrpc":"2.0","id":2,"method":"eth_blockNumber"}`)) == nil {
+		t.Fatal("request reusing the id of a pending subscription passed")
+	}
+	if session.track([]byte(`{"jsonrpc":"2.0","id":3,"method":"eth_blockNumber"}`)) != nil {
+		t.Fatal("request with a new id rejected")
+	}
+}
@@ -5,13 +5,19 @@ package relay
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"errors"
+	"fmt"
 	"net"
-	"time"
+	"net/http"
+	"strings"
 
 	flag "github.com/spf13/pflag"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/broadcastclient"
@@ -20,12 +26,25 @@ import (
 	"github.com/offchainlabs/nitro/cmd/genericconf"
 	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
 	"github.com/offchainlabs/nitro/util/sharedmetrics"
+	"github.com/offchainlabs/nitro/util/signature"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
 	"github.com/offchainlabs/nitro/wsbroadcastserver"
 )
 
+var (
+	invalidSignatureCounter = metrics.NewRegisteredCounter("arb/relay/signature/invalid", nil)
+	droppedMessageCounter   = metrics.NewRegisteredCounter("arb/relay/signature/dropped", nil)
+	resignFailureCounter    = metrics.NewRegisteredCounter("arb/relay/signature/failed/sign", nil)
+)
+
 type Relay struct {
 	stopwaiter.StopWaiter
+	config                      *Config
+	sigVerifier                 *signature.Verifier
+	signer                      signature.DataSignerFunc
+	signerAddress               common.Address
+	feedErrChan                 chan error
+	messageQueue                *MessageQueue
 	broadcastClients            *broadcastclients.BroadcastClients
 	broadcaster                 *broadcaster.Broadcaster
 	confirmedSequenceNumberChan chan arbutil.MessageIndex
@@ -50,78 +69,109 @@ func NewRelay(config *Config, feedErrChan chan error) (*Relay, error) {
 
 	confirmedSequenceNumberListener := make(chan arbutil.MessageIndex, config.Queue)
 
-	clients, err := broadcastclients.NewBroadcastClients(
-		func() *broadcastclient.Config { return &config.Node.Feed.Input },
-		config.L2.ChainId,
-		0,
-		&q,
-		confirmedSequenceNumberListener,
-		feedErrChan,
-		nil,
-	)
-	if err != nil {
+	// The feed clients are created on Start, once the backlog says where to resume from
+	if len(config.Node.Feed.Input.URLs) == 0 {
+		return nil, errors.New("no feed servers found")
+	}
+
+	if err := config.Signature.Validate(); err != nil {
 		return nil, err
 	}
-	if clients == nil {
-		return nil, errors.New("no feed servers found")
+	var sigVerifier *signature.Verifier
+	if config.Signature.Verify {
+		var err error
+		sigVerifier, err = signature.NewVerifier(&config.Signature.Verifier, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var signer signature.DataSignerFunc
+	var signerAddress common.Address
+	if config.Signature.SigningKey != "" {
+		privateKey, err := config.Signature.privateKey()
+		if err != nil {
+			return nil, fmt.Errorf("unable to load relay signing key: %w", err)
+		}
+		signer = signature.DataSignerFromPrivateKey(privateKey)
+		signerAddress = crypto.PubkeyToAddress(privateKey.PublicKey)
+		log.Info("relay re-signing feed messages", "signer", signerAddress)
 	}
 
+	// Messages are re-signed by the relay as they're forwarded, not by the broadcaster
 	dataSignerErr := func([]byte) ([]byte, error) {
 		return nil, errors.New("relay attempted to sign feed message")
 	}
 	return &Relay{
+		config:                      config,
+		sigVerifier:                 sigVerifier,
+		signer:                      signer,
+		signerAddress:               signerAddress,
+		feedErrChan:                 feedErrChan,
+		messageQueue:                &q,
 		broadcaster:                 broadcaster.NewBroadcaster(func() *wsbroadcastserver.BroadcasterConfig { return &config.Node.Feed.Output }, config.L2.ChainId, feedErrChan, dataSignerErr),
-		broadcastClients:            clients,
 		confirmedSequenceNumberChan: confirmedSequenceNumberListener,
 		messageChan:                 q.queue,
 	}, nil
 }
 
-const RECENT_FEED_ITEM_TTL = time.Second * 10
-const RECENT_FEED_INITIAL_MAP_SIZE = 1024
-
 func (r *Relay) Start(ctx context.Context) error {
 	r.StopWaiter.Start(ctx, r)
 	err := r.broadcaster.Initialize()
 	if err != nil {
-		return errors.New("broadcast unable to initialize")
+		return fmt.Errorf("broadcast unable to initialize: %w", err)
+	}
+
+	// Ask the upstream feed for whatever the backlog is missing since the relay last ran
+	nextSeqNum, hasBacklog, err := r.broadcaster.BacklogNextSequenceNumber()
+	if err != nil {
+		return fmt.Errorf("unable to read feed backlog: %w", err)
+	}
+	if hasBacklog {
+		log.Info("resuming feed from backlog", "nextSeqNum", nextSeqNum)
+	}
+	r.broadcastClients, err = broadcastclients.NewBroadcastClients(
+		func() *broadcastclient.Config { return &r.config.Node.Feed.Input },
+		r.config.L2.ChainId,
+		nextSeqNum,
+		r.messageQueue,
+		r.confirmedSequenceNumberChan,
+		r.feedErrChan,
+		nil,
+	)
+	if err != nil {
+		return err
 	}
-	err = r.broadcaster.Start(ctx)
+
+	var extraHeader http.Header
+	if r.signer != nil {
+		extraHeader = http.Header{wsbroadcastserver.HTTPHeaderFeedSigner: []string{r.signerAddress.Hex()}}
+	}
+	err = r.broadcaster.StartWithExtraHeader(ctx, extraHeader)
 	if err != nil {
 		return errors.New("broadcast unable to start")
 	}
 
 	r.broadcastClients.Start(ctx)
 
+	// Messages from more than one feed are deduplicated by the broadcast clients
 	var lastConfirmed arbutil.MessageIndex
-	recentFeedItemsNew := make(map[arbutil.MessageIndex]time.Time, RECENT_FEED_INITIAL_MAP_SIZE)
-	recentFeedItemsOld := make(map[arbutil.MessageIndex]time.Time, RECENT_FEED_INITIAL_MAP_SIZE)
 	r.LaunchThread(func(ctx context.Context) {
-		recentFeedItemsCleanup := time.NewTicker(RECENT_FEED_ITEM_TTL)
-		defer recentFeedItemsCleanup.Stop()
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case msg := <-r.messageChan:
-				if _, ok := recentFeedItemsNew[msg.SequenceNumber]; ok {
-					continue
-				}
-				if _, ok := recentFeedItemsOld[msg.SequenceNumber]; ok {
+				if !r.checkSignature(ctx, &msg) {
 					continue
 				}
-				recentFeedItemsNew[msg.SequenceNumber] = time.Now()
 				sharedmetrics.UpdateSequenceNumberGauge(msg.SequenceNumber)
 				r.broadcaster.BroadcastSingleFeedMessage(&msg)
 			case cs := <-r.confirmedSequenceNumberChan:
-				if lastConfirmed == cs {
+				if cs <= lastConfirmed {
 					continue
 				}
+				lastConfirmed = cs
 				r.broadcaster.Confirm(cs)
-			case <-recentFeedItemsCleanup.C:
-				// Cycle buckets to get rid of old entries
-				recentFeedItemsOld = recentFeedItemsNew
-				recentFeedItemsNew = make(map[arbutil.MessageIndex]time.Time, RECENT_FEED_INITIAL_MAP_SIZE)
 			}
 		}
 	})
@@ -129,13 +179,49 @@ func (r *Relay) Start(ctx context.Context) error {
 	return nil
 }
 
+// checkSignature verifies the signature of a message from upstream, and re-signs it if the relay has a key.
+// It returns false if the message should be dropped.
+func (r *Relay) checkSignature(ctx context.Context, msg *broadcaster.BroadcastFeedMessage) bool {
+	if r.sigVerifier == nil {
+		return true
+	}
+	hash, err := msg.Hash(r.config.L2.ChainId)
+	if err == nil {
+		err = r.sigVerifier.VerifyHash(ctx, msg.Signature, hash)
+	}
+	if err != nil {
+		invalidSignatureCounter.Inc(1)
+		if r.config.Signature.DropInvalid {
+			log.Warn("dropping feed message with invalid signature", "sequenceNumber", msg.SequenceNumber, "err", err)
+			droppedMessageCounter.Inc(1)
+			return false
+		}
+		// Forwarded as it is, without the relay vouching for it
+		log.Warn("forwarding feed message with invalid signature", "sequenceNumber", msg.SequenceNumber, "err", err)
+		return true
+	}
+	if r.signer != nil && len(msg.Signature) > 0 {
+		// a message without a signature is never vouched for, even if the verifier let it through
+		sig, err := r.signer(hash.Bytes())
+		if err != nil {
+			log.Error("unable to re-sign feed message", "sequenceNumber", msg.SequenceNumber, "err", err)
+			resignFailureCounter.Inc(1)
+			return true
+		}
+		msg.Signature = sig
+	}
+	return true
+}
+
 func (r *Relay) GetListenerAddr() net.Addr {
 	return r.broadcaster.ListenerAddr()
 }
 
 func (r *Relay) StopAndWait() {
 	r.StopWaiter.StopAndWait()
-	r.broadcastClients.StopAndWait()
+	if r.broadcastClients != nil {
+		r.broadcastClients.StopAndWait()
+	}
 	r.broadcaster.StopAndWait()
 }
 
@@ -148,6 +234,7 @@ type Config struct {
 	MetricsServer genericconf.MetricsServerConfig `koanf:"metrics-server"`
 	Node          NodeConfig                      `koanf:"node"`
 	Queue         int                             `koanf:"queue"`
+	Signature     SignatureConfig                 `koanf:"signature"`
 }
 
 var ConfigDefault = Config{
@@ -159,6 +246,7 @@ var ConfigDefault = Config{
 	MetricsServer: genericconf.MetricsServerConfigDefault,
 	Node:          NodeConfigDefault,
 	Queue:         1024,
+	Signature:     SignatureConfigDefault,
 }
 
 func ConfigAddOptions(f *flag.FlagSet) {
@@ -170,6 +258,59 @@ func ConfigAddOptions(f *flag.FlagSet) {
 	genericconf.MetricsServerAddOptions("metrics-server", f)
 	NodeConfigAddOptions("node", f)
 	f.Int("queue", ConfigDefault.Queue, "size of relay queue")
+	SignatureConfigAddOptions("signature", f)
+}
+
+// SignatureConfig is how the relay checks the signatures of feed messages, and signs those it forwards
+type SignatureConfig struct {
+	Verify      bool                     `koanf:"verify"`
+	Verifier    signature.VerifierConfig `koanf:"verifier"`
+	DropInvalid bool                     `koanf:"drop-invalid"`
+	SigningKey  string                   `koanf:"signing-key"`
+}
+
+// The relay can't look up the sequencer's batch poster on L1, so it must be one of the allowed addresses
+var relayVerifierConfigDefault = signature.VerifierConfig{
+	AllowedAddresses: []string{},
+	AcceptSequencer:  false,
+	Dangerous: signature.DangerousVerifierConfig{
+		AcceptMissing: false,
+	},
+}
+
+var SignatureConfigDefault = SignatureConfig{
+	Verify:      false,
+	Verifier:    relayVerifierConfigDefault,
+	DropInvalid: true,
+	SigningKey:  "",
+}
+
+func SignatureConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".verify", SignatureConfigDefault.Verify, "verify the signatures of feed messages before forwarding them")
+	signature.VerifierConfigAddOptions(prefix+".verifier", f, &relayVerifierConfigDefault)
+	f.Bool(prefix+".drop-invalid", SignatureConfigDefault.DropInvalid, "drop feed messages with invalid signatures rather than forwarding them without re-signing")
+	f.String(prefix+".signing-key", SignatureConfigDefault.SigningKey, "ecdsa private key to re-sign verified feed messages with, treated as a hex string if prefixed with 0x otherwise treated as a file")
+}
+
+func (c *SignatureConfig) Validate() error {
+	if c.Verify && c.Verifier.AcceptSequencer && !c.Verifier.Dangerous.AcceptMissing {
+		return errors.New("relay can't verify the sequencer's signature, list its address in signature.verifier.allowed-addresses instead")
+	}
+	if c.SigningKey != "" && !c.Verify {
+		return errors.New("relay can only re-sign feed messages it verifies, enable signature.verify")
+	}
+	if c.SigningKey != "" && c.Verifier.Dangerous.AcceptMissing {
+		// the verifier then accepts messages that aren't signed, or signed by anyone
+		return errors.New("relay can't re-sign feed messages when signature.verifier.dangerous.accept-missing is set")
+	}
+	return nil
+}
+
+func (c *SignatureConfig) privateKey() (*ecdsa.PrivateKey, error) {
+	if strings.HasPrefix(c.SigningKey, "0x") {
+		return crypto.HexToECDSA(c.SigningKey[2:])
+	}
+	return crypto.LoadECDSA(c.SigningKey)
 }
 
 type NodeConfig struct {
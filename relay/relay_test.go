@@ -0,0 +1,103 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package relay
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/broadcaster"
+	"github.com/offchainlabs/nitro/util/signature"
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+func TestRelaySignatures(t *testing.T) {
+	ctx := context.Background()
+	chainId := uint64(5555)
+	sequencerKey, err := crypto.GenerateKey()
+	testhelpers.RequireImpl(t, err)
+	relayKey, err := crypto.GenerateKey()
+	testhelpers.RequireImpl(t, err)
+	sequencer := crypto.PubkeyToAddress(sequencerKey.PublicKey)
+
+	config := ConfigDefault
+	config.L2.ChainId = chainId
+	config.Node.Feed.Input.URLs = []string{"ws://127.0.0.1:9642"}
+	config.Signature.Verify = true
+	config.Signature.Verifier.AllowedAddresses = []string{sequencer.Hex()}
+	config.Signature.SigningKey = hexutil.Encode(crypto.FromECDSA(relayKey))
+
+	signed := func(key []byte) *broadcaster.BroadcastFeedMessage {
+		msg := &broadcaster.BroadcastFeedMessage{SequenceNumber: 1, Message: arbostypes.TestMessageWithMetadataAndRequestId}
+		hash, err := msg.Hash(chainId)
+		testhelpers.RequireImpl(t, err)
+		privateKey, err := crypto.ToECDSA(key)
+		testhelpers.RequireImpl(t, err)
+		msg.Signature, err = crypto.Sign(hash.Bytes(), privateKey)
+		testhelpers.RequireImpl(t, err)
+		return msg
+	}
+	signer := func(msg *broadcaster.BroadcastFeedMessage) common.Address {
+		hash, err := msg.Hash(chainId)
+		testhelpers.RequireImpl(t, err)
+		pubKey, err := crypto.SigToPub(hash.Bytes(), msg.Signature)
+		testhelpers.RequireImpl(t, err)
+		return crypto.PubkeyToAddress(*pubKey)
+	}
+
+	r, err := NewRelay(&config, make(chan error, 1))
+	testhelpers.RequireImpl(t, err)
+
+	// Messages from the sequencer are re-signed by the relay
+	msg := signed(crypto.FromECDSA(sequencerKey))
+	if !r.checkSignature(ctx, msg) {
+		testhelpers.FailImpl(t, "dropped message signed by an allowed address")
+	}
+	if signer(msg) != crypto.PubkeyToAddress(relayKey.PublicKey) {
+		testhelpers.FailImpl(t, "message not re-signed by the relay")
+	}
+
+	// Others are dropped
+	msg = signed(crypto.FromECDSA(relayKey))
+	if r.checkSignature(ctx, msg) {
+		testhelpers.FailImpl(t, "forwarded message signed by an address that isn't allowed")
+	}
+	unsigned := &broadcaster.BroadcastFeedMessage{SequenceNumber: 2, Message: arbostypes.TestMessageWithMetadataAndRequestId}
+	if r.checkSignature(ctx, unsigned) {
+		testhelpers.FailImpl(t, "forwarded message without a signature")
+	}
+
+	// Or forwarded as they are
+	config.Signature.DropInvalid = false
+	msg = signed(crypto.FromECDSA(relayKey))
+	original := msg.Signature
+	if !r.checkSignature(ctx, msg) || !bytes.Equal(msg.Signature, original) {
+		testhelpers.FailImpl(t, "message with an invalid signature not forwarded unchanged")
+	}
+
+	config.Signature.Verify = false
+	if err := config.Signature.Validate(); err == nil {
+		testhelpers.FailImpl(t, "re-signing allowed without verifying")
+	}
+	config.Signature.Verify = true
+	config.Signature.Verifier.Dangerous.AcceptMissing = true
+	if err := config.Signature.Validate(); err == nil {
+		testhelpers.FailImpl(t, "re-signing allowed while accepting missing signatures")
+	}
+
+	// Messages without a signature are never re-signed, even if the verifier lets them through
+	r.sigVerifier, err = signature.NewVerifier(&config.Signature.Verifier, nil)
+	testhelpers.RequireImpl(t, err)
+	config.Signature.DropInvalid = true
+	unsigned = &broadcaster.BroadcastFeedMessage{SequenceNumber: 3, Message: arbostypes.TestMessageWithMetadataAndRequestId}
+	if !r.checkSignature(ctx, unsigned) || len(unsigned.Signature) != 0 {
+		testhelpers.FailImpl(t, "message without a signature was re-signed")
+	}
+}
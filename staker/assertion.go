@@ -70,6 +70,7 @@ type NodeInfo struct {
 	AfterInboxBatchAcc common.Hash
 	NodeHash           common.Hash
 	WasmModuleRoot     common.Hash
+	L1TxHash           common.Hash
 }
 
 func (n *NodeInfo) AfterState() *validator.ExecutionState {
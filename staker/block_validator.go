@@ -5,6 +5,7 @@ package staker
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -23,6 +24,7 @@ import (
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
 	"github.com/offchainlabs/nitro/validator"
+	"github.com/offchainlabs/nitro/validator/inputstore"
 )
 
 var (
@@ -75,22 +77,71 @@ type BlockValidator struct {
 	awaitingValidation *types.Header
 	validHeader        *types.Header
 
-	fatalErr chan<- error
+	fatalErr           chan<- error
+	fatalErrorHandlers []func(error)
 }
 
 type BlockValidatorConfig struct {
 	Enable                   bool                          `koanf:"enable"`
 	URL                      string                        `koanf:"url"`
 	JWTSecret                string                        `koanf:"jwtsecret"`
+	ValidationServers        string                        `koanf:"validation-servers"`
 	ValidationPoll           time.Duration                 `koanf:"check-validations-poll" reload:"hot"`
 	PrerecordedBlocks        uint64                        `koanf:"prerecorded-blocks" reload:"hot"`
 	ForwardBlocks            uint64                        `koanf:"forward-blocks" reload:"hot"`
 	CurrentModuleRoot        string                        `koanf:"current-module-root"`         // TODO(magic) requires reinitialization on hot reload
 	PendingUpgradeModuleRoot string                        `koanf:"pending-upgrade-module-root"` // TODO(magic) requires StatelessBlockValidator recreation on hot reload
 	FailureIsFatal           bool                          `koanf:"failure-is-fatal" reload:"hot"`
+	InputStore               inputstore.Config             `koanf:"input-store"`
 	Dangerous                BlockValidatorDangerousConfig `koanf:"dangerous"`
 }
 
+// ValidationServerConfig is one of the servers in the validation-servers JSON list
+type ValidationServerConfig struct {
+	URL       string `json:"url"`
+	JWTSecret string `json:"jwtsecret"`
+}
+
+// LocalValidationServer is the validation server URL for the validation node running in this process
+const LocalValidationServer = "local"
+
+// ValidationServerConfigs returns the validation-servers list, or the url and jwtsecret options if it's empty
+func (c *BlockValidatorConfig) ValidationServerConfigs() ([]ValidationServerConfig, error) {
+	if c.ValidationServers == "" {
+		if c.URL == "" {
+			return nil, nil
+		}
+		return []ValidationServerConfig{{URL: c.URL, JWTSecret: c.JWTSecret}}, nil
+	}
+	var servers []ValidationServerConfig
+	if err := json.Unmarshal([]byte(c.ValidationServers), &servers); err != nil {
+		return nil, fmt.Errorf("failed to parse validation-servers: %w", err)
+	}
+	locals := 0
+	for i := range servers {
+		if servers[i].URL == "" {
+			return nil, fmt.Errorf("validation server %d has no url", i)
+		}
+		if servers[i].URL == LocalValidationServer {
+			locals++
+		}
+		if servers[i].JWTSecret == "self" {
+			servers[i].JWTSecret = c.JWTSecret
+		}
+	}
+	if locals > 1 {
+		return nil, errors.New("validation-servers lists the local validation node more than once")
+	}
+	return servers, nil
+}
+
+func (c *BlockValidatorConfig) Validate() error {
+	if _, err := c.ValidationServerConfigs(); err != nil {
+		return err
+	}
+	return c.InputStore.Validate()
+}
+
 type BlockValidatorDangerousConfig struct {
 	ResetBlockValidation bool `koanf:"reset-block-validation"`
 }
@@ -101,12 +152,14 @@ func BlockValidatorConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultBlockValidatorConfig.Enable, "enable block-by-block validation")
 	f.String(prefix+".url", DefaultBlockValidatorConfig.URL, "url for valiation")
 	f.String(prefix+".jwtsecret", DefaultBlockValidatorConfig.JWTSecret, "path to file with jwtsecret for validation - empty disables jwt, 'self' uses the server's jwt")
+	f.String(prefix+".validation-servers", DefaultBlockValidatorConfig.ValidationServers, "JSON list of validation servers to spread validations between, each with a url and jwtsecret ('self' uses the jwtsecret option); the url 'local' is the validation node in this process; replaces url and jwtsecret")
 	f.Duration(prefix+".check-validations-poll", DefaultBlockValidatorConfig.ValidationPoll, "poll time to check validations")
 	f.Uint64(prefix+".forward-blocks", DefaultBlockValidatorConfig.ForwardBlocks, "prepare entries for up to that many blocks ahead of validation (small footprint)")
 	f.Uint64(prefix+".prerecorded-blocks", DefaultBlockValidatorConfig.PrerecordedBlocks, "record that many blocks ahead of validation (larger footprint)")
 	f.String(prefix+".current-module-root", DefaultBlockValidatorConfig.CurrentModuleRoot, "current wasm module root ('current' read from chain, 'latest' from machines/latest dir, or provide hash)")
 	f.String(prefix+".pending-upgrade-module-root", DefaultBlockValidatorConfig.PendingUpgradeModuleRoot, "pending upgrade wasm module root to additionally validate (hash, 'latest' or empty)")
 	f.Bool(prefix+".failure-is-fatal", DefaultBlockValidatorConfig.FailureIsFatal, "failing a validation is treated as a fatal error")
+	inputstore.ConfigAddOptions(prefix+".input-store", f)
 	BlockValidatorDangerousConfigAddOptions(prefix+".dangerous", f)
 }
 
@@ -118,12 +171,14 @@ var DefaultBlockValidatorConfig = BlockValidatorConfig{
 	Enable:                   false,
 	URL:                      "ws://127.0.0.1:8549/",
 	JWTSecret:                "self",
+	ValidationServers:        "",
 	ValidationPoll:           time.Second,
 	ForwardBlocks:            1024,
 	PrerecordedBlocks:        128,
 	CurrentModuleRoot:        "current",
 	PendingUpgradeModuleRoot: "latest",
 	FailureIsFatal:           true,
+	InputStore:               inputstore.DefaultConfig,
 	Dangerous:                DefaultBlockValidatorDangerousConfig,
 }
 
@@ -131,12 +186,14 @@ var TestBlockValidatorConfig = BlockValidatorConfig{
 	Enable:                   false,
 	URL:                      "",
 	JWTSecret:                "",
+	ValidationServers:        "",
 	ValidationPoll:           100 * time.Millisecond,
 	ForwardBlocks:            128,
 	PrerecordedBlocks:        64,
 	CurrentModuleRoot:        "latest",
 	PendingUpgradeModuleRoot: "latest",
 	FailureIsFatal:           true,
+	InputStore:               inputstore.DefaultConfig,
 	Dangerous:                DefaultBlockValidatorDangerousConfig,
 }
 
@@ -209,6 +266,9 @@ func (v *BlockValidator) possiblyFatal(err error) {
 	}
 	log.Error("Error during validation", "err", err)
 	if v.config().FailureIsFatal {
+		for _, handler := range v.fatalErrorHandlers {
+			handler(err)
+		}
 		select {
 		case v.fatalErr <- err:
 		default:
@@ -216,6 +276,13 @@ func (v *BlockValidator) possiblyFatal(err error) {
 	}
 }
 
+// OnFatalError adds a handler called with validation failures treated as fatal.
+// It must be called before the validator is started. Handlers are called on the validation thread,
+// before the error is sent on, so they mustn't block.
+func (v *BlockValidator) OnFatalError(handler func(error)) {
+	v.fatalErrorHandlers = append(v.fatalErrorHandlers, handler)
+}
+
 func (v *BlockValidator) triggerSendValidations() {
 	select {
 	case v.sendValidationsChan <- struct{}{}:
@@ -624,6 +691,7 @@ func (v *BlockValidator) sendValidations(ctx context.Context) {
 			if !replaced {
 				v.possiblyFatal(errors.New("failed to set status"))
 			}
+			v.storeInput(ctx, validationStatus.Entry, input)
 		})
 		room--
 		v.blockMutex.Lock()
@@ -0,0 +1,223 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/util/containers"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+	"github.com/offchainlabs/nitro/validator"
+)
+
+type executionServer struct {
+	name    string
+	create  func() validator.ExecutionSpawner
+	spawner validator.ExecutionSpawner
+}
+
+// executionPool runs executions on one validation server at a time, the first one in the configured
+// order that can be reached. When it can't be reached anymore, or fails a request, the next one is used.
+// Servers are connected to lazily, so the pool starts even if none can be reached yet.
+type executionPool struct {
+	stopwaiter.StopWaiter
+
+	mutex   sync.Mutex
+	servers []*executionServer
+	current int // index of the server in use, or of the next to try if it isn't started
+}
+
+func newExecutionPool() *executionPool {
+	return &executionPool{}
+}
+
+func (p *executionPool) addRemote(name string, create func() validator.ExecutionSpawner) {
+	p.servers = append(p.servers, &executionServer{name: name, create: create})
+}
+
+func (p *executionPool) Start(ctx_in context.Context) error {
+	p.StopWaiter.Start(ctx_in, p)
+	if _, _, err := p.pick(p.GetContext()); err != nil {
+		log.Warn("couldn't connect to any validation server for executions, will retry when needed", "err", err)
+	}
+	return nil
+}
+
+// pick returns the server in use and its spawner, connecting to the first reachable one from the current one on
+// if there's none. Connecting dials the server, so it's done without holding the mutex.
+func (p *executionPool) pick(ctx context.Context) (*executionServer, validator.ExecutionSpawner, error) {
+	var lastErr error
+	for tries := 0; tries < len(p.servers); tries++ {
+		p.mutex.Lock()
+		index := p.current
+		server := p.servers[index]
+		if server.spawner != nil {
+			spawner := server.spawner
+			p.mutex.Unlock()
+			return server, spawner, nil
+		}
+		p.mutex.Unlock()
+
+		spawner := server.create()
+		err := spawner.Start(ctx)
+		if err != nil {
+			spawner.Stop()
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			log.Warn("couldn't connect to validation server for executions", "server", server.name, "err", err)
+			lastErr = err
+			p.mutex.Lock()
+			if p.current == index {
+				p.current = (index + 1) % len(p.servers)
+			}
+			p.mutex.Unlock()
+			continue
+		}
+
+		p.mutex.Lock()
+		// checked under the mutex, so a connection made as the pool stops is either seen here or stopped by Stop
+		if ctx.Err() != nil {
+			p.mutex.Unlock()
+			spawner.Stop()
+			return nil, nil, ctx.Err()
+		}
+		if server.spawner != nil {
+			// another request connected to it first
+			existing := server.spawner
+			p.mutex.Unlock()
+			spawner.Stop()
+			return server, existing, nil
+		}
+		server.spawner = spawner
+		p.current = index
+		p.mutex.Unlock()
+		log.Info("running executions on validation server", "server", server.name)
+		return server, spawner, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no validation server configured")
+	}
+	return nil, nil, fmt.Errorf("couldn't connect to any validation server for executions: %w", lastErr)
+}
+
+// failed drops the spawner of the server, so the next request goes to the next one
+func (p *executionPool) failed(server *executionServer, spawner validator.ExecutionSpawner) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if server.spawner != spawner {
+		// another request failed on it first
+		return
+	}
+	server.spawner.Stop()
+	server.spawner = nil
+	if p.servers[p.current] == server {
+		p.current = (p.current + 1) % len(p.servers)
+	}
+}
+
+// executionPoolCall makes the call on the server in use, failing over to the others until one succeeds
+func executionPoolCall[T any](p *executionPool, call func(validator.ExecutionSpawner) containers.PromiseInterface[T]) containers.PromiseInterface[T] {
+	return stopwaiter.LaunchPromiseThread[T](p, func(ctx context.Context) (T, error) {
+		var lastErr error
+		for tries := 0; tries < len(p.servers); tries++ {
+			server, spawner, err := p.pick(ctx)
+			if err != nil {
+				var empty T
+				return empty, err
+			}
+			promise := call(spawner)
+			res, err := promise.Await(ctx)
+			if ctx.Err() != nil {
+				promise.Cancel()
+				return res, ctx.Err()
+			}
+			if err == nil {
+				return res, nil
+			}
+			validationRetriesCounter.Inc(1)
+			log.Warn("execution failed on validation server, trying another", "server", server.name, "err", err)
+			p.failed(server, spawner)
+			lastErr = err
+		}
+		var empty T
+		return empty, lastErr
+	})
+}
+
+func (p *executionPool) Launch(input *validator.ValidationInput, moduleRoot common.Hash) validator.ValidationRun {
+	promise := executionPoolCall(p, func(spawner validator.ExecutionSpawner) containers.PromiseInterface[validator.GoGlobalState] {
+		return spawner.Launch(input, moduleRoot)
+	})
+	return &executionPoolRun{PromiseInterface: promise, root: moduleRoot}
+}
+
+func (p *executionPool) CreateExecutionRun(wasmModuleRoot common.Hash, input *validator.ValidationInput) containers.PromiseInterface[validator.ExecutionRun] {
+	return executionPoolCall(p, func(spawner validator.ExecutionSpawner) containers.PromiseInterface[validator.ExecutionRun] {
+		return spawner.CreateExecutionRun(wasmModuleRoot, input)
+	})
+}
+
+func (p *executionPool) LatestWasmModuleRoot() containers.PromiseInterface[common.Hash] {
+	return executionPoolCall(p, func(spawner validator.ExecutionSpawner) containers.PromiseInterface[common.Hash] {
+		return spawner.LatestWasmModuleRoot()
+	})
+}
+
+func (p *executionPool) WriteToFile(input *validator.ValidationInput, expOut validator.GoGlobalState, moduleRoot common.Hash) containers.PromiseInterface[struct{}] {
+	return executionPoolCall(p, func(spawner validator.ExecutionSpawner) containers.PromiseInterface[struct{}] {
+		return spawner.WriteToFile(input, expOut, moduleRoot)
+	})
+}
+
+func (p *executionPool) Stop() {
+	p.StopOnly()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, server := range p.servers {
+		if server.spawner != nil {
+			server.spawner.Stop()
+			server.spawner = nil
+		}
+	}
+}
+
+func (p *executionPool) Name() string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var names []string
+	for _, server := range p.servers {
+		if server.spawner != nil {
+			names = append(names, server.spawner.Name())
+		} else {
+			names = append(names, "(not started) "+server.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// Room is the room on the server in use, 0 if none could be reached
+func (p *executionPool) Room() int {
+	_, spawner, err := p.pick(p.GetContext())
+	if err != nil {
+		return 0
+	}
+	return spawner.Room()
+}
+
+type executionPoolRun struct {
+	containers.PromiseInterface[validator.GoGlobalState]
+	root common.Hash
+}
+
+func (r *executionPoolRun) WasmModuleRoot() common.Hash {
+	return r.root
+}
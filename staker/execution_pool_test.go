@@ -0,0 +1,117 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/util/containers"
+	"github.com/offchainlabs/nitro/validator"
+)
+
+type fakeExecutionSpawner struct {
+	fakeValidationSpawner
+	root common.Hash
+}
+
+func (s *fakeExecutionSpawner) CreateExecutionRun(wasmModuleRoot common.Hash, input *validator.ValidationInput) containers.PromiseInterface[validator.ExecutionRun] {
+	return containers.NewReadyPromise[validator.ExecutionRun](nil, errors.New("not supported"))
+}
+
+func (s *fakeExecutionSpawner) LatestWasmModuleRoot() containers.PromiseInterface[common.Hash] {
+	if s.fail {
+		return containers.NewReadyPromise(common.Hash{}, errors.New("failed on "+s.name))
+	}
+	return containers.NewReadyPromise(s.root, nil)
+}
+
+func (s *fakeExecutionSpawner) WriteToFile(input *validator.ValidationInput, expOut validator.GoGlobalState, moduleRoot common.Hash) containers.PromiseInterface[struct{}] {
+	return containers.NewReadyPromise(struct{}{}, nil)
+}
+
+func TestExecutionPool(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := &fakeExecutionSpawner{fakeValidationSpawner{name: "first", startErr: errors.New("connection refused")}, common.Hash{1}}
+	second := &fakeExecutionSpawner{fakeValidationSpawner{name: "second"}, common.Hash{2}}
+	third := &fakeExecutionSpawner{fakeValidationSpawner{name: "third"}, common.Hash{3}}
+	pool := newExecutionPool()
+	for _, spawner := range []*fakeExecutionSpawner{first, second, third} {
+		spawner := spawner
+		pool.addRemote(spawner.name, func() validator.ExecutionSpawner { return spawner })
+	}
+	Require(t, pool.Start(ctx))
+	defer pool.Stop()
+
+	// The first reachable server is used
+	root, err := pool.LatestWasmModuleRoot().Await(ctx)
+	Require(t, err)
+	if root != second.root {
+		Fail(t, "unexpected server used", root)
+	}
+
+	// Failed requests move on to the next server
+	second.fail = true
+	root, err = pool.LatestWasmModuleRoot().Await(ctx)
+	Require(t, err)
+	if root != third.root {
+		Fail(t, "failed request not retried on the next server", root)
+	}
+	second.fail = false
+	root, err = pool.LatestWasmModuleRoot().Await(ctx)
+	Require(t, err)
+	if root != third.root {
+		Fail(t, "server switched back without failing", root)
+	}
+
+	// The pool starts without any reachable server, and connects once one is
+	down := newExecutionPool()
+	unreachable := &fakeExecutionSpawner{fakeValidationSpawner{name: "unreachable", startErr: errors.New("connection refused")}, common.Hash{4}}
+	down.addRemote(unreachable.name, func() validator.ExecutionSpawner { return unreachable })
+	Require(t, down.Start(ctx))
+	defer down.Stop()
+	if _, err := down.LatestWasmModuleRoot().Await(ctx); err == nil {
+		Fail(t, "request succeeded without a reachable server")
+	}
+	unreachable.startErr = nil
+	root, err = down.LatestWasmModuleRoot().Await(ctx)
+	Require(t, err)
+	if root != unreachable.root {
+		Fail(t, "unexpected root after connecting", root)
+	}
+
+	// Connecting to a server doesn't hold up the rest of the pool
+	gate := make(chan struct{})
+	slow := &fakeExecutionSpawner{fakeValidationSpawner{name: "slow", startErr: errors.New("connection refused")}, common.Hash{5}}
+	slowPool := newExecutionPool()
+	slowPool.addRemote(slow.name, func() validator.ExecutionSpawner { return slow })
+	Require(t, slowPool.Start(ctx))
+	defer slowPool.Stop()
+	slow.startErr = nil
+	slow.startGate = gate
+	promise := slowPool.LatestWasmModuleRoot()
+	<-gate
+	named := make(chan struct{})
+	go func() {
+		slowPool.Name()
+		close(named)
+	}()
+	select {
+	case <-named:
+	case <-time.After(time.Second):
+		Fail(t, "pool locked while connecting")
+	}
+	gate <- struct{}{}
+	root, err = promise.Await(ctx)
+	Require(t, err)
+	if root != slow.root {
+		Fail(t, "unexpected root after a slow connection", root)
+	}
+}
@@ -139,19 +139,12 @@ func (v *L1Validator) updateBlockValidatorModuleRoot(ctx context.Context) error
 	return nil
 }
 
-func (v *L1Validator) resolveTimedOutChallenges(ctx context.Context) (*types.Transaction, error) {
+func (v *L1Validator) timedOutChallenges(ctx context.Context) ([]uint64, error) {
 	challengesToEliminate, _, err := v.validatorUtils.TimedOutChallenges(v.getCallOpts(ctx), v.rollupAddress, 0, 10)
-	if err != nil {
-		return nil, err
-	}
-	if len(challengesToEliminate) == 0 {
-		return nil, nil
-	}
-	log.Info("timing out challenges", "count", len(challengesToEliminate))
-	return v.wallet.TimeoutChallenges(ctx, challengesToEliminate)
+	return challengesToEliminate, err
 }
 
-func (v *L1Validator) resolveNextNode(ctx context.Context, info *StakerInfo, latestConfirmedNode *uint64) (bool, error) {
+func (v *L1Validator) resolveNextNode(ctx context.Context, info *StakerInfo, latestConfirmedNode *uint64, decision *StakerDecision) (bool, error) {
 	callOpts := v.getCallOpts(ctx)
 	confirmType, err := v.validatorUtils.CheckDecidableNextNode(callOpts, v.rollupAddress)
 	if err != nil {
@@ -170,7 +163,11 @@ func (v *L1Validator) resolveNextNode(ctx context.Context, info *StakerInfo, lat
 		}
 		log.Warn("rejecting node", "node", unresolvedNodeIndex)
 		_, err = v.rollup.RejectNextNode(v.builder.Auth(ctx), *addr)
-		return true, err
+		if err != nil {
+			return true, err
+		}
+		decision.addAction("rejectNode", unresolvedNodeIndex, nil, "node is decidably invalid and we're staked on node %v", info.LatestStakedNode)
+		return true, nil
 	case CONFIRM_TYPE_VALID:
 		nodeInfo, err := v.rollup.LookupNode(ctx, unresolvedNodeIndex)
 		if err != nil {
@@ -182,6 +179,7 @@ func (v *L1Validator) resolveNextNode(ctx context.Context, info *StakerInfo, lat
 		if err != nil {
 			return false, err
 		}
+		decision.addAction("confirmNode", unresolvedNodeIndex, nil, "node is decidably valid")
 		*latestConfirmedNode = unresolvedNodeIndex
 		return true, nil
 	default:
@@ -252,7 +250,7 @@ func (v *L1Validator) blockNumberFromGlobalState(gs validator.GoGlobalState) (in
 	return arbutil.MessageCountToBlockNumber(batchHeight+arbutil.MessageIndex(gs.PosInBatch), v.genesisBlockNumber), false, nil
 }
 
-func (v *L1Validator) generateNodeAction(ctx context.Context, stakerInfo *OurStakerInfo, strategy StakerStrategy, makeAssertionInterval time.Duration) (nodeAction, bool, error) {
+func (v *L1Validator) generateNodeAction(ctx context.Context, stakerInfo *OurStakerInfo, strategy StakerStrategy, makeAssertionInterval time.Duration, decision *StakerDecision) (nodeAction, bool, error) {
 	startState, prevInboxMaxCount, startStateProposed, err := lookupNodeStartState(ctx, v.rollup, stakerInfo.LatestStakedNode, stakerInfo.LatestStakedNodeHash)
 	if err != nil {
 		return nil, false, fmt.Errorf("error looking up node %v (hash %v) start state: %w", stakerInfo.LatestStakedNode, stakerInfo.LatestStakedNodeHash, err)
@@ -273,6 +271,7 @@ func (v *L1Validator) generateNodeAction(ctx context.Context, stakerInfo *OurSta
 	}
 	if localBatchCount < startState.RequiredBatches() {
 		log.Info("catching up to chain batches", "localBatches", localBatchCount, "target", startState.RequiredBatches())
+		decision.setReason("catching up to chain batches: %v/%v", localBatchCount, startState.RequiredBatches())
 		return nil, false, nil
 	}
 
@@ -289,6 +288,7 @@ func (v *L1Validator) generateNodeAction(ctx context.Context, stakerInfo *OurSta
 		latestHeader := v.l2Blockchain.CurrentBlock().Header()
 		if latestHeader.Number.Int64() < expectedBlockHeight {
 			log.Info("catching up to chain blocks", "localBlocks", latestHeader.Number, "target", expectedBlockHeight)
+			decision.setReason("catching up to chain blocks: %v/%v", latestHeader.Number, expectedBlockHeight)
 			return nil, false, nil
 		} else {
 			log.Error("unknown start block hash", "hash", startState.GlobalState.BlockHash, "batch", startState.GlobalState.Batch, "pos", startState.GlobalState.PosInBatch)
@@ -349,6 +349,7 @@ func (v *L1Validator) generateNodeAction(ctx context.Context, stakerInfo *OurSta
 	timeSinceProposed := big.NewInt(int64(currentL1BlockNum) - int64(startStateProposed))
 	if timeSinceProposed.Cmp(minAssertionPeriod) < 0 {
 		// Too soon to assert
+		decision.setReason("node %v was proposed %v L1 blocks ago, less than the minimum assertion period of %v", stakerInfo.LatestStakedNode, timeSinceProposed, minAssertionPeriod)
 		return nil, false, nil
 	}
 
@@ -428,6 +429,22 @@ func (v *L1Validator) generateNodeAction(ctx context.Context, stakerInfo *OurSta
 				}
 				continue
 			} else {
+				decision.addEvent(&StakerEvent{
+					Type:               StakerEventConflict,
+					Node:               nd.NodeNum,
+					NodeHash:           &nd.NodeHash,
+					AssertionBlockHash: &afterGs.BlockHash,
+					AssertionSendRoot:  &afterGs.SendRoot,
+					ExpectedBlockHash:  &expectedBlockHash,
+					ExpectedSendRoot:   &expectedSendRoot,
+					L1TxHash:           &nd.L1TxHash,
+					Message:            fmt.Sprintf("found incorrect assertion in node %v", nd.NodeNum),
+				})
+				decision.addAction(
+					"incorrectAssertion", nd.NodeNum, &nd.NodeHash,
+					"expected %v blocks to block %v with send root %v but assertion has %v blocks to block %v with send root %v and machine status %v",
+					expectedNumBlocks, expectedBlockHash, expectedSendRoot, nd.Assertion.NumBlocks, afterGs.BlockHash, afterGs.SendRoot, nd.Assertion.AfterState.MachineStatus,
+				)
 				log.Error(
 					"found incorrect assertion",
 					"node", nd.NodeNum,
@@ -444,12 +461,26 @@ func (v *L1Validator) generateNodeAction(ctx context.Context, stakerInfo *OurSta
 			}
 		} else {
 			log.Error("found younger sibling to correct assertion (implicitly invalid)", "node", nd.NodeNum)
+			decision.addAction("incorrectAssertion", nd.NodeNum, &nd.NodeHash, "younger sibling of a correct assertion")
+			afterGs := nd.AfterState().GlobalState
+			decision.addEvent(&StakerEvent{
+				Type:               StakerEventConflict,
+				Node:               nd.NodeNum,
+				NodeHash:           &nd.NodeHash,
+				AssertionBlockHash: &afterGs.BlockHash,
+				AssertionSendRoot:  &afterGs.SendRoot,
+				L1TxHash:           &nd.L1TxHash,
+				Message:            fmt.Sprintf("found younger sibling node %v of a correct assertion", nd.NodeNum),
+			})
 		}
 		// If we've hit this point, the node is "wrong"
 		wrongNodesExist = true
 	}
 
 	if correctNode != nil || strategy == WatchtowerStrategy {
+		if correctNode == nil && len(successorNodes) == 0 {
+			decision.setReason("node %v has no successors", stakerInfo.LatestStakedNode)
+		}
 		return correctNode, wrongNodesExist, nil
 	}
 
@@ -459,13 +490,14 @@ func (v *L1Validator) generateNodeAction(ctx context.Context, stakerInfo *OurSta
 		if len(successorNodes) > 0 {
 			lastNodeHashIfExists = &successorNodes[len(successorNodes)-1].NodeHash
 		}
-		action, err := v.createNewNodeAction(ctx, stakerInfo, lastBlockValidated, localBatchCount, prevInboxMaxCount, startBlock, startState, lastNodeHashIfExists)
+		action, err := v.createNewNodeAction(ctx, stakerInfo, lastBlockValidated, localBatchCount, prevInboxMaxCount, startBlock, startState, lastNodeHashIfExists, decision)
 		if err != nil {
 			return nil, wrongNodesExist, fmt.Errorf("error generating create new node action (from start block %v to last block validated %v): %w", startBlock, lastBlockValidated, err)
 		}
 		return action, wrongNodesExist, nil
 	}
 
+	decision.setReason("no incorrect successors to node %v and the make assertion interval hasn't elapsed", stakerInfo.LatestStakedNode)
 	return nil, wrongNodesExist, nil
 }
 
@@ -478,6 +510,7 @@ func (v *L1Validator) createNewNodeAction(
 	startBlock *types.Block,
 	startState *validator.ExecutionState,
 	lastNodeHashIfExists *common.Hash,
+	decision *StakerDecision,
 ) (nodeAction, error) {
 	if !prevInboxMaxCount.IsUint64() {
 		return nil, fmt.Errorf("inbox max count %v isn't a uint64", prevInboxMaxCount)
@@ -485,15 +518,18 @@ func (v *L1Validator) createNewNodeAction(
 	minBatchCount := prevInboxMaxCount.Uint64()
 	if localBatchCount < minBatchCount {
 		// not enough batches in database
+		decision.setReason("not enough batches in database to create a node: %v/%v", localBatchCount, minBatchCount)
 		return nil, nil
 	}
 
 	if localBatchCount == 0 {
 		// we haven't validated anything
+		decision.setReason("nothing validated to create a node with")
 		return nil, nil
 	}
 	if startBlock != nil && lastBlockValidated <= startBlock.NumberU64() {
 		// we haven't validated any new blocks
+		decision.setReason("no blocks validated past node %v", stakerInfo.LatestStakedNode)
 		return nil, nil
 	}
 	var assertionCoversBatch uint64
@@ -533,6 +569,7 @@ func (v *L1Validator) createNewNodeAction(
 	}
 	if assertionCoversBatch == 0 {
 		// we haven't validated the next batch completely
+		decision.setReason("the next batch hasn't been completely validated")
 		return nil, nil
 	}
 	validatedBatchAcc, err := v.inboxTracker.GetBatchAcc(assertionCoversBatch)
@@ -0,0 +1,196 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/validator"
+)
+
+// RevalidationResult is the outcome of validating a past block against one module root
+type RevalidationResult struct {
+	BlockNumber uint64                   `json:"blockNumber"`
+	BlockHash   common.Hash              `json:"blockHash"`
+	ModuleRoot  common.Hash              `json:"moduleRoot"`
+	Expected    *validator.GoGlobalState `json:"expected,omitempty"`
+	Computed    *validator.GoGlobalState `json:"computed,omitempty"`
+	Valid       bool                     `json:"valid"`
+	Error       string                   `json:"error,omitempty"`
+	// Dumped is whether the validation input was written out with WriteToFile for the mismatch
+	Dumped    bool   `json:"dumped"`
+	DumpError string `json:"dumpError,omitempty"`
+	Latency   string `json:"latency"`
+}
+
+// LatestWasmModuleRoot is the latest module root of the validation server used for executions
+func (v *StatelessBlockValidator) LatestWasmModuleRoot(ctx context.Context) (common.Hash, error) {
+	return v.execSpawner.LatestWasmModuleRoot().Await(ctx)
+}
+
+// RevalidateBlock validates a block against each of the module roots, returning a result for each.
+// Failures are recorded in the results, and mismatches are written out with WriteToFile if dumpMismatches is set.
+func (v *StatelessBlockValidator) RevalidateBlock(ctx context.Context, blockNum uint64, moduleRoots []common.Hash, dumpMismatches bool) []RevalidationResult {
+	results := make([]RevalidationResult, len(moduleRoots))
+	for i, moduleRoot := range moduleRoots {
+		results[i] = RevalidationResult{BlockNumber: blockNum, ModuleRoot: moduleRoot}
+	}
+	fail := func(err error) []RevalidationResult {
+		for i := range results {
+			results[i].Error = err.Error()
+		}
+		return results
+	}
+	header := v.blockchain.GetHeaderByNumber(blockNum)
+	if header == nil {
+		return fail(fmt.Errorf("block %v not found", blockNum))
+	}
+	for i := range results {
+		results[i].BlockHash = header.Hash()
+	}
+	if !v.blockchain.Config().IsArbitrumNitro(header.Number) {
+		return fail(fmt.Errorf("block %v is from before nitro", blockNum))
+	}
+	entry, err := v.CreateReadyValidationEntry(ctx, header)
+	if err != nil {
+		return fail(err)
+	}
+	expEnd, err := entry.expectedEnd()
+	if err != nil {
+		return fail(err)
+	}
+	input, err := entry.ToInput()
+	if err != nil {
+		return fail(err)
+	}
+	for i := range results {
+		results[i].Expected = &expEnd
+	}
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(result *RevalidationResult) {
+			defer wg.Done()
+			start := time.Now()
+			computed, err := v.validateInput(ctx, input, result.ModuleRoot, expEnd)
+			result.Latency = fmt.Sprintf("%vms", time.Since(start).Milliseconds())
+			result.Computed = computed
+			if err != nil {
+				result.Error = err.Error()
+				return
+			}
+			result.Valid = *computed == expEnd
+			if result.Valid || !dumpMismatches {
+				return
+			}
+			_, err = v.execSpawner.WriteToFile(input, expEnd, result.ModuleRoot).Await(ctx)
+			if err != nil {
+				result.DumpError = err.Error()
+			} else {
+				result.Dumped = true
+			}
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+// validateInput launches the input on every validation spawner, returning the first result that
+// doesn't match the expected end, or the expected end if they all do
+func (v *StatelessBlockValidator) validateInput(ctx context.Context, input *validator.ValidationInput, moduleRoot common.Hash, expEnd validator.GoGlobalState) (*validator.GoGlobalState, error) {
+	var runs []validator.ValidationRun
+	for _, spawner := range v.validationSpawners {
+		runs = append(runs, spawner.Launch(input, moduleRoot))
+	}
+	defer func() {
+		for _, run := range runs {
+			run.Cancel()
+		}
+	}()
+	computed := expEnd
+	for _, run := range runs {
+		gsEnd, err := run.Await(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if gsEnd != expEnd {
+			computed = gsEnd
+			break
+		}
+	}
+	return &computed, nil
+}
+
+// RevalidateRange validates the blocks from fromBlock to toBlock inclusive against each of the module
+// roots with the given number of workers, returning the results ordered by block and module root
+func (v *StatelessBlockValidator) RevalidateRange(ctx context.Context, fromBlock, toBlock uint64, moduleRoots []common.Hash, workers int, dumpMismatches bool) ([]RevalidationResult, error) {
+	if toBlock < fromBlock {
+		return nil, fmt.Errorf("to block %v is before from block %v", toBlock, fromBlock)
+	}
+	if len(moduleRoots) == 0 {
+		return nil, fmt.Errorf("no module roots to validate against")
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	blocks := make(chan uint64)
+	var mutex sync.Mutex
+	var results []RevalidationResult
+	done := 0
+	total := toBlock - fromBlock + 1
+	lastLog := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for blockNum := range blocks {
+				blockResults := v.RevalidateBlock(ctx, blockNum, moduleRoots, dumpMismatches)
+				mutex.Lock()
+				results = append(results, blockResults...)
+				done++
+				if time.Since(lastLog) >= 10*time.Second || uint64(done) == total {
+					log.Info("revalidating blocks", "done", done, "total", total)
+					lastLog = time.Now()
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	for blockNum := fromBlock; blockNum <= toBlock; blockNum++ {
+		select {
+		case blocks <- blockNum:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil || blockNum == toBlock {
+			break
+		}
+	}
+	close(blocks)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	rootOrder := make(map[common.Hash]int)
+	for i, moduleRoot := range moduleRoots {
+		rootOrder[moduleRoot] = i
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].BlockNumber != results[j].BlockNumber {
+			return results[i].BlockNumber < results[j].BlockNumber
+		}
+		return rootOrder[results[i].ModuleRoot] < rootOrder[results[j].ModuleRoot]
+	})
+	return results, nil
+}
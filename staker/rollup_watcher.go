@@ -138,6 +138,7 @@ func (r *RollupWatcher) LookupNode(ctx context.Context, number uint64) (*NodeInf
 		AfterInboxBatchAcc: parsedLog.AfterInboxBatchAcc,
 		NodeHash:           parsedLog.NodeHash,
 		WasmModuleRoot:     parsedLog.WasmModuleRoot,
+		L1TxHash:           ethLog.TxHash,
 	}, nil
 }
 
@@ -186,6 +187,7 @@ func (r *RollupWatcher) LookupNodeChildren(ctx context.Context, nodeNum uint64,
 			AfterInboxBatchAcc: parsedLog.AfterInboxBatchAcc,
 			NodeHash:           lastHash,
 			WasmModuleRoot:     parsedLog.WasmModuleRoot,
+			L1TxHash:           ethLog.TxHash,
 		})
 	}
 	return infos, nil
@@ -244,6 +246,30 @@ func (r *RollupWatcher) LookupChallengedNode(ctx context.Context, address common
 	return challenge.ChallengedNode, nil
 }
 
+// LookupChallenge finds the event that started a challenge, created at or after fromBlock
+func (r *RollupWatcher) LookupChallenge(ctx context.Context, challengeIndex uint64, fromBlock uint64) (*rollupgen.RollupUserLogicRollupChallengeStarted, error) {
+	var indexAsHash common.Hash
+	binary.BigEndian.PutUint64(indexAsHash[(32-8):], challengeIndex)
+	query := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   nil,
+		Addresses: []common.Address{r.address},
+		Topics:    [][]common.Hash{{challengeCreatedID}, {indexAsHash}},
+	}
+	logs, err := r.client.FilterLogs(ctx, query)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(logs) == 0 {
+		return nil, fmt.Errorf("challenge %v not found", challengeIndex)
+	}
+	if len(logs) > 1 {
+		return nil, fmt.Errorf("found multiple instances of challenge %v", challengeIndex)
+	}
+	challenge, err := r.ParseRollupChallengeStarted(logs[0])
+	return challenge, errors.WithStack(err)
+}
+
 func (r *RollupWatcher) StakerInfo(ctx context.Context, staker common.Address) (*StakerInfo, error) {
 	info, err := r.StakerMap(r.getCallOpts(ctx), staker)
 	if err != nil {
@@ -48,6 +48,23 @@ const (
 	MakeNodesStrategy
 )
 
+func (s StakerStrategy) String() string {
+	switch s {
+	case WatchtowerStrategy:
+		return "Watchtower"
+	case DefensiveStrategy:
+		return "Defensive"
+	case StakeLatestStrategy:
+		return "StakeLatest"
+	case ResolveNodesStrategy:
+		return "ResolveNodes"
+	case MakeNodesStrategy:
+		return "MakeNodes"
+	default:
+		return fmt.Sprintf("StakerStrategy(%d)", uint8(s))
+	}
+}
+
 type L1PostingStrategy struct {
 	HighGasThreshold   float64 `koanf:"high-gas-threshold"`
 	HighGasDelayBlocks int64   `koanf:"high-gas-delay-blocks"`
@@ -64,19 +81,22 @@ func L1PostingStrategyAddOptions(prefix string, f *flag.FlagSet) {
 }
 
 type L1ValidatorConfig struct {
-	Enable                   bool              `koanf:"enable"`
-	Strategy                 string            `koanf:"strategy"`
-	StakerInterval           time.Duration     `koanf:"staker-interval"`
-	MakeAssertionInterval    time.Duration     `koanf:"make-assertion-interval"`
-	L1PostingStrategy        L1PostingStrategy `koanf:"posting-strategy"`
-	DisableChallenge         bool              `koanf:"disable-challenge"`
-	ConfirmationBlocks       int64             `koanf:"confirmation-blocks"`
-	UseSmartContractWallet   bool              `koanf:"use-smart-contract-wallet"`
-	OnlyCreateWalletContract bool              `koanf:"only-create-wallet-contract"`
-	StartFromStaked          bool              `koanf:"start-validation-from-staked"`
-	ContractWalletAddress    string            `koanf:"contract-wallet-address"`
-	GasRefunderAddress       string            `koanf:"gas-refunder-address"`
-	Dangerous                DangerousConfig   `koanf:"dangerous"`
+	Enable                   bool                 `koanf:"enable"`
+	Strategy                 string               `koanf:"strategy"`
+	StakerInterval           time.Duration        `koanf:"staker-interval"`
+	MakeAssertionInterval    time.Duration        `koanf:"make-assertion-interval"`
+	L1PostingStrategy        L1PostingStrategy    `koanf:"posting-strategy"`
+	DisableChallenge         bool                 `koanf:"disable-challenge"`
+	ConfirmationBlocks       int64                `koanf:"confirmation-blocks"`
+	UseSmartContractWallet   bool                 `koanf:"use-smart-contract-wallet"`
+	OnlyCreateWalletContract bool                 `koanf:"only-create-wallet-contract"`
+	StartFromStaked          bool                 `koanf:"start-validation-from-staked"`
+	ContractWalletAddress    string               `koanf:"contract-wallet-address"`
+	GasRefunderAddress       string               `koanf:"gas-refunder-address"`
+	DryRun                   bool                 `koanf:"dry-run"`
+	DecisionLogSize          int                  `koanf:"decision-log-size"`
+	Notifier                 StakerNotifierConfig `koanf:"notifier"`
+	Dangerous                DangerousConfig      `koanf:"dangerous"`
 
 	strategy    StakerStrategy
 	gasRefunder common.Address
@@ -121,7 +141,13 @@ func (c *L1ValidatorConfig) Validate() error {
 		return errors.New("invalid validator gas refunder address")
 	}
 	c.gasRefunder = common.HexToAddress(c.GasRefunderAddress)
-	return nil
+	if c.DryRun && c.OnlyCreateWalletContract {
+		return errors.New("cannot create the validator wallet contract in dry-run mode")
+	}
+	if c.DecisionLogSize < 0 {
+		return errors.New("staker decision log size must not be negative")
+	}
+	return c.Notifier.Validate()
 }
 
 var DefaultL1ValidatorConfig = L1ValidatorConfig{
@@ -137,6 +163,9 @@ var DefaultL1ValidatorConfig = L1ValidatorConfig{
 	StartFromStaked:          true,
 	ContractWalletAddress:    "",
 	GasRefunderAddress:       "",
+	DryRun:                   false,
+	DecisionLogSize:          128,
+	Notifier:                 DefaultStakerNotifierConfig,
 	Dangerous:                DefaultDangerousConfig,
 }
 
@@ -153,6 +182,9 @@ func L1ValidatorConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".start-validation-from-staked", DefaultL1ValidatorConfig.StartFromStaked, "assume staked nodes are valid")
 	f.String(prefix+".contract-wallet-address", DefaultL1ValidatorConfig.ContractWalletAddress, "validator smart contract wallet public address")
 	f.String(prefix+".gas-refunder-address", DefaultL1ValidatorConfig.GasRefunderAddress, "The gas refunder contract address (optional)")
+	f.Bool(prefix+".dry-run", DefaultL1ValidatorConfig.DryRun, "run the staker against L1 and log its decisions without sending any transactions")
+	f.Int(prefix+".decision-log-size", DefaultL1ValidatorConfig.DecisionLogSize, "number of recent staker decisions to keep for the arbstaker RPC namespace")
+	StakerNotifierConfigAddOptions(prefix+".notifier", f)
 	DangerousConfigAddOptions(prefix+".dangerous", f)
 }
 
@@ -178,6 +210,7 @@ type Staker struct {
 	stopwaiter.StopWaiter
 	l1Reader                L1ReaderInterface
 	activeChallenge         *ChallengeManager
+	activeChallengeEvent    *StakerEvent
 	baseCallOpts            bind.CallOpts
 	config                  L1ValidatorConfig
 	highGasBlocksBuffer     *big.Int
@@ -186,6 +219,8 @@ type Staker struct {
 	bringActiveUntilNode    uint64
 	inboxReader             InboxReaderInterface
 	statelessBlockValidator *StatelessBlockValidator
+	decisions               *decisionLog
+	notifiers               *stakerNotifiers
 }
 
 func NewStaker(
@@ -208,7 +243,7 @@ func NewStaker(
 		return nil, err
 	}
 	stakerLastSuccessfulActionGauge.Update(time.Now().Unix())
-	return &Staker{
+	staker := &Staker{
 		L1Validator:             val,
 		l1Reader:                l1Reader,
 		baseCallOpts:            callOpts,
@@ -217,7 +252,26 @@ func NewStaker(
 		lastActCalledBlock:      nil,
 		inboxReader:             statelessBlockValidator.inboxReader,
 		statelessBlockValidator: statelessBlockValidator,
-	}, nil
+		decisions:               newDecisionLog(config.DecisionLogSize),
+		notifiers:               newStakerNotifiers(NewStakerNotifiers(&config.Notifier)),
+	}
+	if blockValidator != nil {
+		blockValidator.OnFatalError(staker.notifyValidationFailure)
+	}
+	return staker, nil
+}
+
+// AddNotifier adds a notifier to be told about conflicts, challenges and other events needing attention
+func (s *Staker) AddNotifier(notifier StakerNotifier) {
+	s.notifiers.add(notifier)
+}
+
+func (s *Staker) notifyValidationFailure(err error) {
+	s.notifiers.notify([]*StakerEvent{{
+		Type:    StakerEventValidationFailure,
+		Time:    time.Now(),
+		Message: err.Error(),
+	}})
 }
 
 func (s *Staker) Initialize(ctx context.Context) error {
@@ -252,6 +306,7 @@ func (s *Staker) Initialize(ctx context.Context) error {
 
 func (s *Staker) Start(ctxIn context.Context) {
 	s.StopWaiter.Start(ctxIn, s)
+	s.LaunchThread(s.notifiers.run)
 	backoff := time.Second
 	s.CallIteratively(func(ctx context.Context) (returningWait time.Duration) {
 		defer func() {
@@ -371,6 +426,42 @@ func (s *Staker) shouldAct(ctx context.Context) bool {
 }
 
 func (s *Staker) Act(ctx context.Context) (*types.Transaction, error) {
+	decision := &StakerDecision{
+		Time:     time.Now(),
+		DryRun:   s.config.DryRun,
+		Strategy: s.config.strategy.String(),
+	}
+	arbTx, err := s.act(ctx, decision)
+	if err != nil {
+		decision.Error = err.Error()
+	}
+	if arbTx != nil {
+		hash := arbTx.Hash()
+		decision.TxHash = &hash
+	}
+	if decision.Reason == "" && len(decision.Actions) == 0 && err == nil {
+		decision.setReason("no action needed")
+	}
+	s.notifiers.notify(decision.Events)
+	s.recordDecision(decision)
+	return arbTx, err
+}
+
+// executeTransactions sends the transactions in the builder, or in dry-run mode only records them
+func (s *Staker) executeTransactions(ctx context.Context, decision *StakerDecision) (*types.Transaction, error) {
+	if s.config.DryRun {
+		s.dryRunTransactions(ctx, decision)
+		return nil, nil
+	}
+	decision.Transactions = summarizeTransactions(s.builder.transactions)
+	arbTx, err := s.wallet.ExecuteTransactions(ctx, s.builder, s.config.gasRefunder)
+	if arbTx != nil {
+		decision.ProjectedGas = arbTx.Gas()
+	}
+	return arbTx, err
+}
+
+func (s *Staker) act(ctx context.Context, decision *StakerDecision) (*types.Transaction, error) {
 	if s.config.strategy != WatchtowerStrategy {
 		whitelisted, err := s.IsWhitelisted(ctx)
 		if err != nil {
@@ -382,6 +473,7 @@ func (s *Staker) Act(ctx context.Context) (*types.Transaction, error) {
 	}
 	if !s.shouldAct(ctx) {
 		// The fact that we're delaying acting is already logged in `shouldAct`
+		decision.setReason("delaying action as the L1 gas price is high")
 		return nil, nil
 	}
 	callOpts := s.getCallOpts(ctx)
@@ -453,6 +545,10 @@ func (s *Staker) Act(ctx context.Context) (*types.Transaction, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error getting latest confirmed node: %w", err)
 	}
+	decision.EffectiveStrategy = effectiveStrategy.String()
+	decision.LatestStakedNode = info.LatestStakedNode
+	decision.LatestConfirmedNode = latestConfirmedNode
+	decision.StakeExists = info.StakeExists
 
 	requiredStakeElevated, err := s.isRequiredStakeElevated(ctx)
 	if err != nil {
@@ -465,14 +561,25 @@ func (s *Staker) Act(ctx context.Context) (*types.Transaction, error) {
 		(effectiveStrategy >= StakeLatestStrategy && rawInfo == nil && requiredStakeElevated)
 	resolvingNode := false
 	if shouldResolveNodes {
-		arbTx, err := s.resolveTimedOutChallenges(ctx)
+		timedOutChallenges, err := s.timedOutChallenges(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("error resolving timed out challenges: %w", err)
 		}
-		if arbTx != nil {
-			return arbTx, nil
+		if len(timedOutChallenges) > 0 {
+			log.Info("timing out challenges", "count", len(timedOutChallenges))
+			decision.addAction("timeoutChallenges", 0, nil, "challenges %v have timed out", timedOutChallenges)
+			if s.config.DryRun {
+				return nil, nil
+			}
+			arbTx, err := s.wallet.TimeoutChallenges(ctx, timedOutChallenges)
+			if err != nil {
+				return nil, fmt.Errorf("error resolving timed out challenges: %w", err)
+			}
+			if arbTx != nil {
+				return arbTx, nil
+			}
 		}
-		resolvingNode, err = s.resolveNextNode(ctx, rawInfo, &latestConfirmedNode)
+		resolvingNode, err = s.resolveNextNode(ctx, rawInfo, &latestConfirmedNode, decision)
 		if err != nil {
 			return nil, fmt.Errorf("error resolving node %v: %w", latestConfirmedNode+1, err)
 		}
@@ -490,6 +597,15 @@ func (s *Staker) Act(ctx context.Context) (*types.Transaction, error) {
 		}
 	}
 
+	if rawInfo != nil && rawInfo.LatestStakedNode < latestConfirmedNode {
+		decision.addEvent(&StakerEvent{
+			Type:                StakerEventStakeBehind,
+			Node:                rawInfo.LatestStakedNode,
+			LatestConfirmedNode: latestConfirmedNode,
+			Message:             fmt.Sprintf("our stake on node %v is behind latest confirmed node %v", rawInfo.LatestStakedNode, latestConfirmedNode),
+		})
+	}
+
 	canActFurther := func() bool {
 		return s.wallet.CanBatchTxs() || s.builder.BuildingTransactionCount() == 0
 	}
@@ -510,7 +626,12 @@ func (s *Staker) Act(ctx context.Context) (*types.Transaction, error) {
 				return nil, fmt.Errorf("error withdrawing staker funds from our staker %v: %w", walletAddressOrZero, err)
 			}
 			log.Info("removing old stake and withdrawing funds")
-			return s.wallet.ExecuteTransactions(ctx, s.builder, s.config.gasRefunder)
+			if stakeIsTooOutdated {
+				decision.addAction("returnOldDeposit", rawInfo.LatestStakedNode, nil, "stake on node %v is behind latest confirmed node %v", rawInfo.LatestStakedNode, latestConfirmedNode)
+			} else {
+				decision.addAction("returnOldDeposit", rawInfo.LatestStakedNode, nil, "%v strategy doesn't keep a stake", effectiveStrategy)
+			}
+			return s.executeTransactions(ctx, decision)
 		}
 	}
 
@@ -524,11 +645,12 @@ func (s *Staker) Act(ctx context.Context) (*types.Transaction, error) {
 			if err != nil {
 				return nil, fmt.Errorf("error withdrawing our staker %v funds: %w", walletAddressOrZero, err)
 			}
+			decision.addAction("withdrawStakerFunds", 0, nil, "%v wei is withdrawable", withdrawable)
 		}
 	}
 
 	if rawInfo != nil && canActFurther() {
-		if err = s.handleConflict(ctx, rawInfo); err != nil {
+		if err = s.handleConflict(ctx, rawInfo, decision); err != nil {
 			return nil, fmt.Errorf("error handling conflict: %w", err)
 		}
 	}
@@ -538,7 +660,7 @@ func (s *Staker) Act(ctx context.Context) (*types.Transaction, error) {
 	if (rawInfo != nil || !resolvingNode || !requiredStakeElevated) && canActFurther() {
 		// Advance stake up to 20 times in one transaction
 		for i := 0; info.CanProgress && i < 20; i++ {
-			if err := s.advanceStake(ctx, &info, effectiveStrategy); err != nil {
+			if err := s.advanceStake(ctx, &info, effectiveStrategy, decision); err != nil {
 				return nil, fmt.Errorf("error advancing stake from node %v (hash %v): %w", info.LatestStakedNode, info.LatestStakedNodeHash, err)
 			}
 			if !s.wallet.CanBatchTxs() && effectiveStrategy >= StakeLatestStrategy {
@@ -548,7 +670,7 @@ func (s *Staker) Act(ctx context.Context) (*types.Transaction, error) {
 	}
 
 	if rawInfo != nil && s.builder.BuildingTransactionCount() == 0 && canActFurther() {
-		if err := s.createConflict(ctx, rawInfo); err != nil {
+		if err := s.createConflict(ctx, rawInfo, decision); err != nil {
 			return nil, fmt.Errorf("error creating conflict: %w", err)
 		}
 	}
@@ -560,12 +682,13 @@ func (s *Staker) Act(ctx context.Context) (*types.Transaction, error) {
 	if info.StakerInfo == nil && info.StakeExists {
 		log.Info("staking to execute transactions")
 	}
-	return s.wallet.ExecuteTransactions(ctx, s.builder, s.config.gasRefunder)
+	return s.executeTransactions(ctx, decision)
 }
 
-func (s *Staker) handleConflict(ctx context.Context, info *StakerInfo) error {
+func (s *Staker) handleConflict(ctx context.Context, info *StakerInfo, decision *StakerDecision) error {
 	if info.CurrentChallenge == nil {
 		s.activeChallenge = nil
+		s.activeChallengeEvent = nil
 		return nil
 	}
 
@@ -577,6 +700,21 @@ func (s *Staker) handleConflict(ctx context.Context, info *StakerInfo) error {
 			return fmt.Errorf("error getting latest confirmed creation block: %w", err)
 		}
 
+		challengeIndex := *info.CurrentChallenge
+		event := &StakerEvent{
+			Type:           StakerEventChallenge,
+			ChallengeIndex: &challengeIndex,
+			Message:        fmt.Sprintf("entered challenge %v", challengeIndex),
+		}
+		challenge, err := s.rollup.LookupChallenge(ctx, challengeIndex, latestConfirmedCreated)
+		if err != nil {
+			log.Warn("error looking up challenge creation", "challenge", challengeIndex, "err", err)
+		} else {
+			event.Node = challenge.ChallengedNode
+			event.L1TxHash = &challenge.Raw.TxHash
+			event.Message = fmt.Sprintf("challenge %v over node %v between asserter %v and challenger %v", challengeIndex, challenge.ChallengedNode, challenge.Asserter, challenge.Challenger)
+		}
+
 		newChallengeManager, err := NewChallengeManager(
 			ctx,
 			s.builder,
@@ -595,18 +733,28 @@ func (s *Staker) handleConflict(ctx context.Context, info *StakerInfo) error {
 		}
 
 		s.activeChallenge = newChallengeManager
+		s.activeChallengeEvent = event
 	}
+	// Added on every tick so notifiers that failed to accept it are retried
+	decision.addEvent(s.activeChallengeEvent)
 
 	_, err := s.activeChallenge.Act(ctx)
-	return err
+	if err != nil {
+		return err
+	}
+	decision.addAction("actOnChallenge", 0, nil, "staker is in challenge %v", *info.CurrentChallenge)
+	return nil
 }
 
-func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiveStrategy StakerStrategy) error {
+func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiveStrategy StakerStrategy, decision *StakerDecision) error {
 	active := effectiveStrategy >= StakeLatestStrategy
-	action, wrongNodesExist, err := s.generateNodeAction(ctx, info, effectiveStrategy, s.config.MakeAssertionInterval)
+	action, wrongNodesExist, err := s.generateNodeAction(ctx, info, effectiveStrategy, s.config.MakeAssertionInterval, decision)
 	if err != nil {
 		return fmt.Errorf("error generating node action: %w", err)
 	}
+	if wrongNodesExist {
+		decision.WrongNodesExist = true
+	}
 	if wrongNodesExist && effectiveStrategy == WatchtowerStrategy {
 		log.Error("found incorrect assertion in watchtower mode")
 	}
@@ -619,18 +767,29 @@ func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiv
 	case createNodeAction:
 		if wrongNodesExist && s.config.DisableChallenge {
 			log.Error("refusing to challenge assertion as config disables challenges")
+			decision.setReason("refusing to challenge incorrect assertion as challenges are disabled")
 			info.CanProgress = false
 			return nil
 		}
 		if !active {
 			if wrongNodesExist && effectiveStrategy >= DefensiveStrategy {
 				log.Error("bringing defensive validator online because of incorrect assertion")
+				decision.addAction("bringDefensiveOnline", info.LatestStakedNode+1, nil, "no correct successor to node %v exists but incorrect ones do", info.LatestStakedNode)
 				s.bringActiveUntilNode = info.LatestStakedNode + 1
+			} else {
+				decision.setReason("%v strategy doesn't create nodes", effectiveStrategy)
 			}
 			info.CanProgress = false
 			return nil
 		}
 
+		var reason string
+		if wrongNodesExist {
+			reason = fmt.Sprintf("no correct successor to node %v exists but incorrect ones do", info.LatestStakedNode)
+		} else {
+			reason = fmt.Sprintf("make assertion interval elapsed since node %v was created", info.LatestStakedNode)
+		}
+
 		// Details are already logged with more details in generateNodeAction
 		info.CanProgress = false
 		info.LatestStakedNode = 0
@@ -642,6 +801,7 @@ func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiv
 			if err != nil {
 				return fmt.Errorf("error staking on new node: %w", err)
 			}
+			decision.addAction("stakeOnNewNode", 0, &action.hash, "%v", reason)
 			return nil
 		}
 
@@ -659,6 +819,7 @@ func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiv
 		if err != nil {
 			return fmt.Errorf("error placing new stake on new node: %w", err)
 		}
+		decision.addAction("newStakeOnNewNode", 0, &action.hash, "%v, staking %v wei", reason, stakeAmount)
 		info.StakeExists = true
 		return nil
 	case existingNodeAction:
@@ -667,9 +828,11 @@ func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiv
 		if !active {
 			if wrongNodesExist && effectiveStrategy >= DefensiveStrategy {
 				log.Error("bringing defensive validator online because of incorrect assertion")
+				decision.addAction("bringDefensiveOnline", action.number, &action.hash, "incorrect sibling of correct node %v exists", action.number)
 				s.bringActiveUntilNode = action.number
 				info.CanProgress = false
 			} else {
+				decision.setReason("node %v is correct and %v strategy doesn't stake", action.number, effectiveStrategy)
 				s.inactiveLastCheckedNode = &nodeAndHash{
 					id:   action.number,
 					hash: action.hash,
@@ -684,6 +847,7 @@ func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiv
 			if err != nil {
 				return fmt.Errorf("error staking on existing node: %w", err)
 			}
+			decision.addAction("stakeOnExistingNode", action.number, &action.hash, "node %v is the correct successor to our stake", action.number)
 			return nil
 		}
 
@@ -700,6 +864,7 @@ func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiv
 		if err != nil {
 			return fmt.Errorf("error placing new stake on existing node: %w", err)
 		}
+		decision.addAction("newStakeOnExistingNode", action.number, &action.hash, "node %v is the correct successor, staking %v wei", action.number, stakeAmount)
 		info.StakeExists = true
 		return nil
 	default:
@@ -707,7 +872,7 @@ func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiv
 	}
 }
 
-func (s *Staker) createConflict(ctx context.Context, info *StakerInfo) error {
+func (s *Staker) createConflict(ctx context.Context, info *StakerInfo, decision *StakerDecision) error {
 	if info.CurrentChallenge != nil {
 		return nil
 	}
@@ -783,6 +948,7 @@ func (s *Staker) createConflict(ctx context.Context, info *StakerInfo) error {
 		if err != nil {
 			return fmt.Errorf("error creating challenge: %w", err)
 		}
+		decision.addAction("createChallenge", conflictInfo.Node1, nil, "stakers %v and %v are staked on conflicting nodes %v and %v", staker1, staker2, conflictInfo.Node1, conflictInfo.Node2)
 	}
 	// No conflicts exist
 	return nil
@@ -0,0 +1,212 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/pkg/errors"
+)
+
+// StakerAction is a single step the staker decided to take on L1, such as confirming or staking on a node
+type StakerAction struct {
+	Action   string       `json:"action"`
+	Node     uint64       `json:"node,omitempty"`
+	NodeHash *common.Hash `json:"nodeHash,omitempty"`
+	Reason   string       `json:"reason"`
+}
+
+// StakerTransaction is a transaction queued in the ValidatorTxBuilder
+type StakerTransaction struct {
+	To       common.Address `json:"to"`
+	Value    *hexutil.Big   `json:"value"`
+	Gas      hexutil.Uint64 `json:"gas"`
+	Selector hexutil.Bytes  `json:"selector,omitempty"`
+	Data     hexutil.Bytes  `json:"data"`
+}
+
+// StakerDecision records what the staker decided to do on one tick and why.
+// In dry-run mode the transactions are the ones that would have been sent.
+type StakerDecision struct {
+	Time                time.Time           `json:"time"`
+	DryRun              bool                `json:"dryRun"`
+	Strategy            string              `json:"strategy"`
+	EffectiveStrategy   string              `json:"effectiveStrategy"`
+	LatestStakedNode    uint64              `json:"latestStakedNode"`
+	LatestConfirmedNode uint64              `json:"latestConfirmedNode"`
+	StakeExists         bool                `json:"stakeExists"`
+	WrongNodesExist     bool                `json:"wrongNodesExist"`
+	Actions             []StakerAction      `json:"actions,omitempty"`
+	Events              []*StakerEvent      `json:"events,omitempty"`
+	Reason              string              `json:"reason,omitempty"`
+	ProjectedGas        uint64              `json:"projectedGas"`
+	Transactions        []StakerTransaction `json:"transactions,omitempty"`
+	TxHash              *common.Hash        `json:"txHash,omitempty"`
+	Error               string              `json:"error,omitempty"`
+}
+
+// addAction is safe to call on a nil decision, so callers don't need to check if decisions are being recorded
+func (d *StakerDecision) addAction(action string, node uint64, nodeHash *common.Hash, reason string, args ...interface{}) {
+	if d == nil {
+		return
+	}
+	d.Actions = append(d.Actions, StakerAction{
+		Action:   action,
+		Node:     node,
+		NodeHash: nodeHash,
+		Reason:   fmt.Sprintf(reason, args...),
+	})
+}
+
+// addEvent records an event to notify about
+func (d *StakerDecision) addEvent(event *StakerEvent) {
+	if d == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	d.Events = append(d.Events, event)
+}
+
+// setReason records why the staker stopped short of taking (further) action
+func (d *StakerDecision) setReason(reason string, args ...interface{}) {
+	if d == nil {
+		return
+	}
+	d.Reason = fmt.Sprintf(reason, args...)
+}
+
+func summarizeTransactions(txs []*types.Transaction) []StakerTransaction {
+	summaries := make([]StakerTransaction, 0, len(txs))
+	for _, tx := range txs {
+		summary := StakerTransaction{
+			Value: (*hexutil.Big)(tx.Value()),
+			Gas:   hexutil.Uint64(tx.Gas()),
+			Data:  tx.Data(),
+		}
+		if tx.To() != nil {
+			summary.To = *tx.To()
+		}
+		if len(tx.Data()) >= 4 {
+			summary.Selector = tx.Data()[:4]
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// projectGas estimates the gas the wallet would use to execute the transactions in the builder
+func (s *Staker) projectGas(ctx context.Context) (uint64, error) {
+	txs := s.builder.transactions
+	if len(txs) == 0 {
+		return 0, nil
+	}
+	if s.wallet.AuthIfEoa() != nil {
+		// EOA wallets only execute the first transaction, whose gas was estimated when it was built
+		return txs[0].Gas(), nil
+	}
+	walletAddr := s.wallet.Address()
+	if walletAddr == nil {
+		return 0, errors.New("validator wallet contract doesn't exist yet")
+	}
+	data, dest, amount, totalAmount := combineTxes(txs)
+	realData, err := validatorABI.Pack("executeTransactions", data, dest, amount)
+	if err != nil {
+		return 0, err
+	}
+	var from common.Address
+	if sender := s.wallet.TxSenderAddress(); sender != nil {
+		from = *sender
+	}
+	return s.client.EstimateGas(ctx, ethereum.CallMsg{
+		From:  from,
+		To:    walletAddr,
+		Value: totalAmount,
+		Data:  realData,
+	})
+}
+
+// dryRunTransactions records the transactions in the builder and the gas they would use, then discards them
+func (s *Staker) dryRunTransactions(ctx context.Context, decision *StakerDecision) {
+	decision.Transactions = summarizeTransactions(s.builder.transactions)
+	gas, err := s.projectGas(ctx)
+	if err != nil {
+		log.Warn("error projecting staker transaction gas", "err", err)
+	}
+	decision.ProjectedGas = gas
+	s.builder.ClearTransactions()
+}
+
+func (s *Staker) recordDecision(decision *StakerDecision) {
+	s.decisions.add(decision)
+	if !s.config.DryRun && len(decision.Actions) == 0 && len(decision.Events) == 0 && decision.Error == "" {
+		// Uneventful ticks are still kept for auditing, but only logged in dry-run mode
+		return
+	}
+	encoded, err := json.Marshal(decision)
+	if err != nil {
+		log.Warn("error encoding staker decision", "err", err)
+		return
+	}
+	log.Info("staker decision", "dryRun", decision.DryRun, "actions", len(decision.Actions), "projectedGas", decision.ProjectedGas, "decision", string(encoded))
+}
+
+// Decisions returns up to count of the most recent decisions, oldest first
+func (s *Staker) Decisions(count int) []*StakerDecision {
+	return s.decisions.latest(count)
+}
+
+// decisionLog keeps the most recent staker decisions in a ring buffer
+type decisionLog struct {
+	mutex     sync.Mutex
+	decisions []*StakerDecision
+	next      int
+	full      bool
+}
+
+func newDecisionLog(size int) *decisionLog {
+	return &decisionLog{decisions: make([]*StakerDecision, size)}
+}
+
+func (l *decisionLog) add(decision *StakerDecision) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.decisions) == 0 {
+		return
+	}
+	l.decisions[l.next] = decision
+	l.next++
+	if l.next == len(l.decisions) {
+		l.next = 0
+		l.full = true
+	}
+}
+
+func (l *decisionLog) latest(count int) []*StakerDecision {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	available := l.next
+	if l.full {
+		available = len(l.decisions)
+	}
+	if count <= 0 || count > available {
+		count = available
+	}
+	result := make([]*StakerDecision, 0, count)
+	for i := count; i > 0; i-- {
+		index := (l.next - i + len(l.decisions)) % len(l.decisions)
+		result = append(result, l.decisions[index])
+	}
+	return result
+}
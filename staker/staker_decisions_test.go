@@ -0,0 +1,86 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestDecisionLog(t *testing.T) {
+	log := newDecisionLog(3)
+	if len(log.latest(0)) != 0 {
+		Fail(t, "empty log returned decisions")
+	}
+	for i := uint64(1); i <= 5; i++ {
+		log.add(&StakerDecision{LatestStakedNode: i})
+		expected := i
+		if expected > 3 {
+			expected = 3
+		}
+		if uint64(len(log.latest(0))) != expected {
+			Fail(t, "unexpected number of decisions", len(log.latest(0)), "after adding", i)
+		}
+	}
+	decisions := log.latest(2)
+	if len(decisions) != 2 || decisions[0].LatestStakedNode != 4 || decisions[1].LatestStakedNode != 5 {
+		Fail(t, "unexpected latest decisions", decisions)
+	}
+	decisions = log.latest(10)
+	if len(decisions) != 3 || decisions[0].LatestStakedNode != 3 {
+		Fail(t, "unexpected decisions", decisions)
+	}
+
+	disabled := newDecisionLog(0)
+	disabled.add(&StakerDecision{})
+	if len(disabled.latest(0)) != 0 {
+		Fail(t, "disabled log kept a decision")
+	}
+}
+
+func TestStakerDecisionRecording(t *testing.T) {
+	var nilDecision *StakerDecision
+	nilDecision.addAction("confirmNode", 1, nil, "node is decidably valid")
+	nilDecision.setReason("no action needed")
+
+	decision := &StakerDecision{}
+	hash := common.HexToHash("0x1234")
+	decision.addAction("stakeOnExistingNode", 7, &hash, "node %v is the correct successor to our stake", 7)
+	decision.setReason("catching up to chain batches: %v/%v", 1, 2)
+	if len(decision.Actions) != 1 || decision.Actions[0].Reason != "node 7 is the correct successor to our stake" {
+		Fail(t, "unexpected actions", decision.Actions)
+	}
+	if decision.Reason != "catching up to chain batches: 1/2" {
+		Fail(t, "unexpected reason", decision.Reason)
+	}
+
+	to := common.HexToAddress("0xabcd")
+	txs := []*types.Transaction{
+		types.NewTx(&types.LegacyTx{To: &to, Value: big.NewInt(5), Gas: 21000, Data: []byte{1, 2, 3, 4, 5}}),
+		types.NewTx(&types.LegacyTx{To: &to, Value: big.NewInt(0), Gas: 0, Data: []byte{1}}),
+	}
+	decision.Transactions = summarizeTransactions(txs)
+	if len(decision.Transactions) != 2 {
+		Fail(t, "unexpected number of transactions", len(decision.Transactions))
+	}
+	first := decision.Transactions[0]
+	if first.To != to || first.Value.ToInt().Int64() != 5 || first.Gas != 21000 || len(first.Selector) != 4 || len(first.Data) != 5 {
+		Fail(t, "unexpected transaction summary", first)
+	}
+	if decision.Transactions[1].Selector != nil {
+		Fail(t, "selector set for short calldata")
+	}
+
+	encoded, err := json.Marshal(decision)
+	Require(t, err)
+	var decoded StakerDecision
+	Require(t, json.Unmarshal(encoded, &decoded))
+	if decoded.Actions[0].NodeHash == nil || *decoded.Actions[0].NodeHash != hash || decoded.Transactions[0].Gas != 21000 {
+		Fail(t, "decision didn't round trip through json", string(encoded))
+	}
+}
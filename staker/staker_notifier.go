@@ -0,0 +1,332 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/pkg/errors"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/util/containers"
+)
+
+var (
+	stakerNotifiedCounter      = metrics.NewRegisteredCounter("arb/staker/notifier/notified", nil)
+	stakerNotifyFailureCounter = metrics.NewRegisteredCounter("arb/staker/notifier/failure", nil)
+	stakerNotifyDroppedCounter = metrics.NewRegisteredCounter("arb/staker/notifier/dropped", nil)
+)
+
+const (
+	// how many notify calls can be waiting to be sent before more are dropped
+	stakerNotifyQueueSize = 64
+	// how many events each notifier remembers having accepted, events seen on every tick stay remembered
+	stakerNotifiedKeys = 1024
+	// how long events still queued when the staker stops have to be sent
+	stakerNotifyDrainTimeout = 10 * time.Second
+)
+
+type StakerNotifierConfig struct {
+	WebhookURL string        `koanf:"webhook-url"`
+	File       string        `koanf:"file"`
+	Exec       string        `koanf:"exec"`
+	Timeout    time.Duration `koanf:"timeout"`
+}
+
+var DefaultStakerNotifierConfig = StakerNotifierConfig{
+	WebhookURL: "",
+	File:       "",
+	Exec:       "",
+	Timeout:    10 * time.Second,
+}
+
+func StakerNotifierConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.String(prefix+".webhook-url", DefaultStakerNotifierConfig.WebhookURL, "url staker events are posted to as json (empty to disable)")
+	f.String(prefix+".file", DefaultStakerNotifierConfig.File, "file staker events are appended to as json lines (empty to disable)")
+	f.String(prefix+".exec", DefaultStakerNotifierConfig.Exec, "command run for each staker event, with the event as json on stdin (empty to disable)")
+	f.Duration(prefix+".timeout", DefaultStakerNotifierConfig.Timeout, "how long each notifier has to accept an event")
+}
+
+func (c *StakerNotifierConfig) Validate() error {
+	if c.Timeout <= 0 && (c.WebhookURL != "" || c.Exec != "") {
+		return errors.New("staker notifier timeout must be positive")
+	}
+	return nil
+}
+
+type StakerEventType string
+
+const (
+	// An assertion disagrees with what we validated
+	StakerEventConflict StakerEventType = "conflict"
+	// A challenge was opened against our stake
+	StakerEventChallenge StakerEventType = "challenge"
+	// Our stake is on a node before the latest confirmed node
+	StakerEventStakeBehind StakerEventType = "stakeBehind"
+	// The block validator failed a validation with failure-is-fatal set
+	StakerEventValidationFailure StakerEventType = "validationFailure"
+)
+
+// StakerEvent is something the staker saw that needs an operator's attention
+type StakerEvent struct {
+	Type                StakerEventType `json:"type"`
+	Time                time.Time       `json:"time"`
+	Node                uint64          `json:"node,omitempty"`
+	NodeHash            *common.Hash    `json:"nodeHash,omitempty"`
+	AssertionBlockHash  *common.Hash    `json:"assertionBlockHash,omitempty"`
+	AssertionSendRoot   *common.Hash    `json:"assertionSendRoot,omitempty"`
+	ExpectedBlockHash   *common.Hash    `json:"expectedBlockHash,omitempty"`
+	ExpectedSendRoot    *common.Hash    `json:"expectedSendRoot,omitempty"`
+	LatestConfirmedNode uint64          `json:"latestConfirmedNode,omitempty"`
+	ChallengeIndex      *uint64         `json:"challengeIndex,omitempty"`
+	L1TxHash            *common.Hash    `json:"l1TxHash,omitempty"`
+	Message             string          `json:"message"`
+}
+
+// key identifies the event so it is only notified once, no matter how many ticks it's seen on
+func (e *StakerEvent) key() string {
+	if e.Type == StakerEventValidationFailure {
+		return fmt.Sprintf("%v/%v", e.Type, e.Message)
+	}
+	var nodeHash common.Hash
+	if e.NodeHash != nil {
+		nodeHash = *e.NodeHash
+	}
+	var challenge uint64
+	if e.ChallengeIndex != nil {
+		challenge = *e.ChallengeIndex
+	}
+	return fmt.Sprintf("%v/%v/%v/%v", e.Type, e.Node, nodeHash, challenge)
+}
+
+// StakerNotifier is told about staker events, e.g. to page someone
+type StakerNotifier interface {
+	Notify(ctx context.Context, event *StakerEvent) error
+}
+
+// NewStakerNotifiers creates the notifiers enabled in the config
+func NewStakerNotifiers(config *StakerNotifierConfig) []StakerNotifier {
+	var notifiers []StakerNotifier
+	if config.WebhookURL != "" {
+		notifiers = append(notifiers, NewWebhookNotifier(config.WebhookURL, config.Timeout))
+	}
+	if config.File != "" {
+		notifiers = append(notifiers, NewFileNotifier(config.File))
+	}
+	if config.Exec != "" {
+		notifiers = append(notifiers, NewExecNotifier(config.Exec, config.Timeout))
+	}
+	return notifiers
+}
+
+// WebhookNotifier posts each event as json
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, event *StakerEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned status %v", res.Status)
+	}
+	return nil
+}
+
+func (n *WebhookNotifier) String() string {
+	return "webhook " + n.url
+}
+
+// FileNotifier appends each event to a file as a json line
+type FileNotifier struct {
+	mutex sync.Mutex
+	path  string
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (n *FileNotifier) Notify(ctx context.Context, event *StakerEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	file, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (n *FileNotifier) String() string {
+	return "file " + n.path
+}
+
+// ExecNotifier runs a command for each event, with the event as json on stdin
+// and its type and node in the STAKER_EVENT_TYPE and STAKER_EVENT_NODE environment variables
+type ExecNotifier struct {
+	command []string
+	timeout time.Duration
+}
+
+func NewExecNotifier(command string, timeout time.Duration) *ExecNotifier {
+	return &ExecNotifier{
+		command: strings.Fields(command),
+		timeout: timeout,
+	}
+}
+
+func (n *ExecNotifier) Notify(ctx context.Context, event *StakerEvent) error {
+	if len(n.command) == 0 {
+		return errors.New("no command to run")
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, n.command[0], n.command[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"STAKER_EVENT_TYPE="+string(event.Type),
+		fmt.Sprintf("STAKER_EVENT_NODE=%v", event.Node),
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %v", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (n *ExecNotifier) String() string {
+	return "exec " + strings.Join(n.command, " ")
+}
+
+// stakerNotifiers sends events to all notifiers from its own thread, skipping events a notifier already accepted.
+// Events a notifier failed to accept are sent again the next time they're seen.
+type stakerNotifiers struct {
+	queue chan []*StakerEvent
+
+	mutex     sync.Mutex
+	notifiers []StakerNotifier
+	notified  []*containers.LruCache[string, struct{}]
+}
+
+func newStakerNotifiers(notifiers []StakerNotifier) *stakerNotifiers {
+	n := &stakerNotifiers{
+		queue: make(chan []*StakerEvent, stakerNotifyQueueSize),
+	}
+	for _, notifier := range notifiers {
+		n.add(notifier)
+	}
+	return n
+}
+
+func (n *stakerNotifiers) add(notifier StakerNotifier) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.notifiers = append(n.notifiers, notifier)
+	n.notified = append(n.notified, containers.NewLruCache[string, struct{}](stakerNotifiedKeys))
+}
+
+// notify queues the events for the notifier thread without waiting, so a slow notifier never holds up
+// the staker or the block validator. If the queue is full the events are dropped.
+func (n *stakerNotifiers) notify(events []*StakerEvent) {
+	if len(events) == 0 {
+		return
+	}
+	select {
+	case n.queue <- events:
+	default:
+		stakerNotifyDroppedCounter.Inc(1)
+		log.Warn("staker notifier queue full, dropping events", "count", len(events))
+	}
+}
+
+// run sends the queued events until the context is done, then sends whatever is still queued, such as the
+// fatal validation failure the node is stopping for
+func (n *stakerNotifiers) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		select {
+		case events := <-n.queue:
+			n.send(ctx, events)
+		case <-ctx.Done():
+		}
+	}
+	drainCtx, cancel := context.WithTimeout(context.Background(), stakerNotifyDrainTimeout)
+	defer cancel()
+	for {
+		select {
+		case events := <-n.queue:
+			n.send(drainCtx, events)
+		default:
+			return
+		}
+	}
+}
+
+func (n *stakerNotifiers) send(ctx context.Context, events []*StakerEvent) {
+	n.mutex.Lock()
+	notifiers := append([]StakerNotifier{}, n.notifiers...)
+	notified := append([]*containers.LruCache[string, struct{}]{}, n.notified...)
+	n.mutex.Unlock()
+	for _, event := range events {
+		key := event.key()
+		for i, notifier := range notifiers {
+			// the lru caches are only used from this thread
+			if _, ok := notified[i].Get(key); ok {
+				continue
+			}
+			if err := notifier.Notify(ctx, event); err != nil {
+				stakerNotifyFailureCounter.Inc(1)
+				log.Error("error notifying staker event", "notifier", notifier, "type", event.Type, "node", event.Node, "err", err)
+				continue
+			}
+			stakerNotifiedCounter.Inc(1)
+			notified[i].Add(key, struct{}{})
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type webhookStandIn struct {
+	mutex    sync.Mutex
+	failures int
+	events   []StakerEvent
+}
+
+func (w *webhookStandIn) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.failures > 0 {
+		w.failures--
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var event StakerEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.events = append(w.events, event)
+}
+
+func (w *webhookStandIn) received() []StakerEvent {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]StakerEvent{}, w.events...)
+}
+
+func readEventLines(t *testing.T, path string) []StakerEvent {
+	t.Helper()
+	file, err := os.Open(path)
+	Require(t, err)
+	defer file.Close()
+	var events []StakerEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event StakerEvent
+		Require(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	Require(t, scanner.Err())
+	return events
+}
+
+func TestStakerNotifiers(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	webhook := &webhookStandIn{failures: 1}
+	server := httptest.NewServer(webhook)
+	defer server.Close()
+
+	script := filepath.Join(dir, "notify.sh")
+	execOutput := filepath.Join(dir, "exec.jsonl")
+	Require(t, os.WriteFile(script, []byte("#!/bin/sh\ncat >> \"$1\"\necho >> \"$1\"\necho \"$STAKER_EVENT_TYPE $STAKER_EVENT_NODE\" >> \"$1.env\"\n"), 0755))
+
+	config := DefaultStakerNotifierConfig
+	config.WebhookURL = server.URL
+	config.File = filepath.Join(dir, "events.jsonl")
+	config.Exec = script + " " + execOutput
+	Require(t, config.Validate())
+	notifiers := newStakerNotifiers(NewStakerNotifiers(&config))
+
+	nodeHash := common.HexToHash("0x1234")
+	blockHash := common.HexToHash("0x5678")
+	txHash := common.HexToHash("0x9abc")
+	conflict := &StakerEvent{
+		Type:               StakerEventConflict,
+		Time:               time.Now(),
+		Node:               7,
+		NodeHash:           &nodeHash,
+		AssertionBlockHash: &blockHash,
+		L1TxHash:           &txHash,
+		Message:            "found incorrect assertion in node 7",
+	}
+	// The webhook fails the first time, so only it is sent the event again on the next tick.
+	// The events are sent directly here, rather than through the notifier thread.
+	notifiers.send(ctx, []*StakerEvent{conflict})
+	if len(webhook.received()) != 0 {
+		Fail(t, "webhook accepted an event while failing")
+	}
+	notifiers.send(ctx, []*StakerEvent{conflict})
+	notifiers.send(ctx, []*StakerEvent{conflict})
+
+	received := webhook.received()
+	if len(received) != 1 {
+		Fail(t, "webhook received", len(received), "events")
+	}
+	if received[0].Type != StakerEventConflict || received[0].Node != 7 || *received[0].NodeHash != nodeHash || *received[0].L1TxHash != txHash {
+		Fail(t, "unexpected webhook event", received[0])
+	}
+	fileEvents := readEventLines(t, config.File)
+	if len(fileEvents) != 1 || *fileEvents[0].AssertionBlockHash != blockHash {
+		Fail(t, "unexpected file events", fileEvents)
+	}
+	execEvents := readEventLines(t, execOutput)
+	if len(execEvents) != 1 || execEvents[0].Message != conflict.Message {
+		Fail(t, "unexpected exec events", execEvents)
+	}
+	env, err := os.ReadFile(execOutput + ".env")
+	Require(t, err)
+	if string(env) != "conflict 7\n" {
+		Fail(t, "unexpected exec environment", string(env))
+	}
+
+	// Different events are all sent, custom notifiers included
+	custom := &webhookStandIn{}
+	customServer := httptest.NewServer(custom)
+	defer customServer.Close()
+	notifiers.add(NewWebhookNotifier(customServer.URL, time.Second))
+	notifiers.send(ctx, []*StakerEvent{
+		conflict,
+		{Type: StakerEventStakeBehind, Node: 5, LatestConfirmedNode: 6},
+		{Type: StakerEventValidationFailure, Message: "validation failed"},
+	})
+	if len(webhook.received()) != 3 || len(readEventLines(t, config.File)) != 3 {
+		Fail(t, "new events not sent to configured notifiers")
+	}
+	if len(custom.received()) != 3 {
+		Fail(t, "custom notifier received", len(custom.received()), "events")
+	}
+}
+
+// blockingNotifier records events once it's released
+type blockingNotifier struct {
+	release chan struct{}
+	events  chan *StakerEvent
+}
+
+func (n *blockingNotifier) Notify(ctx context.Context, event *StakerEvent) error {
+	<-n.release
+	n.events <- event
+	return nil
+}
+
+func TestStakerNotifiersThread(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	notifier := &blockingNotifier{release: make(chan struct{}), events: make(chan *StakerEvent, 2*stakerNotifyQueueSize)}
+	notifiers := newStakerNotifiers([]StakerNotifier{notifier})
+	done := make(chan struct{})
+	go func() {
+		notifiers.run(ctx)
+		close(done)
+	}()
+
+	// Notifying never waits for the notifier, even once the queue is full
+	start := time.Now()
+	for i := 0; i < 2*stakerNotifyQueueSize; i++ {
+		notifiers.notify([]*StakerEvent{{Type: StakerEventValidationFailure, Message: "failure " + strconv.Itoa(i)}})
+	}
+	if time.Since(start) > time.Second {
+		Fail(t, "notifying waited for the notifier")
+	}
+
+	// Events still queued as the staker stops are sent
+	cancel()
+	close(notifier.release)
+	<-done
+	// the full queue, and the event the notifier was sent as it filled up if it got it in time
+	if sent := len(notifier.events); sent != stakerNotifyQueueSize && sent != stakerNotifyQueueSize+1 {
+		Fail(t, "unexpected number of events sent", sent)
+	}
+	if received := <-notifier.events; received.Message != "failure 0" {
+		Fail(t, "unexpected first event", received.Message)
+	}
+}
+
+// countingNotifier counts the events it's sent
+type countingNotifier struct {
+	count int
+}
+
+func (n *countingNotifier) Notify(ctx context.Context, event *StakerEvent) error {
+	n.count++
+	return nil
+}
+
+func TestStakerNotifiersForget(t *testing.T) {
+	ctx := context.Background()
+	notifier := &countingNotifier{}
+	notifiers := newStakerNotifiers([]StakerNotifier{notifier})
+	event := func(i int) *StakerEvent {
+		return &StakerEvent{Type: StakerEventValidationFailure, Message: "failure " + strconv.Itoa(i)}
+	}
+
+	// Events seen again are remembered, however many others there are since
+	notifiers.send(ctx, []*StakerEvent{event(0), event(1)})
+	for i := 2; i < 2*stakerNotifiedKeys; i++ {
+		notifiers.send(ctx, []*StakerEvent{event(0), event(i)})
+	}
+	if notifier.count != 2*stakerNotifiedKeys {
+		Fail(t, "unexpected number of events sent", notifier.count)
+	}
+	// But only so many are remembered
+	notifiers.send(ctx, []*StakerEvent{event(0), event(1)})
+	if notifier.count != 2*stakerNotifiedKeys+1 {
+		Fail(t, "forgotten event not sent again", notifier.count)
+	}
+}
+
+func TestExecNotifierFailure(t *testing.T) {
+	notifier := NewExecNotifier("false", time.Second)
+	if notifier.Notify(context.Background(), &StakerEvent{Type: StakerEventChallenge}) == nil {
+		Fail(t, "failing command reported success")
+	}
+	config := DefaultStakerNotifierConfig
+	config.WebhookURL = "http://localhost"
+	config.Timeout = 0
+	if config.Validate() == nil {
+		Fail(t, "accepted webhook without a timeout")
+	}
+}
@@ -4,11 +4,13 @@
 package staker
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"sync"
 
 	"github.com/offchainlabs/nitro/util/signature"
+	"github.com/offchainlabs/nitro/validator/inputstore"
 	"github.com/offchainlabs/nitro/validator/server_api"
 
 	"github.com/offchainlabs/nitro/arbutil"
@@ -18,8 +20,10 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/offchainlabs/nitro/arbos"
 	"github.com/offchainlabs/nitro/arbos/arbosState"
 	"github.com/offchainlabs/nitro/arbos/arbostypes"
@@ -27,11 +31,22 @@ import (
 	"github.com/pkg/errors"
 )
 
+var (
+	validatorInputStoreHitCounter     = metrics.NewRegisteredCounter("arb/validator/input_store/hit", nil)
+	validatorInputStoreMissCounter    = metrics.NewRegisteredCounter("arb/validator/input_store/miss", nil)
+	validatorInputStoreStoredCounter  = metrics.NewRegisteredCounter("arb/validator/input_store/stored", nil)
+	validatorInputStoreFailedCounter  = metrics.NewRegisteredCounter("arb/validator/input_store/failed", nil)
+	validatorInputStoreInvalidCounter = metrics.NewRegisteredCounter("arb/validator/input_store/invalid", nil)
+)
+
 type StatelessBlockValidator struct {
 	config *BlockValidatorConfig
 
 	execSpawner        validator.ExecutionSpawner
 	validationSpawners []validator.ValidationSpawner
+	validationPool     *validationPool
+	// Optional store of recorded inputs, used instead of recording blocks again
+	inputStore inputstore.Store
 
 	inboxReader       InboxReaderInterface
 	inboxTracker      InboxTrackerInterface
@@ -171,6 +186,8 @@ type validationEntry struct {
 	// Valid since Ready:
 	StartPosition GlobalStatePosition
 	EndPosition   GlobalStatePosition
+	// Whether the recorded fields came from the input store, so there's no need to store them
+	fromInputStore bool
 }
 
 func (v *validationEntry) start() (validator.GoGlobalState, error) {
@@ -287,20 +304,50 @@ func NewStatelessBlockValidator(
 	if err != nil {
 		return nil, err
 	}
-	var jwt []byte
-	if config.JWTSecret != "" {
-		jwtHash, err := signature.LoadSigningKey(config.JWTSecret)
-		if err != nil {
-			return nil, err
+	servers, err := config.ValidationServerConfigs()
+	if err != nil {
+		return nil, err
+	}
+	if len(servers) == 0 {
+		return nil, errors.New("no validation servers configured")
+	}
+	pool := newValidationPool()
+	// Executions for challenges and the latest module root are from the first server, or the next reachable one
+	var execClient validator.ExecutionSpawner
+	var execPool *executionPool
+	if servers[0].URL != LocalValidationServer {
+		execPool = newExecutionPool()
+		execClient = execPool
+	}
+	for _, server := range servers {
+		if server.URL == LocalValidationServer {
+			pool.addLocal(server.URL)
+			continue
+		}
+		var jwt []byte
+		if server.JWTSecret != "" {
+			jwtHash, err := signature.LoadSigningKey(server.JWTSecret)
+			if err != nil {
+				return nil, err
+			}
+			jwt = jwtHash.Bytes()
 		}
-		jwt = jwtHash.Bytes()
+		url := server.URL
+		pool.addRemote(url, func() validator.ValidationSpawner { return server_api.NewValidationClient(url, jwt) })
+		if execPool != nil {
+			execPool.addRemote(url, func() validator.ExecutionSpawner { return server_api.NewExecutionClient(url, jwt) })
+		}
+	}
+	inputStore, err := inputstore.NewStore(context.Background(), &config.InputStore)
+	if err != nil {
+		return nil, err
 	}
-	valClient := server_api.NewValidationClient(config.URL, jwt)
-	execClient := server_api.NewExecutionClient(config.URL, jwt)
 	validator := &StatelessBlockValidator{
 		config:             config,
 		execSpawner:        execClient,
-		validationSpawners: []validator.ValidationSpawner{valClient},
+		validationSpawners: []validator.ValidationSpawner{pool},
+		validationPool:     pool,
+		inputStore:         inputStore,
 		inboxReader:        inboxReader,
 		inboxTracker:       inbox,
 		streamer:           streamer,
@@ -313,6 +360,15 @@ func NewStatelessBlockValidator(
 	return validator, nil
 }
 
+// SetLocalValidationNode provides the spawners of the validation node in this process,
+// for when it's one of the validation servers. It must be called before Start.
+func (v *StatelessBlockValidator) SetLocalValidationNode(validationSpawner validator.ValidationSpawner, execSpawner validator.ExecutionSpawner) {
+	v.validationPool.setLocal(validationSpawner)
+	if v.execSpawner == nil {
+		v.execSpawner = execSpawner
+	}
+}
+
 func (v *StatelessBlockValidator) GetModuleRootsToValidate() []common.Hash {
 	v.moduleMutex.Lock()
 	defer v.moduleMutex.Unlock()
@@ -431,12 +487,16 @@ func (v *StatelessBlockValidator) ValidationEntryRecord(ctx context.Context, e *
 		e.Stage = Recorded
 		return nil
 	}
-	blockhash, preimages, readBatchInfo, err := v.RecordBlockCreation(ctx, e.PrevBlockHeader, e.msg, keepReference)
-	if err != nil {
-		return err
-	}
-	if blockhash != e.BlockHash {
-		return fmt.Errorf("recording failed: blockNum %d, hash expected %v, got %v", e.BlockNumber, e.BlockHash, blockhash)
+	if !v.loadFromInputStore(ctx, e, keepReference) {
+		blockhash, preimages, readBatchInfo, err := v.RecordBlockCreation(ctx, e.PrevBlockHeader, e.msg, keepReference)
+		if err != nil {
+			return err
+		}
+		if blockhash != e.BlockHash {
+			return fmt.Errorf("recording failed: blockNum %d, hash expected %v, got %v", e.BlockNumber, e.BlockHash, blockhash)
+		}
+		e.Preimages = preimages
+		e.BatchInfo = readBatchInfo
 	}
 	if e.HasDelayedMsg {
 		delayedMsg, err := v.inboxTracker.GetDelayedMessageBytes(e.DelayedMsgNr)
@@ -449,8 +509,6 @@ func (v *StatelessBlockValidator) ValidationEntryRecord(ctx context.Context, e *
 		}
 		e.DelayedMsg = delayedMsg
 	}
-	e.Preimages = preimages
-	e.BatchInfo = readBatchInfo
 	e.msg = nil // no longer needed
 	e.Stage = Recorded
 	return nil
@@ -470,7 +528,14 @@ func (v *StatelessBlockValidator) ValidationEntryAddSeqMessage(ctx context.Conte
 		Number: startPos.BatchNumber,
 		Data:   seqMsg,
 	}
-	e.BatchInfo = append(e.BatchInfo, seqMsgBatchInfo)
+	// Inputs from the input store already have the sequencer message, it's replaced by the one read from L1
+	batchInfo := make([]validator.BatchInfo, 0, len(e.BatchInfo)+1)
+	for _, batch := range e.BatchInfo {
+		if batch.Number != seqMsgBatchInfo.Number {
+			batchInfo = append(batchInfo, batch)
+		}
+	}
+	e.BatchInfo = append(batchInfo, seqMsgBatchInfo)
 
 	for _, batch := range e.BatchInfo {
 		if len(batch.Data) <= 40 {
@@ -560,10 +625,97 @@ func (v *StatelessBlockValidator) CreateReadyValidationEntry(ctx context.Context
 	if err != nil {
 		return nil, err
 	}
+	if v.inputStore != nil && !entry.fromInputStore {
+		input, err := entry.ToInput()
+		if err != nil {
+			return nil, err
+		}
+		v.storeInput(ctx, entry, input)
+	}
 
 	return entry, nil
 }
 
+// loadFromInputStore fills in the preimages and batches of the entry from the input store, returning false
+// if they have to be recorded. Anyone with access to the store could have written the input, so it's only
+// used if every preimage matches its hash and every batch matches the one read from L1. The delayed message
+// is always read from the inbox tracker. With keepReference, it's only used if the state of the previous
+// block is available to keep a reference to.
+func (v *StatelessBlockValidator) loadFromInputStore(ctx context.Context, e *validationEntry, keepReference bool) bool {
+	if v.inputStore == nil {
+		return false
+	}
+	input, err := inputstore.GetChecked(ctx, v.inputStore, e.BlockHash, func(input *validator.ValidationInput) error {
+		if input.Id != e.BlockNumber || input.HasDelayedMsg != e.HasDelayedMsg || input.DelayedMsgNr != e.DelayedMsgNr || input.StartState.BlockHash != e.PrevBlockHash {
+			return errors.New("input doesn't match the block")
+		}
+		return v.checkStoredInput(ctx, input)
+	})
+	if errors.Is(err, inputstore.ErrInvalid) {
+		log.Warn("ignoring invalid stored validation input", "blockNum", e.BlockNumber, "blockHash", e.BlockHash, "err", err)
+		validatorInputStoreInvalidCounter.Inc(1)
+		return false
+	}
+	if err != nil {
+		if !errors.Is(err, inputstore.ErrNotFound) {
+			log.Warn("failed to get validation input from store", "blockNum", e.BlockNumber, "blockHash", e.BlockHash, "err", err)
+		}
+		validatorInputStoreMissCounter.Inc(1)
+		return false
+	}
+	if keepReference {
+		if _, err := v.recordingDatabase.StateFor(e.PrevBlockHeader); err != nil {
+			return false
+		}
+	}
+	validatorInputStoreHitCounter.Inc(1)
+	e.Preimages = input.Preimages
+	e.BatchInfo = input.BatchInfo
+	e.fromInputStore = true
+	return true
+}
+
+// checkStoredInput checks the preimages of a stored input against their hashes, and its batches against L1
+func (v *StatelessBlockValidator) checkStoredInput(ctx context.Context, input *validator.ValidationInput) error {
+	for hash, preimage := range input.Preimages {
+		if crypto.Keccak256Hash(preimage) != hash {
+			return fmt.Errorf("preimage doesn't match its hash %v", hash)
+		}
+	}
+	for _, batch := range input.BatchInfo {
+		data, err := v.inboxReader.GetSequencerMessageBytes(ctx, batch.Number)
+		if err != nil {
+			return fmt.Errorf("failed to read batch %v: %w", batch.Number, err)
+		}
+		if !bytes.Equal(data, batch.Data) {
+			return fmt.Errorf("batch %v doesn't match the one read from L1", batch.Number)
+		}
+	}
+	return nil
+}
+
+// storeInput stores the input of a ready entry that was recorded, so it doesn't have to be recorded again
+func (v *StatelessBlockValidator) storeInput(ctx context.Context, e *validationEntry, input *validator.ValidationInput) {
+	if v.inputStore == nil || e.fromInputStore || input == nil {
+		return
+	}
+	if err := v.inputStore.Put(ctx, e.BlockHash, input); err != nil {
+		validatorInputStoreFailedCounter.Inc(1)
+		log.Warn("failed to store validation input", "blockNum", e.BlockNumber, "blockHash", e.BlockHash, "err", err)
+		return
+	}
+	validatorInputStoreStoredCounter.Inc(1)
+}
+
+// ValidationInput returns the input for validating the block
+func (v *StatelessBlockValidator) ValidationInput(ctx context.Context, header *types.Header) (*validator.ValidationInput, error) {
+	entry, err := v.CreateReadyValidationEntry(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	return entry.ToInput()
+}
+
 func (v *StatelessBlockValidator) ValidateBlock(
 	ctx context.Context, header *types.Header, useExec bool, moduleRoot common.Hash,
 ) (bool, error) {
@@ -612,9 +764,14 @@ func (v *StatelessBlockValidator) RecordDBReferenceCount() int64 {
 }
 
 func (v *StatelessBlockValidator) Start(ctx_in context.Context) error {
-	err := v.execSpawner.Start(ctx_in)
-	if err != nil {
-		return err
+	if v.execSpawner == nil {
+		return errors.New("local validation server configured without a validation node in this process")
+	}
+	// The local validation node starts its own spawners
+	if _, isPool := v.execSpawner.(*executionPool); isPool {
+		if err := v.execSpawner.Start(ctx_in); err != nil {
+			return err
+		}
 	}
 	for _, spawner := range v.validationSpawners {
 		if err := spawner.Start(ctx_in); err != nil {
@@ -639,7 +796,9 @@ func (v *StatelessBlockValidator) Start(ctx_in context.Context) error {
 }
 
 func (v *StatelessBlockValidator) Stop() {
-	v.execSpawner.Stop()
+	if _, isPool := v.execSpawner.(*executionPool); isPool {
+		v.execSpawner.Stop()
+	}
 	for _, spawner := range v.validationSpawners {
 		spawner.Stop()
 	}
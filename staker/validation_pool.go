@@ -0,0 +1,299 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/util/containers"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+	"github.com/offchainlabs/nitro/validator"
+)
+
+var validationRetriesCounter = metrics.NewRegisteredCounter("arb/validator/servers/retries", nil)
+
+const validationServerRestartInterval = 30 * time.Second
+
+type validationServer struct {
+	name string
+	// create makes a new spawner for a remote server, local spawners are started and stopped by their owner
+	create  func() validator.ValidationSpawner
+	spawner validator.ValidationSpawner
+	started bool
+	// room as of the last Room call, less what's been launched on it since
+	room int
+
+	launchedCounter metrics.Counter
+	failedCounter   metrics.Counter
+	roomGauge       metrics.Gauge
+}
+
+func validationServerMetricName(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+	}
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.TrimSuffix(url, "/"))
+}
+
+func newValidationServer(name string, create func() validator.ValidationSpawner) *validationServer {
+	metricPrefix := "arb/validator/server/" + validationServerMetricName(name)
+	return &validationServer{
+		name:            name,
+		create:          create,
+		launchedCounter: metrics.GetOrRegisterCounter(metricPrefix+"/launched", nil),
+		failedCounter:   metrics.GetOrRegisterCounter(metricPrefix+"/failed", nil),
+		roomGauge:       metrics.GetOrRegisterGauge(metricPrefix+"/room", nil),
+	}
+}
+
+// validationPool spreads validations between servers by the room each reports, retrying
+// runs that fail on one server on the others. Servers that can't be reached on start are
+// retried in the background.
+type validationPool struct {
+	stopwaiter.StopWaiter
+
+	mutex   sync.Mutex
+	servers []*validationServer
+}
+
+type validationPoolRun struct {
+	containers.Promise[validator.GoGlobalState]
+	root common.Hash
+}
+
+func (r *validationPoolRun) WasmModuleRoot() common.Hash {
+	return r.root
+}
+
+func newValidationPool() *validationPool {
+	return &validationPool{}
+}
+
+func (p *validationPool) addRemote(name string, create func() validator.ValidationSpawner) {
+	p.servers = append(p.servers, newValidationServer(name, create))
+}
+
+func (p *validationPool) addLocal(name string) {
+	p.servers = append(p.servers, newValidationServer(name, nil))
+}
+
+// setLocal sets the spawner of local servers, which must be done before Start
+func (p *validationPool) setLocal(spawner validator.ValidationSpawner) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, server := range p.servers {
+		if server.create == nil {
+			server.spawner = spawner
+		}
+	}
+}
+
+func (p *validationPool) startServer(ctx context.Context, server *validationServer) error {
+	if server.create == nil {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		if server.spawner == nil {
+			return fmt.Errorf("validation server %v configured without a validation node in this process", server.name)
+		}
+		server.started = true
+		return nil
+	}
+	spawner := server.create()
+	if err := spawner.Start(ctx); err != nil {
+		spawner.Stop()
+		return err
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	server.spawner = spawner
+	server.started = true
+	return nil
+}
+
+func (p *validationPool) Start(ctx_in context.Context) error {
+	p.StopWaiter.Start(ctx_in, p)
+	ctx := p.GetContext()
+	var lastErr error
+	started := 0
+	for _, server := range p.servers {
+		if err := p.startServer(ctx, server); err != nil {
+			if server.create == nil {
+				return err
+			}
+			log.Warn("couldn't connect to validation server, will retry", "server", server.name, "err", err)
+			lastErr = err
+			continue
+		}
+		started++
+	}
+	if started == 0 {
+		return fmt.Errorf("couldn't connect to any validation server: %w", lastErr)
+	}
+	if started < len(p.servers) {
+		p.LaunchThread(func(ctx context.Context) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(validationServerRestartInterval):
+				}
+				if p.restartServers(ctx) {
+					return
+				}
+			}
+		})
+	}
+	return nil
+}
+
+// restartServers tries connecting to the servers that couldn't be reached, returning true once all have been
+func (p *validationPool) restartServers(ctx context.Context) bool {
+	remaining := 0
+	for _, server := range p.servers {
+		p.mutex.Lock()
+		started := server.started
+		p.mutex.Unlock()
+		if started {
+			continue
+		}
+		if err := p.startServer(ctx, server); err != nil {
+			log.Warn("still couldn't connect to validation server", "server", server.name, "err", err)
+			remaining++
+			continue
+		}
+		log.Info("connected to validation server", "server", server.name)
+	}
+	return remaining == 0
+}
+
+func (p *validationPool) Stop() {
+	p.StopOnly()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, server := range p.servers {
+		if server.started && server.create != nil {
+			server.spawner.Stop()
+		}
+	}
+}
+
+func (p *validationPool) Name() string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var names []string
+	for _, server := range p.servers {
+		if server.started {
+			names = append(names, server.spawner.Name())
+		} else {
+			names = append(names, "(not started) "+server.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func (p *validationPool) startedServers() []*validationServer {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var servers []*validationServer
+	for _, server := range p.servers {
+		if server.started {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+// Room is the total room on all the servers, which is also what launches are spread by until it's next called
+func (p *validationPool) Room() int {
+	total := 0
+	for _, server := range p.startedServers() {
+		// Remote servers are asked over RPC, so it's done without holding the mutex
+		room := server.spawner.Room()
+		server.roomGauge.Update(int64(room))
+		p.mutex.Lock()
+		server.room = room
+		p.mutex.Unlock()
+		if room > 0 {
+			total += room
+		}
+	}
+	return total
+}
+
+// pick returns the server with the most room that hasn't been tried yet
+func (p *validationPool) pick(tried map[*validationServer]bool) *validationServer {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var best *validationServer
+	for _, server := range p.servers {
+		if !server.started || tried[server] {
+			continue
+		}
+		if best == nil || server.room > best.room {
+			best = server
+		}
+	}
+	if best != nil {
+		best.room--
+	}
+	return best
+}
+
+func (p *validationPool) Launch(input *validator.ValidationInput, moduleRoot common.Hash) validator.ValidationRun {
+	ctx, cancel := context.WithCancel(p.GetContext())
+	run := &validationPoolRun{
+		Promise: containers.NewPromise[validator.GoGlobalState](cancel),
+		root:    moduleRoot,
+	}
+	p.LaunchThread(func(context.Context) {
+		defer cancel()
+		tried := make(map[*validationServer]bool)
+		var lastErr error
+		for {
+			server := p.pick(tried)
+			if server == nil {
+				break
+			}
+			if len(tried) > 0 {
+				validationRetriesCounter.Inc(1)
+			}
+			tried[server] = true
+			server.launchedCounter.Inc(1)
+			serverRun := server.spawner.Launch(input, moduleRoot)
+			res, err := serverRun.Await(ctx)
+			if ctx.Err() != nil {
+				// the run was cancelled, or the pool stopped, so the server needn't finish it
+				serverRun.Cancel()
+				run.ProduceError(ctx.Err())
+				return
+			}
+			if err == nil {
+				run.Produce(res)
+				return
+			}
+			server.failedCounter.Inc(1)
+			log.Warn("validation failed on server, trying another", "server", server.name, "err", err)
+			lastErr = err
+		}
+		if lastErr == nil {
+			lastErr = errors.New("no validation server available")
+		}
+		run.ProduceError(lastErr)
+	})
+	return run
+}
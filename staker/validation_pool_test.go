@@ -0,0 +1,191 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/util/containers"
+	"github.com/offchainlabs/nitro/validator"
+)
+
+type fakeValidationRun struct {
+	containers.PromiseInterface[validator.GoGlobalState]
+	root common.Hash
+}
+
+func (r *fakeValidationRun) WasmModuleRoot() common.Hash {
+	return r.root
+}
+
+// hungValidationRun never finishes, it's only cancelled
+type hungValidationRun struct {
+	cancelled chan struct{}
+	once      sync.Once
+}
+
+func (r *hungValidationRun) Ready() bool                 { return false }
+func (r *hungValidationRun) ReadyChan() chan struct{}    { return nil }
+func (r *hungValidationRun) WasmModuleRoot() common.Hash { return common.Hash{} }
+func (r *hungValidationRun) Cancel()                     { r.once.Do(func() { close(r.cancelled) }) }
+
+func (r *hungValidationRun) Await(ctx context.Context) (validator.GoGlobalState, error) {
+	<-ctx.Done()
+	return validator.GoGlobalState{}, ctx.Err()
+}
+
+func (r *hungValidationRun) Current() (validator.GoGlobalState, error) {
+	return validator.GoGlobalState{}, containers.ErrNotReady
+}
+
+type fakeValidationSpawner struct {
+	name     string
+	room     int
+	startErr error
+	// startGate, if set, is sent to as Start begins, which then waits to receive from it
+	startGate chan struct{}
+	fail      bool
+	hung      *hungValidationRun
+
+	mutex    sync.Mutex
+	launched int
+}
+
+func (s *fakeValidationSpawner) Launch(entry *validator.ValidationInput, moduleRoot common.Hash) validator.ValidationRun {
+	s.mutex.Lock()
+	s.launched++
+	s.mutex.Unlock()
+	if s.hung != nil {
+		return s.hung
+	}
+	var err error
+	if s.fail {
+		err = errors.New("validation failed on " + s.name)
+	}
+	return &fakeValidationRun{
+		PromiseInterface: containers.NewReadyPromise(validator.GoGlobalState{Batch: entry.Id}, err),
+		root:             moduleRoot,
+	}
+}
+
+func (s *fakeValidationSpawner) Start(context.Context) error {
+	if s.startGate != nil {
+		s.startGate <- struct{}{}
+		<-s.startGate
+	}
+	return s.startErr
+}
+
+func (s *fakeValidationSpawner) Stop()        {}
+func (s *fakeValidationSpawner) Name() string { return s.name }
+func (s *fakeValidationSpawner) Room() int    { return s.room }
+
+func (s *fakeValidationSpawner) launches() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.launched
+}
+
+func TestValidationPool(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	small := &fakeValidationSpawner{name: "small", room: 1}
+	large := &fakeValidationSpawner{name: "large", room: 3}
+	down := &fakeValidationSpawner{name: "down", room: 10, startErr: errors.New("connection refused")}
+	local := &fakeValidationSpawner{name: "local", room: 2}
+
+	pool := newValidationPool()
+	for _, spawner := range []*fakeValidationSpawner{small, large, down} {
+		spawner := spawner
+		pool.addRemote(spawner.name, func() validator.ValidationSpawner { return spawner })
+	}
+	pool.addLocal(LocalValidationServer)
+	if err := pool.Start(ctx); err == nil {
+		Fail(t, "started with the local validation node missing")
+	}
+
+	pool = newValidationPool()
+	for _, spawner := range []*fakeValidationSpawner{small, large, down} {
+		spawner := spawner
+		pool.addRemote(spawner.name, func() validator.ValidationSpawner { return spawner })
+	}
+	pool.addLocal(LocalValidationServer)
+	pool.setLocal(local)
+	Require(t, pool.Start(ctx))
+	defer pool.Stop()
+
+	// Servers that couldn't be started don't count
+	if room := pool.Room(); room != 6 {
+		Fail(t, "unexpected room", room)
+	}
+
+	// Launches are spread by room
+	for i := uint64(0); i < 6; i++ {
+		res, err := pool.Launch(&validator.ValidationInput{Id: i}, common.Hash{}).Await(ctx)
+		Require(t, err)
+		if res.Batch != i {
+			Fail(t, "unexpected result", res)
+		}
+	}
+	if small.launches() != 1 || large.launches() != 3 || local.launches() != 2 || down.launches() != 0 {
+		Fail(t, "launches not spread by room", small.launches(), large.launches(), local.launches(), down.launches())
+	}
+
+	// Failed runs are retried on other servers
+	large.fail = true
+	pool.Room()
+	_, err := pool.Launch(&validator.ValidationInput{Id: 7}, common.Hash{}).Await(ctx)
+	Require(t, err)
+	if large.launches() != 4 || local.launches() != 3 {
+		Fail(t, "failed run not retried", large.launches(), local.launches())
+	}
+
+	// And fail once every server has
+	small.fail = true
+	local.fail = true
+	pool.Room()
+	if _, err := pool.Launch(&validator.ValidationInput{Id: 8}, common.Hash{}).Await(ctx); err == nil {
+		Fail(t, "run succeeded with every server failing")
+	}
+
+	// Servers that couldn't be started are retried
+	down.startErr = nil
+	if !pool.restartServers(ctx) {
+		Fail(t, "server not restarted")
+	}
+	if room := pool.Room(); room != 16 {
+		Fail(t, "unexpected room after restart", room)
+	}
+}
+
+func TestValidationPoolCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hung := &fakeValidationSpawner{name: "hung", room: 1, hung: &hungValidationRun{cancelled: make(chan struct{})}}
+	pool := newValidationPool()
+	pool.addRemote(hung.name, func() validator.ValidationSpawner { return hung })
+	Require(t, pool.Start(ctx))
+	defer pool.Stop()
+
+	// Cancelling a run cancels it on the server
+	pool.Room()
+	run := pool.Launch(&validator.ValidationInput{Id: 1}, common.Hash{})
+	run.Cancel()
+	select {
+	case <-hung.hung.cancelled:
+	case <-time.After(time.Second):
+		Fail(t, "run not cancelled on the server")
+	}
+	if _, err := run.Await(ctx); err == nil {
+		Fail(t, "cancelled run succeeded")
+	}
+}
@@ -37,9 +37,14 @@ var ErrMissingSignature = fmt.Errorf("%w: signature not found", ErrSignatureNotV
 var ErrSignerNotApproved = fmt.Errorf("%w: signer not approved", ErrSignatureNotVerified)
 
 func FeedVerifierConfigAddOptions(prefix string, f *flag.FlagSet) {
-	f.StringArray(prefix+".allowed-addresses", DefultFeedVerifierConfig.AllowedAddresses, "a list of allowed addresses")
-	f.Bool(prefix+".accept-sequencer", DefultFeedVerifierConfig.AcceptSequencer, "accept verified message from sequencer")
-	DangerousFeedVerifierConfigAddOptions(prefix+".dangerous", f)
+	VerifierConfigAddOptions(prefix, f, &DefultFeedVerifierConfig)
+}
+
+// VerifierConfigAddOptions adds the verifier options with other defaults than the feed's
+func VerifierConfigAddOptions(prefix string, f *flag.FlagSet, defaults *VerifierConfig) {
+	f.StringArray(prefix+".allowed-addresses", defaults.AllowedAddresses, "a list of allowed addresses")
+	f.Bool(prefix+".accept-sequencer", defaults.AcceptSequencer, "accept verified message from sequencer")
+	f.Bool(prefix+".dangerous.accept-missing", defaults.Dangerous.AcceptMissing, "accept empty as valid signature")
 }
 
 func DangerousFeedVerifierConfigAddOptions(prefix string, f *flag.FlagSet) {
@@ -78,6 +83,12 @@ func NewVerifier(config *VerifierConfig, bpValidator contracts.BatchPosterVerifi
 	}, nil
 }
 
+// IsAllowedAddress is whether the address is one of the configured allowed addresses
+func (v *Verifier) IsAllowedAddress(addr common.Address) bool {
+	_, exists := v.authorizedMap[addr]
+	return exists
+}
+
 func (v *Verifier) VerifyHash(ctx context.Context, signature []byte, hash common.Hash) error {
 	return v.verifyClosure(ctx, signature, hash)
 }
@@ -0,0 +1,207 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// Package inputstore persists recorded validation inputs, keyed by the hash of the block they validate,
+// so they can be shared between validators and tools instead of recording the block again.
+package inputstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/validator"
+	"github.com/offchainlabs/nitro/validator/server_api"
+)
+
+var (
+	ErrNotFound = errors.New("validation input not found")
+	ErrInvalid  = errors.New("invalid validation input")
+)
+
+type Store interface {
+	// Get returns the input for validating the block, or ErrNotFound
+	Get(ctx context.Context, blockHash common.Hash) (*validator.ValidationInput, error)
+	Put(ctx context.Context, blockHash common.Hash, input *validator.ValidationInput) error
+	String() string
+}
+
+type Config struct {
+	Enable    bool     `koanf:"enable"`
+	Directory string   `koanf:"directory"`
+	S3        S3Config `koanf:"s3"`
+}
+
+var DefaultConfig = Config{
+	Enable:    false,
+	Directory: "",
+	S3:        DefaultS3Config,
+}
+
+func ConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultConfig.Enable, "store recorded validation inputs and use them instead of recording blocks again")
+	f.String(prefix+".directory", DefaultConfig.Directory, "local directory to store validation inputs in")
+	S3ConfigAddOptions(prefix+".s3", f)
+}
+
+func (c *Config) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.Directory == "" && !c.S3.Enable {
+		return errors.New("validation input store enabled without a directory or s3 bucket")
+	}
+	if c.S3.Enable && c.S3.Bucket == "" {
+		return errors.New("validation input store s3 bucket must be set")
+	}
+	return nil
+}
+
+// NewStore creates the configured store, or returns nil if it isn't enabled.
+// With both a directory and S3, inputs are read from the directory first and written to both.
+func NewStore(ctx context.Context, config *Config) (Store, error) {
+	if !config.Enable {
+		return nil, nil
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	var stores []Store
+	if config.Directory != "" {
+		store, err := NewLocalStore(config.Directory)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, store)
+	}
+	if config.S3.Enable {
+		store, err := NewS3Store(ctx, &config.S3)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, store)
+	}
+	if len(stores) == 1 {
+		return stores[0], nil
+	}
+	return &tieredStore{stores}, nil
+}
+
+func encodeInput(input *validator.ValidationInput) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(writer).Encode(server_api.ValidationInputToJson(input)); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeInput(data []byte) (*validator.ValidationInput, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	var inputJson server_api.ValidationInputJson
+	if err := json.NewDecoder(reader).Decode(&inputJson); err != nil {
+		return nil, err
+	}
+	return server_api.ValidationInputFromJson(&inputJson)
+}
+
+func storeKey(blockHash common.Hash) string {
+	return strings.TrimPrefix(blockHash.Hex(), "0x") + ".json.gz"
+}
+
+// GetChecked returns the input for validating the block if it passes the check, or an ErrInvalid error.
+// Anyone with access to a store could have written the input, so tiered stores only fill it in to the
+// stores before the one it was read from once it has passed.
+func GetChecked(ctx context.Context, store Store, blockHash common.Hash, check func(*validator.ValidationInput) error) (*validator.ValidationInput, error) {
+	if tiered, ok := store.(*tieredStore); ok {
+		return tiered.getChecked(ctx, blockHash, check)
+	}
+	input, err := store.Get(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if err := check(input); err != nil {
+		return nil, fmt.Errorf("%w from %v: %v", ErrInvalid, store, err)
+	}
+	return input, nil
+}
+
+// tieredStore reads from the first store that has an input, and writes to all of them.
+// Inputs read with GetChecked are filled in to the stores before the one they were read from.
+type tieredStore struct {
+	stores []Store
+}
+
+// Get returns the input from the first store that has it, without filling it in to the stores before it
+func (s *tieredStore) Get(ctx context.Context, blockHash common.Hash) (*validator.ValidationInput, error) {
+	return s.getChecked(ctx, blockHash, nil)
+}
+
+// getChecked returns the input from the first store that has one passing the check, filling it in to the
+// stores before it if there's a check. An invalid input in one store doesn't hide a valid one in the next.
+func (s *tieredStore) getChecked(ctx context.Context, blockHash common.Hash, check func(*validator.ValidationInput) error) (*validator.ValidationInput, error) {
+	var invalid error
+	for i, store := range s.stores {
+		input, err := store.Get(ctx, blockHash)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Warn("failed to get validation input", "store", store, "blockHash", blockHash, "err", err)
+			continue
+		}
+		if check == nil {
+			return input, nil
+		}
+		if err := check(input); err != nil {
+			invalid = fmt.Errorf("%w from %v: %v", ErrInvalid, store, err)
+			continue
+		}
+		for _, earlier := range s.stores[:i] {
+			if err := earlier.Put(ctx, blockHash, input); err != nil {
+				log.Warn("failed to fill in validation input", "store", earlier, "blockHash", blockHash, "err", err)
+			}
+		}
+		return input, nil
+	}
+	if invalid != nil {
+		return nil, invalid
+	}
+	return nil, ErrNotFound
+}
+
+func (s *tieredStore) Put(ctx context.Context, blockHash common.Hash, input *validator.ValidationInput) error {
+	var errs []string
+	for _, store := range s.stores {
+		if err := store.Put(ctx, blockHash, input); err != nil {
+			errs = append(errs, fmt.Sprintf("%v: %v", store, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to store validation input: %v", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (s *tieredStore) String() string {
+	var names []string
+	for _, store := range s.stores {
+		names = append(names, store.String())
+	}
+	return "TieredStore(" + strings.Join(names, ", ") + ")"
+}
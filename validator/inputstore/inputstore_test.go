@@ -0,0 +1,143 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package inputstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/offchainlabs/nitro/util/testhelpers"
+	"github.com/offchainlabs/nitro/validator"
+)
+
+type memoryS3Client struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+}
+
+func (c *memoryS3Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	data, ok := c.objects[*params.Bucket+"/"+*params.Key]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+func (c *memoryS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.objects[*params.Bucket+"/"+*params.Key] = data
+	return &s3.PutObjectOutput{}, nil
+}
+
+func testInput(id uint64) *validator.ValidationInput {
+	preimage := []byte{byte(id), 1, 2, 3}
+	return &validator.ValidationInput{
+		Id:            id,
+		HasDelayedMsg: true,
+		DelayedMsgNr:  7,
+		Preimages:     map[common.Hash][]byte{crypto.Keccak256Hash(preimage): preimage},
+		BatchInfo:     []validator.BatchInfo{{Number: 3, Data: []byte("batch")}},
+		DelayedMsg:    []byte("delayed"),
+		StartState:    validator.GoGlobalState{BlockHash: common.HexToHash("0x1234"), Batch: 3, PosInBatch: 1},
+	}
+}
+
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+	blockHash := common.HexToHash("0xabcd")
+	if _, err := store.Get(ctx, blockHash); !errors.Is(err, ErrNotFound) {
+		testhelpers.FailImpl(t, "expected not found, got", err)
+	}
+	input := testInput(5)
+	testhelpers.RequireImpl(t, store.Put(ctx, blockHash, input))
+	stored, err := store.Get(ctx, blockHash)
+	testhelpers.RequireImpl(t, err)
+	if !reflect.DeepEqual(input, stored) {
+		testhelpers.FailImpl(t, "stored input", stored, "differs from", input)
+	}
+	// Storing again replaces the input
+	testhelpers.RequireImpl(t, store.Put(ctx, blockHash, testInput(6)))
+	stored, err = store.Get(ctx, blockHash)
+	testhelpers.RequireImpl(t, err)
+	if stored.Id != 6 {
+		testhelpers.FailImpl(t, "input not replaced")
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	testhelpers.RequireImpl(t, err)
+	testStore(t, store)
+}
+
+func TestS3Store(t *testing.T) {
+	testStore(t, NewS3StoreWithClient(&memoryS3Client{objects: make(map[string][]byte)}, "bucket", "inputs/"))
+}
+
+func TestTieredStore(t *testing.T) {
+	ctx := context.Background()
+	local, err := NewLocalStore(t.TempDir())
+	testhelpers.RequireImpl(t, err)
+	remote := NewS3StoreWithClient(&memoryS3Client{objects: make(map[string][]byte)}, "bucket", "")
+	store := &tieredStore{[]Store{local, remote}}
+	testStore(t, store)
+
+	// Inputs only in the shared store are only filled in locally once they pass the check
+	blockHash := common.HexToHash("0xef01")
+	testhelpers.RequireImpl(t, remote.Put(ctx, blockHash, testInput(8)))
+	_, err = store.Get(ctx, blockHash)
+	testhelpers.RequireImpl(t, err)
+	if _, err := local.Get(ctx, blockHash); !errors.Is(err, ErrNotFound) {
+		testhelpers.FailImpl(t, "unchecked input filled in locally", err)
+	}
+	reject := func(*validator.ValidationInput) error { return errors.New("bad batch") }
+	if _, err := GetChecked(ctx, store, blockHash, reject); !errors.Is(err, ErrInvalid) {
+		testhelpers.FailImpl(t, "expected an invalid input, got", err)
+	}
+	if _, err := local.Get(ctx, blockHash); !errors.Is(err, ErrNotFound) {
+		testhelpers.FailImpl(t, "invalid input filled in locally", err)
+	}
+	accept := func(*validator.ValidationInput) error { return nil }
+	_, err = GetChecked(ctx, store, blockHash, accept)
+	testhelpers.RequireImpl(t, err)
+	stored, err := local.Get(ctx, blockHash)
+	testhelpers.RequireImpl(t, err)
+	if stored.Id != 8 {
+		testhelpers.FailImpl(t, "input not filled in locally")
+	}
+
+	// An invalid local input doesn't hide a valid shared one
+	testhelpers.RequireImpl(t, local.Put(ctx, blockHash, testInput(9)))
+	stored, err = GetChecked(ctx, store, blockHash, func(input *validator.ValidationInput) error {
+		if input.Id != 8 {
+			return errors.New("wrong block")
+		}
+		return nil
+	})
+	testhelpers.RequireImpl(t, err)
+	if stored.Id != 8 {
+		testhelpers.FailImpl(t, "invalid local input used")
+	}
+	if stored, err = local.Get(ctx, blockHash); err != nil || stored.Id != 8 {
+		testhelpers.FailImpl(t, "invalid local input not replaced", err)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package inputstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/validator"
+)
+
+// LocalStore keeps inputs in a directory, one file per block in subdirectories by the first byte of the block hash
+type LocalStore struct {
+	directory string
+}
+
+func NewLocalStore(directory string) (*LocalStore, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+	return &LocalStore{directory}, nil
+}
+
+func (s *LocalStore) path(blockHash common.Hash) string {
+	key := storeKey(blockHash)
+	return filepath.Join(s.directory, key[:2], key)
+}
+
+func (s *LocalStore) Get(ctx context.Context, blockHash common.Hash) (*validator.ValidationInput, error) {
+	data, err := os.ReadFile(s.path(blockHash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeInput(data)
+}
+
+func (s *LocalStore) Put(ctx context.Context, blockHash common.Hash, input *validator.ValidationInput) error {
+	data, err := encodeInput(input)
+	if err != nil {
+		return err
+	}
+	path := s.path(blockHash)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Written to a temporary file and renamed so readers never see a partial input
+	file, err := os.CreateTemp(filepath.Dir(path), "input-*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStore) String() string {
+	return fmt.Sprintf("LocalStore(%v)", s.directory)
+}
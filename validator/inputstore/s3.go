@@ -0,0 +1,135 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package inputstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/ethereum/go-ethereum/common"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/validator"
+)
+
+type S3Config struct {
+	Enable       bool   `koanf:"enable"`
+	Bucket       string `koanf:"bucket"`
+	ObjectPrefix string `koanf:"object-prefix"`
+	Region       string `koanf:"region"`
+	Endpoint     string `koanf:"endpoint"`
+	AccessKey    string `koanf:"access-key"`
+	SecretKey    string `koanf:"secret-key"`
+}
+
+var DefaultS3Config = S3Config{}
+
+func S3ConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultS3Config.Enable, "store validation inputs in an S3 bucket")
+	f.String(prefix+".bucket", DefaultS3Config.Bucket, "S3 bucket")
+	f.String(prefix+".object-prefix", DefaultS3Config.ObjectPrefix, "prefix to add to S3 objects")
+	f.String(prefix+".region", DefaultS3Config.Region, "S3 region")
+	f.String(prefix+".endpoint", DefaultS3Config.Endpoint, "url of an S3 compatible service to use instead of AWS")
+	f.String(prefix+".access-key", DefaultS3Config.AccessKey, "S3 access key")
+	f.String(prefix+".secret-key", DefaultS3Config.SecretKey, "S3 secret key")
+}
+
+// S3Client is the part of the S3 client used by S3Store
+type S3Client interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+type S3Store struct {
+	client       S3Client
+	bucket       string
+	objectPrefix string
+}
+
+func NewS3Store(ctx context.Context, config *S3Config) (*S3Store, error) {
+	client, err := buildS3Client(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return NewS3StoreWithClient(client, config.Bucket, config.ObjectPrefix), nil
+}
+
+func NewS3StoreWithClient(client S3Client, bucket string, objectPrefix string) *S3Store {
+	return &S3Store{
+		client:       client,
+		bucket:       bucket,
+		objectPrefix: objectPrefix,
+	}
+}
+
+func buildS3Client(ctx context.Context, config *S3Config) (*s3.Client, error) {
+	cfg, err := awsConfig.LoadDefaultConfig(ctx, awsConfig.WithRegion(config.Region), func(options *awsConfig.LoadOptions) error {
+		if config.AccessKey != "" && config.SecretKey != "" {
+			options.Credentials = credentials.NewStaticCredentialsProvider(config.AccessKey, config.SecretKey, "")
+		}
+		if config.Endpoint != "" {
+			options.EndpointResolverWithOptions = aws.EndpointResolverWithOptionsFunc(
+				func(service, region string, _ ...interface{}) (aws.Endpoint, error) {
+					return aws.Endpoint{URL: config.Endpoint, SigningRegion: region, HostnameImmutable: true}, nil
+				},
+			)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(cfg, func(options *s3.Options) {
+		// S3 compatible services usually don't support virtual hosted buckets
+		options.UsePathStyle = config.Endpoint != ""
+	}), nil
+}
+
+func (s *S3Store) key(blockHash common.Hash) *string {
+	return aws.String(s.objectPrefix + storeKey(blockHash))
+}
+
+func (s *S3Store) Get(ctx context.Context, blockHash common.Hash) (*validator.ValidationInput, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    s.key(blockHash),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(output.Body); err != nil {
+		return nil, err
+	}
+	return decodeInput(buf.Bytes())
+}
+
+func (s *S3Store) Put(ctx context.Context, blockHash common.Hash, input *validator.ValidationInput) error {
+	data, err := encodeInput(input)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    s.key(blockHash),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+func (s *S3Store) String() string {
+	return fmt.Sprintf("S3Store(%v/%v)", s.bucket, s.objectPrefix)
+}
@@ -125,3 +125,11 @@ func (v *ValidationNode) Start(ctx context.Context) error {
 func (v *ValidationNode) GetExec() validator.ExecutionSpawner {
 	return v.arbSpawner
 }
+
+// GetValidationSpawner returns the jit spawner if it's enabled, and the arbitrator spawner otherwise
+func (v *ValidationNode) GetValidationSpawner() validator.ValidationSpawner {
+	if v.jitSpawner != nil {
+		return v.jitSpawner
+	}
+	return v.arbSpawner
+}
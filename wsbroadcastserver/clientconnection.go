@@ -40,6 +40,8 @@ type ClientConnection struct {
 	compression bool
 	flateReader *wsflate.Reader
 
+	filter ClientFilter
+
 	delay time.Duration
 }
 
@@ -50,6 +52,7 @@ func NewClientConnection(
 	requestedSeqNum arbutil.MessageIndex,
 	connectingIP net.IP,
 	compression bool,
+	filter ClientFilter,
 	delay time.Duration,
 ) *ClientConnection {
 	return &ClientConnection{
@@ -64,6 +67,7 @@ func NewClientConnection(
 		out:             make(chan []byte, clientManager.config().MaxSendQueue),
 		compression:     compression,
 		flateReader:     NewFlateReader(),
+		filter:          filter,
 		delay:           delay,
 	}
 }
@@ -76,6 +80,11 @@ func (cc *ClientConnection) Compression() bool {
 	return cc.compression
 }
 
+// Filter returns what the client subscribed to, or nil if it gets the full feed
+func (cc *ClientConnection) Filter() ClientFilter {
+	return cc.filter
+}
+
 func (cc *ClientConnection) Start(parentCtx context.Context) {
 	cc.StopWaiter.Start(parentCtx, cc)
 	cc.LaunchThread(func(ctx context.Context) {
@@ -161,11 +170,27 @@ func (cc *ClientConnection) readRequest(ctx context.Context, timeout time.Durati
 	return data, opCode, err
 }
 
+// Write sends the client a message, applying the client's filter if it has one
 func (cc *ClientConnection) Write(x interface{}) error {
+	if cc.filter != nil {
+		feedFilter := cc.clientManager.feedFilter
+		prepared, err := feedFilter.Prepare(x)
+		if err != nil {
+			return err
+		}
+		x = feedFilter.Apply(prepared, cc.filter)
+		if x == nil {
+			return nil
+		}
+	}
+	return cc.write(x)
+}
+
+func (cc *ClientConnection) write(x interface{}) error {
 	cc.ioMutex.Lock()
 	defer cc.ioMutex.Unlock()
 
-	notCompressed, compressed, err := serializeMessage(cc.clientManager, x, !cc.compression, cc.compression)
+	notCompressed, compressed, err := serializeMessage(&cc.clientManager.flateWriter, x, !cc.compression, cc.compression)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,149 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package wsbroadcastserver
+
+import (
+	"compress/flate"
+	"context"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	clientsFilteredGauge          = metrics.NewRegisteredGauge("arb/feed/clients/filtered", nil)
+	filteredBroadcastDroppedCount = metrics.NewRegisteredCounter("arb/feed/clients/filtered/dropped", nil)
+)
+
+// ClientFilter is what a client subscribed to, in place of the full feed.
+// Clients whose filters have equal keys are sent the same messages.
+type ClientFilter interface {
+	Key() string
+}
+
+// FeedFilter is a Protocol-specific way for clients to subscribe to part of the feed.
+// Filtered clients are served from their own thread, so they don't slow down clients of the full feed.
+type FeedFilter interface {
+	// ParseFilter parses the filter a client sent in the HTTPHeaderFeedFilter header
+	ParseFilter(value []byte) (ClientFilter, error)
+	// Prepare does the work common to every filter once per broadcast message, it must be thread safe
+	Prepare(bm interface{}) (interface{}, error)
+	// Apply returns what clients with the filter are sent for a prepared message, or nil if nothing
+	Apply(prepared interface{}, filter ClientFilter) interface{}
+	// Heartbeat returns what filtered clients are periodically sent to know how far the feed has got,
+	// or nil if there's nothing to report yet. It's called from the ClientManager thread.
+	Heartbeat() interface{}
+}
+
+// filterJob is either a broadcast message or a heartbeat for the filtered clients connected at the time
+type filterJob struct {
+	bm        interface{}
+	heartbeat interface{}
+	clients   []*ClientConnection
+}
+
+const filterQueueSize = 1024
+
+// queueFiltered hands a message to the filter thread, returning the clients to disconnect if it's too far behind
+func (cm *ClientManager) queueFiltered(bm interface{}, heartbeat interface{}) []*ClientConnection {
+	clients := make([]*ClientConnection, 0, len(cm.filteredClients))
+	for client := range cm.filteredClients {
+		clients = append(clients, client)
+	}
+	select {
+	case cm.filterChan <- filterJob{bm, heartbeat, clients}:
+		return nil
+	default:
+		// Disconnect rather than block clients of the full feed, filtered clients will catch up when they reconnect
+		log.Warn("disconnecting filtered clients because filter queue too large", "count", len(clients))
+		filteredBroadcastDroppedCount.Inc(1)
+		return clients
+	}
+}
+
+func (cm *ClientManager) startFilterThread() {
+	cm.LaunchThread(func(ctx context.Context) {
+		var flateWriter *flate.Writer
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case job := <-cm.filterChan:
+				cm.doFilteredBroadcast(ctx, &flateWriter, job)
+			}
+		}
+	})
+}
+
+type filteredMessage struct {
+	notCompressed []byte
+	compressed    []byte
+	empty         bool
+}
+
+func (cm *ClientManager) doFilteredBroadcast(ctx context.Context, flateWriter **flate.Writer, job filterJob) {
+	config := cm.config()
+	var prepared interface{}
+	if job.bm != nil {
+		var err error
+		prepared, err = cm.feedFilter.Prepare(job.bm)
+		if err != nil {
+			log.Warn("failed to prepare message for filtered clients", "err", err)
+			return
+		}
+	}
+
+	remove := func(client *ClientConnection) {
+		select {
+		case <-ctx.Done():
+		case cm.clientAction <- ClientConnectionAction{client, false}:
+		}
+	}
+
+	// Each distinct filter is only applied and serialized once
+	messages := make(map[string]*filteredMessage)
+	sendQueueTooLargeCount := 0
+	for _, client := range job.clients {
+		key := client.filter.Key()
+		message, ok := messages[key]
+		if !ok {
+			msg := job.heartbeat
+			if job.bm != nil {
+				msg = cm.feedFilter.Apply(prepared, client.filter)
+			}
+			message = &filteredMessage{empty: msg == nil}
+			if msg != nil {
+				notCompressed, compressed, err := serializeMessage(flateWriter, msg, !config.RequireCompression, config.EnableCompression)
+				if err != nil {
+					log.Warn("failed to serialize filtered message", "err", err)
+					return
+				}
+				message.notCompressed = notCompressed.Bytes()
+				message.compressed = compressed.Bytes()
+			}
+			messages[key] = message
+		}
+		if message.empty {
+			continue
+		}
+		data := message.notCompressed
+		if client.Compression() {
+			data = message.compressed
+		}
+		if len(data) == 0 {
+			log.Warn("disconnecting because client compression doesn't match compression support", "client", client.Name)
+			remove(client)
+			continue
+		}
+		select {
+		case client.out <- data:
+		default:
+			sendQueueTooLargeCount++
+			remove(client)
+		}
+	}
+	if sendQueueTooLargeCount > 0 {
+		log.Warn("disconnecting filtered clients because send queue too large", "count", sendQueueTooLargeCount)
+	}
+}
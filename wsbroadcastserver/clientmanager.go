@@ -61,6 +61,10 @@ type ClientManager struct {
 	catchupBuffer CatchupBuffer
 	flateWriter   *flate.Writer
 
+	feedFilter      FeedFilter
+	filteredClients map[*ClientConnection]bool
+	filterChan      chan filterJob
+
 	connectionLimiter *ConnectionLimiter
 }
 
@@ -69,7 +73,7 @@ type ClientConnectionAction struct {
 	create bool
 }
 
-func NewClientManager(poller netpoll.Poller, configFetcher BroadcasterConfigFetcher, catchupBuffer CatchupBuffer) *ClientManager {
+func NewClientManager(poller netpoll.Poller, configFetcher BroadcasterConfigFetcher, catchupBuffer CatchupBuffer, feedFilter FeedFilter) *ClientManager {
 	config := configFetcher()
 	return &ClientManager{
 		poller:            poller,
@@ -79,6 +83,9 @@ func NewClientManager(poller netpoll.Poller, configFetcher BroadcasterConfigFetc
 		clientAction:      make(chan ClientConnectionAction, 128),
 		config:            configFetcher,
 		catchupBuffer:     catchupBuffer,
+		feedFilter:        feedFilter,
+		filteredClients:   make(map[*ClientConnection]bool),
+		filterChan:        make(chan filterJob, filterQueueSize),
 		connectionLimiter: NewConnectionLimiter(func() *ConnectionLimiterConfig { return &configFetcher().ConnectionLimits }),
 	}
 }
@@ -107,11 +114,21 @@ func (cm *ClientManager) registerClient(ctx context.Context, clientConnection *C
 		return err
 	}
 	if cm.config().LogConnect {
-		log.Info("client registered", "client", clientConnection.Name, "requestedSeqNum", clientConnection.RequestedSeqNum(), "sentCount", sent, "elapsed", elapsed)
+		log.Info("client registered", "client", clientConnection.Name, "requestedSeqNum", clientConnection.RequestedSeqNum(), "filtered", clientConnection.filter != nil, "sentCount", sent, "elapsed", elapsed)
 	}
 
 	clientConnection.Start(ctx)
 	cm.clientPtrMap[clientConnection] = true
+	if clientConnection.filter != nil {
+		// Let the client know where the feed is, even if nothing in the catchup buffer matched
+		if heartbeat := cm.feedFilter.Heartbeat(); heartbeat != nil {
+			if err := clientConnection.write(heartbeat); err != nil {
+				log.Warn("error sending filtered client heartbeat", "client", clientConnection.Name, "err", err)
+			}
+		}
+		cm.filteredClients[clientConnection] = true
+		clientsFilteredGauge.Inc(1)
+	}
 	clientsTotalSuccessCounter.Inc(1)
 
 	return nil
@@ -124,9 +141,10 @@ func (cm *ClientManager) Register(
 	requestedSeqNum arbutil.MessageIndex,
 	connectingIP net.IP,
 	compression bool,
+	filter ClientFilter,
 ) *ClientConnection {
 	createClient := ClientConnectionAction{
-		NewClientConnection(conn, desc, cm, requestedSeqNum, connectingIP, compression, cm.config().ClientDelay),
+		NewClientConnection(conn, desc, cm, requestedSeqNum, connectingIP, compression, filter, cm.config().ClientDelay),
 		true,
 	}
 	cm.clientAction <- createClient
@@ -176,6 +194,10 @@ func (cm *ClientManager) removeClient(clientConnection *ClientConnection) {
 	}
 
 	delete(cm.clientPtrMap, clientConnection)
+	if cm.filteredClients[clientConnection] {
+		delete(cm.filteredClients, clientConnection)
+		clientsFilteredGauge.Dec(1)
+	}
 }
 
 func (cm *ClientManager) Remove(clientConnection *ClientConnection) {
@@ -209,7 +231,7 @@ func (cm *ClientManager) doBroadcast(bm interface{}) ([]*ClientConnection, error
 	// bm -> json.Encoder -> io.MultiWriter -|
 	//                                        \-> cm.flateWriter -> wsutil.Writer -> compressed msg buffer
 
-	notCompressed, compressed, err := serializeMessage(cm, bm, !config.RequireCompression, config.EnableCompression)
+	notCompressed, compressed, err := serializeMessage(&cm.flateWriter, bm, !config.RequireCompression, config.EnableCompression)
 	if err != nil {
 		return nil, err
 	}
@@ -217,6 +239,9 @@ func (cm *ClientManager) doBroadcast(bm interface{}) ([]*ClientConnection, error
 	sendQueueTooLargeCount := 0
 	clientDeleteList := make([]*ClientConnection, 0, len(cm.clientPtrMap))
 	for client := range cm.clientPtrMap {
+		if client.filter != nil {
+			continue
+		}
 		var data []byte
 		if client.Compression() {
 			if config.EnableCompression {
@@ -252,10 +277,14 @@ func (cm *ClientManager) doBroadcast(bm interface{}) ([]*ClientConnection, error
 		}
 	}
 
+	if len(cm.filteredClients) > 0 {
+		clientDeleteList = append(clientDeleteList, cm.queueFiltered(bm, nil)...)
+	}
+
 	return clientDeleteList, nil
 }
 
-func serializeMessage(cm *ClientManager, bm interface{}, enableNonCompressedOutput, enableCompressedOutput bool) (bytes.Buffer, bytes.Buffer, error) {
+func serializeMessage(flateWriter **flate.Writer, bm interface{}, enableNonCompressedOutput, enableCompressedOutput bool) (bytes.Buffer, bytes.Buffer, error) {
 	var notCompressed bytes.Buffer
 	var compressed bytes.Buffer
 	writers := []io.Writer{}
@@ -266,9 +295,9 @@ func serializeMessage(cm *ClientManager, bm interface{}, enableNonCompressedOutp
 		writers = append(writers, notCompressedWriter)
 	}
 	if enableCompressedOutput {
-		if cm.flateWriter == nil {
+		if *flateWriter == nil {
 			var err error
-			cm.flateWriter, err = flate.NewWriterDict(nil, DeflateCompressionLevel, GetStaticCompressorDictionary())
+			*flateWriter, err = flate.NewWriterDict(nil, DeflateCompressionLevel, GetStaticCompressorDictionary())
 			if err != nil {
 				return bytes.Buffer{}, bytes.Buffer{}, errors.Wrap(err, "unable to create flate writer")
 			}
@@ -277,8 +306,8 @@ func serializeMessage(cm *ClientManager, bm interface{}, enableNonCompressedOutp
 		var msg wsflate.MessageState
 		msg.SetCompressed(true)
 		compressedWriter.SetExtensions(&msg)
-		cm.flateWriter.Reset(compressedWriter)
-		writers = append(writers, cm.flateWriter)
+		(*flateWriter).Reset(compressedWriter)
+		writers = append(writers, *flateWriter)
 	}
 
 	multiWriter := io.MultiWriter(writers...)
@@ -292,7 +321,7 @@ func serializeMessage(cm *ClientManager, bm interface{}, enableNonCompressedOutp
 		}
 	}
 	if compressedWriter != nil {
-		if err := cm.flateWriter.Close(); err != nil {
+		if err := (*flateWriter).Close(); err != nil {
 			return bytes.Buffer{}, bytes.Buffer{}, errors.Wrap(err, "unable to close flate writer")
 		}
 		if err := compressedWriter.Flush(); err != nil {
@@ -328,9 +357,22 @@ func (cm *ClientManager) verifyClients() []*ClientConnection {
 	return clientDeleteList
 }
 
+func (cm *ClientManager) heartbeatInterval() time.Duration {
+	config := cm.config()
+	if config.FilterHeartbeat <= 0 {
+		// Not validated, don't spin
+		return config.Ping
+	}
+	return config.FilterHeartbeat
+}
+
 func (cm *ClientManager) Start(parentCtx context.Context) {
 	cm.StopWaiter.Start(parentCtx, cm)
 
+	if cm.feedFilter != nil {
+		cm.startFilterThread()
+	}
+
 	cm.LaunchThread(func(ctx context.Context) {
 		defer cm.removeAll()
 
@@ -338,6 +380,10 @@ func (cm *ClientManager) Start(parentCtx context.Context) {
 		pingTimer := time.NewTimer(cm.config().Ping)
 		var clientDeleteList []*ClientConnection
 		defer pingTimer.Stop()
+
+		// As do heartbeats to filtered clients, which may not be sent anything else for a while
+		heartbeatTimer := time.NewTimer(cm.heartbeatInterval())
+		defer heartbeatTimer.Stop()
 		for {
 			select {
 			case <-ctx.Done():
@@ -359,6 +405,13 @@ func (cm *ClientManager) Start(parentCtx context.Context) {
 			case <-pingTimer.C:
 				clientDeleteList = cm.verifyClients()
 				pingTimer.Reset(cm.config().Ping)
+			case <-heartbeatTimer.C:
+				if len(cm.filteredClients) > 0 {
+					if heartbeat := cm.feedFilter.Heartbeat(); heartbeat != nil {
+						clientDeleteList = cm.queueFiltered(nil, heartbeat)
+					}
+				}
+				heartbeatTimer.Reset(cm.heartbeatInterval())
 			}
 
 			if len(clientDeleteList) > 0 {
@@ -32,6 +32,8 @@ var (
 	HTTPHeaderFeedClientVersion       = textproto.CanonicalMIMEHeaderKey("Arbitrum-Feed-Client-Version")
 	HTTPHeaderRequestedSequenceNumber = textproto.CanonicalMIMEHeaderKey("Arbitrum-Requested-Sequence-Number")
 	HTTPHeaderChainId                 = textproto.CanonicalMIMEHeaderKey("Arbitrum-Chain-Id")
+	HTTPHeaderFeedFilter              = textproto.CanonicalMIMEHeaderKey("Arbitrum-Feed-Filter")
+	HTTPHeaderFeedSigner              = textproto.CanonicalMIMEHeaderKey("Arbitrum-Feed-Signer")
 )
 
 const (
@@ -62,15 +64,47 @@ type BroadcasterConfig struct {
 	LimitCatchup       bool                    `koanf:"limit-catchup" reload:"hot"`
 	ConnectionLimits   ConnectionLimiterConfig `koanf:"connection-limits" reload:"hot"`
 	ClientDelay        time.Duration           `koanf:"client-delay" reload:"hot"`
+	EnableFilters      bool                    `koanf:"enable-filters" reload:"hot"`   // reloaded value will affect only new connections
+	FilterHeartbeat    time.Duration           `koanf:"filter-heartbeat" reload:"hot"` // reloaded value will change future heartbeat intervals
+	Backlog            BacklogConfig           `koanf:"backlog"`
 }
 
 func (bc *BroadcasterConfig) Validate() error {
 	if !bc.EnableCompression && bc.RequireCompression {
 		return errors.New("require-compression cannot be true while enable-compression is false")
 	}
+	if bc.FilterHeartbeat <= 0 {
+		return errors.New("filter-heartbeat must be positive")
+	}
+	if bc.Backlog.Enable && bc.Backlog.Directory == "" {
+		return errors.New("backlog.directory must be set when backlog is enabled")
+	}
+	if bc.Backlog.Enable && bc.Backlog.Retention <= 0 {
+		return errors.New("backlog.retention must be positive")
+	}
 	return nil
 }
 
+// BacklogConfig is for keeping the catchup backlog on disk, so that it survives restarts
+// and clients can catch up from further back than what's unconfirmed
+type BacklogConfig struct {
+	Enable    bool          `koanf:"enable"`
+	Directory string        `koanf:"directory"`
+	Retention time.Duration `koanf:"retention" reload:"hot"` // reloaded value will affect the next pruning
+}
+
+var DefaultBacklogConfig = BacklogConfig{
+	Enable:    false,
+	Directory: "",
+	Retention: 24 * time.Hour,
+}
+
+func BacklogConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultBacklogConfig.Enable, "keep the catchup backlog on disk, so it's still served after a restart")
+	f.String(prefix+".directory", DefaultBacklogConfig.Directory, "directory of the leveldb database for the backlog")
+	f.Duration(prefix+".retention", DefaultBacklogConfig.Retention, "how long messages are kept in the backlog")
+}
+
 type BroadcasterConfigFetcher func() *BroadcasterConfig
 
 func BroadcasterConfigAddOptions(prefix string, f *flag.FlagSet) {
@@ -95,6 +129,9 @@ func BroadcasterConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".limit-catchup", DefaultBroadcasterConfig.LimitCatchup, "only supply catchup buffer if requested sequence number is reasonable")
 	ConnectionLimiterConfigAddOptions(prefix+".connection-limits", f)
 	f.Duration(prefix+".client-delay", DefaultBroadcasterConfig.ClientDelay, "delay the first messages sent to each client by this amount")
+	f.Bool(prefix+".enable-filters", DefaultBroadcasterConfig.EnableFilters, "allow clients to subscribe to only the transactions matching a filter sent in the "+HTTPHeaderFeedFilter+" header")
+	f.Duration(prefix+".filter-heartbeat", DefaultBroadcasterConfig.FilterHeartbeat, "duration between sequence number heartbeats sent to filtered clients")
+	BacklogConfigAddOptions(prefix+".backlog", f)
 }
 
 var DefaultBroadcasterConfig = BroadcasterConfig{
@@ -119,6 +156,9 @@ var DefaultBroadcasterConfig = BroadcasterConfig{
 	LimitCatchup:       false,
 	ConnectionLimits:   DefaultConnectionLimiterConfig,
 	ClientDelay:        0,
+	EnableFilters:      false,
+	FilterHeartbeat:    5 * time.Second,
+	Backlog:            DefaultBacklogConfig,
 }
 
 var DefaultTestBroadcasterConfig = BroadcasterConfig{
@@ -143,6 +183,9 @@ var DefaultTestBroadcasterConfig = BroadcasterConfig{
 	LimitCatchup:       false,
 	ConnectionLimits:   DefaultConnectionLimiterConfig,
 	ClientDelay:        0,
+	EnableFilters:      false,
+	FilterHeartbeat:    5 * time.Second,
+	Backlog:            DefaultBacklogConfig,
 }
 
 type WSBroadcastServer struct {
@@ -157,15 +200,18 @@ type WSBroadcastServer struct {
 	started       bool
 	clientManager *ClientManager
 	catchupBuffer CatchupBuffer
+	feedFilter    FeedFilter
 	chainId       uint64
 	fatalErrChan  chan error
 }
 
-func NewWSBroadcastServer(config BroadcasterConfigFetcher, catchupBuffer CatchupBuffer, chainId uint64, fatalErrChan chan error) *WSBroadcastServer {
+// NewWSBroadcastServer creates a server for the feed, feedFilter may be nil if clients can't filter it
+func NewWSBroadcastServer(config BroadcasterConfigFetcher, catchupBuffer CatchupBuffer, feedFilter FeedFilter, chainId uint64, fatalErrChan chan error) *WSBroadcastServer {
 	return &WSBroadcastServer{
 		config:        config,
 		started:       false,
 		catchupBuffer: catchupBuffer,
+		feedFilter:    feedFilter,
 		chainId:       chainId,
 		fatalErrChan:  fatalErrChan,
 	}
@@ -185,19 +231,27 @@ func (s *WSBroadcastServer) Initialize() error {
 
 	// Make pool of X size, Y sized work queue and one pre-spawned
 	// goroutine.
-	s.clientManager = NewClientManager(s.poller, s.config, s.catchupBuffer)
+	s.clientManager = NewClientManager(s.poller, s.config, s.catchupBuffer, s.feedFilter)
 
 	return nil
 }
 
 func (s *WSBroadcastServer) Start(ctx context.Context) error {
+	return s.StartWithExtraHeader(ctx, nil)
+}
+
+// StartWithExtraHeader starts the server, sending clients the extra headers along with the usual ones on handshake
+func (s *WSBroadcastServer) StartWithExtraHeader(ctx context.Context, extra http.Header) error {
 	// Prepare handshake header writer from http.Header mapping.
-	header := ws.HandshakeHeaderHTTP(http.Header{
+	httpHeader := http.Header{
 		HTTPHeaderFeedServerVersion: []string{strconv.Itoa(FeedServerVersion)},
 		HTTPHeaderChainId:           []string{strconv.FormatUint(s.chainId, 10)},
-	})
+	}
+	for key, values := range extra {
+		httpHeader[key] = values
+	}
 
-	return s.StartWithHeader(ctx, header)
+	return s.StartWithHeader(ctx, ws.HandshakeHeaderHTTP(httpHeader))
 }
 
 func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.HandshakeHeader) error {
@@ -241,6 +295,8 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 		var feedClientVersionSeen bool
 		var connectingIP net.IP
 		var requestedSeqNum arbutil.MessageIndex
+		var filterValue []byte
+		var filter ClientFilter
 		upgrader := ws.Upgrader{
 			OnRequest: func(uri []byte) error {
 				if strings.Contains(string(uri), LivenessProbeURI) {
@@ -276,6 +332,9 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 						)
 					}
 					requestedSeqNum = arbutil.MessageIndex(num)
+				} else if headerName == HTTPHeaderFeedFilter {
+					// value is only valid until the next header is read
+					filterValue = append([]byte(nil), value...)
 				} else if headerName == HTTPHeaderCloudflareConnectingIP {
 					connectingIP = net.ParseIP(string(value))
 					log.Trace("Client IP parsed from header", "ip", connectingIP, "header", headerName, "value", string(value))
@@ -290,6 +349,22 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 						ws.RejectionReason(fmt.Sprintf("Missing HTTP header %s", HTTPHeaderFeedClientVersion)),
 					)
 				}
+				if filterValue != nil {
+					if !config.EnableFilters || s.feedFilter == nil {
+						return nil, ws.RejectConnectionError(
+							ws.RejectionStatus(http.StatusBadRequest),
+							ws.RejectionReason("Feed filters not enabled"),
+						)
+					}
+					var err error
+					filter, err = s.feedFilter.ParseFilter(filterValue)
+					if err != nil {
+						return nil, ws.RejectConnectionError(
+							ws.RejectionStatus(http.StatusBadRequest),
+							ws.RejectionReason(fmt.Sprintf("Malformed HTTP header %s: %s", HTTPHeaderFeedFilter, err)),
+						)
+					}
+				}
 				if connectingIP == nil {
 					if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
 						connectingIP = addr.IP
@@ -358,7 +433,7 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 		// Register incoming client in clientManager.
 		safeConn := writeDeadliner{conn, config.WriteTimeout}
 
-		client := s.clientManager.Register(safeConn, desc, requestedSeqNum, connectingIP, compressionAccepted)
+		client := s.clientManager.Register(safeConn, desc, requestedSeqNum, connectingIP, compressionAccepted, filter)
 
 		// Subscribe to events about conn.
 		err = s.poller.Start(desc, func(ev netpoll.Event) {